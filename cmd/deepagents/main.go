@@ -46,6 +46,11 @@ func main() {
 		return
 	}
 
+	var thinking *llm.ThinkingConfig
+	if cfg.ThinkingBudget > 0 {
+		thinking = &llm.ThinkingConfig{BudgetTokens: cfg.ThinkingBudget}
+	}
+
 	builder := agentkit.New(
 		agentkit.WithLLM(llm.NewAnthropicClient(cfg.APIKey, cfg.Model, cfg.BaseUrl)),
 		agentkit.WithConfig(agentkit.AgentConfig{
@@ -53,9 +58,13 @@ func main() {
 			MaxIterations: cfg.MaxIterations,
 			MaxTokens:     cfg.MaxTokens,
 			Temperature:   cfg.Temperature,
+			Thinking:      thinking,
 		}),
 		agentkit.WithFilesystem(cfg.WorkDir),
 		agentkit.WithSkillsDirs("skills"),
+		agentkit.WithModelFactory(func(model string) (llm.Client, error) {
+			return llm.NewAnthropicClient(cfg.APIKey, model, cfg.BaseUrl), nil
+		}),
 		agentkit.WithSessionID(sessionID),
 		agentkit.EnableSummarization(),
 		agentkit.EnableVerboseLogging(),
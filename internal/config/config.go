@@ -23,9 +23,10 @@ type Config struct {
 	SystemPromptFile string `yaml:"system_prompt_file" json:"system_prompt_file"` // 系统提示词文件路径
 
 	// Agent 配置
-	MaxIterations int     `yaml:"max_iterations" json:"max_iterations"`
-	MaxTokens     int     `yaml:"max_tokens" json:"max_tokens"`
-	Temperature   float64 `yaml:"temperature" json:"temperature"`
+	MaxIterations  int     `yaml:"max_iterations" json:"max_iterations"`
+	MaxTokens      int     `yaml:"max_tokens" json:"max_tokens"`
+	Temperature    float64 `yaml:"temperature" json:"temperature"`
+	ThinkingBudget int     `yaml:"thinking_budget" json:"thinking_budget"` // 扩展思考的 token 预算，0 表示不启用
 
 	// 日志配置
 	LogLevel  string `yaml:"log_level" json:"log_level"`   // debug, info, warn, error
@@ -158,6 +159,9 @@ func (c *Config) Merge(other *Config) {
 	if other.Temperature > 0 {
 		c.Temperature = other.Temperature
 	}
+	if other.ThinkingBudget > 0 {
+		c.ThinkingBudget = other.ThinkingBudget
+	}
 	if other.LogLevel != "" {
 		c.LogLevel = other.LogLevel
 	}
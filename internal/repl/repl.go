@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/chzyer/readline"
@@ -16,6 +17,7 @@ import (
 	"github.com/zhoucx/deepagents-go/internal/progress"
 	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/agentkit"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
 	"github.com/zhoucx/deepagents-go/pkg/middleware"
 	"golang.org/x/term"
@@ -31,12 +33,13 @@ type BannerInfo struct {
 // REPL 交互式命令行
 type REPL struct {
 	executor     *agent.Runnable
-	messages     []llm.Message
+	thread       *agent.Thread // 多轮对话线程，保存跨轮次的消息和元数据
 	rl           *readline.Instance
 	writer       io.Writer
 	sessionID    string                   // 会话 ID
 	sessionStore *middleware.SessionStore // 会话存储
 	bannerInfo   *BannerInfo              // 启动 Banner 信息
+	showSubAgent bool                     // 展开显示子 Agent 的工具调用和输出
 }
 
 // New 创建新的 REPL
@@ -69,7 +72,7 @@ func New(builder *agentkit.AgentBuilder, sessionId string, banner *BannerInfo) *
 
 	return &REPL{
 		executor:     builder.Runnable,
-		messages:     make([]llm.Message, 0),
+		thread:       newThread(builder.Runnable, sessionId, nil),
 		rl:           rl,
 		writer:       os.Stdout,
 		sessionID:    sessionId,
@@ -78,6 +81,15 @@ func New(builder *agentkit.AgentBuilder, sessionId string, banner *BannerInfo) *
 	}
 }
 
+// newThread 创建对话线程，sessionID 非空时作为线程 ID 并写入元数据
+func newThread(a agent.Agent, sessionID string, messages []llm.Message) *agent.Thread {
+	config := &agent.ThreadConfig{ID: sessionID, Messages: messages}
+	if sessionID != "" {
+		config.Metadata = map[string]any{"session_id": sessionID}
+	}
+	return agent.NewThread(a, config)
+}
+
 // Run 运行 REPL
 func (r *REPL) Run() error {
 	defer r.rl.Close()
@@ -169,6 +181,19 @@ func (r *REPL) handleCommand(input string) bool {
 		r.handleResume(args[0])
 		return true
 
+	case "rewind":
+		r.handleRewind(args)
+		return true
+
+	case "subagents":
+		r.showSubAgent = !r.showSubAgent
+		if r.showSubAgent {
+			fmt.Fprintln(r.writer, color.Gray("子 Agent 执行过程：展开（显示工具调用和输出）"))
+		} else {
+			fmt.Fprintln(r.writer, color.Gray("子 Agent 执行过程：折叠（仅显示开始和结束）"))
+		}
+		return true
+
 	default:
 		fmt.Fprintf(r.writer, color.Red("未知命令: %s\n"), input)
 		fmt.Fprintln(r.writer, color.Gray("输入 /help 查看可用命令"))
@@ -180,54 +205,78 @@ func (r *REPL) handleCommand(input string) bool {
 func (r *REPL) execute(input string) error {
 	logger.Debug("执行用户输入: %s", input)
 
-	// 添加用户消息到历史
-	r.messages = append(r.messages, llm.Message{
+	return r.executeStream(context.Background(), llm.Message{
 		Role:    llm.RoleUser,
 		Content: input,
 	})
-
-	return r.executeStream(context.Background())
 }
 
-// executeStream 执行流式调用
-func (r *REPL) executeStream(ctx context.Context) error {
+// executeStream 执行流式调用（对话历史由线程维护，只传入新消息）
+func (r *REPL) executeStream(ctx context.Context, message llm.Message) error {
 	// 创建进度跟踪器
 	tracker := progress.NewTracker(true)
 	tracker.Start()
 	defer tracker.Stop()
 
-	// 创建输入，包含 session_id
-	input := &agent.InvokeInput{
-		Messages: r.messages,
-	}
-	if r.sessionID != "" {
-		input.Metadata = map[string]any{
-			"session_id": r.sessionID,
-		}
-	}
-
 	// 调用流式 Agent
-	stream, err := r.executor.InvokeStream(ctx, input)
+	stream, err := r.thread.InvokeStream(ctx, message)
 	if err != nil {
 		return err
 	}
 
+	// 事件总线把执行事件分发给终端渲染和调试日志；日志订阅者缓冲区满时丢弃事件，不拖慢渲染
+	bus := agent.NewEventBus()
+	render := bus.Subscribe(&agent.SubscribeOptions{BufferSize: 256})
+	defer render.Close()
+	trace := bus.Subscribe(&agent.SubscribeOptions{Policy: agent.DropPolicyDropNewest, Filter: isTraceEvent})
+	traced := make(chan struct{})
+	go func() {
+		defer close(traced)
+		logEvents(trace.C)
+	}()
+	defer func() {
+		trace.Close()
+		<-traced
+	}()
+	go bus.Forward(ctx, stream)
+
 	fmt.Fprint(r.writer, "\n")
 
 	var assistantContent string
 	var isFirstText bool
+	var isThinking bool
+	subAgents := make(map[string]*subAgentView)
 
 	// 处理流式事件
-	for event := range stream {
+	for event := range render.C {
+		// 子 Agent 的事件单独渲染，不影响主 Agent 的输出和消息
+		if event.SubAgent != nil {
+			r.renderSubAgentEvent(event, subAgents, tracker)
+			continue
+		}
+
 		switch event.Type {
 		case agent.AgentEventTypeLLMStart:
 			// 更新进度：开始新的迭代
 			tracker.StartIteration(event.Iteration)
 			isFirstText = true
+			isThinking = false
+
+		case agent.AgentEventTypeLLMThinking:
+			// 思考内容以灰色显示，不计入助手回复
+			if !isThinking {
+				tracker.Stop()
+				fmt.Fprint(r.writer, color.Gray("Thinking: "))
+				isThinking = true
+			}
+			fmt.Fprint(r.writer, color.Gray(event.Content))
 
 		case agent.AgentEventTypeLLMText:
 			// 第一次输出文本时，停止进度指示器并显示 Assistant 前缀
 			if isFirstText {
+				if isThinking {
+					fmt.Fprintln(r.writer)
+				}
 				tracker.Stop()
 				fmt.Fprint(r.writer, color.Green("Assistant: "))
 				isFirstText = false
@@ -253,12 +302,6 @@ func (r *REPL) executeStream(ctx context.Context) error {
 		case agent.AgentEventTypeEnd:
 			// Agent 执行完成
 			tracker.Stop()
-			// 从元数据中获取最终消息
-			if event.Metadata != nil {
-				if messages, ok := event.Metadata["messages"].([]llm.Message); ok {
-					r.messages = messages
-				}
-			}
 			// 显示统计信息
 			tracker.PrintStats()
 
@@ -268,10 +311,109 @@ func (r *REPL) executeStream(ctx context.Context) error {
 		}
 	}
 
-	logger.Debug("流式执行完成，消息数量: %d", len(r.messages))
+	logger.Debug("流式执行完成，消息数量: %d", len(r.thread.Messages()))
 	return nil
 }
 
+// isTraceEvent 是否为需要写入调试日志的事件（工具调用、移交和错误）
+func isTraceEvent(event agent.AgentEvent) bool {
+	switch event.Type {
+	case agent.AgentEventTypeToolStart, agent.AgentEventTypeToolResult, agent.AgentEventTypeError,
+		agent.AgentEventTypeHandoff, agent.AgentEventTypeHandoffEnd:
+		return true
+	}
+	return false
+}
+
+// logEvents 将事件写入调试日志，直到事件流关闭
+func logEvents(events <-chan agent.AgentEvent) {
+	for event := range events {
+		source := "main"
+		if event.SubAgent != nil {
+			source = event.SubAgent.Name + "(" + event.SubAgent.ID + ")"
+		}
+		switch {
+		case event.ToolCall != nil:
+			logger.Debug("[%s] 工具调用: %s", source, event.ToolCall.Name)
+		case event.ToolResult != nil:
+			logger.Debug("[%s] 工具结果: %s (%d 字节, 错误: %v)", source, event.ToolResult.ToolCallID, len(event.ToolResult.Content), event.ToolResult.IsError)
+		case event.Error != nil:
+			logger.Debug("[%s] %s: %v", source, event.Type, event.Error)
+		default:
+			logger.Debug("[%s] %s: %v", source, event.Type, event.Metadata)
+		}
+	}
+}
+
+// subAgentView 子 Agent 执行过程的渲染状态
+type subAgentView struct {
+	toolCalls int
+	text      strings.Builder
+}
+
+// renderSubAgentEvent 以缩进形式渲染子 Agent 事件
+// 折叠模式只显示开始、结束和错误，展开模式额外显示工具调用和每轮输出
+func (r *REPL) renderSubAgentEvent(event agent.AgentEvent, views map[string]*subAgentView, tracker *progress.Tracker) {
+	// 输出前暂停进度指示器，避免与动画交错
+	printf := func(format string, args ...any) {
+		tracker.Stop()
+		fmt.Fprintf(r.writer, format, args...)
+		tracker.Start()
+	}
+
+	info := event.SubAgent
+	indent := strings.Repeat("  ", info.Depth)
+	label := fmt.Sprintf("%s %s", info.Name, color.Gray("("+info.ID+")"))
+	view := views[info.ID]
+	if view == nil {
+		view = &subAgentView{}
+		views[info.ID] = view
+	}
+
+	switch event.Type {
+	case agent.AgentEventTypeStart:
+		printf("%s%s %s: %s\n", indent, color.Cyan("⎿ 子Agent"), label, info.Task)
+
+	case agent.AgentEventTypeToolStart:
+		view.toolCalls++
+		if r.showSubAgent && event.ToolCall != nil {
+			input, _ := json.Marshal(event.ToolCall.Input)
+			printf("%s  %s %s\n", indent, color.Yellow("· "+event.ToolCall.Name), color.Gray(summarize(string(input), 80)))
+		}
+
+	case agent.AgentEventTypeToolResult:
+		if r.showSubAgent && event.ToolResult != nil && event.ToolResult.IsError {
+			printf("%s    %s\n", indent, color.Red(summarize(event.ToolResult.Content, 120)))
+		}
+
+	case agent.AgentEventTypeLLMText:
+		view.text.WriteString(event.Content)
+
+	case agent.AgentEventTypeLLMEnd:
+		if r.showSubAgent && view.text.Len() > 0 {
+			printf("%s  %s\n", indent, color.Gray(summarize(view.text.String(), 160)))
+		}
+		view.text.Reset()
+
+	case agent.AgentEventTypeEnd:
+		printf("%s%s %s（%d 次工具调用）\n", indent, color.Green("⎿ 完成"), label, view.toolCalls)
+		delete(views, info.ID)
+
+	case agent.AgentEventTypeError:
+		printf("%s%s %s: %v\n", indent, color.Red("⎿ 失败"), label, event.Error)
+		delete(views, info.ID)
+	}
+}
+
+// summarize 将文本压缩为单行并按字符数截断
+func summarize(s string, maxRunes int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxRunes {
+		return string(r[:maxRunes]) + "..."
+	}
+	return s
+}
+
 // printWelcome 打印欢迎信息
 func (r *REPL) printWelcome() {
 	const boxWidth = 40 // 内容区宽度（不含左右边距）
@@ -347,6 +489,9 @@ func (r *REPL) printHelp() {
 	fmt.Fprintln(r.writer, color.Gray("  /history            - 显示对话历史"))
 	fmt.Fprintln(r.writer, color.Gray("  /sessions           - 列出可用的历史会话"))
 	fmt.Fprintln(r.writer, color.Gray("  /resume <id>        - 恢复指定的会话（支持前缀匹配）"))
+	fmt.Fprintln(r.writer, color.Gray("  /rewind             - 列出可回退的轮次"))
+	fmt.Fprintln(r.writer, color.Gray("  /rewind <n> [--conversation] - 将文件（及对话）回退到第 n 轮之前"))
+	fmt.Fprintln(r.writer, color.Gray("  /subagents          - 展开/折叠子 Agent 的执行过程"))
 	fmt.Fprintln(r.writer, "")
 	fmt.Fprintln(r.writer, color.Gray("直接输入文本即可与 AI 对话"))
 }
@@ -440,8 +585,8 @@ func (r *REPL) resumeSession(ctx context.Context, info *middleware.SessionInfo)
 				return fmt.Errorf("加载历史消息失败: %w", err)
 			}
 
-			// 4. 更新 REPL 的消息列表
-			r.messages = messages
+			// 4. 以历史消息创建新的对话线程
+			r.thread = newThread(r.executor, info.SessionID, messages)
 
 			return nil
 		}
@@ -450,6 +595,75 @@ func (r *REPL) resumeSession(ctx context.Context, info *middleware.SessionInfo)
 	return fmt.Errorf("MemoryMiddleware not found")
 }
 
+// checkpointer 文件快照接口，避免直接依赖具体中间件类型
+type checkpointer interface {
+	Turns() []middleware.TurnSummary
+	Rewind(ctx context.Context, turn int) ([]backend.FileChange, error)
+}
+
+// handleRewind 处理 /rewind 命令
+func (r *REPL) handleRewind(args []string) {
+	var cp checkpointer
+	for _, mw := range r.executor.GetMiddlewares() {
+		if c, ok := mw.(checkpointer); ok {
+			cp = c
+			break
+		}
+	}
+	if cp == nil {
+		fmt.Fprintln(r.writer, color.Red("❌ 未启用文件快照"))
+		return
+	}
+
+	// 无参数：列出有文件变更的轮次
+	if len(args) == 0 {
+		turns := cp.Turns()
+		if len(turns) == 0 {
+			fmt.Fprintln(r.writer, "本会话暂无文件变更")
+			return
+		}
+		fmt.Fprintln(r.writer, "\n=== 文件变更 ===")
+		for _, t := range turns {
+			fmt.Fprintf(r.writer, "  第 %d 轮: %d 次变更 - %s\n", t.Turn, t.Changes, strings.Join(t.Files, ", "))
+		}
+		fmt.Fprintln(r.writer, "\n使用 /rewind <n> 将文件回退到第 n 轮之前，追加 --conversation 同时回退对话")
+		return
+	}
+
+	turn, err := strconv.Atoi(args[0])
+	if err != nil || turn < 1 {
+		fmt.Fprintln(r.writer, color.Red("❌ 用法: /rewind <n> [--conversation]"))
+		return
+	}
+	withConversation := len(args) > 1 && args[1] == "--conversation"
+
+	ctx := context.Background()
+	reverted, err := cp.Rewind(ctx, turn)
+	if err != nil {
+		fmt.Fprintf(r.writer, color.Red("❌ 回退失败: %v\n"), err)
+		return
+	}
+	fmt.Fprintf(r.writer, color.Green("✅ 已撤销 %d 次文件变更\n"), len(reverted))
+
+	if withConversation {
+		n := len(middleware.MessagesBeforeTurn(r.thread.Messages(), turn))
+		if err := r.thread.Truncate(ctx, n); err != nil {
+			fmt.Fprintf(r.writer, color.Red("❌ 回退对话失败: %v\n"), err)
+			return
+		}
+		for _, mw := range r.executor.GetMiddlewares() {
+			if t, ok := mw.(interface {
+				TruncateSession(context.Context, int) error
+			}); ok {
+				if err := t.TruncateSession(ctx, n); err != nil {
+					logger.Warn("截断会话记录失败: %v", err)
+				}
+			}
+		}
+		fmt.Fprintf(r.writer, color.Green("✅ 对话已回退到第 %d 轮之前 (%d 条消息)\n"), turn, n)
+	}
+}
+
 // sessionClearer 会话清除接口，避免直接依赖 pkg/middleware
 type sessionClearer interface {
 	ClearSession()
@@ -458,7 +672,7 @@ type sessionClearer interface {
 // clear 清除对话历史和会话记录
 func (r *REPL) clear() {
 	// 清除对话历史
-	r.messages = make([]llm.Message, 0)
+	r.thread = newThread(r.executor, r.sessionID, nil)
 	fmt.Fprintln(r.writer, color.Yellow("对话历史已清除"))
 
 	// 清除会话记录
@@ -474,13 +688,14 @@ func (r *REPL) clear() {
 
 // printHistory 打印对话历史
 func (r *REPL) printHistory() {
-	if len(r.messages) == 0 {
+	messages := r.thread.Messages()
+	if len(messages) == 0 {
 		fmt.Fprintln(r.writer, "暂无对话历史")
 		return
 	}
 
 	fmt.Fprintln(r.writer, "\n=== 对话历史 ===")
-	for i, msg := range r.messages {
+	for i, msg := range messages {
 		switch {
 		case msg.Role == llm.RoleAssistant:
 			if msg.Content != "" {
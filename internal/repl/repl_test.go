@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/agentkit"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
 	"github.com/zhoucx/deepagents-go/pkg/tools"
 )
@@ -14,26 +15,27 @@ import (
 // mockLLMClient 用于测试的 mock LLM 客户端
 type mockLLMClient struct{}
 
-// mockMemoryMiddleware 模拟记忆中间件
-type mockMemoryMiddleware struct {
-	memories []string
+// mockSessionRecorder 模拟会话记录中间件
+type mockSessionRecorder struct {
+	cleared bool
 }
 
-func (m *mockMemoryMiddleware) Name() string                                              { return "memory" }
-func (m *mockMemoryMiddleware) BeforeAgent(ctx context.Context, state *agent.State) error { return nil }
-func (m *mockMemoryMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
+func (m *mockSessionRecorder) Name() string                                              { return "memory" }
+func (m *mockSessionRecorder) BeforeAgent(ctx context.Context, state *agent.State) error { return nil }
+func (m *mockSessionRecorder) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
 	return nil
 }
-func (m *mockMemoryMiddleware) AfterModel(ctx context.Context, resp *llm.ModelResponse, state *agent.State) error {
+func (m *mockSessionRecorder) AfterModel(ctx context.Context, resp *llm.ModelResponse, state *agent.State) error {
 	return nil
 }
-func (m *mockMemoryMiddleware) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *agent.State) error {
+func (m *mockSessionRecorder) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *agent.State) error {
 	return nil
 }
-func (m *mockMemoryMiddleware) AfterTool(ctx context.Context, result *llm.ToolResult, state *agent.State) error {
+func (m *mockSessionRecorder) AfterTool(ctx context.Context, result *llm.ToolResult, state *agent.State) error {
 	return nil
 }
-func (m *mockMemoryMiddleware) ClearMemories() { m.memories = nil }
+func (m *mockSessionRecorder) AfterAgent(ctx context.Context, state *agent.State) error { return nil }
+func (m *mockSessionRecorder) ClearSession()                                            { m.cleared = true }
 
 func (m *mockLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
 	return &llm.ModelResponse{
@@ -68,48 +70,43 @@ func (m *mockLLMClient) CountTokens(messages []llm.Message) int {
 	return len(messages) * 10
 }
 
-func createTestExecutor() *agent.Runnable {
-	llmClient := &mockLLMClient{}
-	toolRegistry := tools.NewRegistry()
-
-	config := &agent.Config{
-		LLMClient:     llmClient,
-		ToolRegistry:  toolRegistry,
+// createTestREPL 创建使用 mock LLM 的 REPL，输出写入返回的缓冲区
+func createTestREPL(middlewares ...agent.Middleware) (*REPL, *bytes.Buffer) {
+	runnable := agent.NewRunnable(&agent.Config{
+		LLMClient:     &mockLLMClient{},
+		ToolRegistry:  tools.NewRegistry(),
+		Middlewares:   middlewares,
 		SystemPrompt:  "Test system prompt",
 		MaxIterations: 5,
 		MaxTokens:     100,
-	}
+	})
+	repl := New(&agentkit.AgentBuilder{Runnable: runnable}, "", nil)
+	var buf bytes.Buffer
+	repl.writer = &buf
+	return repl, &buf
+}
 
-	return agent.NewExecutor(config)
+// setMessages 用给定的对话历史替换 REPL 的线程
+func setMessages(repl *REPL, messages []llm.Message) {
+	repl.thread = newThread(repl.executor, repl.sessionID, messages)
 }
 
 func TestNew(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	if repl == nil {
-		t.Fatal("Expected non-nil REPL")
-	}
+	repl, _ := createTestREPL()
 
 	if repl.executor == nil {
 		t.Error("Expected executor to be set")
 	}
-
-	if repl.messages == nil {
-		t.Error("Expected messages to be initialized")
+	if repl.thread == nil {
+		t.Fatal("Expected thread to be initialized")
 	}
-
-	if len(repl.messages) != 0 {
+	if len(repl.thread.Messages()) != 0 {
 		t.Error("Expected empty messages initially")
 	}
 }
 
 func TestHandleCommand_Help(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
 	tests := []string{"/help", "/h", "/?", "/HELP"}
 	for _, cmd := range tests {
@@ -125,78 +122,48 @@ func TestHandleCommand_Help(t *testing.T) {
 }
 
 func TestHandleCommand_Clear(t *testing.T) {
-	// 带记忆中间件：/clear 同时清除对话历史和记忆
-	mockMem := &mockMemoryMiddleware{memories: []string{"a", "b"}}
-	config := &agent.Config{
-		LLMClient:    &mockLLMClient{},
-		ToolRegistry: tools.NewRegistry(),
-		Middlewares:  []agent.Middleware{mockMem},
-	}
-	executor := agent.NewExecutor(config)
-	repl := New(executor)
+	// 带会话记录中间件：/clear 同时清除对话历史和会话记录
+	recorder := &mockSessionRecorder{}
+	repl, buf := createTestREPL(recorder)
+	setMessages(repl, []llm.Message{{Role: llm.RoleUser, Content: "test"}})
 
-	var buf bytes.Buffer
-	repl.writer = &buf
-
-	repl.messages = []llm.Message{
-		{Role: llm.RoleUser, Content: "test"},
-	}
-
-	result := repl.handleCommand("/clear")
-	if !result {
+	if !repl.handleCommand("/clear") {
 		t.Error("Expected handleCommand('/clear') to return true")
 	}
-
-	if len(repl.messages) != 0 {
+	if len(repl.thread.Messages()) != 0 {
 		t.Error("Expected messages to be cleared")
 	}
-
-	if len(mockMem.memories) != 0 {
-		t.Errorf("Expected memories to be cleared, got %d", len(mockMem.memories))
+	if !recorder.cleared {
+		t.Error("Expected session record to be cleared")
 	}
 
 	output := buf.String()
 	if !strings.Contains(output, "对话历史已清除") {
 		t.Error("Expected history clear confirmation")
 	}
-	if !strings.Contains(output, "记忆已清除") {
-		t.Error("Expected memory clear confirmation")
+	if !strings.Contains(output, "会话记录已清除") {
+		t.Error("Expected session clear confirmation")
 	}
 }
 
-func TestHandleCommand_Clear_NoMemoryMiddleware(t *testing.T) {
-	// 无记忆中间件：/clear 只清除对话历史，不 panic
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
-
-	repl.messages = []llm.Message{
-		{Role: llm.RoleUser, Content: "test"},
-	}
+func TestHandleCommand_Clear_NoSessionRecorder(t *testing.T) {
+	// 无会话记录中间件：/clear 只清除对话历史，不 panic
+	repl, buf := createTestREPL()
+	setMessages(repl, []llm.Message{{Role: llm.RoleUser, Content: "test"}})
 
-	result := repl.handleCommand("/clear")
-	if !result {
+	if !repl.handleCommand("/clear") {
 		t.Error("Expected handleCommand('/clear') to return true")
 	}
-
-	if len(repl.messages) != 0 {
+	if len(repl.thread.Messages()) != 0 {
 		t.Error("Expected messages to be cleared")
 	}
-
-	output := buf.String()
-	if !strings.Contains(output, "对话历史已清除") {
+	if !strings.Contains(buf.String(), "对话历史已清除") {
 		t.Error("Expected history clear confirmation")
 	}
 }
 
 func TestHandleCommand_History(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
 	// 测试空历史
 	repl.handleCommand("/history")
@@ -206,10 +173,10 @@ func TestHandleCommand_History(t *testing.T) {
 
 	// 添加消息
 	buf.Reset()
-	repl.messages = []llm.Message{
+	setMessages(repl, []llm.Message{
 		{Role: llm.RoleUser, Content: "test message"},
 		{Role: llm.RoleAssistant, Content: "response"},
-	}
+	})
 
 	repl.handleCommand("/history")
 	output := buf.String()
@@ -225,11 +192,7 @@ func TestHandleCommand_History(t *testing.T) {
 }
 
 func TestHandleCommand_Unknown(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
 	// 不带 / 前缀的输入不是命令，返回 false
 	result := repl.handleCommand("unknown_command")
@@ -269,11 +232,7 @@ func TestTruncate(t *testing.T) {
 }
 
 func TestPrintWelcome(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
 	repl.printWelcome()
 
@@ -287,11 +246,7 @@ func TestPrintWelcome(t *testing.T) {
 }
 
 func TestPrintHelp(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
 	repl.printHelp()
 
@@ -311,11 +266,7 @@ func TestPrintHelp(t *testing.T) {
 }
 
 func TestPrintHistory_Empty(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
 	repl.printHistory()
 
@@ -325,16 +276,12 @@ func TestPrintHistory_Empty(t *testing.T) {
 }
 
 func TestPrintHistory_WithMessages(t *testing.T) {
-	executor := createTestExecutor()
-	repl := New(executor)
-
-	var buf bytes.Buffer
-	repl.writer = &buf
+	repl, buf := createTestREPL()
 
-	repl.messages = []llm.Message{
+	setMessages(repl, []llm.Message{
 		{Role: llm.RoleUser, Content: "Hello"},
 		{Role: llm.RoleAssistant, Content: "Hi there!"},
-	}
+	})
 
 	repl.printHistory()
 
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"time"
 
 	"github.com/zhoucx/deepagents-go/pkg/llm"
 )
@@ -35,6 +36,7 @@ type AgentEventType string
 const (
 	AgentEventTypeStart        AgentEventType = "start"         // Agent 开始执行
 	AgentEventTypeLLMStart     AgentEventType = "llm_start"     // LLM 开始生成
+	AgentEventTypeLLMThinking  AgentEventType = "llm_thinking"  // LLM 思考内容
 	AgentEventTypeLLMText      AgentEventType = "llm_text"      // LLM 文本内容
 	AgentEventTypeLLMToolCall  AgentEventType = "llm_tool_call" // LLM 工具调用
 	AgentEventTypeLLMEnd       AgentEventType = "llm_end"       // LLM 生成结束
@@ -43,6 +45,10 @@ const (
 	AgentEventTypeIterationEnd AgentEventType = "iteration_end" // 迭代结束
 	AgentEventTypeEnd          AgentEventType = "end"           // Agent 执行结束
 	AgentEventTypeError        AgentEventType = "error"         // 错误
+	AgentEventTypeHandoff      AgentEventType = "handoff"       // 控制权移交给其他 Agent
+	AgentEventTypeHandoffEnd   AgentEventType = "handoff_end"   // 控制权交回
+	AgentEventTypeNodeStart    AgentEventType = "node_start"    // 工作流节点开始执行
+	AgentEventTypeNodeEnd      AgentEventType = "node_end"      // 工作流节点执行结束
 )
 
 // AgentEvent 表示 Agent 执行事件
@@ -54,7 +60,19 @@ type AgentEvent struct {
 	Iteration  int             `json:"iteration,omitempty"`   // 当前迭代次数
 	Error      error           `json:"error,omitempty"`       // 错误信息
 	Metadata   map[string]any  `json:"metadata,omitempty"`    // 元数据
-	Done       bool            `json:"done"`                  // 是否完成
+	SubAgent   *SubAgentInfo   `json:"subagent,omitempty"`    // 非 nil 表示由子 Agent 产生的事件
+	Done       bool            `json:"done"`                  // 是否完成（子 Agent 转发的事件始终为 false）
+	RunID      string          `json:"run_id,omitempty"`      // 产生事件的执行 ID
+	Timestamp  time.Time       `json:"timestamp,omitzero"`    // 事件产生时间
+}
+
+// SubAgentInfo 标识产生事件的子 Agent
+type SubAgentInfo struct {
+	ID       string `json:"id"`                  // 子 Agent 运行 ID
+	Name     string `json:"name"`                // 子 Agent 类型
+	Depth    int    `json:"depth"`               // 嵌套深度（主 Agent 直接委派的子 Agent 为 1）
+	ParentID string `json:"parent_id,omitempty"` // 上级子 Agent 的运行 ID（由主 Agent 委派时为空）
+	Task     string `json:"task,omitempty"`      // 任务标题
 }
 
 // Middleware 定义中间件接口
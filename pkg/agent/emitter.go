@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// EventEmitter 向当前事件流发送事件
+type EventEmitter func(event AgentEvent)
+
+// eventEmitterKey 用于在上下文中存储 EventEmitter
+type eventEmitterKey struct{}
+
+// WithEventEmitter 返回携带事件发送函数的上下文
+// 流式执行时工具通过它把内部过程（如子 Agent 的执行事件）转发到调用方的事件流
+func WithEventEmitter(ctx context.Context, emit EventEmitter) context.Context {
+	return context.WithValue(ctx, eventEmitterKey{}, emit)
+}
+
+// EventEmitterFromContext 返回上下文中的事件发送函数，非流式执行时返回 nil
+func EventEmitterFromContext(ctx context.Context) EventEmitter {
+	emit, _ := ctx.Value(eventEmitterKey{}).(EventEmitter)
+	return emit
+}
+
+// SendEvent 向事件通道发送事件，上下文取消时放弃发送并返回 false
+// 通道有空位时总是优先发送，避免取消后丢失已能送达的结束或错误事件
+func SendEvent(ctx context.Context, ch chan<- AgentEvent, event AgentEvent) bool {
+	select {
+	case ch <- event:
+		return true
+	default:
+	}
+	select {
+	case ch <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// StampEvent 为尚未标记的事件填充执行 ID 和产生时间
+// 已带有 RunID 的事件（如子 Agent 转发的事件）保持不变
+func StampEvent(event AgentEvent, runID string) AgentEvent {
+	if event.RunID == "" {
+		event.RunID = runID
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	return event
+}
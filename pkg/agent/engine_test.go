@@ -0,0 +1,391 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// recordingMiddleware 记录钩子的调用顺序，failAt 指定的钩子返回错误
+type recordingMiddleware struct {
+	calls  []string
+	failAt string
+}
+
+func (m *recordingMiddleware) Name() string { return "recording" }
+
+func (m *recordingMiddleware) record(hook string) error {
+	m.calls = append(m.calls, hook)
+	if hook == m.failAt {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (m *recordingMiddleware) BeforeAgent(ctx context.Context, state *State) error {
+	return m.record("before_agent")
+}
+
+func (m *recordingMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
+	return m.record("before_model")
+}
+
+func (m *recordingMiddleware) AfterModel(ctx context.Context, resp *llm.ModelResponse, state *State) error {
+	return m.record("after_model")
+}
+
+func (m *recordingMiddleware) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *State) error {
+	return m.record("before_tool:" + toolCall.Name)
+}
+
+func (m *recordingMiddleware) AfterTool(ctx context.Context, result *llm.ToolResult, state *State) error {
+	return m.record("after_tool")
+}
+
+func (m *recordingMiddleware) AfterAgent(ctx context.Context, state *State) error {
+	return m.record("after_agent")
+}
+
+// failingLLMClient 总是返回错误的模拟客户端
+type failingLLMClient struct{}
+
+func (c *failingLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	return nil, errors.New("model down")
+}
+
+func (c *failingLLMClient) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	return nil, errors.New("model down")
+}
+
+func (c *failingLLMClient) CountTokens(messages []llm.Message) int {
+	return 0
+}
+
+// engineMode 以不同方式调用执行引擎，返回输出、过程事件（不含结束和错误事件）和错误
+type engineMode struct {
+	name string
+	run  func(r *Runnable, input *InvokeInput) (*InvokeOutput, []AgentEventType, error)
+}
+
+var engineModes = []engineMode{
+	{
+		name: "invoke",
+		run: func(r *Runnable, input *InvokeInput) (*InvokeOutput, []AgentEventType, error) {
+			output, err := r.Invoke(context.Background(), input)
+			return output, nil, err
+		},
+	},
+	{
+		name: "stream",
+		run: func(r *Runnable, input *InvokeInput) (*InvokeOutput, []AgentEventType, error) {
+			stream, err := r.InvokeStream(context.Background(), input)
+			if err != nil {
+				return nil, nil, err
+			}
+			var types []AgentEventType
+			var output *InvokeOutput
+			for event := range stream {
+				switch event.Type {
+				case AgentEventTypeError:
+					err = event.Error
+				case AgentEventTypeEnd:
+					output = &InvokeOutput{
+						Messages: event.Metadata["messages"].([]llm.Message),
+						Files:    event.Metadata["files"].(map[string]string),
+						Metadata: event.Metadata["metadata"].(map[string]any),
+					}
+				default:
+					types = append(types, event.Type)
+				}
+			}
+			return output, types, err
+		},
+	},
+}
+
+// newEngineFixture 创建执行器：模型先调用 echo 和 missing 两个工具，再结束
+func newEngineFixture(client llm.Client, recorder *recordingMiddleware) *Runnable {
+	if client == nil {
+		client = &MockLLMClient{
+			responses: []*llm.ModelResponse{
+				{
+					Content: "调用工具",
+					ToolCalls: []llm.ToolCall{
+						{ID: "call_1", Name: "echo", Input: map[string]any{}},
+						{ID: "call_2", Name: "missing", Input: map[string]any{}},
+					},
+					StopReason: "tool_use",
+				},
+			},
+		}
+	}
+	toolRegistry := tools.NewRegistry()
+	toolRegistry.Register(tools.NewBaseTool("echo", "回显", map[string]any{"type": "object"},
+		func(ctx context.Context, args map[string]any) (string, error) { return "OK", nil }))
+	return NewRunnable(&Config{
+		LLMClient:    client,
+		ToolRegistry: toolRegistry,
+		Middlewares:  []Middleware{recorder},
+	})
+}
+
+func TestEngine_HookOrderAndOutput(t *testing.T) {
+	wantCalls := []string{
+		"before_agent",
+		"before_model", "after_model",
+		"before_tool:echo", "after_tool",
+		"before_tool:missing", "after_tool",
+		"before_model", "after_model",
+		"after_agent",
+	}
+
+	for _, mode := range engineModes {
+		t.Run(mode.name, func(t *testing.T) {
+			recorder := &recordingMiddleware{}
+			output, _, err := mode.run(newEngineFixture(nil, recorder), &InvokeInput{
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+			})
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			if !slices.Equal(recorder.calls, wantCalls) {
+				t.Errorf("hook order = %v, want %v", recorder.calls, wantCalls)
+			}
+
+			// 用户消息、助手消息、工具结果、最终回复
+			if len(output.Messages) != 4 {
+				t.Fatalf("expected 4 messages, got %d", len(output.Messages))
+			}
+			results := output.Messages[2].ToolResults
+			if len(results) != 2 || results[0].Content != "OK" || !results[1].IsError {
+				t.Errorf("unexpected tool results: %+v", results)
+			}
+			if output.Messages[3].Content != "Done" {
+				t.Errorf("unexpected final message: %+v", output.Messages[3])
+			}
+		})
+	}
+}
+
+func TestEngine_HookErrors(t *testing.T) {
+	hooks := map[string]string{
+		"before_agent":     "before agent hook failed: boom",
+		"before_model":     "before model hook failed: boom",
+		"after_model":      "after model hook failed: boom",
+		"before_tool:echo": "before tool hook failed: boom",
+		"after_tool":       "after tool hook failed: boom",
+		"after_agent":      "after agent hook failed: boom",
+	}
+
+	for hook, want := range hooks {
+		for _, mode := range engineModes {
+			t.Run(fmt.Sprintf("%s/%s", hook, mode.name), func(t *testing.T) {
+				recorder := &recordingMiddleware{failAt: hook}
+				output, _, err := mode.run(newEngineFixture(nil, recorder), &InvokeInput{
+					Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+				})
+				if err == nil || err.Error() != want {
+					t.Fatalf("expected error %q, got %v", want, err)
+				}
+				if output != nil {
+					t.Errorf("expected no output on error, got %+v", output)
+				}
+				// 出错后不再执行后续钩子
+				if last := recorder.calls[len(recorder.calls)-1]; last != hook {
+					t.Errorf("expected %s to be the last hook, got %v", hook, recorder.calls)
+				}
+			})
+		}
+	}
+}
+
+func TestEngine_ModelError(t *testing.T) {
+	for _, mode := range engineModes {
+		t.Run(mode.name, func(t *testing.T) {
+			recorder := &recordingMiddleware{}
+			_, _, err := mode.run(newEngineFixture(&failingLLMClient{}, recorder), &InvokeInput{
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+			})
+			if err == nil || !strings.HasPrefix(err.Error(), "llm generate failed:") {
+				t.Fatalf("expected llm generate error, got %v", err)
+			}
+			if !slices.Equal(recorder.calls, []string{"before_agent", "before_model"}) {
+				t.Errorf("unexpected hooks after model error: %v", recorder.calls)
+			}
+		})
+	}
+}
+
+func TestEngine_EventSequence(t *testing.T) {
+	want := []AgentEventType{
+		AgentEventTypeStart,
+		AgentEventTypeLLMStart, AgentEventTypeLLMText,
+		AgentEventTypeLLMToolCall, AgentEventTypeLLMToolCall, AgentEventTypeLLMEnd,
+		AgentEventTypeToolStart, AgentEventTypeToolResult,
+		AgentEventTypeToolStart, AgentEventTypeToolResult,
+		AgentEventTypeIterationEnd,
+		AgentEventTypeLLMStart, AgentEventTypeLLMText, AgentEventTypeLLMEnd,
+		AgentEventTypeIterationEnd,
+	}
+
+	_, streamed, err := engineModes[1].run(newEngineFixture(nil, &recordingMiddleware{}), &InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if !slices.Equal(streamed, want) {
+		t.Errorf("streamed events = %v, want %v", streamed, want)
+	}
+
+	// 非流式执行在引擎内产生相同的事件序列
+	var generated []AgentEventType
+	_, err = newEngineFixture(nil, &recordingMiddleware{}).execute(context.Background(), "run-test", &InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+	}, false, func(event AgentEvent) { generated = append(generated, event.Type) })
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if !slices.Equal(generated, want) {
+		t.Errorf("generated events = %v, want %v", generated, want)
+	}
+}
+
+// thinkingLLMClient 返回带思考块的响应，并记录收到的请求
+type thinkingLLMClient struct {
+	requests []*llm.ModelRequest
+}
+
+func (c *thinkingLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	c.requests = append(c.requests, req)
+	resp := &llm.ModelResponse{
+		Reasoning:  []llm.ReasoningBlock{{Text: "想一想", Signature: "sig"}},
+		StopReason: "end_turn",
+	}
+	if len(c.requests) == 1 {
+		resp.ToolCalls = []llm.ToolCall{{ID: "call_1", Name: "echo", Input: map[string]any{}}}
+		resp.StopReason = "tool_use"
+	} else {
+		resp.Content = "完成"
+	}
+	return resp, nil
+}
+
+func (c *thinkingLLMClient) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	resp, _ := c.Generate(ctx, req)
+	eventChan := make(chan llm.StreamEvent, 8)
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeThinking, Content: "想"}
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeThinking, Content: "一想"}
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeThinking, Reasoning: &resp.Reasoning[0]}
+	if resp.Content != "" {
+		eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeText, Content: resp.Content}
+	}
+	for i := range resp.ToolCalls {
+		eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeToolUse, ToolCall: &resp.ToolCalls[i]}
+	}
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeEnd, StopReason: resp.StopReason, Done: true}
+	close(eventChan)
+	return eventChan, nil
+}
+
+func (c *thinkingLLMClient) CountTokens(messages []llm.Message) int {
+	return 0
+}
+
+func TestEngine_Thinking(t *testing.T) {
+	for _, mode := range engineModes {
+		t.Run(mode.name, func(t *testing.T) {
+			client := &thinkingLLMClient{}
+			executor := newEngineFixture(client, &recordingMiddleware{})
+			executor.config.Thinking = &llm.ThinkingConfig{BudgetTokens: 1024}
+
+			var thinking string
+			if mode.name == "stream" {
+				stream, err := executor.InvokeStream(context.Background(), &InvokeInput{
+					Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+				})
+				if err != nil {
+					t.Fatalf("InvokeStream failed: %v", err)
+				}
+				for event := range stream {
+					if event.Type == AgentEventTypeLLMThinking {
+						thinking += event.Content
+					}
+				}
+			} else {
+				if _, err := executor.execute(context.Background(), "run-test", &InvokeInput{
+					Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+				}, false, func(event AgentEvent) {
+					if event.Type == AgentEventTypeLLMThinking {
+						thinking += event.Content
+					}
+				}); err != nil {
+					t.Fatalf("execute failed: %v", err)
+				}
+			}
+
+			// 两次模型调用各产生一段思考
+			if thinking != "想一想想一想" {
+				t.Errorf("unexpected thinking events: %q", thinking)
+			}
+
+			// 第二次请求携带思考配置，且上一轮的思考块（含签名）原样回传
+			second := client.requests[1]
+			if second.Thinking == nil || second.Thinking.BudgetTokens != 1024 {
+				t.Errorf("expected thinking config, got %+v", second.Thinking)
+			}
+			assistant := second.Messages[1]
+			if len(assistant.Reasoning) != 1 || assistant.Reasoning[0].Signature != "sig" || len(assistant.ToolCalls) != 1 {
+				t.Errorf("expected reasoning preserved in assistant message, got %+v", assistant)
+			}
+		})
+	}
+}
+
+func TestEngine_ToolAttachments(t *testing.T) {
+	for _, mode := range engineModes {
+		t.Run(mode.name, func(t *testing.T) {
+			client := &MockLLMClient{
+				responses: []*llm.ModelResponse{
+					{
+						ToolCalls: []llm.ToolCall{
+							{ID: "call_1", Name: "snapshot", Input: map[string]any{}},
+							{ID: "call_2", Name: "echo", Input: map[string]any{}},
+						},
+						StopReason: "tool_use",
+					},
+				},
+			}
+			executor := newEngineFixture(client, &recordingMiddleware{})
+			executor.config.ToolRegistry.Register(tools.NewBaseTool("snapshot", "截图", map[string]any{"type": "object"},
+				func(ctx context.Context, args map[string]any) (string, error) {
+					if !tools.Attach(ctx, llm.NewImageBlock("image/png", []byte("png"))) {
+						t.Error("expected attachment collector in tool context")
+					}
+					return "Image attached.", nil
+				}))
+
+			output, _, err := mode.run(executor, &InvokeInput{
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+			})
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+
+			// 附件只属于调用它的工具结果
+			results := output.Messages[2].ToolResults
+			if len(results) != 2 || len(results[0].Blocks) != 1 || results[0].Blocks[0].Type != llm.ContentBlockTypeImage {
+				t.Fatalf("unexpected tool results: %+v", results)
+			}
+			if len(results[1].Blocks) != 0 {
+				t.Errorf("unexpected blocks on echo result: %+v", results[1].Blocks)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DropPolicy 订阅者缓冲区已满时的处理策略
+type DropPolicy int
+
+const (
+	DropPolicyBlock      DropPolicy = iota // 阻塞发布者直到有空位（或上下文取消）
+	DropPolicyDropNewest                   // 丢弃新事件
+	DropPolicyDropOldest                   // 丢弃缓冲区中最旧的事件
+)
+
+// SubscribeOptions 订阅选项
+type SubscribeOptions struct {
+	BufferSize int                   // 缓冲区大小，默认 64
+	Policy     DropPolicy            // 缓冲区已满时的处理策略，默认阻塞
+	Filter     func(AgentEvent) bool // 只接收返回 true 的事件，为 nil 时接收全部事件
+}
+
+// Subscription 事件订阅，事件总线关闭或取消订阅后 C 被关闭
+type Subscription struct {
+	C <-chan AgentEvent
+
+	bus     *EventBus
+	ch      chan AgentEvent
+	policy  DropPolicy
+	filter  func(AgentEvent) bool
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// Dropped 返回因缓冲区已满被丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅并关闭 C，可重复调用
+func (s *Subscription) Close() {
+	s.bus.remove(s)
+	s.close()
+}
+
+// close 关闭订阅：先通知阻塞中的发送放弃，再关闭通道
+func (s *Subscription) close() {
+	s.once.Do(func() { close(s.done) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// send 按订阅的策略投递事件
+func (s *Subscription) send(ctx context.Context, event AgentEvent) {
+	if s.filter != nil && !s.filter(event) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.ch <- event:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case DropPolicyDropNewest:
+		s.dropped.Add(1)
+	case DropPolicyDropOldest:
+		for {
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+			select {
+			case s.ch <- event:
+				return
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		case <-ctx.Done():
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// EventBus 把一次执行的事件分发给多个订阅者
+// 每个订阅者拥有独立的缓冲区和丢弃策略，例如 REPL 渲染、会话记录和指标导出可以同时观察同一次执行
+type EventBus struct {
+	mu     sync.Mutex
+	subs   []*Subscription
+	closed bool
+}
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe 订阅事件，opts 为 nil 时使用默认选项；总线已关闭时返回已关闭的订阅
+func (b *EventBus) Subscribe(opts *SubscribeOptions) *Subscription {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	size := opts.BufferSize
+	if size <= 0 {
+		size = 64
+	}
+
+	ch := make(chan AgentEvent, size)
+	sub := &Subscription{
+		C:      ch,
+		bus:    b,
+		ch:     ch,
+		policy: opts.Policy,
+		filter: opts.Filter,
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.close()
+		return sub
+	}
+	b.subs = append(b.subs, sub)
+	return sub
+}
+
+// Publish 向所有订阅者发布事件
+// 阻塞策略的订阅者缓冲区已满时等待，上下文取消后放弃该订阅者的这个事件
+func (b *EventBus) Publish(ctx context.Context, event AgentEvent) {
+	b.mu.Lock()
+	subs := append([]*Subscription(nil), b.subs...)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.send(ctx, event)
+	}
+}
+
+// Forward 把事件流中的事件发布给所有订阅者，事件流结束后关闭总线
+// 上下文取消后继续读取（丢弃）剩余事件，直到事件流结束，返回上下文的错误
+func (b *EventBus) Forward(ctx context.Context, stream <-chan AgentEvent) error {
+	defer b.Close()
+	for event := range stream {
+		if ctx.Err() != nil {
+			continue
+		}
+		b.Publish(ctx, event)
+	}
+	return ctx.Err()
+}
+
+// Close 关闭总线及所有订阅，可重复调用
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.closed = true
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// remove 从总线中移除订阅
+func (b *EventBus) remove(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, s := range b.subs {
+		if s == sub {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+func TestEventBus_MultipleSubscribers(t *testing.T) {
+	executor := NewRunnable(&Config{LLMClient: &MockLLMClient{}, ToolRegistry: tools.NewRegistry()})
+	stream, err := executor.InvokeStream(context.Background(), &InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+	})
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	bus := NewEventBus()
+	all := bus.Subscribe(nil)
+	texts := bus.Subscribe(&SubscribeOptions{
+		Filter: func(event AgentEvent) bool { return event.Type == AgentEventTypeLLMText },
+	})
+	if err := bus.Forward(context.Background(), stream); err != nil {
+		t.Fatalf("Forward failed: %v", err)
+	}
+
+	var types []AgentEventType
+	for event := range all.C {
+		types = append(types, event.Type)
+	}
+	if len(types) == 0 || types[0] != AgentEventTypeStart || types[len(types)-1] != AgentEventTypeEnd {
+		t.Errorf("unexpected events: %v", types)
+	}
+
+	var contents []string
+	for event := range texts.C {
+		contents = append(contents, event.Content)
+	}
+	if len(contents) != 1 || contents[0] != "Done" {
+		t.Errorf("expected filtered text event, got %v", contents)
+	}
+
+	// 总线关闭后订阅立即关闭
+	if _, ok := <-bus.Subscribe(nil).C; ok {
+		t.Error("expected closed subscription after bus closed")
+	}
+}
+
+func TestEventBus_DropPolicies(t *testing.T) {
+	bus := NewEventBus()
+	newest := bus.Subscribe(&SubscribeOptions{BufferSize: 2, Policy: DropPolicyDropNewest})
+	oldest := bus.Subscribe(&SubscribeOptions{BufferSize: 2, Policy: DropPolicyDropOldest})
+	for i := 1; i <= 4; i++ {
+		bus.Publish(context.Background(), AgentEvent{Type: AgentEventTypeIterationEnd, Iteration: i})
+	}
+	bus.Close()
+
+	collect := func(sub *Subscription) []int {
+		var iterations []int
+		for event := range sub.C {
+			iterations = append(iterations, event.Iteration)
+		}
+		return iterations
+	}
+	if got := collect(newest); len(got) != 2 || got[0] != 1 || got[1] != 2 || newest.Dropped() != 2 {
+		t.Errorf("drop newest: got %v, dropped %d", got, newest.Dropped())
+	}
+	if got := collect(oldest); len(got) != 2 || got[0] != 3 || got[1] != 4 || oldest.Dropped() != 2 {
+		t.Errorf("drop oldest: got %v, dropped %d", got, oldest.Dropped())
+	}
+}
+
+func TestEventBus_BlockingSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(&SubscribeOptions{BufferSize: 1})
+	bus.Publish(context.Background(), AgentEvent{Type: AgentEventTypeStart})
+
+	// 缓冲区已满时阻塞，上下文取消后放弃
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	bus.Publish(ctx, AgentEvent{Type: AgentEventTypeEnd})
+	if sub.Dropped() != 1 {
+		t.Errorf("expected 1 dropped event, got %d", sub.Dropped())
+	}
+
+	// 取消订阅会唤醒阻塞中的发布者
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(context.Background(), AgentEvent{Type: AgentEventTypeEnd})
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish still blocked after subscription closed")
+	}
+	sub.Close()
+}
+
+func TestRunnable_InvokeStreamStopsOnCancel(t *testing.T) {
+	// 模型不断调用工具，消费者只读取第一个事件
+	responses := make([]*llm.ModelResponse, 50)
+	for i := range responses {
+		responses[i] = &llm.ModelResponse{
+			ToolCalls:  []llm.ToolCall{{ID: "call", Name: "missing", Input: map[string]any{}}},
+			StopReason: "tool_use",
+		}
+	}
+	executor := NewRunnable(&Config{
+		LLMClient:     &MockLLMClient{responses: responses},
+		ToolRegistry:  tools.NewRegistry(),
+		MaxIterations: 50,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := executor.InvokeStream(ctx, &InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+	})
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+	<-stream
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	// 取消后执行停止并关闭通道，而不是阻塞在发送上；剩余的只有缓冲区中的事件
+	time.Sleep(10 * time.Millisecond)
+	remaining := 0
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-stream:
+			if !ok {
+				if remaining > cap(stream) {
+					t.Errorf("expected execution to stop after cancel, got %d more events", remaining)
+				}
+				return
+			}
+			remaining++
+		case <-timeout:
+			t.Fatal("stream not closed after cancel")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// subAgentInfoKey 用于在上下文中存储当前子 Agent 的标识
+type subAgentInfoKey struct{}
+
+// WithSubAgentInfo 返回携带当前子 Agent 标识的上下文
+func WithSubAgentInfo(ctx context.Context, info *SubAgentInfo) context.Context {
+	return context.WithValue(ctx, subAgentInfoKey{}, info)
+}
+
+// SubAgentInfoFromContext 返回上下文中当前子 Agent 的标识，主 Agent 返回 nil
+func SubAgentInfoFromContext(ctx context.Context) *SubAgentInfo {
+	info, _ := ctx.Value(subAgentInfoKey{}).(*SubAgentInfo)
+	return info
+}
+
+// InvokeNested 在工具中执行另一个 Agent
+// 调用方流式执行时以流式方式运行，事件标记为 info 后转发到调用方的事件流（更深层的事件已带标记，原样转发）；
+// 否则直接 Invoke。info 的 ID、Depth 和 ParentID 为空时根据上下文补全
+func InvokeNested(ctx context.Context, a Agent, input *InvokeInput, info SubAgentInfo) (*InvokeOutput, error) {
+	parent := SubAgentInfoFromContext(ctx)
+	if info.ID == "" {
+		b := make([]byte, 4)
+		rand.Read(b)
+		info.ID = "sub-" + hex.EncodeToString(b)
+	}
+	if parent != nil && info.ParentID == "" {
+		info.ParentID = parent.ID
+	}
+	if info.Depth == 0 {
+		info.Depth = 1
+		if parent != nil {
+			info.Depth = parent.Depth + 1
+		}
+	}
+	ctx = WithSubAgentInfo(ctx, &info)
+
+	emit := EventEmitterFromContext(ctx)
+	if emit == nil {
+		return a.Invoke(ctx, input)
+	}
+
+	stream, err := a.InvokeStream(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var output *InvokeOutput
+	for event := range stream {
+		if event.SubAgent == nil {
+			switch event.Type {
+			case AgentEventTypeEnd:
+				output = &InvokeOutput{}
+				output.Messages, _ = event.Metadata["messages"].([]llm.Message)
+				output.Files, _ = event.Metadata["files"].(map[string]string)
+				output.Metadata, _ = event.Metadata["metadata"].(map[string]any)
+				// 最终消息由调用方通过返回值处理，不随事件转发
+				event.Metadata = nil
+			case AgentEventTypeError:
+				err = event.Error
+			}
+			event.SubAgent = &info
+		}
+		event.Done = false
+		emit(event)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if output == nil {
+		return nil, fmt.Errorf("nested agent stream ended without a result")
+	}
+	return output, nil
+}
+
+// LastAssistantMessage 返回输出中最后一条助手消息的文本，没有时返回空字符串
+func LastAssistantMessage(output *InvokeOutput) string {
+	if output == nil {
+		return ""
+	}
+	for i := len(output.Messages) - 1; i >= 0; i-- {
+		if output.Messages[i].Role == llm.RoleAssistant {
+			return output.Messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// runKey 用于在上下文中存储当前执行
+type runKey struct{}
+
+// run 一次 Invoke/InvokeStream 的执行作用域
+// 同一个 Runnable 可以被并发调用，中间件的会话级状态保存在执行作用域中而不是中间件实例上
+type run struct {
+	id     string
+	parent *run // 发起本次嵌套执行的上级执行（顶层执行为 nil）
+	mu     sync.Mutex
+	values map[any]any
+	tools  *tools.Registry
+}
+
+// withRun 返回携带新执行作用域的上下文（嵌套执行拥有独立的作用域，并记录上级执行）
+func withRun(ctx context.Context, id string, registry *tools.Registry) context.Context {
+	parent, _ := ctx.Value(runKey{}).(*run)
+	return context.WithValue(ctx, runKey{}, &run{
+		id:     id,
+		parent: parent,
+		values: make(map[any]any),
+		tools:  registry,
+	})
+}
+
+// RunLocal 返回当前执行中 key 对应的状态，首次访问时调用 init 创建
+// 中间件以自身为 key 保存只属于本次执行的状态；ctx 不属于任何执行（如直接调用中间件钩子）时返回 false
+func RunLocal[T any](ctx context.Context, key any, init func() T) (T, bool) {
+	r, _ := ctx.Value(runKey{}).(*run)
+	if r == nil {
+		var zero T
+		return zero, false
+	}
+
+	r.mu.Lock()
+	v, ok := r.values[key]
+	r.mu.Unlock()
+	if ok {
+		return v.(T), true
+	}
+
+	// init 可能再次调用 RunLocal，不持锁执行；并发初始化时以先保存的为准
+	created := init()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.values[key]; ok {
+		return v.(T), true
+	}
+	r.values[key] = created
+	return created, true
+}
+
+// LookupRunLocal 依次在当前执行及其上级执行中查找 key 对应的状态，不创建新状态
+// 用于嵌套执行（子 Agent）继承上级执行的状态
+func LookupRunLocal[T any](ctx context.Context, key any) (T, bool) {
+	r, _ := ctx.Value(runKey{}).(*run)
+	for ; r != nil; r = r.parent {
+		r.mu.Lock()
+		v, ok := r.values[key]
+		r.mu.Unlock()
+		if ok {
+			return v.(T), true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// ToolsFromContext 返回当前执行的工具注册表
+// 每次执行开始时从 Config.ToolRegistry 复制，执行期间对它的修改只影响本次执行；ctx 不属于任何执行时返回 nil
+func ToolsFromContext(ctx context.Context) *tools.Registry {
+	if r, _ := ctx.Value(runKey{}).(*run); r != nil {
+		return r.tools
+	}
+	return nil
+}
+
+// RunIDFromContext 返回当前执行的 ID（与该执行产生的事件的 RunID 一致），ctx 不属于任何执行时返回空字符串
+func RunIDFromContext(ctx context.Context) string {
+	if r, _ := ctx.Value(runKey{}).(*run); r != nil {
+		return r.id
+	}
+	return ""
+}
+
+// newRunID 生成执行 ID
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "run-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// scriptedLLMClient 无状态的模拟客户端：首轮调用请求对应的工具，收到工具结果后结束
+type scriptedLLMClient struct{}
+
+func (c *scriptedLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if len(last.ToolResults) > 0 {
+		return &llm.ModelResponse{Content: "done: " + last.ToolResults[0].Content, StopReason: "end_turn"}, nil
+	}
+	return &llm.ModelResponse{
+		ToolCalls:  []llm.ToolCall{{ID: "call_" + last.Content, Name: "private_" + last.Content, Input: map[string]any{}}},
+		StopReason: "tool_use",
+	}, nil
+}
+
+func (c *scriptedLLMClient) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	resp, _ := c.Generate(ctx, req)
+	eventChan := make(chan llm.StreamEvent, len(resp.ToolCalls)+2)
+	if resp.Content != "" {
+		eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeText, Content: resp.Content}
+	}
+	for i := range resp.ToolCalls {
+		eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeToolUse, ToolCall: &resp.ToolCalls[i]}
+	}
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeEnd, StopReason: resp.StopReason, Done: true}
+	close(eventChan)
+	return eventChan, nil
+}
+
+func (c *scriptedLLMClient) CountTokens(messages []llm.Message) int {
+	return 0
+}
+
+// runScopedMiddleware 在执行作用域中记录状态，并为本次执行注册专属工具
+type runScopedMiddleware struct{}
+
+type runCounter struct {
+	task   string
+	models int
+}
+
+func (m *runScopedMiddleware) Name() string { return "run_scoped" }
+
+func (m *runScopedMiddleware) counter(ctx context.Context) *runCounter {
+	c, _ := RunLocal(ctx, m, func() *runCounter { return &runCounter{} })
+	return c
+}
+
+func (m *runScopedMiddleware) BeforeAgent(ctx context.Context, state *State) error {
+	task := state.GetMessages()[0].Content
+	m.counter(ctx).task = task
+	return ToolsFromContext(ctx).Register(tools.NewBaseTool("private_"+task, "", map[string]any{"type": "object"},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			return task, nil
+		}))
+}
+
+func (m *runScopedMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
+	m.counter(ctx).models++
+	for _, tool := range req.Tools {
+		if strings.HasPrefix(tool.Name, "private_") && tool.Name != "private_"+m.counter(ctx).task {
+			return fmt.Errorf("saw tool %s of another run", tool.Name)
+		}
+	}
+	return nil
+}
+
+func (m *runScopedMiddleware) AfterModel(ctx context.Context, resp *llm.ModelResponse, state *State) error {
+	return nil
+}
+
+func (m *runScopedMiddleware) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *State) error {
+	return nil
+}
+
+func (m *runScopedMiddleware) AfterTool(ctx context.Context, result *llm.ToolResult, state *State) error {
+	return nil
+}
+
+func (m *runScopedMiddleware) AfterAgent(ctx context.Context, state *State) error {
+	c := m.counter(ctx)
+	state.SetMetadata("task", c.task)
+	state.SetMetadata("model_calls", c.models)
+	return nil
+}
+
+func TestRunLocal_OutsideRun(t *testing.T) {
+	if _, ok := RunLocal(context.Background(), "key", func() int { return 1 }); ok {
+		t.Error("expected RunLocal to report no run outside of an invocation")
+	}
+	if ToolsFromContext(context.Background()) != nil {
+		t.Error("expected nil tools outside of an invocation")
+	}
+}
+
+func TestLookupRunLocal_InheritsFromParentRun(t *testing.T) {
+	parent := withRun(context.Background(), "run-parent", tools.NewRegistry())
+	RunLocal(parent, "key", func() string { return "parent" })
+	child := withRun(parent, "run-child", tools.NewRegistry())
+
+	if v, ok := LookupRunLocal[string](child, "key"); !ok || v != "parent" {
+		t.Errorf("expected nested run to inherit parent state, got %q (%v)", v, ok)
+	}
+	if _, ok := RunLocal(child, "other", func() int { return 1 }); !ok {
+		t.Fatal("expected RunLocal to succeed in nested run")
+	}
+	if _, ok := LookupRunLocal[int](parent, "other"); ok {
+		t.Error("expected nested run state to stay out of the parent run")
+	}
+	if _, ok := LookupRunLocal[string](context.Background(), "key"); ok {
+		t.Error("expected no state outside of an invocation")
+	}
+}
+
+func TestRunnable_ConcurrentInvoke(t *testing.T) {
+	registry := tools.NewRegistry()
+	runnable := NewRunnable(&Config{
+		LLMClient:    &scriptedLLMClient{},
+		ToolRegistry: registry,
+		Middlewares:  []Middleware{&runScopedMiddleware{}},
+	})
+
+	// 所有执行共享同一个元数据输入
+	shared := map[string]any{"tenant": "acme"}
+
+	const runs = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task := fmt.Sprintf("task%d", i)
+			input := &InvokeInput{
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: task}},
+				Metadata: shared,
+			}
+
+			var output *InvokeOutput
+			if i%2 == 0 {
+				var err error
+				if output, err = runnable.Invoke(context.Background(), input); err != nil {
+					errs <- err
+					return
+				}
+			} else {
+				stream, err := runnable.InvokeStream(context.Background(), input)
+				if err != nil {
+					errs <- err
+					return
+				}
+				for event := range stream {
+					switch event.Type {
+					case AgentEventTypeError:
+						errs <- event.Error
+					case AgentEventTypeEnd:
+						output = &InvokeOutput{
+							Messages: event.Metadata["messages"].([]llm.Message),
+							Metadata: event.Metadata["metadata"].(map[string]any),
+						}
+					}
+				}
+				if output == nil {
+					return
+				}
+			}
+
+			if got := LastAssistantMessage(output); got != "done: "+task {
+				errs <- fmt.Errorf("%s: unexpected answer %q", task, got)
+			}
+			if output.Metadata["task"] != task || output.Metadata["model_calls"] != 2 {
+				errs <- fmt.Errorf("%s: unexpected metadata %v", task, output.Metadata)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// 执行期间注册的工具和写入的元数据不影响共享的注册表和输入
+	if n := len(registry.List()); n != 0 {
+		t.Errorf("expected shared registry to stay empty, got %d tools", n)
+	}
+	if len(shared) != 1 {
+		t.Errorf("expected shared metadata to be untouched, got %v", shared)
+	}
+}
@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/zhoucx/deepagents-go/pkg/llm"
 	"github.com/zhoucx/deepagents-go/pkg/tools"
@@ -17,11 +19,14 @@ type Config struct {
 	MaxIterations int
 	MaxTokens     int
 	Temperature   float64
+	Thinking      *llm.ThinkingConfig                                // 扩展思考配置，为 nil 时不启用
 	OnToolCall    func(toolName string, input map[string]any)        // 工具调用回调
 	OnToolResult  func(toolName string, result string, isError bool) // 工具结果回调
 }
 
 // Runnable 实现 Agent 执行器
+// 同一个 Runnable 可以被并发调用：每次执行拥有独立的状态、执行作用域（见 RunLocal）和工具注册表副本
+// Invoke 和 InvokeStream 共用同一个执行引擎，钩子、错误和事件的语义完全一致
 type Runnable struct {
 	config      *Config
 	middlewares []Middleware
@@ -47,17 +52,68 @@ func (e *Runnable) GetMiddlewares() []Middleware {
 	return e.middlewares
 }
 
-// Invoke 执行 Agent
+// Invoke 执行 Agent（非流式）
+// 在执行引擎之上丢弃过程事件，模型通过 Generate 一次性调用
 func (e *Runnable) Invoke(ctx context.Context, input *InvokeInput) (*InvokeOutput, error) {
-	// 初始化状态
-	state := NewState()
-	state.Messages = input.Messages
-	if input.Files != nil {
-		state.Files = input.Files
+	return e.execute(ctx, newRunID(), input, false, func(AgentEvent) {})
+}
+
+// InvokeStream 执行 Agent（流式）
+// 执行引擎产生的事件依次发送到通道，最后以结束事件（携带最终的消息、文件和元数据）或错误事件结束；
+// 上下文取消后不再阻塞在发送上，执行在下一个步骤前停止并关闭通道。需要多个订阅者时使用 EventBus
+// 事件带有本次执行的 RunID 和产生时间（子 Agent 转发的事件保留各自的 RunID）
+func (e *Runnable) InvokeStream(ctx context.Context, input *InvokeInput) (<-chan AgentEvent, error) {
+	eventChan := make(chan AgentEvent, 20)
+	runID := newRunID()
+	send := func(event AgentEvent) {
+		SendEvent(ctx, eventChan, StampEvent(event, runID))
 	}
-	if input.Metadata != nil {
-		state.Metadata = input.Metadata
+
+	go func() {
+		defer close(eventChan)
+
+		output, err := e.execute(ctx, runID, input, true, send)
+		if err != nil {
+			send(AgentEvent{
+				Type:  AgentEventTypeError,
+				Error: err,
+				Done:  true,
+			})
+			return
+		}
+
+		// 发送结束事件
+		send(AgentEvent{
+			Type: AgentEventTypeEnd,
+			Metadata: map[string]any{
+				"messages": output.Messages,
+				"files":    output.Files,
+				"metadata": output.Metadata,
+			},
+			Done: true,
+		})
+	}()
+
+	return eventChan, nil
+}
+
+// execute 执行引擎：按步骤执行钩子、模型调用和工具调用，并通过 emit 发送过程事件（不含结束和错误事件）
+// streaming 为 true 时通过 StreamGenerate 调用模型，工具可通过 EventEmitterFromContext 向事件流转发事件
+func (e *Runnable) execute(ctx context.Context, runID string, input *InvokeInput, streaming bool, emit EventEmitter) (*InvokeOutput, error) {
+	// 初始化状态和执行作用域
+	state := newRunState(input)
+	ctx = withRun(ctx, runID, e.config.ToolRegistry.Clone())
+
+	// 工具执行期间产生的事件（如子 Agent 的执行过程）转发到本次执行的事件流；
+	// 非流式执行时屏蔽外层的事件发送函数
+	var toolEmit EventEmitter
+	if streaming {
+		toolEmit = emit
 	}
+	toolCtx := WithEventEmitter(ctx, toolEmit)
+
+	// 发送开始事件
+	emit(AgentEvent{Type: AgentEventTypeStart})
 
 	// 执行 BeforeAgent 钩子
 	for _, m := range e.middlewares {
@@ -67,130 +123,17 @@ func (e *Runnable) Invoke(ctx context.Context, input *InvokeInput) (*InvokeOutpu
 	}
 
 	// 主循环
-	for i := 0; i < e.config.MaxIterations; i++ {
-		// 构建 LLM 请求
-		req := &llm.ModelRequest{
-			Messages:     state.GetMessages(),
-			SystemPrompt: e.config.SystemPrompt,
-			MaxTokens:    e.config.MaxTokens,
-			Temperature:  e.config.Temperature,
-		}
-
-		// 添加工具定义
-		toolsList := e.config.ToolRegistry.List()
-		req.Tools = make([]llm.ToolSchema, 0, len(toolsList))
-		for _, tool := range toolsList {
-			req.Tools = append(req.Tools, llm.ToolSchema{
-				Name:        tool.Name(),
-				Description: tool.Description(),
-				InputSchema: tool.Parameters(),
-			})
+	for i := 1; i <= e.config.MaxIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
-
-		// 执行 BeforeModel 钩子
-		for _, m := range e.middlewares {
-			if err := m.BeforeModel(ctx, req); err != nil {
-				return nil, fmt.Errorf("before model hook failed: %w", err)
-			}
-		}
-
-		// 调用 LLM
-		resp, err := e.config.LLMClient.Generate(ctx, req)
+		done, err := e.step(ctx, toolCtx, state, i, streaming, emit)
 		if err != nil {
-			return nil, fmt.Errorf("llm generate failed: %w", err)
-		}
-
-		// 添加助手消息（包含文本内容和工具调用）
-		assistantMsg := llm.Message{
-			Role:      llm.RoleAssistant,
-			Content:   resp.Content,
-			ToolCalls: resp.ToolCalls,
-		}
-		state.AddMessage(assistantMsg)
-
-		// 执行 AfterModel 钩子
-		for _, m := range e.middlewares {
-			if err := m.AfterModel(ctx, resp, state); err != nil {
-				return nil, fmt.Errorf("after model hook failed: %w", err)
-			}
+			return nil, err
 		}
-
-		// 检查是否需要执行工具
-		if len(resp.ToolCalls) == 0 {
-			// 没有工具调用，结束循环
+		if done {
 			break
 		}
-
-		// 执行工具调用，收集所有结果
-		var toolResults []llm.ToolResult
-		for _, toolCall := range resp.ToolCalls {
-			// 通知工具调用开始
-			if e.config.OnToolCall != nil {
-				e.config.OnToolCall(toolCall.Name, toolCall.Input)
-			}
-
-			// 执行 BeforeTool 钩子
-			for _, m := range e.middlewares {
-				if err := m.BeforeTool(ctx, &toolCall, state); err != nil {
-					return nil, fmt.Errorf("before tool hook failed: %w", err)
-				}
-			}
-
-			// 获取工具
-			tool, ok := e.config.ToolRegistry.Get(toolCall.Name)
-			if !ok {
-				// 工具不存在，返回错误
-				result := &llm.ToolResult{
-					ToolCallID: toolCall.ID,
-					Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
-					IsError:    true,
-				}
-
-				// 通知工具结果
-				if e.config.OnToolResult != nil {
-					e.config.OnToolResult(toolCall.Name, result.Content, true)
-				}
-
-				for _, m := range e.middlewares {
-					if err := m.AfterTool(ctx, result, state); err != nil {
-						return nil, fmt.Errorf("after tool hook failed: %w", err)
-					}
-				}
-				toolResults = append(toolResults, *result)
-				continue
-			}
-
-			// 执行工具
-			output, err := tool.Execute(ctx, toolCall.Input)
-			result := &llm.ToolResult{
-				ToolCallID: toolCall.ID,
-				Content:    output,
-				IsError:    err != nil,
-			}
-			if err != nil {
-				result.Content = fmt.Sprintf("Tool execution error: %v", err)
-			}
-
-			// 通知工具结果
-			if e.config.OnToolResult != nil {
-				e.config.OnToolResult(toolCall.Name, result.Content, result.IsError)
-			}
-
-			// 执行 AfterTool 钩子
-			for _, m := range e.middlewares {
-				if err := m.AfterTool(ctx, result, state); err != nil {
-					return nil, fmt.Errorf("after tool hook failed: %w", err)
-				}
-			}
-
-			toolResults = append(toolResults, *result)
-		}
-
-		// 添加工具结果到消息（结构化格式）
-		state.AddMessage(llm.Message{
-			Role:        llm.RoleUser,
-			ToolResults: toolResults,
-		})
 	}
 
 	// 执行 AfterAgent 钩子
@@ -200,7 +143,6 @@ func (e *Runnable) Invoke(ctx context.Context, input *InvokeInput) (*InvokeOutpu
 		}
 	}
 
-	// 返回结果
 	return &InvokeOutput{
 		Messages: state.GetMessages(),
 		Files:    state.Files,
@@ -208,298 +150,220 @@ func (e *Runnable) Invoke(ctx context.Context, input *InvokeInput) (*InvokeOutpu
 	}, nil
 }
 
-// InvokeStream 执行 Agent（流式）
-func (e *Runnable) InvokeStream(ctx context.Context, input *InvokeInput) (<-chan AgentEvent, error) {
-	eventChan := make(chan AgentEvent, 20)
+// step 执行一次迭代：调用模型并执行其请求的工具，模型不再调用工具时返回 done
+func (e *Runnable) step(ctx, toolCtx context.Context, state *State, iteration int, streaming bool, emit EventEmitter) (done bool, err error) {
+	registry := ToolsFromContext(ctx)
+
+	// 构建 LLM 请求
+	req := &llm.ModelRequest{
+		Messages:     state.GetMessages(),
+		SystemPrompt: e.config.SystemPrompt,
+		MaxTokens:    e.config.MaxTokens,
+		Temperature:  e.config.Temperature,
+		Thinking:     e.config.Thinking,
+	}
 
-	go func() {
-		defer close(eventChan)
+	// 添加工具定义
+	toolsList := registry.List()
+	req.Tools = make([]llm.ToolSchema, 0, len(toolsList))
+	for _, tool := range toolsList {
+		req.Tools = append(req.Tools, llm.ToolSchema{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			InputSchema: tool.Parameters(),
+		})
+	}
 
-		// 初始化状态
-		state := NewState()
-		state.Messages = input.Messages
-		if input.Files != nil {
-			state.Files = input.Files
-		}
-		if input.Metadata != nil {
-			state.Metadata = input.Metadata
+	// 执行 BeforeModel 钩子
+	for _, m := range e.middlewares {
+		if err := m.BeforeModel(ctx, req); err != nil {
+			return false, fmt.Errorf("before model hook failed: %w", err)
 		}
+	}
+
+	// 调用 LLM
+	emit(AgentEvent{Type: AgentEventTypeLLMStart, Iteration: iteration})
+	resp, err := e.generate(ctx, req, iteration, streaming, emit)
+	if err != nil {
+		return false, err
+	}
 
-		// 发送开始事件
-		eventChan <- AgentEvent{
-			Type: AgentEventTypeStart,
-			Done: false,
+	// 添加助手消息（包含思考、文本内容和工具调用）
+	state.AddMessage(llm.Message{
+		Role:      llm.RoleAssistant,
+		Content:   resp.Content,
+		Reasoning: resp.Reasoning,
+		ToolCalls: resp.ToolCalls,
+	})
+
+	// 执行 AfterModel 钩子
+	for _, m := range e.middlewares {
+		if err := m.AfterModel(ctx, resp, state); err != nil {
+			return false, fmt.Errorf("after model hook failed: %w", err)
 		}
+	}
 
-		// 执行 BeforeAgent 钩子
-		for _, m := range e.middlewares {
-			if err := m.BeforeAgent(ctx, state); err != nil {
-				eventChan <- AgentEvent{
-					Type:  AgentEventTypeError,
-					Error: fmt.Errorf("before agent hook failed: %w", err),
-					Done:  true,
-				}
-				return
-			}
+	// 检查是否需要执行工具
+	if len(resp.ToolCalls) == 0 {
+		// 没有工具调用，结束循环
+		emit(AgentEvent{Type: AgentEventTypeIterationEnd, Iteration: iteration})
+		return true, nil
+	}
+
+	// 执行工具调用，收集所有结果
+	var toolResults []llm.ToolResult
+	for _, toolCall := range resp.ToolCalls {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		result, err := e.executeTool(ctx, toolCtx, registry, state, toolCall, iteration, emit)
+		if err != nil {
+			return false, err
 		}
+		toolResults = append(toolResults, *result)
+	}
 
-		// 主循环
-		for i := 0; i < e.config.MaxIterations; i++ {
-			// 构建 LLM 请求
-			req := &llm.ModelRequest{
-				Messages:     state.GetMessages(),
-				SystemPrompt: e.config.SystemPrompt,
-				MaxTokens:    e.config.MaxTokens,
-				Temperature:  e.config.Temperature,
-			}
+	// 添加工具结果到消息（结构化格式）
+	state.AddMessage(llm.Message{
+		Role:        llm.RoleUser,
+		ToolResults: toolResults,
+	})
 
-			// 添加工具定义
-			toolsList := e.config.ToolRegistry.List()
-			req.Tools = make([]llm.ToolSchema, 0, len(toolsList))
-			for _, tool := range toolsList {
-				req.Tools = append(req.Tools, llm.ToolSchema{
-					Name:        tool.Name(),
-					Description: tool.Description(),
-					InputSchema: tool.Parameters(),
-				})
-			}
+	// 迭代结束
+	emit(AgentEvent{Type: AgentEventTypeIterationEnd, Iteration: iteration})
+	return false, nil
+}
 
-			// 执行 BeforeModel 钩子
-			for _, m := range e.middlewares {
-				if err := m.BeforeModel(ctx, req); err != nil {
-					eventChan <- AgentEvent{
-						Type:  AgentEventTypeError,
-						Error: fmt.Errorf("before model hook failed: %w", err),
-						Done:  true,
-					}
-					return
-				}
+// generate 调用模型并发送思考、文本、工具调用和生成结束事件
+// 流式调用时逐块转发；非流式调用时在响应返回后一次性发送相同的事件
+func (e *Runnable) generate(ctx context.Context, req *llm.ModelRequest, iteration int, streaming bool, emit EventEmitter) (*llm.ModelResponse, error) {
+	if !streaming {
+		resp, err := e.config.LLMClient.Generate(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("llm generate failed: %w", err)
+		}
+		for _, r := range resp.Reasoning {
+			if r.Text != "" {
+				emit(AgentEvent{Type: AgentEventTypeLLMThinking, Content: r.Text, Iteration: iteration})
 			}
+		}
+		if resp.Content != "" {
+			emit(AgentEvent{Type: AgentEventTypeLLMText, Content: resp.Content, Iteration: iteration})
+		}
+		for i := range resp.ToolCalls {
+			emit(AgentEvent{Type: AgentEventTypeLLMToolCall, ToolCall: &resp.ToolCalls[i], Iteration: iteration})
+		}
+		emit(AgentEvent{Type: AgentEventTypeLLMEnd, Iteration: iteration, Metadata: map[string]any{"stop_reason": resp.StopReason}})
+		return resp, nil
+	}
 
-			// 发送 LLM 开始事件
-			eventChan <- AgentEvent{
-				Type:      AgentEventTypeLLMStart,
-				Iteration: i + 1,
-				Done:      false,
-			}
+	stream, err := e.config.LLMClient.StreamGenerate(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("llm generate failed: %w", err)
+	}
 
-			// 调用流式 LLM
-			stream, err := e.config.LLMClient.StreamGenerate(ctx, req)
-			if err != nil {
-				eventChan <- AgentEvent{
-					Type:  AgentEventTypeError,
-					Error: fmt.Errorf("llm stream generate failed: %w", err),
-					Done:  true,
-				}
-				return
+	// 累积响应内容和工具调用
+	resp := &llm.ModelResponse{}
+	for streamEvent := range stream {
+		switch streamEvent.Type {
+		case llm.StreamEventTypeThinking:
+			// 转发思考增量，累积完整的思考块
+			if streamEvent.Reasoning != nil {
+				resp.Reasoning = append(resp.Reasoning, *streamEvent.Reasoning)
+			} else if streamEvent.Content != "" {
+				emit(AgentEvent{Type: AgentEventTypeLLMThinking, Content: streamEvent.Content, Iteration: iteration})
 			}
 
-			// 累积响应内容和工具调用
-			var fullContent string
-			var toolCalls []llm.ToolCall
-			var stopReason string
-
-			// 处理流式事件
-			for streamEvent := range stream {
-				switch streamEvent.Type {
-				case llm.StreamEventTypeText:
-					// 转发文本事件
-					fullContent += streamEvent.Content
-					eventChan <- AgentEvent{
-						Type:      AgentEventTypeLLMText,
-						Content:   streamEvent.Content,
-						Iteration: i + 1,
-						Done:      false,
-					}
-
-				case llm.StreamEventTypeToolUse:
-					// 转发工具调用事件
-					if streamEvent.ToolCall != nil {
-						toolCalls = append(toolCalls, *streamEvent.ToolCall)
-						eventChan <- AgentEvent{
-							Type:      AgentEventTypeLLMToolCall,
-							ToolCall:  streamEvent.ToolCall,
-							Iteration: i + 1,
-							Done:      false,
-						}
-					}
-
-				case llm.StreamEventTypeEnd:
-					// LLM 生成结束
-					stopReason = streamEvent.StopReason
-					eventChan <- AgentEvent{
-						Type:      AgentEventTypeLLMEnd,
-						Iteration: i + 1,
-						Metadata:  map[string]any{"stop_reason": stopReason},
-						Done:      false,
-					}
-
-				case llm.StreamEventTypeError:
-					// 错误事件
-					eventChan <- AgentEvent{
-						Type:  AgentEventTypeError,
-						Error: streamEvent.Error,
-						Done:  true,
-					}
-					return
-				}
-			}
+		case llm.StreamEventTypeText:
+			// 转发文本事件
+			resp.Content += streamEvent.Content
+			emit(AgentEvent{Type: AgentEventTypeLLMText, Content: streamEvent.Content, Iteration: iteration})
 
-			// 构建响应对象（用于中间件）
-			resp := &llm.ModelResponse{
-				Content:    fullContent,
-				ToolCalls:  toolCalls,
-				StopReason: stopReason,
+		case llm.StreamEventTypeToolUse:
+			// 转发工具调用事件
+			if streamEvent.ToolCall != nil {
+				resp.ToolCalls = append(resp.ToolCalls, *streamEvent.ToolCall)
+				emit(AgentEvent{Type: AgentEventTypeLLMToolCall, ToolCall: streamEvent.ToolCall, Iteration: iteration})
 			}
 
-			// 添加助手消息（包含文本内容和工具调用）
-			assistantMsg := llm.Message{
-				Role:      llm.RoleAssistant,
-				Content:   resp.Content,
-				ToolCalls: resp.ToolCalls,
-			}
-			state.AddMessage(assistantMsg)
-
-			// 执行 AfterModel 钩子
-			for _, m := range e.middlewares {
-				if err := m.AfterModel(ctx, resp, state); err != nil {
-					eventChan <- AgentEvent{
-						Type:  AgentEventTypeError,
-						Error: fmt.Errorf("after model hook failed: %w", err),
-						Done:  true,
-					}
-					return
-				}
-			}
+		case llm.StreamEventTypeEnd:
+			// LLM 生成结束
+			resp.StopReason = streamEvent.StopReason
+			emit(AgentEvent{Type: AgentEventTypeLLMEnd, Iteration: iteration, Metadata: map[string]any{"stop_reason": resp.StopReason}})
 
-			// 检查是否需要执行工具
-			if len(resp.ToolCalls) == 0 {
-				// 没有工具调用，结束循环
-				eventChan <- AgentEvent{
-					Type:      AgentEventTypeIterationEnd,
-					Iteration: i + 1,
-					Done:      false,
-				}
-				break
+		case llm.StreamEventTypeError:
+			// 排空剩余事件，避免生产者阻塞
+			for range stream {
 			}
+			return nil, fmt.Errorf("llm generate failed: %w", streamEvent.Error)
+		}
+	}
+	return resp, nil
+}
 
-			// 执行工具调用，收集所有结果
-			var toolResults []llm.ToolResult
-			for _, toolCall := range resp.ToolCalls {
-				// 发送工具开始事件
-				eventChan <- AgentEvent{
-					Type:      AgentEventTypeToolStart,
-					ToolCall:  &toolCall,
-					Iteration: i + 1,
-					Done:      false,
-				}
-
-				// 通知工具调用开始
-				if e.config.OnToolCall != nil {
-					e.config.OnToolCall(toolCall.Name, toolCall.Input)
-				}
-
-				// 执行 BeforeTool 钩子
-				for _, m := range e.middlewares {
-					if err := m.BeforeTool(ctx, &toolCall, state); err != nil {
-						eventChan <- AgentEvent{
-							Type:  AgentEventTypeError,
-							Error: fmt.Errorf("before tool hook failed: %w", err),
-							Done:  true,
-						}
-						return
-					}
-				}
-
-				// 获取工具
-				tool, ok := e.config.ToolRegistry.Get(toolCall.Name)
-				var result *llm.ToolResult
-
-				if !ok {
-					// 工具不存在，返回错误
-					result = &llm.ToolResult{
-						ToolCallID: toolCall.ID,
-						Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
-						IsError:    true,
-					}
-				} else {
-					// 执行工具
-					output, err := tool.Execute(ctx, toolCall.Input)
-					result = &llm.ToolResult{
-						ToolCallID: toolCall.ID,
-						Content:    output,
-						IsError:    err != nil,
-					}
-					if err != nil {
-						result.Content = fmt.Sprintf("Tool execution error: %v", err)
-					}
-				}
-
-				// 发送工具结果事件
-				eventChan <- AgentEvent{
-					Type:       AgentEventTypeToolResult,
-					ToolResult: result,
-					Iteration:  i + 1,
-					Done:       false,
-				}
-
-				// 通知工具结果
-				if e.config.OnToolResult != nil {
-					e.config.OnToolResult(toolCall.Name, result.Content, result.IsError)
-				}
-
-				// 执行 AfterTool 钩子
-				for _, m := range e.middlewares {
-					if err := m.AfterTool(ctx, result, state); err != nil {
-						eventChan <- AgentEvent{
-							Type:  AgentEventTypeError,
-							Error: fmt.Errorf("after tool hook failed: %w", err),
-							Done:  true,
-						}
-						return
-					}
-				}
-
-				toolResults = append(toolResults, *result)
-			}
+// executeTool 执行单个工具调用（包括前后钩子和回调），工具不存在或执行失败时返回错误结果而不是错误
+func (e *Runnable) executeTool(ctx, toolCtx context.Context, registry *tools.Registry, state *State, toolCall llm.ToolCall, iteration int, emit EventEmitter) (*llm.ToolResult, error) {
+	// 发送工具开始事件
+	emit(AgentEvent{Type: AgentEventTypeToolStart, ToolCall: &toolCall, Iteration: iteration})
 
-			// 添加工具结果到消息（结构化格式）
-			state.AddMessage(llm.Message{
-				Role:        llm.RoleUser,
-				ToolResults: toolResults,
-			})
+	// 通知工具调用开始
+	if e.config.OnToolCall != nil {
+		e.config.OnToolCall(toolCall.Name, toolCall.Input)
+	}
 
-			// 迭代结束
-			eventChan <- AgentEvent{
-				Type:      AgentEventTypeIterationEnd,
-				Iteration: i + 1,
-				Done:      false,
-			}
+	// 执行 BeforeTool 钩子
+	for _, m := range e.middlewares {
+		if err := m.BeforeTool(ctx, &toolCall, state); err != nil {
+			return nil, fmt.Errorf("before tool hook failed: %w", err)
 		}
+	}
 
-		// 执行 AfterAgent 钩子
-		for _, m := range e.middlewares {
-			if err := m.AfterAgent(ctx, state); err != nil {
-				eventChan <- AgentEvent{
-					Type:  AgentEventTypeError,
-					Error: fmt.Errorf("after agent hook failed: %w", err),
-					Done:  true,
-				}
-				return
-			}
+	result := &llm.ToolResult{ToolCallID: toolCall.ID}
+	if tool, ok := registry.Get(toolCall.Name); !ok {
+		// 工具不存在，返回错误
+		result.Content = fmt.Sprintf("Tool not found: %s", toolCall.Name)
+		result.IsError = true
+	} else {
+		// 执行工具，工具可通过 tools.Attach 在结果中附带图片等内容块
+		attachCtx, attachments := tools.WithAttachments(toolCtx)
+		output, err := tool.Execute(attachCtx, toolCall.Input)
+		result.Content = output
+		result.Blocks = attachments.Blocks()
+		if err != nil {
+			result.Content = fmt.Sprintf("Tool execution error: %v", err)
+			result.Blocks = nil
+			result.IsError = true
 		}
+	}
 
-		// 发送结束事件
-		eventChan <- AgentEvent{
-			Type: AgentEventTypeEnd,
-			Metadata: map[string]any{
-				"messages": state.GetMessages(),
-				"files":    state.Files,
-				"metadata": state.Metadata,
-			},
-			Done: true,
+	// 发送工具结果事件
+	emit(AgentEvent{Type: AgentEventTypeToolResult, ToolResult: result, Iteration: iteration})
+
+	// 通知工具结果
+	if e.config.OnToolResult != nil {
+		e.config.OnToolResult(toolCall.Name, result.Content, result.IsError)
+	}
+
+	// 执行 AfterTool 钩子
+	for _, m := range e.middlewares {
+		if err := m.AfterTool(ctx, result, state); err != nil {
+			return nil, fmt.Errorf("after tool hook failed: %w", err)
 		}
-	}()
+	}
 
-	return eventChan, nil
+	return result, nil
+}
+
+// newRunState 根据输入创建本次执行的状态
+// 消息、文件和元数据均复制一份，同一输入被并发执行时互不影响
+func newRunState(input *InvokeInput) *State {
+	state := NewState()
+	state.Messages = slices.Clone(input.Messages)
+	if input.Files != nil {
+		state.Files = maps.Clone(input.Files)
+	}
+	if input.Metadata != nil {
+		state.Metadata = maps.Clone(input.Metadata)
+	}
+	return state
 }
@@ -279,3 +279,70 @@ func TestExecutor_ToolNotFound(t *testing.T) {
 		t.Error("Expected error message for tool not found")
 	}
 }
+
+func TestRunnable_InvokeStreamForwardsToolEvents(t *testing.T) {
+	mockClient := &MockLLMClient{
+		responses: []*llm.ModelResponse{
+			{
+				ToolCalls:  []llm.ToolCall{{ID: "call_1", Name: "nested", Input: map[string]any{}}},
+				StopReason: "tool_use",
+			},
+		},
+	}
+
+	toolRegistry := tools.NewRegistry()
+	toolRegistry.Register(tools.NewBaseTool(
+		"nested",
+		"转发内部事件的工具",
+		map[string]any{"type": "object"},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			emit := EventEmitterFromContext(ctx)
+			if emit == nil {
+				return "", nil
+			}
+			emit(AgentEvent{Type: AgentEventTypeLLMText, Content: "inner", SubAgent: &SubAgentInfo{ID: "sub-1", Name: "child", Depth: 1}})
+			return "OK", nil
+		},
+	))
+
+	executor := NewRunnable(&Config{LLMClient: mockClient, ToolRegistry: toolRegistry})
+	stream, err := executor.InvokeStream(context.Background(), &InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "测试"}},
+	})
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	var order []AgentEventType
+	var inner *AgentEvent
+	for event := range stream {
+		order = append(order, event.Type)
+		if event.SubAgent != nil {
+			inner = &event
+		}
+	}
+	if inner == nil || inner.Content != "inner" || inner.SubAgent.Name != "child" {
+		t.Fatalf("expected forwarded event, got %+v", inner)
+	}
+	// 内部事件位于工具开始和工具结果之间
+	var sawStart, sawInner bool
+	for i, typ := range order {
+		switch {
+		case typ == AgentEventTypeToolStart:
+			sawStart = true
+		case typ == AgentEventTypeLLMText && sawStart && !sawInner:
+			sawInner = true
+			if order[i+1] != AgentEventTypeToolResult {
+				t.Errorf("expected tool result after forwarded event, got %v", order)
+			}
+		}
+	}
+	if !sawInner {
+		t.Errorf("forwarded event not emitted during tool execution: %v", order)
+	}
+
+	// 非流式执行时没有事件发送函数
+	if EventEmitterFromContext(context.Background()) != nil {
+		t.Error("expected nil emitter outside of streaming")
+	}
+}
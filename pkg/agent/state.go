@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"maps"
 	"sync"
 
 	"github.com/zhoucx/deepagents-go/pkg/llm"
@@ -66,3 +67,14 @@ func (s *State) GetMetadata(key string) (any, bool) {
 	value, ok := s.Metadata[key]
 	return value, ok
 }
+
+// Clone 复制状态（消息列表和映射表为新副本，其中的值浅拷贝）
+func (s *State) Clone() *State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := NewState()
+	c.Messages = append(c.Messages, s.Messages...)
+	maps.Copy(c.Files, s.Files)
+	maps.Copy(c.Metadata, s.Metadata)
+	return c
+}
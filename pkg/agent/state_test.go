@@ -141,3 +141,28 @@ func TestNewState(t *testing.T) {
 		t.Error("Expected empty Metadata")
 	}
 }
+
+func TestState_Clone(t *testing.T) {
+	state := NewState()
+	state.AddMessage(llm.Message{Role: llm.RoleUser, Content: "hi"})
+	state.SetFile("/a.txt", "a")
+	state.SetMetadata("k", "v")
+
+	clone := state.Clone()
+	clone.AddMessage(llm.Message{Role: llm.RoleAssistant, Content: "hello"})
+	clone.SetFile("/b.txt", "b")
+	clone.SetMetadata("k", "changed")
+
+	if len(state.GetMessages()) != 1 {
+		t.Errorf("Clone should not share messages, got %d", len(state.GetMessages()))
+	}
+	if _, ok := state.GetFile("/b.txt"); ok {
+		t.Error("Clone should not share files")
+	}
+	if v, _ := state.GetMetadata("k"); v != "v" {
+		t.Errorf("Clone should not share metadata, got %v", v)
+	}
+	if len(clone.GetMessages()) != 2 || clone.Files["/a.txt"] != "a" {
+		t.Errorf("Clone should copy existing content: %+v", clone)
+	}
+}
@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// ErrThreadBusy 对话线程正在执行中
+var ErrThreadBusy = errors.New("thread is busy with another invocation")
+
+// ThreadData 对话线程的持久化数据
+type ThreadData struct {
+	ID        string            `json:"id"`
+	ParentID  string            `json:"parent_id,omitempty"` // 由 Fork 创建时为来源线程 ID
+	Messages  []llm.Message     `json:"messages"`
+	Files     map[string]string `json:"files,omitempty"`
+	Metadata  map[string]any    `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ThreadConfig 对话线程配置
+type ThreadConfig struct {
+	ID       string         // 线程 ID，为空时自动生成
+	Store    ThreadStore    // 非 nil 时每次变更后保存线程
+	Messages []llm.Message  // 初始消息（如恢复的历史会话）
+	Metadata map[string]any // 初始元数据（如 session_id）
+}
+
+// Thread 多轮对话线程
+// 在多次 Invoke/InvokeStream 之间保存对话状态（消息、文件、元数据，包括 Todo 中间件写入的 todos），
+// 每次调用只需传入新消息；同一线程同一时刻只能有一次执行
+type Thread struct {
+	agent   Agent
+	store   ThreadStore
+	mu      sync.Mutex
+	data    ThreadData
+	running bool
+}
+
+// NewThread 创建对话线程
+func NewThread(a Agent, config *ThreadConfig) *Thread {
+	if config == nil {
+		config = &ThreadConfig{}
+	}
+	id := config.ID
+	if id == "" {
+		id = newThreadID()
+	}
+	now := time.Now()
+	return &Thread{
+		agent: a,
+		store: config.Store,
+		data: ThreadData{
+			ID:        id,
+			Messages:  slices.Clone(config.Messages),
+			Files:     make(map[string]string),
+			Metadata:  cloneMetadata(config.Metadata),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+}
+
+// LoadThread 从存储加载对话线程
+func LoadThread(ctx context.Context, a Agent, store ThreadStore, id string) (*Thread, error) {
+	data, err := store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if data.Files == nil {
+		data.Files = make(map[string]string)
+	}
+	if data.Metadata == nil {
+		data.Metadata = make(map[string]any)
+	}
+	return &Thread{agent: a, store: store, data: *data}, nil
+}
+
+// ID 返回线程 ID
+func (t *Thread) ID() string {
+	return t.data.ID
+}
+
+// ParentID 返回来源线程 ID（非 Fork 创建时为空）
+func (t *Thread) ParentID() string {
+	return t.data.ParentID
+}
+
+// Messages 返回消息列表的副本
+func (t *Thread) Messages() []llm.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.data.Messages)
+}
+
+// Files 返回文件的副本
+func (t *Thread) Files() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.data.Files)
+}
+
+// Metadata 返回元数据的副本
+func (t *Thread) Metadata() map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return cloneMetadata(t.data.Metadata)
+}
+
+// Todos 返回 Todo 中间件记录的当前 Todo 列表（Markdown），没有时返回空字符串
+func (t *Thread) Todos() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	todos, _ := t.data.Metadata["todos"].(string)
+	return todos
+}
+
+// Invoke 追加新消息并执行一轮对话，成功后更新线程状态；失败时线程状态不变
+func (t *Thread) Invoke(ctx context.Context, messages ...llm.Message) (*InvokeOutput, error) {
+	input, err := t.begin(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := t.agent.Invoke(ctx, input)
+	if err != nil {
+		t.end(ctx, nil)
+		return nil, err
+	}
+	if err := t.end(ctx, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// InvokeStream 追加新消息并流式执行一轮对话
+// 线程状态在转发结束事件之前更新，收到结束事件后即可读取最新状态；出错时线程状态不变
+func (t *Thread) InvokeStream(ctx context.Context, messages ...llm.Message) (<-chan AgentEvent, error) {
+	input, err := t.begin(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := t.agent.InvokeStream(ctx, input)
+	if err != nil {
+		t.end(ctx, nil)
+		return nil, err
+	}
+
+	eventChan := make(chan AgentEvent, 20)
+	go func() {
+		defer close(eventChan)
+		finished := false
+		for event := range stream {
+			if event.SubAgent == nil && event.Type == AgentEventTypeEnd && !finished {
+				finished = true
+				output := &InvokeOutput{}
+				output.Messages, _ = event.Metadata["messages"].([]llm.Message)
+				output.Files, _ = event.Metadata["files"].(map[string]string)
+				output.Metadata, _ = event.Metadata["metadata"].(map[string]any)
+				if err := t.end(ctx, output); err != nil {
+					SendEvent(ctx, eventChan, AgentEvent{Type: AgentEventTypeError, Error: err, Done: true})
+					continue
+				}
+			}
+			// 上下文取消后继续读取（丢弃）底层事件直到其结束
+			SendEvent(ctx, eventChan, event)
+		}
+		if !finished {
+			t.end(ctx, nil)
+		}
+	}()
+	return eventChan, nil
+}
+
+// begin 标记线程执行中并构造输入（传入副本，执行期间不修改线程状态）
+func (t *Thread) begin(messages []llm.Message) (*InvokeInput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return nil, ErrThreadBusy
+	}
+	t.running = true
+	return &InvokeInput{
+		Messages: append(slices.Clone(t.data.Messages), messages...),
+		Files:    maps.Clone(t.data.Files),
+		Metadata: cloneMetadata(t.data.Metadata),
+	}, nil
+}
+
+// end 结束执行，output 非 nil 时更新线程状态并保存
+func (t *Thread) end(ctx context.Context, output *InvokeOutput) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = false
+	if output == nil {
+		return nil
+	}
+	t.data.Messages = output.Messages
+	if output.Files != nil {
+		t.data.Files = output.Files
+	}
+	if output.Metadata != nil {
+		t.data.Metadata = output.Metadata
+	}
+	return t.saveLocked(ctx)
+}
+
+// Truncate 截断对话，只保留前 n 条消息（用于回退到某条消息之前）
+// 不能在工具调用和其结果之间截断
+func (t *Thread) Truncate(ctx context.Context, n int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return ErrThreadBusy
+	}
+	if n < 0 || n > len(t.data.Messages) {
+		return fmt.Errorf("truncate position %d out of range [0, %d]", n, len(t.data.Messages))
+	}
+	if n > 0 && n < len(t.data.Messages) && len(t.data.Messages[n-1].ToolCalls) > 0 {
+		return fmt.Errorf("cannot truncate between a tool call and its result at message %d", n)
+	}
+	t.data.Messages = slices.Clone(t.data.Messages[:n])
+	return t.saveLocked(ctx)
+}
+
+// Fork 复制当前线程为新线程（新 ID，ParentID 指向当前线程），两者之后互不影响
+func (t *Thread) Fork(ctx context.Context) (*Thread, error) {
+	t.mu.Lock()
+	data := ThreadData{
+		ID:        newThreadID(),
+		ParentID:  t.data.ID,
+		Messages:  slices.Clone(t.data.Messages),
+		Files:     maps.Clone(t.data.Files),
+		Metadata:  cloneMetadata(t.data.Metadata),
+		CreatedAt: time.Now(),
+	}
+	t.mu.Unlock()
+
+	fork := &Thread{agent: t.agent, store: t.store, data: data}
+	fork.mu.Lock()
+	defer fork.mu.Unlock()
+	if err := fork.saveLocked(ctx); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// saveLocked 保存线程（调用方持有锁）
+func (t *Thread) saveLocked(ctx context.Context) error {
+	t.data.UpdatedAt = time.Now()
+	if t.store == nil {
+		return nil
+	}
+	if err := t.store.Save(ctx, &t.data); err != nil {
+		return fmt.Errorf("failed to save thread %s: %w", t.data.ID, err)
+	}
+	return nil
+}
+
+// cloneMetadata 复制元数据，nil 时返回空表
+func cloneMetadata(metadata map[string]any) map[string]any {
+	if metadata == nil {
+		return make(map[string]any)
+	}
+	return maps.Clone(metadata)
+}
+
+// newThreadID 生成线程 ID
+func newThreadID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "thread-" + hex.EncodeToString(b)
+}
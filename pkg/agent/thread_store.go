@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// ThreadStore 对话线程存储
+type ThreadStore interface {
+	Save(ctx context.Context, data *ThreadData) error
+	Load(ctx context.Context, id string) (*ThreadData, error)
+}
+
+// MemoryThreadStore 内存中的线程存储（保存序列化后的副本）
+type MemoryThreadStore struct {
+	mu      sync.Mutex
+	threads map[string][]byte
+}
+
+// NewMemoryThreadStore 创建内存线程存储
+func NewMemoryThreadStore() *MemoryThreadStore {
+	return &MemoryThreadStore{threads: make(map[string][]byte)}
+}
+
+// Save 保存线程
+func (s *MemoryThreadStore) Save(ctx context.Context, data *ThreadData) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化线程失败: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.threads[data.ID] = content
+	return nil
+}
+
+// Load 加载线程
+func (s *MemoryThreadStore) Load(ctx context.Context, id string) (*ThreadData, error) {
+	s.mu.Lock()
+	content, ok := s.threads[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("未找到线程: %s", id)
+	}
+	var data ThreadData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("解析线程失败: %w", err)
+	}
+	return &data, nil
+}
+
+// BackendThreadStore 通过后端存储线程，每个线程一个 JSON 文件
+type BackendThreadStore struct {
+	backend backend.Backend
+	dir     string
+}
+
+// NewBackendThreadStore 创建后端线程存储，dir 为空时使用 memory/threads
+func NewBackendThreadStore(b backend.Backend, dir string) *BackendThreadStore {
+	if dir == "" {
+		dir = "memory/threads"
+	}
+	return &BackendThreadStore{backend: b, dir: dir}
+}
+
+// Save 保存线程
+func (s *BackendThreadStore) Save(ctx context.Context, data *ThreadData) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化线程失败: %w", err)
+	}
+	if _, err := s.backend.WriteFile(ctx, s.path(data.ID), string(content)); err != nil {
+		return fmt.Errorf("写入线程失败: %w", err)
+	}
+	return nil
+}
+
+// Load 加载线程
+func (s *BackendThreadStore) Load(ctx context.Context, id string) (*ThreadData, error) {
+	content, err := s.backend.ReadFile(ctx, s.path(id), 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("未找到线程: %s: %w", id, err)
+	}
+	var data ThreadData
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		return nil, fmt.Errorf("解析线程失败: %w", err)
+	}
+	return &data, nil
+}
+
+// path 返回线程文件路径
+func (s *BackendThreadStore) path(id string) string {
+	return path.Join(s.dir, id+".json")
+}
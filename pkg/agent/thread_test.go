@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// echoAgent 回复最后一条用户消息的测试 Agent，并记录收到的输入
+type echoAgent struct {
+	inputs []*InvokeInput
+	err    error
+}
+
+func (a *echoAgent) Invoke(ctx context.Context, input *InvokeInput) (*InvokeOutput, error) {
+	a.inputs = append(a.inputs, input)
+	if a.err != nil {
+		return nil, a.err
+	}
+	last := input.Messages[len(input.Messages)-1]
+	metadata := input.Metadata
+	metadata["turns"] = len(a.inputs)
+	return &InvokeOutput{
+		Messages: append(input.Messages, llm.Message{Role: llm.RoleAssistant, Content: "echo: " + last.Content}),
+		Files:    input.Files,
+		Metadata: metadata,
+	}, nil
+}
+
+func (a *echoAgent) InvokeStream(ctx context.Context, input *InvokeInput) (<-chan AgentEvent, error) {
+	ch := make(chan AgentEvent, 4)
+	go func() {
+		defer close(ch)
+		output, err := a.Invoke(ctx, input)
+		if err != nil {
+			ch <- AgentEvent{Type: AgentEventTypeError, Error: err, Done: true}
+			return
+		}
+		ch <- AgentEvent{Type: AgentEventTypeLLMText, Content: LastAssistantMessage(output)}
+		ch <- AgentEvent{Type: AgentEventTypeEnd, Done: true, Metadata: map[string]any{
+			"messages": output.Messages,
+			"files":    output.Files,
+			"metadata": output.Metadata,
+		}}
+	}()
+	return ch, nil
+}
+
+func userMessage(content string) llm.Message {
+	return llm.Message{Role: llm.RoleUser, Content: content}
+}
+
+func TestThread_InvokeKeepsHistory(t *testing.T) {
+	ctx := context.Background()
+	a := &echoAgent{}
+	thread := NewThread(a, &ThreadConfig{Metadata: map[string]any{"session_id": "s1"}})
+
+	if _, err := thread.Invoke(ctx, userMessage("hello")); err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	output, err := thread.Invoke(ctx, userMessage("again"))
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	if got := LastAssistantMessage(output); got != "echo: again" {
+		t.Errorf("unexpected answer: %q", got)
+	}
+	if len(a.inputs[1].Messages) != 3 {
+		t.Errorf("expected second turn to send 3 messages, got %d", len(a.inputs[1].Messages))
+	}
+	if len(thread.Messages()) != 4 {
+		t.Errorf("expected 4 messages, got %d", len(thread.Messages()))
+	}
+	metadata := thread.Metadata()
+	if metadata["session_id"] != "s1" || metadata["turns"] != 2 {
+		t.Errorf("unexpected metadata: %v", metadata)
+	}
+}
+
+func TestThread_InvokeErrorLeavesStateUnchanged(t *testing.T) {
+	ctx := context.Background()
+	a := &echoAgent{}
+	thread := NewThread(a, nil)
+	thread.Invoke(ctx, userMessage("hello"))
+
+	a.err = errors.New("boom")
+	if _, err := thread.Invoke(ctx, userMessage("fail")); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(thread.Messages()) != 2 {
+		t.Errorf("expected thread to keep 2 messages, got %d", len(thread.Messages()))
+	}
+
+	// 出错后可以继续对话
+	a.err = nil
+	if _, err := thread.Invoke(ctx, userMessage("retry")); err != nil {
+		t.Fatalf("Invoke after error failed: %v", err)
+	}
+}
+
+func TestThread_InvokeStream(t *testing.T) {
+	ctx := context.Background()
+	thread := NewThread(&echoAgent{}, nil)
+
+	stream, err := thread.InvokeStream(ctx, userMessage("hello"))
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+	for event := range stream {
+		if event.Type == AgentEventTypeEnd {
+			// 收到结束事件时线程状态已更新
+			if n := len(thread.Messages()); n != 2 {
+				t.Errorf("expected 2 messages at end event, got %d", n)
+			}
+		}
+	}
+
+	if _, err := thread.Invoke(ctx, userMessage("next")); err != nil {
+		t.Fatalf("thread should be idle after stream: %v", err)
+	}
+}
+
+func TestThread_Busy(t *testing.T) {
+	thread := NewThread(&echoAgent{}, nil)
+	thread.running = true
+	if _, err := thread.Invoke(context.Background(), userMessage("hello")); !errors.Is(err, ErrThreadBusy) {
+		t.Errorf("expected ErrThreadBusy, got %v", err)
+	}
+}
+
+func TestThread_Truncate(t *testing.T) {
+	ctx := context.Background()
+	thread := NewThread(&echoAgent{}, &ThreadConfig{Messages: []llm.Message{
+		userMessage("read a.go"),
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "1", Name: "read_file"}}},
+		{Role: llm.RoleUser, ToolResults: []llm.ToolResult{{ToolCallID: "1", Content: "package a"}}},
+		{Role: llm.RoleAssistant, Content: "done"},
+	}})
+
+	if err := thread.Truncate(ctx, 2); err == nil {
+		t.Error("expected error when truncating between tool call and result")
+	}
+	if err := thread.Truncate(ctx, 5); err == nil {
+		t.Error("expected error for out of range position")
+	}
+	if err := thread.Truncate(ctx, 1); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if n := len(thread.Messages()); n != 1 {
+		t.Errorf("expected 1 message, got %d", n)
+	}
+}
+
+func TestThread_ForkAndLoad(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]ThreadStore{
+		"memory":  NewMemoryThreadStore(),
+		"backend": NewBackendThreadStore(backend.NewStateBackend(), ""),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			a := &echoAgent{}
+			thread := NewThread(a, &ThreadConfig{Store: store})
+			if _, err := thread.Invoke(ctx, userMessage("hello")); err != nil {
+				t.Fatalf("Invoke failed: %v", err)
+			}
+
+			fork, err := thread.Fork(ctx)
+			if err != nil {
+				t.Fatalf("Fork failed: %v", err)
+			}
+			if fork.ID() == thread.ID() || fork.ParentID() != thread.ID() {
+				t.Errorf("unexpected fork ids: id=%s parent=%s", fork.ID(), fork.ParentID())
+			}
+			if _, err := fork.Invoke(ctx, userMessage("branch")); err != nil {
+				t.Fatalf("fork Invoke failed: %v", err)
+			}
+			if len(thread.Messages()) != 2 || len(fork.Messages()) != 4 {
+				t.Errorf("fork should not affect original: %d, %d", len(thread.Messages()), len(fork.Messages()))
+			}
+
+			loaded, err := LoadThread(ctx, a, store, fork.ID())
+			if err != nil {
+				t.Fatalf("LoadThread failed: %v", err)
+			}
+			messages := loaded.Messages()
+			if len(messages) != 4 || messages[3].Content != "echo: branch" {
+				t.Errorf("unexpected loaded messages: %+v", messages)
+			}
+			if loaded.ParentID() != thread.ID() {
+				t.Errorf("expected parent %s, got %s", thread.ID(), loaded.ParentID())
+			}
+
+			if _, err := LoadThread(ctx, a, store, "missing"); err == nil {
+				t.Error("expected error for missing thread")
+			}
+		})
+	}
+}
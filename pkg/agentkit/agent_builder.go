@@ -1,12 +1,18 @@
 package agentkit
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/backend"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/lsp"
 	"github.com/zhoucx/deepagents-go/pkg/middleware"
 	"github.com/zhoucx/deepagents-go/pkg/tools"
 )
@@ -19,28 +25,41 @@ type AgentBuilder struct {
 	maxIterations int
 	maxTokens     int
 	temperature   float64
+	thinking      *llm.ThinkingConfig
 
 	// 回调
 	onToolCall   func(string, map[string]any)
 	onToolResult func(string, string, bool)
 
 	// 中间件配置参数
-	fsWorkDir       string
-	webConfig       *middleware.WebConfig
-	skillsDirs      []string
-	memoryDirs      []string
-	enableSummarize bool
-	summarizeConfig *middleware.SummarizationConfig
-	sessionID       string // 会话 ID
-
-	// 自定义中间件
+	fsWorkDir           string
+	webConfig           *middleware.WebConfig
+	skillsDirs          []string
+	memoryDirs          []string
+	enableSummarize     bool
+	summarizeConfig     *middleware.SummarizationConfig
+	sessionID           string // 会话 ID
+	enableLSP           bool
+	enableDiagnostics   bool
+	linters             []middleware.Linter
+	worktreeIsolation   bool
+	subAgents           []middleware.SubAgentDefinition
+	subAgentDirs        []string
+	subAgentConcurrency int
+	subAgentTimeout     time.Duration
+	modelFactory        func(model string) (llm.Client, error)
+	lspServers          []lsp.ServerConfig
+
+	// 自定义中间件和工具
 	customMiddlewares []agent.Middleware
+	customTools       []tools.Tool
 
 	// 构建后的内部组件
 	toolRegistry *tools.Registry
 	Backend      backend.Backend
 	SessionStore *middleware.SessionStore
 	middlewares  []agent.Middleware
+	lspManager   *lsp.Manager
 
 	Runnable *agent.Runnable
 }
@@ -60,7 +79,7 @@ func New(opts ...Option) *AgentBuilder {
 }
 
 // Build 根据配置构建内部组件（工具注册表、中间件、Runnable）
-// 默认启用所有内置中间件：Filesystem、ContextInjection、Todo、Web、Skills、Memory、SubAgent
+// 默认启用所有内置中间件：Filesystem、Git、ContextInjection、Checkpoint、Todo、Web、Skills、Memory、SubAgent
 func (a *AgentBuilder) Build() error {
 	if a.fsWorkDir == "" {
 		return fmt.Errorf("WithFilesystem 是必须的，请指定工作目录")
@@ -75,7 +94,27 @@ func (a *AgentBuilder) Build() error {
 		return fmt.Errorf("创建文件系统后端失败: %w", err)
 	}
 	a.Backend = fsBackend
-	a.middlewares = append(a.middlewares, middleware.NewFilesystemMiddleware(fsBackend, a.toolRegistry))
+	// 文件工具通过快照后端写入，记录每轮的变更以支持回退
+	snapshotBackend := backend.NewSnapshotBackend(fsBackend)
+	a.middlewares = append(a.middlewares, middleware.NewFilesystemMiddleware(snapshotBackend, a.toolRegistry))
+
+	// 编辑后诊断中间件（可选），紧跟文件系统中间件，诊断结果追加到文件修改类工具的结果中
+	if a.enableDiagnostics {
+		a.middlewares = append(a.middlewares, middleware.NewDiagnosticsMiddleware(fsBackend, a.linters...))
+	}
+
+	// Git 中间件同样使用快照后端，切换分支、stash 等修改工作区的操作会记录文件变更（回退时不恢复分支和 stash）
+	a.middlewares = append(a.middlewares, middleware.NewGitMiddleware(snapshotBackend, a.toolRegistry))
+
+	// 语言服务器中间件（可选）
+	if a.enableLSP {
+		a.lspManager = lsp.NewManager(lsp.Config{
+			RootDir:      fsBackend.RootDir(),
+			VirtualPaths: fsBackend.VirtualMode(),
+			Servers:      a.lspServers,
+		})
+		a.middlewares = append(a.middlewares, middleware.NewLSPMiddleware(a.lspManager, a.toolRegistry))
+	}
 
 	// 2. Agent 配置中间件
 	agentMemMiddleware := middleware.NewAgentMemMiddleware(fsBackend)
@@ -90,17 +129,20 @@ func (a *AgentBuilder) Build() error {
 	sessionRecorder := middleware.NewHistoryMiddleware(a.SessionStore)
 	a.middlewares = append(a.middlewares, sessionRecorder)
 
-	// 5. Todo 中间件
+	// 5. 文件快照中间件（依赖会话记录中间件生成的 session_id）
+	a.middlewares = append(a.middlewares, middleware.NewCheckpointMiddleware(snapshotBackend, a.SessionStore))
+
+	// 6. Todo 中间件
 	a.middlewares = append(a.middlewares, middleware.NewTodoMiddlewareWithInjector(a.Backend, a.toolRegistry, contextInjector))
 
-	// 6. Web 中间件
+	// 7. Web 中间件
 	webCfg := middleware.DefaultWebConfig()
 	if a.webConfig != nil {
 		webCfg = *a.webConfig
 	}
 	a.middlewares = append(a.middlewares, middleware.NewWebMiddleware(a.toolRegistry, webCfg))
 
-	// 7. Skills 中间件
+	// 8. Skills 中间件
 	skillsMiddleware := middleware.NewSkillsMiddleware(a.toolRegistry)
 	for _, dir := range a.skillsDirs {
 		if err := skillsMiddleware.LoadFromDirectory(dir); err != nil {
@@ -109,13 +151,22 @@ func (a *AgentBuilder) Build() error {
 	}
 	a.middlewares = append(a.middlewares, skillsMiddleware)
 
-	// 8. SubAgent 中间件
+	// 9. SubAgent 中间件
+	subAgentConfig := &middleware.SubAgentConfig{
+		MaxDepth:       3,
+		SubAgents:      a.loadSubAgents(),
+		MaxConcurrency: a.subAgentConcurrency,
+		TaskTimeout:    a.subAgentTimeout,
+	}
+	if a.worktreeIsolation {
+		subAgentConfig.Worktree = &middleware.WorktreeConfig{Repo: fsBackend}
+	}
 	a.middlewares = append(a.middlewares, middleware.NewSubAgentMiddleware(
-		nil, a.llmClient, a.toolRegistry, a.middlewares,
+		subAgentConfig, a.llmClient, a.toolRegistry, a.middlewares,
 		a.systemPrompt, a.maxTokens, a.temperature,
 	))
 
-	// 9. Summarization 中间件（可选）
+	// 10. Summarization 中间件（可选）
 	if a.enableSummarize {
 		cfg := a.summarizeConfig
 		if cfg == nil {
@@ -129,8 +180,13 @@ func (a *AgentBuilder) Build() error {
 		a.middlewares = append(a.middlewares, middleware.NewSummarizationMiddleware(cfg))
 	}
 
-	// 10. 自定义中间件
+	// 11. 自定义中间件和工具
 	a.middlewares = append(a.middlewares, a.customMiddlewares...)
+	for _, tool := range a.customTools {
+		if err := a.toolRegistry.Register(tool); err != nil {
+			log.Printf("警告: 忽略自定义工具 %s: %v\n", tool.Name(), err)
+		}
+	}
 
 	// 构建 Runnable
 	a.Runnable = agent.NewRunnable(&agent.Config{
@@ -141,8 +197,65 @@ func (a *AgentBuilder) Build() error {
 		MaxIterations: a.maxIterations,
 		MaxTokens:     a.maxTokens,
 		Temperature:   a.temperature,
+		Thinking:      a.thinking,
 		OnToolCall:    a.onToolCall,
 		OnToolResult:  a.onToolResult,
 	})
 	return nil
 }
+
+// loadSubAgents 汇总子Agent类型：内置类型、用户级目录（~/.deepagents/agents）、
+// 项目级目录（<工作目录>/.deepagents/agents）、WithSubAgentDirs 目录和 WithSubAgents 定义，同名时后者覆盖前者
+func (a *AgentBuilder) loadSubAgents() []middleware.SubAgentDefinition {
+	defs := []middleware.SubAgentDefinition{middleware.ResearcherSubAgent(), middleware.CodeReviewerSubAgent()}
+
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".deepagents", "agents"))
+	}
+	dirs = append(dirs, filepath.Join(a.fsWorkDir, ".deepagents", "agents"))
+	dirs = append(dirs, a.subAgentDirs...)
+	for _, dir := range dirs {
+		loaded, err := middleware.LoadSubAgentDefinitions(dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("警告: 加载子Agent定义 %s 时出错: %v\n", dir, err)
+		}
+		defs = append(defs, loaded...)
+	}
+	defs = append(defs, a.subAgents...)
+
+	merged := make([]middleware.SubAgentDefinition, 0, len(defs))
+	index := make(map[string]int)
+	for _, def := range defs {
+		if i, ok := index[def.Name]; ok {
+			merged[i] = def
+			continue
+		}
+		index[def.Name] = len(merged)
+		merged = append(merged, def)
+	}
+
+	// 解析 Markdown 定义中的模型名称
+	for i := range merged {
+		def := &merged[i]
+		if def.Model == "" || def.LLMClient != nil {
+			continue
+		}
+		if a.modelFactory == nil {
+			log.Printf("警告: 子Agent %s 指定了模型 %s，但未配置 WithModelFactory，将使用主Agent的模型\n", def.Name, def.Model)
+		} else if client, err := a.modelFactory(def.Model); err != nil {
+			log.Printf("警告: 子Agent %s 的模型 %s 不可用，将使用主Agent的模型: %v\n", def.Name, def.Model, err)
+		} else {
+			def.LLMClient = client
+		}
+	}
+	return merged
+}
+
+// Close 释放构建时创建的外部资源（如语言服务器进程）
+func (a *AgentBuilder) Close() error {
+	if a.lspManager != nil {
+		return a.lspManager.Close()
+	}
+	return nil
+}
@@ -157,6 +157,35 @@ func TestBuild_WithMemoryPaths_Directory(t *testing.T) {
 	}
 }
 
+func TestBuild_WithDiagnostics(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	builder := New(WithLLM(&mockLLMClient{}), WithFilesystem(tmpDir))
+	if err := builder.Build(); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	for _, mw := range builder.middlewares {
+		if mw.Name() == "diagnostics" {
+			t.Error("Diagnostics middleware should be disabled by default")
+		}
+	}
+
+	builder = New(WithLLM(&mockLLMClient{}), WithFilesystem(tmpDir), WithDiagnostics())
+	if err := builder.Build(); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	// 紧跟文件系统中间件
+	for i, mw := range builder.middlewares {
+		if mw.Name() == "diagnostics" {
+			if i == 0 || builder.middlewares[i-1].Name() != "filesystem" {
+				t.Errorf("Expected diagnostics right after filesystem, got position %d", i)
+			}
+			return
+		}
+	}
+	t.Error("Expected diagnostics middleware to be registered")
+}
+
 func TestBuild_WithSummarization(t *testing.T) {
 	tmpDir := t.TempDir()
 	mockClient := &mockLLMClient{}
@@ -237,3 +266,60 @@ func TestBuild_WithSkillsDirs(t *testing.T) {
 		t.Error("Expected skills middleware to be registered")
 	}
 }
+
+func TestBuild_SubAgentDefinitionFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	workDir := t.TempDir()
+
+	writeAgent := func(dir, name, description, extra string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "---\nname: " + name + "\ndescription: " + description + "\n" + extra + "---\nprompt"
+		if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeAgent(filepath.Join(home, ".deepagents", "agents"), "translator", "user level", "")
+	writeAgent(filepath.Join(home, ".deepagents", "agents"), "researcher", "user researcher", "")
+	writeAgent(filepath.Join(workDir, ".deepagents", "agents"), "translator", "project level", "model: small-model\n")
+
+	var models []string
+	builder := New(
+		WithLLM(&mockLLMClient{}),
+		WithFilesystem(workDir),
+		WithModelFactory(func(model string) (llm.Client, error) {
+			models = append(models, model)
+			return &mockLLMClient{}, nil
+		}),
+	)
+	if err := builder.Build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	var subAgents []middleware.SubAgentDefinition
+	for _, mw := range builder.middlewares {
+		if m, ok := mw.(*middleware.SubAgentMiddleware); ok {
+			subAgents = m.SubAgents()
+		}
+	}
+	byName := make(map[string]middleware.SubAgentDefinition)
+	for _, def := range subAgents {
+		byName[def.Name] = def
+	}
+	if len(byName) != len(subAgents) {
+		t.Errorf("duplicate subagent names: %+v", subAgents)
+	}
+	if byName["researcher"].Description != "user researcher" {
+		t.Errorf("user definition should override built-in researcher, got %q", byName["researcher"].Description)
+	}
+	translator := byName["translator"]
+	if translator.Description != "project level" || translator.LLMClient == nil {
+		t.Errorf("project definition should override user level and resolve model, got %+v", translator)
+	}
+	if len(models) != 1 || models[0] != "small-model" {
+		t.Errorf("model factory calls = %v", models)
+	}
+}
@@ -1,9 +1,13 @@
 package agentkit
 
 import (
+	"time"
+
 	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/lsp"
 	"github.com/zhoucx/deepagents-go/pkg/middleware"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
 )
 
 // Option 配置 AgentBuilder 的函数选项
@@ -15,6 +19,7 @@ type AgentConfig struct {
 	MaxIterations int
 	MaxTokens     int
 	Temperature   float64
+	Thinking      *llm.ThinkingConfig // 扩展思考配置，为 nil 时不启用
 }
 
 // WithLLM 设置 LLM 客户端
@@ -39,6 +44,9 @@ func WithConfig(cfg AgentConfig) Option {
 		if cfg.Temperature > 0 {
 			a.temperature = cfg.Temperature
 		}
+		if cfg.Thinking != nil {
+			a.thinking = cfg.Thinking
+		}
 	}
 }
 
@@ -78,6 +86,60 @@ func WithSessionID(sessionID string) Option {
 	}
 }
 
+// WithLSP 启用语言服务器中间件（代码导航工具），服务器以工作目录为根启动
+// 未指定服务器时默认使用 gopls
+func WithLSP(servers ...lsp.ServerConfig) Option {
+	return func(a *AgentBuilder) {
+		a.enableLSP = true
+		a.lspServers = append(a.lspServers, servers...)
+	}
+}
+
+// WithDiagnostics 启用编辑后诊断中间件，文件修改后运行诊断器并将结果追加到工具结果中
+// 未指定诊断器时默认使用 Go 诊断器（gofmt、go build、go vet）
+func WithDiagnostics(linters ...middleware.Linter) Option {
+	return func(a *AgentBuilder) {
+		a.enableDiagnostics = true
+		a.linters = append(a.linters, linters...)
+	}
+}
+
+// WithSubAgents 注册命名子Agent类型（内置 researcher 和 code-reviewer 之外）
+func WithSubAgents(defs ...middleware.SubAgentDefinition) Option {
+	return func(a *AgentBuilder) {
+		a.subAgents = append(a.subAgents, defs...)
+	}
+}
+
+// WithSubAgentConcurrency 设置 delegate_tasks 并发委派的最大并发数和每个任务的超时时间
+func WithSubAgentConcurrency(maxConcurrency int, taskTimeout time.Duration) Option {
+	return func(a *AgentBuilder) {
+		a.subAgentConcurrency = maxConcurrency
+		a.subAgentTimeout = taskTimeout
+	}
+}
+
+// WithSubAgentDirs 添加 Markdown 子Agent定义目录（在用户级和项目级目录之后加载，同名定义后者覆盖前者）
+func WithSubAgentDirs(dirs ...string) Option {
+	return func(a *AgentBuilder) {
+		a.subAgentDirs = append(a.subAgentDirs, dirs...)
+	}
+}
+
+// WithModelFactory 设置按模型名称创建 LLM 客户端的函数，用于解析子Agent定义中的 model 字段
+func WithModelFactory(factory func(model string) (llm.Client, error)) Option {
+	return func(a *AgentBuilder) {
+		a.modelFactory = factory
+	}
+}
+
+// EnableWorktreeIsolation 子 Agent 在独立的 git 工作树和分支中工作（工作目录需在 git 仓库中）
+func EnableWorktreeIsolation() Option {
+	return func(a *AgentBuilder) {
+		a.worktreeIsolation = true
+	}
+}
+
 // EnableSummarization 启用摘要中间件（可选，自动复用 LLM 客户端）
 func EnableSummarization(cfg ...*middleware.SummarizationConfig) Option {
 	return func(a *AgentBuilder) {
@@ -95,6 +157,13 @@ func WithMiddleware(m agent.Middleware) Option {
 	}
 }
 
+// WithTools 注册自定义工具（如 team.NewAgentTool、team.NewHandoffTool 创建的工具），与内置工具同名时忽略
+func WithTools(ts ...tools.Tool) Option {
+	return func(a *AgentBuilder) {
+		a.customTools = append(a.customTools, ts...)
+	}
+}
+
 // WithOnToolCall 设置工具调用回调
 func WithOnToolCall(fn func(string, map[string]any)) Option {
 	return func(a *AgentBuilder) {
@@ -1,6 +1,9 @@
 package backend
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // Backend 定义存储后端接口
 type Backend interface {
@@ -29,6 +32,53 @@ type Backend interface {
 	Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error)
 }
 
+// FileManager 文件管理扩展接口（可选）
+// 后端实现该接口表示原生支持移动、复制、创建目录和查询文件信息，
+// 调用方通过类型断言检测能力，或使用包级函数 Move/Copy/MakeDir/Stat 自动降级
+type FileManager interface {
+	// Move 移动或重命名文件/目录（目标已存在且 overwrite 为 false 时返回错误）
+	Move(ctx context.Context, src, dst string, overwrite bool) error
+
+	// Copy 复制文件/目录（目标已存在且 overwrite 为 false 时返回错误）
+	Copy(ctx context.Context, src, dst string, overwrite bool) error
+
+	// MakeDir 创建目录（包括父目录，已存在时不报错）
+	MakeDir(ctx context.Context, path string) error
+
+	// Stat 获取单个文件或目录的信息
+	Stat(ctx context.Context, path string) (*FileInfo, error)
+}
+
+// BinaryBackend 字节读写扩展接口（可选）
+// ReadFile/WriteFile 以字符串传递内容，二进制或非 UTF-8 文件应使用字节接口，
+// 调用方可使用包级函数 ReadBytes/WriteBytes 自动降级
+type BinaryBackend interface {
+	// ReadBytes 读取文件的原始字节
+	ReadBytes(ctx context.Context, path string) ([]byte, error)
+
+	// WriteBytes 写入原始字节（创建或覆盖）
+	WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error)
+}
+
+// CommandExecutor 参数列表执行扩展接口（可选）
+// Execute 以单个字符串传递命令，无法表达本身包含空白的参数（如提交信息、带空格的路径），
+// 需要按参数列表执行；调用方可使用包级函数 ExecuteArgs 自动降级
+type CommandExecutor interface {
+	// ExecuteArgs 执行命令，args[0] 为命令名，timeout 单位为毫秒
+	ExecuteArgs(ctx context.Context, args []string, timeout int) (*ExecuteResult, error)
+}
+
+// ChangeRecorder 外部变更记录扩展接口（可选）
+// 命令（如 git switch、git stash）直接修改工作区时后端无法感知具体的文件操作，
+// 由调用方给出可能受影响的文件，后端比较执行前后的内容并记录变更；调用方可使用包级函数 TrackChanges 自动降级
+type ChangeRecorder interface {
+	// TrackChanges 执行 fn，并记录 paths 中内容发生变化的文件
+	TrackChanges(ctx context.Context, paths []string, fn func() error) error
+}
+
+// ErrNotSupported 后端不支持该操作
+var ErrNotSupported = errors.New("operation not supported")
+
 // FileInfo 文件信息
 type FileInfo struct {
 	Path    string `json:"path"`
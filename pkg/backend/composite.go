@@ -203,3 +203,61 @@ func (b *CompositeBackend) DeleteFile(ctx context.Context, path string) error {
 func (b *CompositeBackend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
 	return nil, fmt.Errorf("execute not supported by CompositeBackend")
 }
+
+// Move 移动文件，源和目标位于不同路由时逐文件复制后删除源文件（不支持跨路由移动目录）
+func (b *CompositeBackend) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	srcBackend, srcKey := b.getBackendAndKey(src)
+	dstBackend, dstKey := b.getBackendAndKey(dst)
+	if srcBackend == dstBackend {
+		return Move(ctx, srcBackend, srcKey, dstKey, overwrite)
+	}
+	return transferFile(ctx, srcBackend, srcKey, dstBackend, dstKey, overwrite, true)
+}
+
+// Copy 复制文件，源和目标位于不同路由时逐文件复制（不支持跨路由复制目录）
+func (b *CompositeBackend) Copy(ctx context.Context, src, dst string, overwrite bool) error {
+	srcBackend, srcKey := b.getBackendAndKey(src)
+	dstBackend, dstKey := b.getBackendAndKey(dst)
+	if srcBackend == dstBackend {
+		return Copy(ctx, srcBackend, srcKey, dstKey, overwrite)
+	}
+	return transferFile(ctx, srcBackend, srcKey, dstBackend, dstKey, overwrite, false)
+}
+
+// MakeDir 创建目录
+func (b *CompositeBackend) MakeDir(ctx context.Context, path string) error {
+	backend, relPath := b.getBackendAndKey(path)
+	return MakeDir(ctx, backend, relPath)
+}
+
+// Stat 获取文件信息
+func (b *CompositeBackend) Stat(ctx context.Context, path string) (*FileInfo, error) {
+	backend, relPath := b.getBackendAndKey(path)
+	info, err := Stat(ctx, backend, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调整路径
+	info.Path = path
+	return info, nil
+}
+
+// ReadBytes 读取文件的原始字节
+func (b *CompositeBackend) ReadBytes(ctx context.Context, path string) ([]byte, error) {
+	backend, relPath := b.getBackendAndKey(path)
+	return ReadBytes(ctx, backend, relPath)
+}
+
+// WriteBytes 写入原始字节
+func (b *CompositeBackend) WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error) {
+	backend, relPath := b.getBackendAndKey(path)
+	result, err := WriteBytes(ctx, backend, relPath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调整路径
+	result.Path = path
+	return result, nil
+}
@@ -2,6 +2,7 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 )
@@ -215,3 +216,63 @@ func TestCompositeBackend_WithFilesystemBackend(t *testing.T) {
 		t.Error("File should exist in memory")
 	}
 }
+
+func TestCompositeBackend_MoveWithinRoute(t *testing.T) {
+	defaultBackend := NewStateBackend()
+	dataBackend := NewStateBackend()
+	composite := NewCompositeBackend(defaultBackend)
+	composite.AddRoute("/data", dataBackend)
+	ctx := context.Background()
+
+	composite.WriteFile(ctx, "/data/a.txt", "A")
+	if err := composite.Move(ctx, "/data/a.txt", "/data/b.txt", false); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if content, _ := dataBackend.ReadFile(ctx, "/b.txt", 0, 0); content != "A" {
+		t.Errorf("Expected %q in data backend, got %q", "A", content)
+	}
+
+	info, err := composite.Stat(ctx, "/data/b.txt")
+	if err != nil || info.Path != "/data/b.txt" {
+		t.Errorf("Unexpected stat result: %+v, %v", info, err)
+	}
+}
+
+func TestCompositeBackend_MoveAcrossRoutes(t *testing.T) {
+	defaultBackend := NewStateBackend()
+	archive := NewStateBackend()
+	composite := NewCompositeBackend(defaultBackend)
+	composite.AddRoute("/archive", archive)
+	ctx := context.Background()
+
+	composite.WriteFile(ctx, "/report.md", "report")
+	composite.WriteFile(ctx, "/draft.md", "draft")
+
+	if err := composite.Move(ctx, "/report.md", "/archive/report.md", false); err != nil {
+		t.Fatalf("Move across routes failed: %v", err)
+	}
+	if _, err := defaultBackend.ReadFile(ctx, "/report.md", 0, 0); err == nil {
+		t.Error("Expected source to be removed from default backend")
+	}
+	if content, _ := archive.ReadFile(ctx, "/report.md", 0, 0); content != "report" {
+		t.Errorf("Expected %q in archive, got %q", "report", content)
+	}
+
+	if err := composite.Copy(ctx, "/draft.md", "/archive/draft.md", false); err != nil {
+		t.Fatalf("Copy across routes failed: %v", err)
+	}
+	if _, err := defaultBackend.ReadFile(ctx, "/draft.md", 0, 0); err != nil {
+		t.Error("Expected source to remain after copy")
+	}
+
+	// 目标已存在
+	if err := composite.Move(ctx, "/draft.md", "/archive/draft.md", false); err == nil {
+		t.Error("Expected error when destination exists")
+	}
+
+	// 跨路由不支持移动目录
+	composite.WriteFile(ctx, "/dir/file.txt", "x")
+	if err := composite.Move(ctx, "/dir", "/archive/dir", false); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// TextEncoding 文本编码
+type TextEncoding string
+
+const (
+	EncodingUTF8    TextEncoding = "utf-8"
+	EncodingUTF8BOM TextEncoding = "utf-8-bom"
+	EncodingUTF16LE TextEncoding = "utf-16le"
+	EncodingUTF16BE TextEncoding = "utf-16be"
+	EncodingLatin1  TextEncoding = "iso-8859-1"
+)
+
+// 行尾风格
+const (
+	LineEndingLF   = "\n"
+	LineEndingCRLF = "\r\n"
+)
+
+// sniffLen 检测二进制内容时检查的字节数
+const sniffLen = 8192
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// ContentInfo 文件内容检测结果
+type ContentInfo struct {
+	Binary     bool         // 是否为二进制内容
+	MIMEType   string       // MIME 类型
+	Encoding   TextEncoding // 文本编码（仅文本）
+	LineEnding string       // 行尾风格（仅文本，混合行尾视为 LF）
+}
+
+// DetectContent 检测内容是二进制还是文本，并识别文本编码和行尾风格
+func DetectContent(data []byte) ContentInfo {
+	var info ContentInfo
+
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		info.Encoding = EncodingUTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE):
+		info.Encoding = EncodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		info.Encoding = EncodingUTF16BE
+	case bytes.IndexByte(data[:min(len(data), sniffLen)], 0) != -1:
+		// 无 BOM 且包含 NUL 字节，视为二进制
+		info.Binary = true
+	case utf8.Valid(data):
+		info.Encoding = EncodingUTF8
+	case looksLikeLatin1(data):
+		info.Encoding = EncodingLatin1
+	default:
+		info.Binary = true
+	}
+
+	if info.Binary {
+		info.MIMEType = http.DetectContentType(data)
+		return info
+	}
+
+	info.MIMEType = "text/plain; charset=" + string(info.Encoding)
+	info.LineEnding = LineEndingLF
+	if text, err := DecodeText(data, info.Encoding); err == nil {
+		if crlf := strings.Count(text, "\r\n"); crlf > 0 && crlf == strings.Count(text, "\n") {
+			info.LineEnding = LineEndingCRLF
+		}
+	}
+	return info
+}
+
+// looksLikeLatin1 判断非 UTF-8 内容是否像单字节编码的文本（控制字符占比较低）
+func looksLikeLatin1(data []byte) bool {
+	sample := data[:min(len(data), sniffLen)]
+	control := 0
+	for _, c := range sample {
+		if (c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\b' && c != 0x1B) || c == 0x7F {
+			control++
+		}
+	}
+	return control*100 <= len(sample)
+}
+
+// DecodeText 将指定编码的内容解码为 UTF-8 字符串（去除 BOM）
+func DecodeText(data []byte, enc TextEncoding) (string, error) {
+	switch enc {
+	case EncodingUTF8, "":
+		return string(data), nil
+	case EncodingUTF8BOM:
+		return string(bytes.TrimPrefix(data, bomUTF8)), nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		bom := bomUTF16LE
+		if enc == EncodingUTF16BE {
+			order, bom = binary.BigEndian, bomUTF16BE
+		}
+		data = bytes.TrimPrefix(data, bom)
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("invalid %s content: odd length", enc)
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case EncodingLatin1:
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	default:
+		return "", fmt.Errorf("unsupported encoding: %s", enc)
+	}
+}
+
+// EncodeText 将 UTF-8 字符串编码为指定编码（与 DecodeText 互逆，会补回 BOM）
+func EncodeText(text string, enc TextEncoding) ([]byte, error) {
+	switch enc {
+	case EncodingUTF8, "":
+		return []byte(text), nil
+	case EncodingUTF8BOM:
+		return append(append([]byte{}, bomUTF8...), text...), nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		bom := bomUTF16LE
+		if enc == EncodingUTF16BE {
+			order, bom = binary.BigEndian, bomUTF16BE
+		}
+		units := utf16.Encode([]rune(text))
+		data := make([]byte, len(bom)+2*len(units))
+		copy(data, bom)
+		for i, u := range units {
+			order.PutUint16(data[len(bom)+2*i:], u)
+		}
+		return data, nil
+	case EncodingLatin1:
+		data := make([]byte, 0, len(text))
+		for _, r := range text {
+			if r > 0xFF {
+				return nil, fmt.Errorf("character %q cannot be encoded in %s", r, enc)
+			}
+			data = append(data, byte(r))
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", enc)
+	}
+}
+
+// applyEdit 对文件原始内容执行字符串替换，保留原有的编码和行尾风格
+// 内容按检测到的编码解码后匹配；CRLF 文件中 old/new 字符串的 LF 会被视为 CRLF
+func applyEdit(content, oldStr, newStr string, replaceAll bool) (string, int, error) {
+	raw := []byte(content)
+	info := DetectContent(raw)
+	if info.Binary {
+		return "", 0, fmt.Errorf("cannot edit binary file (%s)", info.MIMEType)
+	}
+
+	text, err := DecodeText(raw, info.Encoding)
+	if err != nil {
+		return "", 0, err
+	}
+
+	crlf := info.LineEnding == LineEndingCRLF
+	if crlf {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		oldStr = strings.ReplaceAll(oldStr, "\r\n", "\n")
+		newStr = strings.ReplaceAll(newStr, "\r\n", "\n")
+	}
+
+	var replacements int
+	if replaceAll {
+		// 替换所有匹配
+		replacements = strings.Count(text, oldStr)
+		text = strings.ReplaceAll(text, oldStr, newStr)
+	} else {
+		// 只替换第一个匹配
+		idx := strings.Index(text, oldStr)
+		if idx == -1 {
+			return "", 0, fmt.Errorf("string not found: %s", oldStr)
+		}
+		text = text[:idx] + newStr + text[idx+len(oldStr):]
+		replacements = 1
+	}
+
+	if crlf {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+
+	encoded, err := EncodeText(text, info.Encoding)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(encoded), replacements, nil
+}
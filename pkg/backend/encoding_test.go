@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// pngHeader 1x1 PNG 文件的前几个字节
+var pngHeader = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0, 0, 0, 0x0D, 'I', 'H', 'D', 'R'}
+
+func TestDetectContent(t *testing.T) {
+	utf16le, _ := EncodeText("héllo\r\nworld\r\n", EncodingUTF16LE)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		binary     bool
+		encoding   TextEncoding
+		lineEnding string
+	}{
+		{"empty", nil, false, EncodingUTF8, LineEndingLF},
+		{"utf-8", []byte("héllo\nworld\n"), false, EncodingUTF8, LineEndingLF},
+		{"utf-8 crlf", []byte("a\r\nb\r\n"), false, EncodingUTF8, LineEndingCRLF},
+		{"mixed line endings", []byte("a\r\nb\nc\r\n"), false, EncodingUTF8, LineEndingLF},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "text"...), false, EncodingUTF8BOM, LineEndingLF},
+		{"utf-16le", utf16le, false, EncodingUTF16LE, LineEndingCRLF},
+		{"latin-1", []byte("caf\xe9 cr\xe8me\n"), false, EncodingLatin1, LineEndingLF},
+		{"png", pngHeader, true, "", ""},
+		{"random binary", []byte{0x01, 0x02, 0x03, 0x04, 0xff, 0xfe, 0x05, 0x06}, true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := DetectContent(tt.data)
+			if info.Binary != tt.binary {
+				t.Fatalf("Expected binary=%v, got %+v", tt.binary, info)
+			}
+			if info.Encoding != tt.encoding {
+				t.Errorf("Expected encoding %q, got %q", tt.encoding, info.Encoding)
+			}
+			if info.LineEnding != tt.lineEnding {
+				t.Errorf("Expected line ending %q, got %q", tt.lineEnding, info.LineEnding)
+			}
+		})
+	}
+
+	if info := DetectContent(pngHeader); info.MIMEType != "image/png" {
+		t.Errorf("Expected image/png, got %q", info.MIMEType)
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	text := "naïve café – ok"
+	for _, enc := range []TextEncoding{EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE, EncodingUTF16BE} {
+		data, err := EncodeText(text, enc)
+		if err != nil {
+			t.Fatalf("EncodeText(%s) failed: %v", enc, err)
+		}
+		if info := DetectContent(data); info.Encoding != enc {
+			t.Errorf("Expected detected encoding %s, got %s", enc, info.Encoding)
+		}
+		decoded, err := DecodeText(data, enc)
+		if err != nil || decoded != text {
+			t.Errorf("DecodeText(%s): expected %q, got %q, %v", enc, text, decoded, err)
+		}
+	}
+
+	// Latin-1 无法表示的字符
+	if _, err := EncodeText("€", EncodingLatin1); err == nil {
+		t.Error("Expected error encoding € in Latin-1")
+	}
+}
+
+func TestApplyEdit_PreservesEncodingAndLineEndings(t *testing.T) {
+	// Latin-1 + CRLF：使用 UTF-8 和 LF 的 old/new 字符串编辑
+	original := "caf\xe9\r\nna\xefve\r\n"
+	edited, n, err := applyEdit(original, "café\nnaïve", "café\ncrème", false)
+	if err != nil {
+		t.Fatalf("applyEdit failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 replacement, got %d", n)
+	}
+	if want := "caf\xe9\r\ncr\xe8me\r\n"; edited != want {
+		t.Errorf("Expected %q, got %q", want, edited)
+	}
+
+	// UTF-16 保留 BOM
+	utf16, _ := EncodeText("key = 1\r\n", EncodingUTF16LE)
+	edited, _, err = applyEdit(string(utf16), "1", "2", false)
+	if err != nil {
+		t.Fatalf("applyEdit failed: %v", err)
+	}
+	if want, _ := EncodeText("key = 2\r\n", EncodingUTF16LE); !bytes.Equal([]byte(edited), want) {
+		t.Errorf("Expected %q, got %q", want, edited)
+	}
+
+	// 新内容无法用原编码表示
+	if _, _, err := applyEdit(original, "café", "€", false); err == nil {
+		t.Error("Expected error for unencodable character")
+	}
+
+	// 二进制文件不可编辑
+	if _, _, err := applyEdit(string(pngHeader), "PNG", "JPG", false); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("Expected binary file error, got %v", err)
+	}
+}
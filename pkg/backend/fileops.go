@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Move 移动文件或目录
+// 后端实现 FileManager 时使用原生实现，否则降级为读取、写入、删除（仅支持单个文件）
+func Move(ctx context.Context, b Backend, src, dst string, overwrite bool) error {
+	if fm, ok := b.(FileManager); ok {
+		return fm.Move(ctx, src, dst, overwrite)
+	}
+	return transferFile(ctx, b, src, b, dst, overwrite, true)
+}
+
+// Copy 复制文件或目录
+// 后端实现 FileManager 时使用原生实现，否则降级为读取、写入（仅支持单个文件）
+func Copy(ctx context.Context, b Backend, src, dst string, overwrite bool) error {
+	if fm, ok := b.(FileManager); ok {
+		return fm.Copy(ctx, src, dst, overwrite)
+	}
+	return transferFile(ctx, b, src, b, dst, overwrite, false)
+}
+
+// MakeDir 创建目录，后端不支持时返回 ErrNotSupported
+func MakeDir(ctx context.Context, b Backend, path string) error {
+	if fm, ok := b.(FileManager); ok {
+		return fm.MakeDir(ctx, path)
+	}
+	return fmt.Errorf("%w: make directory %s", ErrNotSupported, path)
+}
+
+// Stat 获取文件信息
+// 后端实现 FileManager 时使用原生实现，否则通过 ReadFile 降级（仅支持文件）
+func Stat(ctx context.Context, b Backend, path string) (*FileInfo, error) {
+	if fm, ok := b.(FileManager); ok {
+		return fm.Stat(ctx, path)
+	}
+
+	content, err := b.ReadFile(ctx, path, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &FileInfo{
+		Path: path,
+		Size: int64(len(content)),
+	}, nil
+}
+
+// ExecuteArgs 按参数列表执行命令
+// 后端实现 CommandExecutor 时使用原生实现，否则拼接为命令字符串调用 Execute（参数不能包含空白）
+func ExecuteArgs(ctx context.Context, b Backend, args []string, timeout int) (*ExecuteResult, error) {
+	if ce, ok := b.(CommandExecutor); ok {
+		return ce.ExecuteArgs(ctx, args, timeout)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsFunc(arg, unicode.IsSpace) {
+			return nil, fmt.Errorf("%w: argument %q requires argument-list execution", ErrNotSupported, arg)
+		}
+	}
+	return b.Execute(ctx, strings.Join(args, " "), timeout)
+}
+
+// TrackChanges 执行 fn 并记录 paths 中文件的变更
+// 后端实现 ChangeRecorder 时由后端记录，否则直接执行 fn
+func TrackChanges(ctx context.Context, b Backend, paths []string, fn func() error) error {
+	if cr, ok := b.(ChangeRecorder); ok {
+		return cr.TrackChanges(ctx, paths, fn)
+	}
+	return fn()
+}
+
+// exists 判断路径是否存在
+func exists(ctx context.Context, b Backend, path string) bool {
+	_, err := Stat(ctx, b, path)
+	return err == nil
+}
+
+// transferFile 在两个后端之间复制单个文件，remove 为 true 时复制成功后删除源文件
+func transferFile(ctx context.Context, from Backend, src string, to Backend, dst string, overwrite, remove bool) error {
+	if from == to && src == dst {
+		return fmt.Errorf("source and destination are the same: %s", src)
+	}
+
+	info, err := Stat(ctx, from, src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return fmt.Errorf("%w: directory transfer between backends: %s", ErrNotSupported, src)
+	}
+
+	if !overwrite && exists(ctx, to, dst) {
+		return fmt.Errorf("destination already exists: %s", dst)
+	}
+
+	data, err := ReadBytes(ctx, from, src)
+	if err != nil {
+		return err
+	}
+	if _, err := WriteBytes(ctx, to, dst, data); err != nil {
+		return err
+	}
+
+	if remove {
+		if err := from.DeleteFile(ctx, src); err != nil {
+			return fmt.Errorf("copied to %s but failed to remove source: %w", dst, err)
+		}
+	}
+	return nil
+}
+
+// ReadBytes 读取文件的原始字节
+// 后端未实现 BinaryBackend 时通过 ReadFile 降级（字符串内容按原样转换为字节）
+func ReadBytes(ctx context.Context, b Backend, path string) ([]byte, error) {
+	if bb, ok := b.(BinaryBackend); ok {
+		return bb.ReadBytes(ctx, path)
+	}
+
+	content, err := b.ReadFile(ctx, path, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// WriteBytes 写入原始字节
+// 后端未实现 BinaryBackend 时通过 WriteFile 降级
+func WriteBytes(ctx context.Context, b Backend, path string, data []byte) (*WriteResult, error) {
+	if bb, ok := b.(BinaryBackend); ok {
+		return bb.WriteBytes(ctx, path, data)
+	}
+	return b.WriteFile(ctx, path, string(data))
+}
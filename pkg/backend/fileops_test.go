@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileOps_FallbackWithoutFileManager(t *testing.T) {
+	// SQLiteBackend 未实现 FileManager，使用读取、写入、删除降级
+	b, err := NewSQLiteBackend(filepath.Join(t.TempDir(), "files.db"), "test")
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend failed: %v", err)
+	}
+	defer b.Close()
+	ctx := context.Background()
+
+	b.WriteFile(ctx, "/a.txt", "hello")
+
+	if err := Move(ctx, b, "/a.txt", "/b.txt", false); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if _, err := b.ReadFile(ctx, "/a.txt", 0, 0); err == nil {
+		t.Error("Expected source to be removed after move")
+	}
+
+	if err := Copy(ctx, b, "/b.txt", "/c.txt", false); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if err := Copy(ctx, b, "/b.txt", "/c.txt", false); err == nil {
+		t.Error("Expected error when destination exists")
+	}
+
+	info, err := Stat(ctx, b, "/c.txt")
+	if err != nil || info.Size != 5 {
+		t.Errorf("Unexpected stat result: %+v, %v", info, err)
+	}
+
+	if err := MakeDir(ctx, b, "/dir"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	if err := Move(ctx, b, "/c.txt", "/c.txt", true); err == nil {
+		t.Error("Expected error when source equals destination")
+	}
+}
+
+func TestExecuteArgs(t *testing.T) {
+	ctx := context.Background()
+
+	// 原生支持参数列表执行，参数可以包含空白
+	fsBackend, err := NewFilesystemBackend(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ExecuteArgs(ctx, fsBackend, []string{"echo", "hello  world"}, 0)
+	if err != nil || result.Stdout != "hello  world\n" {
+		t.Errorf("unexpected result: %+v (err=%v)", result, err)
+	}
+
+	// 降级为 Execute 时参数不能包含空白
+	if _, err := ExecuteArgs(ctx, NewStateBackend(), []string{"echo", "a b"}, 0); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -3,12 +3,15 @@ package backend
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	ignore "github.com/sabhiram/go-gitignore"
 )
@@ -59,6 +62,16 @@ func (b *FilesystemBackend) resolvePath(key string) (string, error) {
 	return filepath.Join(b.rootDir, key), nil
 }
 
+// RootDir 返回根目录（绝对路径）
+func (b *FilesystemBackend) RootDir() string {
+	return b.rootDir
+}
+
+// VirtualMode 返回是否为虚拟模式
+func (b *FilesystemBackend) VirtualMode() bool {
+	return b.virtualMode
+}
+
 // ListFiles 列出目录下的文件
 func (b *FilesystemBackend) ListFiles(ctx context.Context, path string) ([]FileInfo, error) {
 	fullPath, err := b.resolvePath(path)
@@ -143,7 +156,7 @@ func (b *FilesystemBackend) WriteFile(ctx context.Context, path, content string)
 	}, nil
 }
 
-// EditFile 编辑文件
+// EditFile 编辑文件（保留原有的编码和行尾风格）
 func (b *FilesystemBackend) EditFile(ctx context.Context, path, oldStr, newStr string, replaceAll bool) (*EditResult, error) {
 	fullPath, err := b.resolvePath(path)
 	if err != nil {
@@ -157,21 +170,9 @@ func (b *FilesystemBackend) EditFile(ctx context.Context, path, oldStr, newStr s
 	}
 
 	oldContent := string(data)
-	newContent := oldContent
-	replacements := 0
-
-	if replaceAll {
-		// 替换所有匹配
-		replacements = strings.Count(oldContent, oldStr)
-		newContent = strings.ReplaceAll(oldContent, oldStr, newStr)
-	} else {
-		// 只替换第一个匹配
-		idx := strings.Index(oldContent, oldStr)
-		if idx == -1 {
-			return nil, fmt.Errorf("string not found: %s", oldStr)
-		}
-		newContent = oldContent[:idx] + newStr + oldContent[idx+len(oldStr):]
-		replacements = 1
+	newContent, replacements, err := applyEdit(oldContent, oldStr, newStr, replaceAll)
+	if err != nil {
+		return nil, err
 	}
 
 	// 写回文件
@@ -371,7 +372,190 @@ func (b *FilesystemBackend) DeleteFile(ctx context.Context, path string) error {
 	return nil
 }
 
-// Execute 不支持命令执行
+// defaultExecuteTimeout 未指定超时时的命令执行超时
+const defaultExecuteTimeout = 2 * time.Minute
+
+// Execute 不支持命令字符串执行（无法可靠地拆分参数），需要执行命令时使用 ExecuteArgs
 func (b *FilesystemBackend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
 	return nil, fmt.Errorf("execute not supported by FilesystemBackend")
 }
+
+// ExecuteArgs 在根目录下按参数列表执行命令，timeout 单位为毫秒
+func (b *FilesystemBackend) ExecuteArgs(ctx context.Context, args []string, timeout int) (*ExecuteResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	execTimeout := time.Duration(timeout) * time.Millisecond
+	if execTimeout <= 0 {
+		execTimeout = defaultExecuteTimeout
+	}
+	execCtx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, args[0], args[1:]...)
+	cmd.Dir = b.rootDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &ExecuteResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to execute command: %w", err)
+		}
+		result.ExitCode = exitErr.ExitCode()
+		if execCtx.Err() == context.DeadlineExceeded {
+			result.Stderr += fmt.Sprintf("\ncommand timed out after %s", execTimeout)
+		}
+	}
+	return result, nil
+}
+
+// Move 移动或重命名文件/目录
+func (b *FilesystemBackend) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	srcPath, dstPath, err := b.resolveTransfer(src, dst, overwrite)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if overwrite {
+		// os.Rename 无法覆盖非空目录
+		if err := os.RemoveAll(dstPath); err != nil {
+			return fmt.Errorf("failed to remove destination: %w", err)
+		}
+	}
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	return nil
+}
+
+// Copy 复制文件/目录（目录递归复制）
+func (b *FilesystemBackend) Copy(ctx context.Context, src, dst string, overwrite bool) error {
+	srcPath, dstPath, err := b.resolveTransfer(src, dst, overwrite)
+	if err != nil {
+		return err
+	}
+
+	if overwrite {
+		if err := os.RemoveAll(dstPath); err != nil {
+			return fmt.Errorf("failed to remove destination: %w", err)
+		}
+	}
+
+	err = filepath.WalkDir(srcPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(srcPath, p)
+		target := filepath.Join(dstPath, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return nil
+}
+
+// resolveTransfer 解析并校验移动/复制的源路径和目标路径
+func (b *FilesystemBackend) resolveTransfer(src, dst string, overwrite bool) (string, string, error) {
+	srcPath, err := b.resolvePath(src)
+	if err != nil {
+		return "", "", err
+	}
+	dstPath, err := b.resolvePath(dst)
+	if err != nil {
+		return "", "", err
+	}
+
+	if _, err := os.Stat(srcPath); err != nil {
+		return "", "", fmt.Errorf("file not found: %s", src)
+	}
+	if srcPath == dstPath {
+		return "", "", fmt.Errorf("source and destination are the same: %s", src)
+	}
+	if strings.HasPrefix(dstPath, srcPath+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("cannot move or copy a directory into itself: %s", dst)
+	}
+	if _, err := os.Stat(dstPath); err == nil && !overwrite {
+		return "", "", fmt.Errorf("destination already exists: %s", dst)
+	}
+
+	return srcPath, dstPath, nil
+}
+
+// MakeDir 创建目录（包括父目录）
+func (b *FilesystemBackend) MakeDir(ctx context.Context, path string) error {
+	fullPath, err := b.resolvePath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+// Stat 获取文件信息
+func (b *FilesystemBackend) Stat(ctx context.Context, path string) (*FileInfo, error) {
+	fullPath, err := b.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return &FileInfo{
+		Path:    path,
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime().Unix(),
+	}, nil
+}
+
+// ReadBytes 读取文件的原始字节
+func (b *FilesystemBackend) ReadBytes(ctx context.Context, path string) ([]byte, error) {
+	fullPath, err := b.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// WriteBytes 写入原始字节
+func (b *FilesystemBackend) WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error) {
+	return b.WriteFile(ctx, path, string(data))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -418,3 +419,135 @@ func TestFilesystemBackend_Grep_NoGitignore(t *testing.T) {
 		t.Errorf("Expected 2 matches without gitignore, got %d", len(matches))
 	}
 }
+
+func TestFilesystemBackend_MoveCopyStat(t *testing.T) {
+	backend, err := NewFilesystemBackend(t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("NewFilesystemBackend failed: %v", err)
+	}
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/pkg/a.go", "package pkg")
+	backend.WriteFile(ctx, "/pkg/sub/b.go", "package sub")
+
+	// 递归复制目录
+	if err := backend.Copy(ctx, "/pkg", "/vendor/pkg", false); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if content, _ := backend.ReadFile(ctx, "/vendor/pkg/sub/b.go", 0, 0); content != "package sub" {
+		t.Errorf("Expected copied content, got %q", content)
+	}
+
+	// 移动到新目录（自动创建父目录）
+	if err := backend.Move(ctx, "/pkg/a.go", "/internal/a.go", false); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if _, err := backend.Stat(ctx, "/pkg/a.go"); err == nil {
+		t.Error("Expected source to be removed after move")
+	}
+
+	info, err := backend.Stat(ctx, "/internal/a.go")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.IsDir || info.Size != int64(len("package pkg")) || info.Path != "/internal/a.go" {
+		t.Errorf("Unexpected file info: %+v", info)
+	}
+
+	// 目标已存在
+	if err := backend.Move(ctx, "/internal/a.go", "/vendor/pkg/a.go", false); err == nil {
+		t.Error("Expected error when destination exists")
+	}
+	if err := backend.Move(ctx, "/internal/a.go", "/vendor/pkg/a.go", true); err != nil {
+		t.Errorf("Move with overwrite failed: %v", err)
+	}
+
+	// 不能复制到自身内部，也不能逃逸根目录
+	if err := backend.Copy(ctx, "/vendor", "/vendor/nested", false); err == nil {
+		t.Error("Expected error copying directory into itself")
+	}
+	if err := backend.Move(ctx, "/vendor", "/../outside", false); err == nil {
+		t.Error("Expected error for path traversal")
+	}
+
+	if err := backend.MakeDir(ctx, "/a/b/c"); err != nil {
+		t.Fatalf("MakeDir failed: %v", err)
+	}
+	if info, err := backend.Stat(ctx, "/a/b/c"); err != nil || !info.IsDir {
+		t.Errorf("Expected directory, got %+v, %v", info, err)
+	}
+}
+
+func TestFilesystemBackend_BinaryAndLegacyEncoding(t *testing.T) {
+	tmpDir := t.TempDir()
+	backend, err := NewFilesystemBackend(tmpDir, true)
+	if err != nil {
+		t.Fatalf("NewFilesystemBackend failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// 二进制内容按字节原样读写
+	data := append(append([]byte{}, pngHeader...), 0xff, 0x00, 0x80)
+	if _, err := backend.WriteBytes(ctx, "/img.png", data); err != nil {
+		t.Fatalf("WriteBytes failed: %v", err)
+	}
+	read, err := backend.ReadBytes(ctx, "/img.png")
+	if err != nil || string(read) != string(data) {
+		t.Errorf("Binary round trip mismatch: %v", err)
+	}
+
+	// Latin-1 + CRLF 文件编辑后保持原编码和行尾
+	os.WriteFile(filepath.Join(tmpDir, "legacy.txt"), []byte("r\xe9sum\xe9\r\nend\r\n"), 0644)
+	if _, err := backend.EditFile(ctx, "/legacy.txt", "résumé\n", "résumé v2\n", false); err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+	raw, _ := os.ReadFile(filepath.Join(tmpDir, "legacy.txt"))
+	if want := "r\xe9sum\xe9 v2\r\nend\r\n"; string(raw) != want {
+		t.Errorf("Expected %q, got %q", want, raw)
+	}
+}
+
+func TestFilesystemBackend_Execute(t *testing.T) {
+	tmpDir := t.TempDir()
+	backend, err := NewFilesystemBackend(tmpDir, true)
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	ctx := context.Background()
+
+	// 不支持命令字符串，避免参数被错误拆分
+	if _, err := backend.Execute(ctx, "pwd", 0); err == nil {
+		t.Error("Expected Execute to be unsupported")
+	}
+
+	// 命令在根目录下执行
+	result, err := backend.ExecuteArgs(ctx, []string{"pwd"}, 0)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if strings.TrimSpace(result.Stdout) != backend.RootDir() || result.ExitCode != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// 非零退出码不视为错误
+	result, err = backend.ExecuteArgs(ctx, []string{"ls", "missing-file"}, 0)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ExitCode == 0 || result.Stderr == "" {
+		t.Errorf("Expected failure with stderr, got %+v", result)
+	}
+
+	// 参数中的空白原样保留
+	result, err = backend.ExecuteArgs(ctx, []string{"echo", "a  b"}, 0)
+	if err != nil || result.Stdout != "a  b\n" {
+		t.Errorf("Expected argument to keep its whitespace, got %+v (err=%v)", result, err)
+	}
+
+	if _, err := backend.ExecuteArgs(ctx, nil, 0); err == nil {
+		t.Error("Expected error for empty command")
+	}
+	if _, err := backend.ExecuteArgs(ctx, []string{"no-such-command-xyz"}, 0); err == nil {
+		t.Error("Expected error for unknown command")
+	}
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"path"
+	"strings"
+)
+
+// matchGlob 判断相对路径是否匹配 glob 模式
+// 在 path.Match 的基础上支持 "**"（匹配任意层级目录，包括零层）和 "{a,b}" 多选
+func matchGlob(pattern, name string) bool {
+	for _, p := range expandBraces(pattern) {
+		if matchSegments(strings.Split(p, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments 逐段匹配路径
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// 折叠连续的 **
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// expandBraces 展开 "{a,b}" 形式的多选（不支持嵌套）
+func expandBraces(pattern string) []string {
+	start := strings.Index(pattern, "{")
+	if start == -1 {
+		return []string{pattern}
+	}
+	end := strings.Index(pattern[start:], "}")
+	if end == -1 {
+		return []string{pattern}
+	}
+	end += start
+
+	var result []string
+	for _, alt := range strings.Split(pattern[start+1:end], ",") {
+		result = append(result, expandBraces(pattern[:start]+alt+pattern[end+1:])...)
+	}
+	return result
+}
+
+// normalizePath 规范化虚拟路径：以 "/" 开头，去除多余的分隔符和 "."
+func normalizePath(p string) string {
+	return path.Clean("/" + strings.TrimPrefix(p, "/"))
+}
@@ -0,0 +1,470 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/zhoucx/deepagents-go/pkg/internal/stringutil"
+)
+
+const (
+	// s3ReadChunkSize 分段读取时每次 Range 请求的字节数
+	s3ReadChunkSize = 256 * 1024
+
+	// s3EditRetries 条件写入冲突时的最大重试次数
+	s3EditRetries = 3
+
+	// s3GrepMaxSize Grep 跳过超过该大小的对象（与 FilesystemBackend 一致）
+	s3GrepMaxSize = 1 << 20
+)
+
+// S3Config S3 兼容对象存储配置（AWS S3、MinIO 等）
+type S3Config struct {
+	// Endpoint 服务地址（不含协议），如 "localhost:9000"、"s3.amazonaws.com"
+	Endpoint string
+
+	// AccessKey 访问密钥 ID
+	AccessKey string
+
+	// SecretKey 访问密钥
+	SecretKey string
+
+	// Bucket 存储桶名称
+	Bucket string
+
+	// Prefix 对象键前缀（可选，用于多租户隔离，如 "tenants/acme/"）
+	Prefix string
+
+	// Region 区域（默认 us-east-1）
+	Region string
+
+	// UseSSL 是否使用 HTTPS
+	UseSSL bool
+
+	// CreateBucket 存储桶不存在时自动创建
+	CreateBucket bool
+}
+
+// S3Backend 实现基于 S3 兼容对象存储的后端
+// 路径映射为桶内的对象键，目录通过键前缀模拟
+type S3Backend struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Backend 创建 S3 后端
+func NewS3Backend(cfg *S3Config) (*S3Backend, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket cannot be empty")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	if cfg.CreateBucket {
+		ctx := context.Background()
+		exists, err := client.BucketExists(ctx, cfg.Bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bucket: %w", err)
+		}
+		if !exists {
+			if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: region}); err != nil {
+				return nil, fmt.Errorf("failed to create bucket: %w", err)
+			}
+		}
+	}
+
+	return NewS3BackendWithClient(client, cfg.Bucket, cfg.Prefix), nil
+}
+
+// NewS3BackendWithClient 使用已有的客户端创建 S3 后端
+func NewS3BackendWithClient(client *minio.Client, bucket, prefix string) *S3Backend {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Backend{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+// objectKey 将虚拟路径转换为对象键
+func (b *S3Backend) objectKey(path string) string {
+	return b.prefix + strings.TrimPrefix(normalizePath(path), "/")
+}
+
+// dirPrefix 将目录路径转换为列举用的键前缀（以 "/" 结尾）
+func (b *S3Backend) dirPrefix(path string) string {
+	key := b.objectKey(path)
+	if key == "" || strings.HasSuffix(key, "/") {
+		return key
+	}
+	return key + "/"
+}
+
+// virtualPath 将对象键转换为虚拟路径
+func (b *S3Backend) virtualPath(key string) string {
+	return "/" + strings.TrimSuffix(strings.TrimPrefix(key, b.prefix), "/")
+}
+
+// ListFiles 列出目录下的文件（子目录对应公共前缀）
+func (b *S3Backend) ListFiles(ctx context.Context, path string) ([]FileInfo, error) {
+	var files []FileInfo
+	for obj := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{
+		Prefix: b.dirPrefix(path),
+	}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+
+		if strings.HasSuffix(obj.Key, "/") {
+			files = append(files, FileInfo{
+				Path:  b.virtualPath(obj.Key),
+				IsDir: true,
+			})
+			continue
+		}
+
+		files = append(files, FileInfo{
+			Path:    b.virtualPath(obj.Key),
+			Size:    obj.Size,
+			ModTime: obj.LastModified.Unix(),
+		})
+	}
+	return files, nil
+}
+
+// ReadFile 读取文件内容
+// 指定 offset/limit 时按 Range 分段读取，读够所需行数即停止
+func (b *S3Backend) ReadFile(ctx context.Context, path string, offset, limit int) (string, error) {
+	key := b.objectKey(path)
+
+	info, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return "", b.wrapError(err, path, "failed to read file")
+	}
+
+	if offset > 0 || limit > 0 {
+		return b.readLines(ctx, key, info.Size, offset, limit)
+	}
+
+	data, err := b.getRange(ctx, key, 0, info.Size-1)
+	if err != nil {
+		return "", b.wrapError(err, path, "failed to read file")
+	}
+	return string(data), nil
+}
+
+// readLines 分段读取 [offset, offset+limit) 范围内的行
+func (b *S3Backend) readLines(ctx context.Context, key string, size int64, offset, limit int) (string, error) {
+	var selected []string
+	var pending []byte
+	lineNo := 0
+	done := func() bool { return limit > 0 && lineNo >= offset+limit }
+
+	for start := int64(0); start < size && !done(); start += s3ReadChunkSize {
+		chunk, err := b.getRange(ctx, key, start, min(start+s3ReadChunkSize, size)-1)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		pending = append(pending, chunk...)
+
+		for !done() {
+			idx := bytes.IndexByte(pending, '\n')
+			if idx == -1 {
+				break
+			}
+			if lineNo >= offset {
+				selected = append(selected, string(pending[:idx]))
+			}
+			lineNo++
+			pending = pending[idx+1:]
+		}
+	}
+
+	// 最后一行没有换行符
+	if !done() && len(pending) > 0 && lineNo >= offset {
+		selected = append(selected, string(pending))
+	}
+
+	return stringutil.JoinLines(selected), nil
+}
+
+// getRange 读取对象的 [start, end] 字节范围
+func (b *S3Backend) getRange(ctx context.Context, key string, start, end int64) ([]byte, error) {
+	if end < start {
+		return nil, nil
+	}
+
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(start, end); err != nil {
+		return nil, err
+	}
+
+	obj, err := b.client.GetObject(ctx, b.bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(obj)
+}
+
+// WriteFile 写入文件（创建或覆盖）
+func (b *S3Backend) WriteFile(ctx context.Context, path, content string) (*WriteResult, error) {
+	if err := b.putObject(ctx, b.objectKey(path), content, ""); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return &WriteResult{
+		Path:         path,
+		BytesWritten: len(content),
+	}, nil
+}
+
+// putObject 上传对象（Content-Type 根据内容检测），etag 非空时仅在对象未被修改时写入（If-Match）
+func (b *S3Backend) putObject(ctx context.Context, key, content, etag string) error {
+	opts := minio.PutObjectOptions{ContentType: DetectContent([]byte(content)).MIMEType}
+	if etag != "" {
+		opts.SetMatchETag(etag)
+	}
+
+	_, err := b.client.PutObject(ctx, b.bucket, key, strings.NewReader(content), int64(len(content)), opts)
+	return err
+}
+
+// ReadBytes 读取对象的原始字节
+func (b *S3Backend) ReadBytes(ctx context.Context, path string) ([]byte, error) {
+	obj, err := b.client.GetObject(ctx, b.bucket, b.objectKey(path), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, b.wrapError(err, path, "failed to read file")
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, b.wrapError(err, path, "failed to read file")
+	}
+	return data, nil
+}
+
+// WriteBytes 写入原始字节
+func (b *S3Backend) WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error) {
+	return b.WriteFile(ctx, path, string(data))
+}
+
+// EditFile 编辑文件
+// 使用 ETag 条件写入，若对象在读取后被他人修改则基于最新内容重试，避免丢失更新
+func (b *S3Backend) EditFile(ctx context.Context, path, oldStr, newStr string, replaceAll bool) (*EditResult, error) {
+	key := b.objectKey(path)
+
+	for attempt := 0; attempt < s3EditRetries; attempt++ {
+		obj, err := b.client.GetObject(ctx, b.bucket, key, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, b.wrapError(err, path, "failed to read file")
+		}
+		info, err := obj.Stat()
+		if err != nil {
+			obj.Close()
+			return nil, b.wrapError(err, path, "failed to read file")
+		}
+		data, err := io.ReadAll(obj)
+		obj.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		oldContent := string(data)
+		newContent, replacements, err := applyEdit(oldContent, oldStr, newStr, replaceAll)
+		if err != nil {
+			return nil, err
+		}
+
+		err = b.putObject(ctx, key, newContent, info.ETag)
+		if isPreconditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write file: %w", err)
+		}
+
+		return &EditResult{
+			Path:         path,
+			Replacements: replacements,
+			OldContent:   oldContent,
+			NewContent:   newContent,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("file modified concurrently, edit aborted after %d attempts: %s", s3EditRetries, path)
+}
+
+// Grep 搜索文件内容（在客户端逐个读取对象并匹配）
+func (b *S3Backend) Grep(ctx context.Context, pattern, path, glob string) ([]GrepMatch, error) {
+	// 编译正则表达式
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// 编译失败时，降级到字面字符串匹配
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+
+	objects, err := b.listRecursive(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []GrepMatch
+	for _, obj := range objects {
+		filePath := b.virtualPath(obj.Key)
+
+		// glob 过滤
+		if glob != "" {
+			matched, err := filepath.Match(glob, filepath.Base(filePath))
+			if err != nil || !matched {
+				continue
+			}
+		}
+
+		// 跳过二进制文件和大文件
+		if isBinaryExtension(filePath) || obj.Size > s3GrepMaxSize {
+			continue
+		}
+
+		data, err := b.getRange(ctx, obj.Key, 0, obj.Size-1)
+		if err != nil {
+			continue // 忽略读取错误
+		}
+		if bytes.IndexByte(data[:min(len(data), 8192)], 0) != -1 {
+			continue
+		}
+
+		lines := stringutil.SplitLines(string(data))
+		for i, line := range lines {
+			if re.MatchString(line) {
+				matchStr := re.FindString(line)
+				if matchStr == "" {
+					matchStr = pattern // 降级情况下使用原始 pattern
+				}
+				matches = append(matches, GrepMatch{
+					Path:       filePath,
+					LineNumber: i + 1,
+					Line:       line,
+					Match:      matchStr,
+				})
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// Glob 查找匹配的文件（pattern 相对于 path，支持 ** 和 {a,b}）
+func (b *S3Backend) Glob(ctx context.Context, pattern, path string) ([]FileInfo, error) {
+	base := b.dirPrefix(path)
+
+	objects, err := b.listRecursive(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileInfo
+	for _, obj := range objects {
+		if !matchGlob(pattern, strings.TrimPrefix(obj.Key, base)) {
+			continue
+		}
+		files = append(files, FileInfo{
+			Path:    b.virtualPath(obj.Key),
+			Size:    obj.Size,
+			ModTime: obj.LastModified.Unix(),
+		})
+	}
+	return files, nil
+}
+
+// listRecursive 递归列出路径下的所有对象（path 也可以是单个文件）
+func (b *S3Backend) listRecursive(ctx context.Context, path string) ([]minio.ObjectInfo, error) {
+	key := b.objectKey(path)
+	dir := b.dirPrefix(path)
+
+	var objects []minio.ObjectInfo
+	for obj := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{
+		Prefix:    key,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+		// 前缀匹配可能包含同名前缀的其他对象（如 /src 匹配到 /src2/a.go）
+		if obj.Key != key && !strings.HasPrefix(obj.Key, dir) {
+			continue
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// DeleteFile 删除文件
+func (b *S3Backend) DeleteFile(ctx context.Context, path string) error {
+	key := b.objectKey(path)
+
+	// S3 删除不存在的对象不会报错，先检查是否存在以保持与其他后端一致
+	if _, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{}); err != nil {
+		return b.wrapError(err, path, "failed to delete file")
+	}
+
+	if err := b.client.RemoveObject(ctx, b.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Execute 不支持命令执行
+func (b *S3Backend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
+	return nil, fmt.Errorf("execute not supported by S3Backend")
+}
+
+// wrapError 将对象不存在的错误转换为统一的 "file not found"
+func (b *S3Backend) wrapError(err error, path, msg string) error {
+	resp := minio.ToErrorResponse(err)
+	if resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("file not found: %s", path)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// isPreconditionFailed 判断是否为条件写入失败（对象已被修改）
+func isPreconditionFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	resp := minio.ToErrorResponse(err)
+	return resp.Code == "PreconditionFailed" || resp.StatusCode == http.StatusPreconditionFailed
+}
@@ -0,0 +1,607 @@
+package backend
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3Object 假 S3 服务中的对象
+type fakeS3Object struct {
+	data    []byte
+	etag    string
+	modTime time.Time
+}
+
+// fakeS3Server 进程内的最小 S3 兼容服务（仅支持单个桶，忽略鉴权）
+// 支持 HEAD/GET（含 Range）、PUT（含 If-Match）、DELETE 和 ListObjectsV2
+type fakeS3Server struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string]*fakeS3Object
+
+	// beforeConditionalPut 在处理带 If-Match 的 PUT 之前调用（用于模拟并发修改）
+	beforeConditionalPut func(key string)
+
+	// rangeRequests 记录收到的 Range 请求数
+	rangeRequests int
+}
+
+func newFakeS3Server(t *testing.T, bucket string) (*fakeS3Server, *httptest.Server) {
+	t.Helper()
+	fake := &fakeS3Server{
+		bucket:  bucket,
+		objects: make(map[string]*fakeS3Object),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// put 直接写入对象（绕过 HTTP）
+func (s *fakeS3Server) put(key string, data []byte) {
+	sum := md5.Sum(data)
+	s.objects[key] = &fakeS3Object{
+		data:    data,
+		etag:    `"` + hex.EncodeToString(sum[:]) + `"`,
+		modTime: time.Now().UTC(),
+	}
+}
+
+func (s *fakeS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != s.bucket {
+		s.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if len(parts) == 1 || parts[1] == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			s.listObjects(w, r.URL.Query())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	key := parts[1]
+
+	if r.Method == http.MethodPut {
+		if r.Header.Get("If-Match") != "" && s.beforeConditionalPut != nil {
+			s.beforeConditionalPut(key)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		obj, ok := s.objects[key]
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		data := obj.data
+		status := http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" && r.Method == http.MethodGet {
+			s.rangeRequests++
+			start, end := parseRange(rng, int64(len(data)))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); match != "" {
+			obj, ok := s.objects[key]
+			if !ok || strings.Trim(obj.etag, `"`) != strings.Trim(match, `"`) {
+				s.writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+				return
+			}
+		}
+		data, _ := io.ReadAll(r.Body)
+		s.put(key, data)
+		w.Header().Set("ETag", s.objects[key].etag)
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// listObjects 实现 ListObjectsV2（一次返回全部结果）
+func (s *fakeS3Server) listObjects(w http.ResponseWriter, query url.Values) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	type listResult struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		Delimiter      string
+		KeyCount       int
+		MaxKeys        int
+		IsTruncated    bool
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}
+
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	result := listResult{Name: s.bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		if delimiter != "" {
+			if idx := strings.Index(rest, delimiter); idx != -1 {
+				p := prefix + rest[:idx+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: p})
+				}
+				continue
+			}
+		}
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: obj.modTime.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         obj.etag,
+			Size:         int64(len(obj.data)),
+		})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (s *fakeS3Server) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// parseRange 解析 "bytes=start-end"
+func parseRange(header string, size int64) (int64, int64) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	startStr, endStr, _ := strings.Cut(spec, "-")
+	start, _ := strconv.ParseInt(startStr, 10, 64)
+	end := size - 1
+	if endStr != "" {
+		end, _ = strconv.ParseInt(endStr, 10, 64)
+	}
+	if end >= size {
+		end = size - 1
+	}
+	return start, end
+}
+
+// newTestS3Backend 创建连接到进程内假 S3 服务的后端
+func newTestS3Backend(t *testing.T, prefix string) (*S3Backend, *fakeS3Server) {
+	t.Helper()
+	fake, server := newFakeS3Server(t, "workspace")
+	backend, err := NewS3Backend(&S3Config{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Bucket:   "workspace",
+		Prefix:   prefix,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Backend failed: %v", err)
+	}
+	return backend, fake
+}
+
+func TestS3Backend_WriteAndRead(t *testing.T) {
+	backend, fake := newTestS3Backend(t, "agents/a1")
+	ctx := context.Background()
+
+	content := "Hello, World!"
+	result, err := backend.WriteFile(ctx, "/test.txt", content)
+	if err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if result.BytesWritten != len(content) {
+		t.Errorf("Expected %d bytes written, got %d", len(content), result.BytesWritten)
+	}
+
+	// 对象键应包含前缀
+	if _, ok := fake.objects["agents/a1/test.txt"]; !ok {
+		t.Errorf("Expected object key agents/a1/test.txt, got %v", fake.objects)
+	}
+
+	readContent, err := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if readContent != content {
+		t.Errorf("Expected content %q, got %q", content, readContent)
+	}
+}
+
+func TestS3Backend_ReadFileNotFound(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	_, err := backend.ReadFile(ctx, "/nonexistent.txt", 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "file not found") {
+		t.Errorf("Expected file not found error, got %v", err)
+	}
+}
+
+func TestS3Backend_ReadFileWithOffset(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	content := "Line 1\nLine 2\nLine 3\nLine 4\nLine 5"
+	backend.WriteFile(ctx, "/test.txt", content)
+
+	tests := []struct {
+		offset, limit int
+		want          string
+	}{
+		{1, 2, "Line 2\nLine 3"},
+		{3, 0, "Line 4\nLine 5"},
+		{4, 10, "Line 5"},
+		{10, 1, ""},
+	}
+	for _, tt := range tests {
+		got, err := backend.ReadFile(ctx, "/test.txt", tt.offset, tt.limit)
+		if err != nil {
+			t.Fatalf("ReadFile(%d, %d) failed: %v", tt.offset, tt.limit, err)
+		}
+		if got != tt.want {
+			t.Errorf("ReadFile(%d, %d): expected %q, got %q", tt.offset, tt.limit, tt.want, got)
+		}
+	}
+}
+
+func TestS3Backend_ReadFileRanged(t *testing.T) {
+	backend, fake := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	// 约 4 个分段大小的文件
+	var sb strings.Builder
+	for i := 0; sb.Len() < 4*s3ReadChunkSize; i++ {
+		fmt.Fprintf(&sb, "line %06d %s\n", i, strings.Repeat("x", 50))
+	}
+	backend.WriteFile(ctx, "/big.log", sb.String())
+
+	got, err := backend.ReadFile(ctx, "/big.log", 10, 2)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	want := fmt.Sprintf("line %06d %s\nline %06d %s", 10, strings.Repeat("x", 50), 11, strings.Repeat("x", 50))
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	// 只需读取第一个分段
+	if fake.rangeRequests != 1 {
+		t.Errorf("Expected 1 range request, got %d", fake.rangeRequests)
+	}
+
+	// 跨分段边界读取
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	offset := len(lines) / 2
+	got, _ = backend.ReadFile(ctx, "/big.log", offset, 3)
+	if want := strings.Join(lines[offset:offset+3], "\n"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestS3Backend_EditFile(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/test.txt", "Hello, World!\nHello, Go!")
+
+	result, err := backend.EditFile(ctx, "/test.txt", "Hello", "Hi", false)
+	if err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+	if result.Replacements != 1 {
+		t.Errorf("Expected 1 replacement, got %d", result.Replacements)
+	}
+
+	result, err = backend.EditFile(ctx, "/test.txt", "o", "0", true)
+	if err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+	if result.Replacements != 3 {
+		t.Errorf("Expected 3 replacements, got %d", result.Replacements)
+	}
+
+	newContent, _ := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	if expected := "Hi, W0rld!\nHell0, G0!"; newContent != expected {
+		t.Errorf("Expected content %q, got %q", expected, newContent)
+	}
+
+	if _, err := backend.EditFile(ctx, "/missing.txt", "a", "b", false); err == nil {
+		t.Error("Expected error for nonexistent file, got nil")
+	}
+	if _, err := backend.EditFile(ctx, "/test.txt", "missing", "b", false); err == nil {
+		t.Error("Expected error for missing string, got nil")
+	}
+}
+
+func TestS3Backend_EditFileConflictRetries(t *testing.T) {
+	backend, fake := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/test.txt", "alpha beta")
+
+	// 第一次条件写入前，另一个写入者修改了对象
+	modified := false
+	fake.beforeConditionalPut = func(key string) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if !modified {
+			modified = true
+			fake.put(key, []byte("alpha beta gamma"))
+		}
+	}
+
+	if _, err := backend.EditFile(ctx, "/test.txt", "alpha", "ALPHA", false); err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+
+	// 编辑应基于最新内容重新应用，不丢失并发写入
+	content, _ := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	if content != "ALPHA beta gamma" {
+		t.Errorf("Expected %q, got %q", "ALPHA beta gamma", content)
+	}
+}
+
+func TestS3Backend_EditFileConflictGivesUp(t *testing.T) {
+	backend, fake := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/test.txt", "alpha")
+
+	// 每次条件写入前都被修改
+	n := 0
+	fake.beforeConditionalPut = func(key string) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		n++
+		fake.put(key, []byte(fmt.Sprintf("alpha %d", n)))
+	}
+
+	_, err := backend.EditFile(ctx, "/test.txt", "alpha", "beta", false)
+	if err == nil || !strings.Contains(err.Error(), "modified concurrently") {
+		t.Errorf("Expected concurrent modification error, got %v", err)
+	}
+	if n != s3EditRetries {
+		t.Errorf("Expected %d attempts, got %d", s3EditRetries, n)
+	}
+}
+
+func TestS3Backend_ListFiles(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "ws")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/file1.txt", "content1")
+	backend.WriteFile(ctx, "/file2.txt", "content2")
+	backend.WriteFile(ctx, "/dir/nested.txt", "nested")
+	backend.WriteFile(ctx, "/dir2/other.txt", "other")
+
+	files, err := backend.ListFiles(ctx, "/")
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 entries, got %d: %+v", len(files), files)
+	}
+
+	var dirs int
+	for _, f := range files {
+		if f.IsDir {
+			dirs++
+		}
+	}
+	if dirs != 2 {
+		t.Errorf("Expected 2 directories, got %d", dirs)
+	}
+
+	// 前缀 /dir 不应匹配 /dir2
+	files, _ = backend.ListFiles(ctx, "/dir")
+	if len(files) != 1 || files[0].Path != "/dir/nested.txt" {
+		t.Errorf("Unexpected files in /dir: %+v", files)
+	}
+}
+
+func TestS3Backend_Grep(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/file1.txt", "Hello World\nGoodbye World")
+	backend.WriteFile(ctx, "/file2.txt", "Hello Go\nGoodbye Go")
+	backend.WriteFile(ctx, "/src/main.go", "package main\n// Hello")
+	backend.WriteFile(ctx, "/src2/other.go", "// Hello")
+	backend.WriteFile(ctx, "/logo.png", "Hello")
+
+	matches, err := backend.Grep(ctx, "Hello", "", "")
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	// 跳过二进制扩展名的文件
+	if len(matches) != 4 {
+		t.Errorf("Expected 4 matches, got %d: %+v", len(matches), matches)
+	}
+
+	matches, err = backend.Grep(ctx, "Hel+o", "/src", "*.go")
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != "/src/main.go" || matches[0].LineNumber != 2 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+
+	// path 为单个文件
+	matches, _ = backend.Grep(ctx, "Goodbye", "/file1.txt", "")
+	if len(matches) != 1 {
+		t.Errorf("Expected 1 match in single file, got %d", len(matches))
+	}
+}
+
+func TestS3Backend_Glob(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/main.go", "")
+	backend.WriteFile(ctx, "/README.md", "")
+	backend.WriteFile(ctx, "/pkg/a/a.go", "")
+	backend.WriteFile(ctx, "/pkg/b/b.ts", "")
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    int
+	}{
+		{"*.go", "", 1},
+		{"**/*.go", "", 2},
+		{"**/*.{go,ts}", "", 3},
+		{"*/*.go", "/pkg", 1},
+		{"*", "/", 2},
+	}
+
+	for _, tt := range tests {
+		files, err := backend.Glob(ctx, tt.pattern, tt.path)
+		if err != nil {
+			t.Fatalf("Glob(%q, %q) failed: %v", tt.pattern, tt.path, err)
+		}
+		if len(files) != tt.want {
+			t.Errorf("Glob(%q, %q): expected %d files, got %d", tt.pattern, tt.path, tt.want, len(files))
+		}
+	}
+}
+
+func TestS3Backend_DeleteFile(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/test.txt", "content")
+
+	if err := backend.DeleteFile(ctx, "/test.txt"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	if _, err := backend.ReadFile(ctx, "/test.txt", 0, 0); err == nil {
+		t.Error("Expected error after delete, got nil")
+	}
+	if err := backend.DeleteFile(ctx, "/test.txt"); err == nil {
+		t.Error("Expected error deleting nonexistent file, got nil")
+	}
+}
+
+func TestS3Backend_CompositeRoute(t *testing.T) {
+	s3, _ := newTestS3Backend(t, "")
+	defaultBackend := NewStateBackend()
+	composite := NewCompositeBackend(defaultBackend)
+	composite.AddRoute("/artifacts", s3)
+	ctx := context.Background()
+
+	if _, err := composite.WriteFile(ctx, "/artifacts/report.md", "# Report"); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// 文件应存储在 S3 后端中
+	content, err := s3.ReadFile(ctx, "/report.md", 0, 0)
+	if err != nil || content != "# Report" {
+		t.Errorf("Expected file in S3 backend, got %q, %v", content, err)
+	}
+	if _, err := defaultBackend.ReadFile(ctx, "/artifacts/report.md", 0, 0); err == nil {
+		t.Error("File should not exist in default backend")
+	}
+
+	files, err := composite.ListFiles(ctx, "/artifacts/")
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "/artifacts/report.md" {
+		t.Errorf("Unexpected files: %+v", files)
+	}
+}
+
+func TestS3Backend_ExecuteNotSupported(t *testing.T) {
+	backend, _ := newTestS3Backend(t, "")
+	if _, err := backend.Execute(context.Background(), "ls", 0); err == nil {
+		t.Error("Expected error for Execute, got nil")
+	}
+}
+
+// TestS3Backend_MinIO 针对真实 MinIO 运行（需设置 DEEPAGENTS_TEST_S3_ENDPOINT 等环境变量）
+func TestS3Backend_MinIO(t *testing.T) {
+	endpoint := os.Getenv("DEEPAGENTS_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DEEPAGENTS_TEST_S3_ENDPOINT not set")
+	}
+
+	backend, err := NewS3Backend(&S3Config{
+		Endpoint:     endpoint,
+		AccessKey:    os.Getenv("DEEPAGENTS_TEST_S3_ACCESS_KEY"),
+		SecretKey:    os.Getenv("DEEPAGENTS_TEST_S3_SECRET_KEY"),
+		Bucket:       "deepagents-test",
+		Prefix:       fmt.Sprintf("run-%d", time.Now().UnixNano()),
+		CreateBucket: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Backend failed: %v", err)
+	}
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/a/b.txt", "one\ntwo\nthree")
+	defer backend.DeleteFile(ctx, "/a/b.txt")
+
+	if got, _ := backend.ReadFile(ctx, "/a/b.txt", 1, 1); got != "two" {
+		t.Errorf("Expected %q, got %q", "two", got)
+	}
+	if _, err := backend.EditFile(ctx, "/a/b.txt", "two", "2", false); err != nil {
+		t.Errorf("EditFile failed: %v", err)
+	}
+	if files, _ := backend.Glob(ctx, "**/*.txt", "/"); len(files) != 1 {
+		t.Errorf("Expected 1 file, got %d", len(files))
+	}
+}
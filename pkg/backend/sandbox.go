@@ -314,6 +314,16 @@ func (b *SandboxBackend) DeleteFile(ctx context.Context, path string) error {
 
 // Execute 执行命令
 func (b *SandboxBackend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
+	return b.execute(ctx, command, strings.Fields(command), timeout)
+}
+
+// ExecuteArgs 按参数列表执行命令
+func (b *SandboxBackend) ExecuteArgs(ctx context.Context, args []string, timeout int) (*ExecuteResult, error) {
+	return b.execute(ctx, strings.Join(args, " "), args, timeout)
+}
+
+// execute 检查命令白名单后执行，command 仅用于审计日志
+func (b *SandboxBackend) execute(ctx context.Context, command string, cmdParts []string, timeout int) (*ExecuteResult, error) {
 	if b.config.ReadOnly {
 		err := fmt.Errorf("execute operation not allowed in read-only mode")
 		b.audit("Execute", command, false, err)
@@ -326,7 +336,6 @@ func (b *SandboxBackend) Execute(ctx context.Context, command string, timeout in
 	}
 
 	// 检查命令是否在白名单中
-	cmdParts := strings.Fields(command)
 	if len(cmdParts) == 0 {
 		err := fmt.Errorf("empty command")
 		b.audit("Execute", command, false, err)
@@ -374,3 +383,87 @@ func (b *SandboxBackend) Execute(ctx context.Context, command string, timeout in
 	b.audit("Execute", command, err == nil, err)
 	return result, nil
 }
+
+// Move 移动或重命名文件/目录（源路径和目标路径都需通过访问检查）
+func (b *SandboxBackend) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	return b.transfer(ctx, "Move", src, dst, func() error {
+		return b.backend.Move(ctx, src, dst, overwrite)
+	})
+}
+
+// Copy 复制文件/目录（源路径和目标路径都需通过访问检查）
+func (b *SandboxBackend) Copy(ctx context.Context, src, dst string, overwrite bool) error {
+	return b.transfer(ctx, "Copy", src, dst, func() error {
+		return b.backend.Copy(ctx, src, dst, overwrite)
+	})
+}
+
+// transfer 检查移动/复制操作并记录审计日志
+func (b *SandboxBackend) transfer(ctx context.Context, operation, src, dst string, fn func() error) error {
+	auditPath := src + " -> " + dst
+
+	if b.config.ReadOnly {
+		err := fmt.Errorf("%s operation not allowed in read-only mode", strings.ToLower(operation))
+		b.audit(operation, auditPath, false, err)
+		return err
+	}
+
+	for _, path := range []string{src, dst} {
+		if err := b.checkOperation(ctx, operation, path); err != nil {
+			b.audit(operation, auditPath, false, err)
+			return err
+		}
+	}
+
+	err := fn()
+	b.audit(operation, auditPath, err == nil, err)
+	return err
+}
+
+// MakeDir 创建目录
+func (b *SandboxBackend) MakeDir(ctx context.Context, path string) error {
+	if b.config.ReadOnly {
+		err := fmt.Errorf("mkdir operation not allowed in read-only mode")
+		b.audit("MakeDir", path, false, err)
+		return err
+	}
+
+	if err := b.checkOperation(ctx, "MakeDir", path); err != nil {
+		b.audit("MakeDir", path, false, err)
+		return err
+	}
+
+	err := b.backend.MakeDir(ctx, path)
+	b.audit("MakeDir", path, err == nil, err)
+	return err
+}
+
+// Stat 获取文件信息
+func (b *SandboxBackend) Stat(ctx context.Context, path string) (*FileInfo, error) {
+	if err := b.checkOperation(ctx, "Stat", path); err != nil {
+		b.audit("Stat", path, false, err)
+		return nil, err
+	}
+
+	info, err := b.backend.Stat(ctx, path)
+	b.audit("Stat", path, err == nil, err)
+	return info, err
+}
+
+// ReadBytes 读取文件的原始字节
+func (b *SandboxBackend) ReadBytes(ctx context.Context, path string) ([]byte, error) {
+	if err := b.checkOperation(ctx, "ReadBytes", path); err != nil {
+		b.audit("ReadBytes", path, false, err)
+		return nil, err
+	}
+
+	data, err := b.backend.ReadBytes(ctx, path)
+	b.audit("ReadBytes", path, err == nil, err)
+	return data, err
+}
+
+// WriteBytes 写入原始字节
+func (b *SandboxBackend) WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error) {
+	// 与 WriteFile 共用只读、路径和大小检查
+	return b.WriteFile(ctx, path, string(data))
+}
@@ -648,3 +648,68 @@ func TestDefaultSandboxConfig(t *testing.T) {
 		t.Error("Expected audit log to be enabled by default")
 	}
 }
+
+func TestSandboxBackend_MoveAndCopy(t *testing.T) {
+	config := DefaultSandboxConfig(t.TempDir())
+	config.BlockedPaths = []string{"/secret"}
+	backend, err := NewSandboxBackend(config)
+	if err != nil {
+		t.Fatalf("Failed to create sandbox backend: %v", err)
+	}
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/notes.txt", "content")
+
+	// 目标路径同样受黑名单限制
+	if err := backend.Move(ctx, "/notes.txt", "/secret/notes.txt", false); err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("Expected blocked path error, got %v", err)
+	}
+
+	if err := backend.Copy(ctx, "/notes.txt", "/backup/notes.txt", false); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if err := backend.Move(ctx, "/notes.txt", "/archive/notes.txt", false); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if info, err := backend.Stat(ctx, "/archive/notes.txt"); err != nil || info.Size != int64(len("content")) {
+		t.Errorf("Unexpected stat result: %+v, %v", info, err)
+	}
+
+	// 审计日志记录源和目标
+	log := backend.GetAuditLog()
+	found := false
+	for _, entry := range log {
+		if entry.Operation == "Move" && entry.Success && entry.Path == "/notes.txt -> /archive/notes.txt" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected successful Move entry in audit log, got %+v", log)
+	}
+}
+
+func TestSandboxBackend_MoveReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(tmpDir+"/a.txt", []byte("a"), 0644)
+
+	config := DefaultSandboxConfig(tmpDir)
+	config.ReadOnly = true
+	backend, err := NewSandboxBackend(config)
+	if err != nil {
+		t.Fatalf("Failed to create sandbox backend: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := backend.Move(ctx, "/a.txt", "/b.txt", false); err == nil {
+		t.Error("Expected error for move in read-only mode")
+	}
+	if err := backend.Copy(ctx, "/a.txt", "/b.txt", false); err == nil {
+		t.Error("Expected error for copy in read-only mode")
+	}
+	if err := backend.MakeDir(ctx, "/dir"); err == nil {
+		t.Error("Expected error for mkdir in read-only mode")
+	}
+	if _, err := backend.Stat(ctx, "/a.txt"); err != nil {
+		t.Errorf("Stat should be allowed in read-only mode: %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FileChangeOp 文件变更操作类型
+type FileChangeOp string
+
+const (
+	FileChangeOpWrite  FileChangeOp = "write"  // WriteFile
+	FileChangeOpEdit   FileChangeOp = "edit"   // EditFile
+	FileChangeOpDelete FileChangeOp = "delete" // DeleteFile
+)
+
+// FileChange 一次变更操作的快照（包含变更前后的内容）
+type FileChange struct {
+	Turn      int          `json:"turn"`      // 所属对话轮次（从 1 开始）
+	Iteration int          `json:"iteration"` // 所属 Agent 迭代（从 1 开始）
+	Op        FileChangeOp `json:"op"`        // 操作类型
+	Path      string       `json:"path"`      // 文件路径
+	Existed   bool         `json:"existed"`   // 变更前文件是否存在
+	Before    string       `json:"before"`    // 变更前内容
+	After     string       `json:"after"`     // 变更后内容（删除时为空）
+	Timestamp string       `json:"timestamp"` // RFC3339 格式
+}
+
+// fileChangeJSON FileChange 的序列化格式
+type fileChangeJSON struct {
+	fileChange
+	Encoding string `json:"encoding,omitempty"` // 内容编码（"base64" 表示 Before/After 经过 base64 编码）
+}
+
+type fileChange FileChange
+
+// MarshalJSON 内容不是合法 UTF-8 时（二进制或非 UTF-8 文本）使用 base64 编码，避免序列化时损坏
+func (c FileChange) MarshalJSON() ([]byte, error) {
+	out := fileChangeJSON{fileChange: fileChange(c)}
+	if !utf8.ValidString(c.Before) || !utf8.ValidString(c.After) {
+		out.Encoding = "base64"
+		out.Before = base64.StdEncoding.EncodeToString([]byte(c.Before))
+		out.After = base64.StdEncoding.EncodeToString([]byte(c.After))
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 解析 FileChange，必要时解码 base64 内容
+func (c *FileChange) UnmarshalJSON(data []byte) error {
+	var in fileChangeJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if in.Encoding == "base64" {
+		before, err := base64.StdEncoding.DecodeString(in.Before)
+		if err != nil {
+			return fmt.Errorf("invalid base64 content: %w", err)
+		}
+		after, err := base64.StdEncoding.DecodeString(in.After)
+		if err != nil {
+			return fmt.Errorf("invalid base64 content: %w", err)
+		}
+		in.Before, in.After = string(before), string(after)
+	}
+
+	*c = FileChange(in.fileChange)
+	return nil
+}
+
+// SnapshotPosition 变更所属的会话、轮次和迭代
+type SnapshotPosition struct {
+	Session   string // 会话 ID，变更记录按会话分别保存
+	Turn      int    // 对话轮次
+	Iteration int    // Agent 迭代
+}
+
+// SnapshotBackend 快照后端，记录所有变更操作以支持回退
+// 包装任意 Backend，WriteFile、EditFile、DeleteFile、Move、Copy 成功后按 (会话, 轮次, 迭代) 记录变更前后的内容
+// 设置 SetPositionFunc 后由 ctx 确定变更的位置，使并发执行的多个会话分别记录；否则使用 SetPosition 设置的位置（会话为空）
+type SnapshotBackend struct {
+	backend    Backend
+	mu         sync.Mutex
+	turn       int
+	iteration  int
+	positionFn func(ctx context.Context) (SnapshotPosition, bool)
+	changes    map[string][]FileChange // 会话 ID -> 变更记录
+}
+
+// NewSnapshotBackend 创建快照后端
+func NewSnapshotBackend(backend Backend) *SnapshotBackend {
+	return &SnapshotBackend{
+		backend: backend,
+		changes: make(map[string][]FileChange),
+	}
+}
+
+// SetPosition 设置默认的轮次和迭代，之后 ctx 未指定位置的变更都归属于该位置
+func (b *SnapshotBackend) SetPosition(turn, iteration int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.turn = turn
+	b.iteration = iteration
+}
+
+// SetPositionFunc 设置根据 ctx 确定变更位置的函数，fn 返回 false 时使用默认位置
+func (b *SnapshotBackend) SetPositionFunc(fn func(ctx context.Context) (SnapshotPosition, bool)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.positionFn = fn
+}
+
+// Changes 返回会话已记录的变更（副本）
+func (b *SnapshotBackend) Changes(session string) []FileChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	changes := make([]FileChange, len(b.changes[session]))
+	copy(changes, b.changes[session])
+	return changes
+}
+
+// LoadChanges 替换会话已记录的变更（用于从持久化记录恢复）
+func (b *SnapshotBackend) LoadChanges(session string, changes []FileChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	loaded := make([]FileChange, len(changes))
+	copy(loaded, changes)
+	b.changes[session] = loaded
+}
+
+// Rewind 将会话修改过的文件回退到指定轮次开始之前的状态
+// 按逆序撤销该会话在该轮次及之后的所有变更，返回被撤销的变更（按撤销顺序）
+func (b *SnapshotBackend) Rewind(ctx context.Context, session string, turn int) ([]FileChange, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	changes := b.changes[session]
+	var reverted []FileChange
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.Turn < turn {
+			break
+		}
+
+		var err error
+		if change.Existed {
+			_, err = b.backend.WriteFile(ctx, change.Path, change.Before)
+		} else {
+			err = b.backend.DeleteFile(ctx, change.Path)
+		}
+		if err != nil {
+			// 保留尚未撤销的变更，便于重试
+			b.changes[session] = changes[:i+1]
+			return reverted, fmt.Errorf("failed to revert %s: %w", change.Path, err)
+		}
+
+		reverted = append(reverted, change)
+		b.changes[session] = changes[:i]
+	}
+
+	return reverted, nil
+}
+
+// record 按 ctx 对应的位置记录一次变更
+func (b *SnapshotBackend) record(ctx context.Context, change FileChange) {
+	b.mu.Lock()
+	positionFn := b.positionFn
+	pos := SnapshotPosition{Turn: b.turn, Iteration: b.iteration}
+	b.mu.Unlock()
+
+	// positionFn 可能访问调用方的锁，不持锁调用
+	if positionFn != nil {
+		if p, ok := positionFn(ctx); ok {
+			pos = p
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	change.Turn = pos.Turn
+	change.Iteration = pos.Iteration
+	change.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	b.changes[pos.Session] = append(b.changes[pos.Session], change)
+}
+
+// ListFiles 列出目录下的文件
+func (b *SnapshotBackend) ListFiles(ctx context.Context, path string) ([]FileInfo, error) {
+	return b.backend.ListFiles(ctx, path)
+}
+
+// ReadFile 读取文件内容
+func (b *SnapshotBackend) ReadFile(ctx context.Context, path string, offset, limit int) (string, error) {
+	return b.backend.ReadFile(ctx, path, offset, limit)
+}
+
+// WriteFile 写入文件并记录快照
+func (b *SnapshotBackend) WriteFile(ctx context.Context, path, content string) (*WriteResult, error) {
+	before, readErr := b.backend.ReadFile(ctx, path, 0, 0)
+
+	result, err := b.backend.WriteFile(ctx, path, content)
+	if err != nil {
+		return nil, err
+	}
+
+	b.record(ctx, FileChange{
+		Op:      FileChangeOpWrite,
+		Path:    path,
+		Existed: readErr == nil,
+		Before:  before,
+		After:   content,
+	})
+	return result, nil
+}
+
+// EditFile 编辑文件并记录快照
+func (b *SnapshotBackend) EditFile(ctx context.Context, path, oldStr, newStr string, replaceAll bool) (*EditResult, error) {
+	result, err := b.backend.EditFile(ctx, path, oldStr, newStr, replaceAll)
+	if err != nil {
+		return nil, err
+	}
+
+	b.record(ctx, FileChange{
+		Op:      FileChangeOpEdit,
+		Path:    path,
+		Existed: true,
+		Before:  result.OldContent,
+		After:   result.NewContent,
+	})
+	return result, nil
+}
+
+// Grep 搜索文件内容
+func (b *SnapshotBackend) Grep(ctx context.Context, pattern, path, glob string) ([]GrepMatch, error) {
+	return b.backend.Grep(ctx, pattern, path, glob)
+}
+
+// Glob 查找匹配的文件
+func (b *SnapshotBackend) Glob(ctx context.Context, pattern, path string) ([]FileInfo, error) {
+	return b.backend.Glob(ctx, pattern, path)
+}
+
+// DeleteFile 删除文件并记录快照
+func (b *SnapshotBackend) DeleteFile(ctx context.Context, path string) error {
+	before, readErr := b.backend.ReadFile(ctx, path, 0, 0)
+	if readErr != nil {
+		return b.backend.DeleteFile(ctx, path)
+	}
+
+	if err := b.backend.DeleteFile(ctx, path); err != nil {
+		return err
+	}
+
+	b.record(ctx, FileChange{
+		Op:      FileChangeOpDelete,
+		Path:    path,
+		Existed: true,
+		Before:  before,
+	})
+	return nil
+}
+
+// Execute 执行命令（命令产生的文件变更不会被记录，需要记录时使用 TrackChanges）
+func (b *SnapshotBackend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
+	return b.backend.Execute(ctx, command, timeout)
+}
+
+// ExecuteArgs 按参数列表执行命令（命令产生的文件变更不会被记录，需要记录时使用 TrackChanges）
+func (b *SnapshotBackend) ExecuteArgs(ctx context.Context, args []string, timeout int) (*ExecuteResult, error) {
+	return ExecuteArgs(ctx, b.backend, args, timeout)
+}
+
+// TrackChanges 执行 fn（如直接修改工作区的命令），比较 paths 中文件执行前后的内容并记录变更
+// 执行后不存在的文件记录为删除，其余内容变化的文件记录为写入；fn 返回错误时同样记录已发生的变更
+func (b *SnapshotBackend) TrackChanges(ctx context.Context, paths []string, fn func() error) error {
+	type fileState struct {
+		data   []byte
+		exists bool
+	}
+	read := func(path string) fileState {
+		data, err := ReadBytes(ctx, b.backend, path)
+		return fileState{data: data, exists: err == nil}
+	}
+
+	var unique []string
+	before := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		if _, ok := before[path]; ok {
+			continue
+		}
+		unique = append(unique, path)
+		before[path] = read(path)
+	}
+
+	err := fn()
+
+	for _, path := range unique {
+		prev, cur := before[path], read(path)
+		if prev.exists == cur.exists && bytes.Equal(prev.data, cur.data) {
+			continue
+		}
+		change := FileChange{
+			Op:      FileChangeOpWrite,
+			Path:    path,
+			Existed: prev.exists,
+			Before:  string(prev.data),
+			After:   string(cur.data),
+		}
+		if !cur.exists {
+			change.Op = FileChangeOpDelete
+		}
+		b.record(ctx, change)
+	}
+	return err
+}
+
+// Move 移动文件并记录快照（记录为目标写入和源删除两次变更）
+// 被包装的后端不支持 FileManager 时降级为逐文件读取、写入、删除；目录移动不记录快照
+func (b *SnapshotBackend) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	fm, ok := b.backend.(FileManager)
+	if !ok {
+		return transferFile(ctx, b, src, b, dst, overwrite, true)
+	}
+
+	change, err := b.transferChange(ctx, fm, src, dst)
+	if err != nil {
+		return err
+	}
+	if err := fm.Move(ctx, src, dst, overwrite); err != nil || change == nil {
+		return err
+	}
+
+	b.record(ctx, *change)
+	b.record(ctx, FileChange{
+		Op:      FileChangeOpDelete,
+		Path:    src,
+		Existed: true,
+		Before:  change.After,
+	})
+	return nil
+}
+
+// Copy 复制文件并记录快照（记录为目标写入）
+// 被包装的后端不支持 FileManager 时降级为逐文件读取、写入；目录复制不记录快照
+func (b *SnapshotBackend) Copy(ctx context.Context, src, dst string, overwrite bool) error {
+	fm, ok := b.backend.(FileManager)
+	if !ok {
+		return transferFile(ctx, b, src, b, dst, overwrite, false)
+	}
+
+	change, err := b.transferChange(ctx, fm, src, dst)
+	if err != nil {
+		return err
+	}
+	if err := fm.Copy(ctx, src, dst, overwrite); err != nil || change == nil {
+		return err
+	}
+
+	b.record(ctx, *change)
+	return nil
+}
+
+// transferChange 在移动/复制前读取源文件和目标文件，构造目标文件的写入变更（源为目录时返回 nil）
+func (b *SnapshotBackend) transferChange(ctx context.Context, fm FileManager, src, dst string) (*FileChange, error) {
+	info, err := fm.Stat(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir {
+		return nil, nil
+	}
+
+	content, err := b.backend.ReadFile(ctx, src, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	before, readErr := b.backend.ReadFile(ctx, dst, 0, 0)
+
+	return &FileChange{
+		Op:      FileChangeOpWrite,
+		Path:    dst,
+		Existed: readErr == nil,
+		Before:  before,
+		After:   content,
+	}, nil
+}
+
+// MakeDir 创建目录
+func (b *SnapshotBackend) MakeDir(ctx context.Context, path string) error {
+	return MakeDir(ctx, b.backend, path)
+}
+
+// Stat 获取文件信息
+func (b *SnapshotBackend) Stat(ctx context.Context, path string) (*FileInfo, error) {
+	return Stat(ctx, b.backend, path)
+}
+
+// ReadBytes 读取文件的原始字节
+func (b *SnapshotBackend) ReadBytes(ctx context.Context, path string) ([]byte, error) {
+	return ReadBytes(ctx, b.backend, path)
+}
+
+// WriteBytes 写入原始字节并记录快照
+func (b *SnapshotBackend) WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error) {
+	before, readErr := ReadBytes(ctx, b.backend, path)
+
+	result, err := WriteBytes(ctx, b.backend, path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	b.record(ctx, FileChange{
+		Op:      FileChangeOpWrite,
+		Path:    path,
+		Existed: readErr == nil,
+		Before:  string(before),
+		After:   string(data),
+	})
+	return result, nil
+}
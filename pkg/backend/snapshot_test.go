@@ -0,0 +1,274 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotBackend_RecordsChanges(t *testing.T) {
+	b := NewSnapshotBackend(NewStateBackend())
+	ctx := context.Background()
+
+	b.SetPosition(1, 1)
+	b.WriteFile(ctx, "/a.txt", "hello")
+	b.SetPosition(1, 2)
+	b.EditFile(ctx, "/a.txt", "hello", "hi", false)
+	b.SetPosition(2, 1)
+	b.DeleteFile(ctx, "/a.txt")
+
+	changes := b.Changes("")
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(changes))
+	}
+
+	if changes[0].Op != FileChangeOpWrite || changes[0].Existed || changes[0].After != "hello" {
+		t.Errorf("Unexpected write change: %+v", changes[0])
+	}
+	if changes[1].Op != FileChangeOpEdit || changes[1].Before != "hello" || changes[1].After != "hi" {
+		t.Errorf("Unexpected edit change: %+v", changes[1])
+	}
+	if changes[1].Turn != 1 || changes[1].Iteration != 2 {
+		t.Errorf("Expected position (1, 2), got (%d, %d)", changes[1].Turn, changes[1].Iteration)
+	}
+	if changes[2].Op != FileChangeOpDelete || changes[2].Before != "hi" || changes[2].Turn != 2 {
+		t.Errorf("Unexpected delete change: %+v", changes[2])
+	}
+}
+
+func TestSnapshotBackend_FailedOperationNotRecorded(t *testing.T) {
+	b := NewSnapshotBackend(NewStateBackend())
+	ctx := context.Background()
+
+	if _, err := b.EditFile(ctx, "/missing.txt", "a", "b", false); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if err := b.DeleteFile(ctx, "/missing.txt"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	if len(b.Changes("")) != 0 {
+		t.Errorf("Expected no changes, got %d", len(b.Changes("")))
+	}
+}
+
+func TestSnapshotBackend_Rewind(t *testing.T) {
+	state := NewStateBackend()
+	b := NewSnapshotBackend(state)
+	ctx := context.Background()
+
+	state.WriteFile(ctx, "/existing.txt", "original")
+
+	b.SetPosition(1, 1)
+	b.WriteFile(ctx, "/new.txt", "v1")
+	b.SetPosition(2, 1)
+	b.WriteFile(ctx, "/new.txt", "v2")
+	b.EditFile(ctx, "/existing.txt", "original", "changed", false)
+	b.SetPosition(3, 1)
+	b.DeleteFile(ctx, "/existing.txt")
+	b.WriteFile(ctx, "/another.txt", "x")
+
+	// 回退到第 2 轮开始之前
+	reverted, err := b.Rewind(ctx, "", 2)
+	if err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if len(reverted) != 4 {
+		t.Errorf("Expected 4 reverted changes, got %d", len(reverted))
+	}
+
+	content, _ := state.ReadFile(ctx, "/new.txt", 0, 0)
+	if content != "v1" {
+		t.Errorf("Expected /new.txt to be %q, got %q", "v1", content)
+	}
+	content, _ = state.ReadFile(ctx, "/existing.txt", 0, 0)
+	if content != "original" {
+		t.Errorf("Expected /existing.txt to be %q, got %q", "original", content)
+	}
+	if _, err := state.ReadFile(ctx, "/another.txt", 0, 0); err == nil {
+		t.Error("Expected /another.txt to be removed")
+	}
+
+	if len(b.Changes("")) != 1 {
+		t.Errorf("Expected 1 remaining change, got %d", len(b.Changes("")))
+	}
+
+	// 继续回退到第 1 轮，新建的文件应被删除
+	if _, err := b.Rewind(ctx, "", 1); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if _, err := state.ReadFile(ctx, "/new.txt", 0, 0); err == nil {
+		t.Error("Expected /new.txt to be removed")
+	}
+}
+
+func TestSnapshotBackend_LoadChanges(t *testing.T) {
+	state := NewStateBackend()
+	ctx := context.Background()
+	state.WriteFile(ctx, "/a.txt", "after")
+
+	b := NewSnapshotBackend(state)
+	b.LoadChanges("", []FileChange{
+		{Turn: 1, Iteration: 1, Op: FileChangeOpEdit, Path: "/a.txt", Existed: true, Before: "before", After: "after"},
+	})
+
+	if _, err := b.Rewind(ctx, "", 1); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+
+	content, _ := state.ReadFile(ctx, "/a.txt", 0, 0)
+	if content != "before" {
+		t.Errorf("Expected %q, got %q", "before", content)
+	}
+}
+
+func TestSnapshotBackend_SessionsFromContext(t *testing.T) {
+	type sessionKey struct{}
+	state := NewStateBackend()
+	b := NewSnapshotBackend(state)
+	b.SetPositionFunc(func(ctx context.Context) (SnapshotPosition, bool) {
+		session, ok := ctx.Value(sessionKey{}).(string)
+		return SnapshotPosition{Session: session, Turn: 2, Iteration: 1}, ok
+	})
+
+	ctxA := context.WithValue(context.Background(), sessionKey{}, "a")
+	ctxB := context.WithValue(context.Background(), sessionKey{}, "b")
+	b.WriteFile(ctxA, "/a.txt", "A")
+	b.WriteFile(ctxB, "/b.txt", "B")
+	b.WriteFile(context.Background(), "/default.txt", "D")
+
+	if changes := b.Changes("a"); len(changes) != 1 || changes[0].Path != "/a.txt" || changes[0].Turn != 2 {
+		t.Errorf("Unexpected changes for session a: %+v", changes)
+	}
+	if changes := b.Changes(""); len(changes) != 1 || changes[0].Path != "/default.txt" {
+		t.Errorf("Unexpected default changes: %+v", changes)
+	}
+
+	// 回退一个会话不影响其他会话的文件和记录
+	if _, err := b.Rewind(ctxA, "a", 1); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if _, err := state.ReadFile(ctxA, "/a.txt", 0, 0); err == nil {
+		t.Error("Expected /a.txt to be removed")
+	}
+	if content, _ := state.ReadFile(ctxB, "/b.txt", 0, 0); content != "B" || len(b.Changes("b")) != 1 {
+		t.Errorf("Expected session b to be untouched, got %q and %d changes", content, len(b.Changes("b")))
+	}
+}
+
+func TestSnapshotBackend_MoveAndRewind(t *testing.T) {
+	workspace := NewStateBackend()
+	b := NewSnapshotBackend(workspace)
+	ctx := context.Background()
+
+	b.SetPosition(1, 1)
+	b.WriteFile(ctx, "/a.txt", "A")
+	b.WriteFile(ctx, "/b.txt", "old B")
+
+	b.SetPosition(2, 1)
+	if err := b.Move(ctx, "/a.txt", "/b.txt", true); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if err := b.Copy(ctx, "/b.txt", "/c.txt", false); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if len(b.Changes("")) != 5 {
+		t.Fatalf("Expected 5 changes, got %d", len(b.Changes("")))
+	}
+
+	if _, err := b.Rewind(ctx, "", 2); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+
+	if content, _ := workspace.ReadFile(ctx, "/a.txt", 0, 0); content != "A" {
+		t.Errorf("Expected /a.txt restored, got %q", content)
+	}
+	if content, _ := workspace.ReadFile(ctx, "/b.txt", 0, 0); content != "old B" {
+		t.Errorf("Expected /b.txt restored, got %q", content)
+	}
+	if _, err := workspace.ReadFile(ctx, "/c.txt", 0, 0); err == nil {
+		t.Error("Expected /c.txt to be removed")
+	}
+}
+
+func TestSnapshotBackend_TrackChanges(t *testing.T) {
+	state := NewStateBackend()
+	b := NewSnapshotBackend(state)
+	ctx := context.Background()
+
+	state.WriteFile(ctx, "/modified.txt", "old")
+	state.WriteFile(ctx, "/deleted.txt", "gone")
+	state.WriteFile(ctx, "/same.txt", "same")
+
+	b.SetPosition(1, 1)
+	paths := []string{"/modified.txt", "/deleted.txt", "/same.txt", "/created.txt", "/modified.txt"}
+	err := b.TrackChanges(ctx, paths, func() error {
+		// 直接修改被包装的后端，模拟外部命令
+		state.WriteFile(ctx, "/modified.txt", "new")
+		state.DeleteFile(ctx, "/deleted.txt")
+		state.WriteFile(ctx, "/created.txt", "created")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TrackChanges failed: %v", err)
+	}
+
+	changes := b.Changes("")
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %+v", changes)
+	}
+	if changes[0].Path != "/modified.txt" || changes[0].Op != FileChangeOpWrite || changes[0].Before != "old" || changes[0].After != "new" {
+		t.Errorf("Unexpected modify change: %+v", changes[0])
+	}
+	if changes[1].Path != "/deleted.txt" || changes[1].Op != FileChangeOpDelete || !changes[1].Existed {
+		t.Errorf("Unexpected delete change: %+v", changes[1])
+	}
+	if changes[2].Path != "/created.txt" || changes[2].Existed || changes[2].After != "created" {
+		t.Errorf("Unexpected create change: %+v", changes[2])
+	}
+
+	if _, err := b.Rewind(ctx, "", 1); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if content, _ := state.ReadFile(ctx, "/modified.txt", 0, 0); content != "old" {
+		t.Errorf("Expected modified.txt restored, got %q", content)
+	}
+	if content, _ := state.ReadFile(ctx, "/deleted.txt", 0, 0); content != "gone" {
+		t.Errorf("Expected deleted.txt restored, got %q", content)
+	}
+	if _, err := state.ReadFile(ctx, "/created.txt", 0, 0); err == nil {
+		t.Error("Expected created.txt removed")
+	}
+}
+
+func TestFileChange_JSONBinaryContent(t *testing.T) {
+	change := FileChange{
+		Op:     FileChangeOpWrite,
+		Path:   "/img.png",
+		Before: "",
+		After:  string(pngHeader) + "\xff",
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"encoding":"base64"`) {
+		t.Errorf("Expected base64 encoding, got %s", data)
+	}
+
+	var decoded FileChange
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded != change {
+		t.Errorf("Expected %+v, got %+v", change, decoded)
+	}
+
+	// 文本内容保持可读
+	data, _ = json.Marshal(FileChange{Path: "/a.txt", After: "hello"})
+	if strings.Contains(string(data), "encoding") || !strings.Contains(string(data), `"after":"hello"`) {
+		t.Errorf("Unexpected JSON for text content: %s", data)
+	}
+}
@@ -0,0 +1,324 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/internal/stringutil"
+	_ "modernc.org/sqlite" // 纯 Go 实现的 SQLite 驱动
+)
+
+// sqliteSchema 文件表结构（按命名空间隔离）
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS files (
+	namespace TEXT    NOT NULL,
+	path      TEXT    NOT NULL,
+	content   TEXT    NOT NULL,
+	size      INTEGER NOT NULL,
+	mod_time  INTEGER NOT NULL,
+	PRIMARY KEY (namespace, path)
+)`
+
+// SQLiteBackend 实现基于 SQLite 的持久化虚拟文件系统
+// 所有文件存储在单个数据库文件中，通过命名空间（如 Agent 或会话 ID）隔离
+type SQLiteBackend struct {
+	db        *sql.DB
+	namespace string
+	ownsDB    bool // 是否由本实例负责关闭数据库
+}
+
+// NewSQLiteBackend 打开（或创建）SQLite 数据库文件并创建后端
+// dsn 为数据库文件路径，":memory:" 表示内存数据库
+func NewSQLiteBackend(dsn, namespace string) (*SQLiteBackend, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite 同一时间只允许一个写事务，串行化连接避免 SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	b, err := NewSQLiteBackendWithDB(db, namespace)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	b.ownsDB = true
+	return b, nil
+}
+
+// NewSQLiteBackendWithDB 使用已打开的数据库创建后端（多个命名空间共享同一数据库）
+func NewSQLiteBackendWithDB(db *sql.DB, namespace string) (*SQLiteBackend, error) {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+
+	return &SQLiteBackend{
+		db:        db,
+		namespace: namespace,
+	}, nil
+}
+
+// WithNamespace 返回共享同一数据库、但使用另一命名空间的后端
+func (b *SQLiteBackend) WithNamespace(namespace string) *SQLiteBackend {
+	return &SQLiteBackend{
+		db:        b.db,
+		namespace: namespace,
+	}
+}
+
+// Namespace 返回当前命名空间
+func (b *SQLiteBackend) Namespace() string {
+	return b.namespace
+}
+
+// Close 关闭数据库（仅当数据库由 NewSQLiteBackend 打开时）
+func (b *SQLiteBackend) Close() error {
+	if !b.ownsDB {
+		return nil
+	}
+	return b.db.Close()
+}
+
+// ListFiles 列出目录下的文件（直接子项，子目录根据文件路径推导）
+func (b *SQLiteBackend) ListFiles(ctx context.Context, path string) ([]FileInfo, error) {
+	dir := normalizePath(path)
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+
+	rows, err := b.db.QueryContext(ctx,
+		`SELECT path, size, mod_time FROM files WHERE namespace = ? AND path >= ? AND path < ? ORDER BY path`,
+		b.namespace, prefix, prefixUpperBound(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []FileInfo
+	seenDirs := make(map[string]bool)
+	for rows.Next() {
+		var info FileInfo
+		if err := rows.Scan(&info.Path, &info.Size, &info.ModTime); err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+
+		rest := strings.TrimPrefix(info.Path, prefix)
+		if idx := strings.Index(rest, "/"); idx != -1 {
+			// 位于子目录中的文件，只返回子目录本身
+			subDir := prefix + rest[:idx]
+			if !seenDirs[subDir] {
+				seenDirs[subDir] = true
+				files = append(files, FileInfo{Path: subDir, IsDir: true})
+			}
+			continue
+		}
+		files = append(files, info)
+	}
+
+	return files, rows.Err()
+}
+
+// ReadFile 读取文件内容
+func (b *SQLiteBackend) ReadFile(ctx context.Context, path string, offset, limit int) (string, error) {
+	var content string
+	err := b.db.QueryRowContext(ctx,
+		`SELECT content FROM files WHERE namespace = ? AND path = ?`,
+		b.namespace, normalizePath(path)).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("file not found: %s", path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// 处理偏移和限制
+	if offset > 0 || limit > 0 {
+		lines := stringutil.SplitLines(content)
+		if offset >= len(lines) {
+			return "", nil
+		}
+		end := len(lines)
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		return stringutil.JoinLines(lines[offset:end]), nil
+	}
+
+	return content, nil
+}
+
+// WriteFile 写入文件（创建或覆盖）
+func (b *SQLiteBackend) WriteFile(ctx context.Context, path, content string) (*WriteResult, error) {
+	_, err := b.db.ExecContext(ctx,
+		`INSERT INTO files (namespace, path, content, size, mod_time) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (namespace, path) DO UPDATE SET content = excluded.content, size = excluded.size, mod_time = excluded.mod_time`,
+		b.namespace, normalizePath(path), content, len(content), time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return &WriteResult{
+		Path:         path,
+		BytesWritten: len(content),
+	}, nil
+}
+
+// EditFile 编辑文件（在事务中读取、替换、写回，避免并发编辑丢失更新）
+func (b *SQLiteBackend) EditFile(ctx context.Context, path, oldStr, newStr string, replaceAll bool) (*EditResult, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	key := normalizePath(path)
+	var oldContent string
+	err = tx.QueryRowContext(ctx,
+		`SELECT content FROM files WHERE namespace = ? AND path = ?`,
+		b.namespace, key).Scan(&oldContent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	newContent, replacements, err := applyEdit(oldContent, oldStr, newStr, replaceAll)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE files SET content = ?, size = ?, mod_time = ? WHERE namespace = ? AND path = ?`,
+		newContent, len(newContent), time.Now().Unix(), b.namespace, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit edit: %w", err)
+	}
+
+	return &EditResult{
+		Path:         path,
+		Replacements: replacements,
+		OldContent:   oldContent,
+		NewContent:   newContent,
+	}, nil
+}
+
+// Grep 搜索文件内容
+func (b *SQLiteBackend) Grep(ctx context.Context, pattern, path, glob string) ([]GrepMatch, error) {
+	// 编译正则表达式
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// 编译失败时，降级到字面字符串匹配
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+
+	query := `SELECT path, content FROM files WHERE namespace = ? ORDER BY path`
+	args := []any{b.namespace}
+	if path != "" && normalizePath(path) != "/" {
+		dir := normalizePath(path)
+		query = `SELECT path, content FROM files WHERE namespace = ? AND (path = ? OR (path >= ? AND path < ?)) ORDER BY path`
+		args = append(args, dir, dir+"/", prefixUpperBound(dir+"/"))
+	}
+
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query files: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []GrepMatch
+	for rows.Next() {
+		var filePath, content string
+		if err := rows.Scan(&filePath, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+
+		// glob 过滤
+		if glob != "" {
+			matched, err := filepath.Match(glob, filepath.Base(filePath))
+			if err != nil || !matched {
+				continue
+			}
+		}
+
+		lines := stringutil.SplitLines(content)
+		for i, line := range lines {
+			if re.MatchString(line) {
+				matchStr := re.FindString(line)
+				if matchStr == "" {
+					matchStr = pattern // 降级情况下使用原始 pattern
+				}
+				matches = append(matches, GrepMatch{
+					Path:       filePath,
+					LineNumber: i + 1,
+					Line:       line,
+					Match:      matchStr,
+				})
+			}
+		}
+	}
+
+	return matches, rows.Err()
+}
+
+// Glob 查找匹配的文件（pattern 相对于 path，支持 ** 和 {a,b}）
+func (b *SQLiteBackend) Glob(ctx context.Context, pattern, path string) ([]FileInfo, error) {
+	base := normalizePath(path)
+	prefix := strings.TrimSuffix(base, "/") + "/"
+
+	rows, err := b.db.QueryContext(ctx,
+		`SELECT path, size, mod_time FROM files WHERE namespace = ? AND path >= ? AND path < ? ORDER BY path`,
+		b.namespace, prefix, prefixUpperBound(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob: %w", err)
+	}
+	defer rows.Close()
+
+	var files []FileInfo
+	for rows.Next() {
+		var info FileInfo
+		if err := rows.Scan(&info.Path, &info.Size, &info.ModTime); err != nil {
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		if matchGlob(pattern, strings.TrimPrefix(info.Path, prefix)) {
+			files = append(files, info)
+		}
+	}
+
+	return files, rows.Err()
+}
+
+// DeleteFile 删除文件
+func (b *SQLiteBackend) DeleteFile(ctx context.Context, path string) error {
+	result, err := b.db.ExecContext(ctx,
+		`DELETE FROM files WHERE namespace = ? AND path = ?`,
+		b.namespace, normalizePath(path))
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("file not found: %s", path)
+	}
+	return nil
+}
+
+// Execute 不支持命令执行
+func (b *SQLiteBackend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
+	return nil, fmt.Errorf("execute not supported by SQLiteBackend")
+}
+
+// prefixUpperBound 返回以 "/" 结尾的前缀对应的上界（不含），
+// 使 path >= prefix AND path < upper 等价于前缀匹配（按字节比较，可使用主键索引）
+func prefixUpperBound(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "0" // '0' 是 '/' 的下一个字符
+}
@@ -0,0 +1,381 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// newTestSQLiteBackend 在临时目录中创建 SQLite 后端
+func newTestSQLiteBackend(t *testing.T) *SQLiteBackend {
+	t.Helper()
+	backend, err := NewSQLiteBackend(filepath.Join(t.TempDir(), "agents.db"), "agent-1")
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend failed: %v", err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
+
+func TestSQLiteBackend_WriteAndRead(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 写入文件
+	content := "Hello, World!"
+	result, err := backend.WriteFile(ctx, "/test.txt", content)
+	if err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if result.BytesWritten != len(content) {
+		t.Errorf("Expected %d bytes written, got %d", len(content), result.BytesWritten)
+	}
+
+	// 读取文件
+	readContent, err := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	if readContent != content {
+		t.Errorf("Expected content %q, got %q", content, readContent)
+	}
+}
+
+func TestSQLiteBackend_Overwrite(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/test.txt", "first")
+	backend.WriteFile(ctx, "test.txt", "second")
+
+	content, _ := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	if content != "second" {
+		t.Errorf("Expected content %q, got %q", "second", content)
+	}
+}
+
+func TestSQLiteBackend_EditFile(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 写入初始内容
+	content := "Hello, World!\nHello, Go!"
+	backend.WriteFile(ctx, "/test.txt", content)
+
+	// 编辑文件（替换第一个 Hello）
+	result, err := backend.EditFile(ctx, "/test.txt", "Hello", "Hi", false)
+	if err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+
+	if result.Replacements != 1 {
+		t.Errorf("Expected 1 replacement, got %d", result.Replacements)
+	}
+
+	// 验证内容
+	newContent, _ := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	expected := "Hi, World!\nHello, Go!"
+	if newContent != expected {
+		t.Errorf("Expected content %q, got %q", expected, newContent)
+	}
+}
+
+func TestSQLiteBackend_EditFileReplaceAll(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 写入初始内容
+	content := "Hello, World!\nHello, Go!"
+	backend.WriteFile(ctx, "/test.txt", content)
+
+	// 编辑文件（替换所有 Hello）
+	result, err := backend.EditFile(ctx, "/test.txt", "Hello", "Hi", true)
+	if err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+
+	if result.Replacements != 2 {
+		t.Errorf("Expected 2 replacements, got %d", result.Replacements)
+	}
+
+	// 验证内容
+	newContent, _ := backend.ReadFile(ctx, "/test.txt", 0, 0)
+	expected := "Hi, World!\nHi, Go!"
+	if newContent != expected {
+		t.Errorf("Expected content %q, got %q", expected, newContent)
+	}
+}
+
+func TestSQLiteBackend_EditFileNotFound(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	if _, err := backend.EditFile(ctx, "/missing.txt", "a", "b", false); err == nil {
+		t.Error("Expected error for nonexistent file, got nil")
+	}
+
+	backend.WriteFile(ctx, "/test.txt", "content")
+	if _, err := backend.EditFile(ctx, "/test.txt", "missing", "b", false); err == nil {
+		t.Error("Expected error for missing string, got nil")
+	}
+}
+
+func TestSQLiteBackend_ConcurrentEdits(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/counter.txt", "")
+
+	// 并发追加不同的行，事务保证不丢失更新
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			line := fmt.Sprintf("line-%02d;", i)
+			for {
+				content, err := backend.ReadFile(ctx, "/counter.txt", 0, 0)
+				if err != nil {
+					t.Errorf("ReadFile failed: %v", err)
+					return
+				}
+				// 以整个旧内容作为锚点，只有内容未被他人修改时才会成功
+				if content == "" {
+					if _, err := backend.EditFile(ctx, "/counter.txt", "", line, false); err == nil {
+						return
+					}
+					continue
+				}
+				if _, err := backend.EditFile(ctx, "/counter.txt", content, content+line, false); err == nil {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	content, _ := backend.ReadFile(ctx, "/counter.txt", 0, 0)
+	if len(content) != 20*len("line-00;") {
+		t.Errorf("Expected %d bytes, got %d: %q", 20*len("line-00;"), len(content), content)
+	}
+}
+
+func TestSQLiteBackend_Grep(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 写入多个文件
+	backend.WriteFile(ctx, "/file1.txt", "Hello World\nGoodbye World")
+	backend.WriteFile(ctx, "/file2.txt", "Hello Go\nGoodbye Go")
+	backend.WriteFile(ctx, "/src/main.go", "package main\n// Hello")
+
+	// 搜索 "Hello"
+	matches, err := backend.Grep(ctx, "Hello", "", "")
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+
+	if len(matches) != 3 {
+		t.Errorf("Expected 3 matches, got %d", len(matches))
+	}
+
+	// 限定路径和 glob
+	matches, err = backend.Grep(ctx, "Hel+o", "/src", "*.go")
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != "/src/main.go" || matches[0].LineNumber != 2 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+}
+
+func TestSQLiteBackend_Glob(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/main.go", "")
+	backend.WriteFile(ctx, "/README.md", "")
+	backend.WriteFile(ctx, "/pkg/a/a.go", "")
+	backend.WriteFile(ctx, "/pkg/b/b.ts", "")
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    int
+	}{
+		{"*.go", "", 1},
+		{"**/*.go", "", 2},
+		{"**/*.{go,ts}", "", 3},
+		{"*/*.go", "/pkg", 1},
+		{"*", "/", 2},
+	}
+
+	for _, tt := range tests {
+		files, err := backend.Glob(ctx, tt.pattern, tt.path)
+		if err != nil {
+			t.Fatalf("Glob(%q, %q) failed: %v", tt.pattern, tt.path, err)
+		}
+		if len(files) != tt.want {
+			t.Errorf("Glob(%q, %q): expected %d files, got %d", tt.pattern, tt.path, tt.want, len(files))
+		}
+	}
+}
+
+func TestSQLiteBackend_ListFiles(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 写入多个文件
+	backend.WriteFile(ctx, "/file1.txt", "content1")
+	backend.WriteFile(ctx, "/file2.txt", "content2")
+	backend.WriteFile(ctx, "/file3.txt", "content3")
+	backend.WriteFile(ctx, "/dir/nested.txt", "nested")
+
+	// 列出文件
+	files, err := backend.ListFiles(ctx, "/")
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+
+	if len(files) != 4 {
+		t.Errorf("Expected 4 entries, got %d", len(files))
+	}
+
+	var dirs int
+	for _, f := range files {
+		if f.IsDir {
+			dirs++
+		}
+	}
+	if dirs != 1 {
+		t.Errorf("Expected 1 directory, got %d", dirs)
+	}
+
+	// 列出子目录
+	files, _ = backend.ListFiles(ctx, "/dir")
+	if len(files) != 1 || files[0].Path != "/dir/nested.txt" {
+		t.Errorf("Unexpected files in /dir: %+v", files)
+	}
+}
+
+func TestSQLiteBackend_ReadFileNotFound(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 读取不存在的文件
+	_, err := backend.ReadFile(ctx, "/nonexistent.txt", 0, 0)
+	if err == nil {
+		t.Error("Expected error for nonexistent file, got nil")
+	}
+}
+
+func TestSQLiteBackend_ReadFileWithOffset(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// 写入多行内容
+	content := "Line 1\nLine 2\nLine 3\nLine 4\nLine 5"
+	backend.WriteFile(ctx, "/test.txt", content)
+
+	// 读取从第 2 行开始的 2 行
+	result, err := backend.ReadFile(ctx, "/test.txt", 1, 2)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	expected := "Line 2\nLine 3"
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestSQLiteBackend_DeleteFile(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/test.txt", "content")
+
+	if err := backend.DeleteFile(ctx, "/test.txt"); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	if _, err := backend.ReadFile(ctx, "/test.txt", 0, 0); err == nil {
+		t.Error("Expected error after delete, got nil")
+	}
+	if err := backend.DeleteFile(ctx, "/test.txt"); err == nil {
+		t.Error("Expected error deleting nonexistent file, got nil")
+	}
+}
+
+func TestSQLiteBackend_NamespaceIsolation(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	other := backend.WithNamespace("agent-2")
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/shared.txt", "from agent-1")
+	other.WriteFile(ctx, "/shared.txt", "from agent-2")
+
+	content, _ := backend.ReadFile(ctx, "/shared.txt", 0, 0)
+	if content != "from agent-1" {
+		t.Errorf("Expected %q, got %q", "from agent-1", content)
+	}
+
+	matches, _ := other.Grep(ctx, "agent", "", "")
+	if len(matches) != 1 || matches[0].Line != "from agent-2" {
+		t.Errorf("Unexpected matches in agent-2: %+v", matches)
+	}
+}
+
+func TestSQLiteBackend_PersistsAcrossReopen(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "agents.db")
+	ctx := context.Background()
+
+	backend, err := NewSQLiteBackend(dbPath, "session-1")
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend failed: %v", err)
+	}
+	backend.WriteFile(ctx, "/notes.md", "persisted")
+	backend.Close()
+
+	reopened, err := NewSQLiteBackend(dbPath, "session-1")
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend failed: %v", err)
+	}
+	defer reopened.Close()
+
+	content, err := reopened.ReadFile(ctx, "/notes.md", 0, 0)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if content != "persisted" {
+		t.Errorf("Expected %q, got %q", "persisted", content)
+	}
+}
+
+func TestSQLiteBackend_ExecuteNotSupported(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	if _, err := backend.Execute(context.Background(), "ls", 0); err == nil {
+		t.Error("Expected error for Execute, got nil")
+	}
+}
+
+func TestSQLiteBackend_BinaryRoundTrip(t *testing.T) {
+	backend := newTestSQLiteBackend(t)
+	ctx := context.Background()
+
+	// SQLiteBackend 未实现 BinaryBackend，通过字符串降级也应保持字节不变
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe, 0x80}
+	if _, err := WriteBytes(ctx, backend, "/img.png", data); err != nil {
+		t.Fatalf("WriteBytes failed: %v", err)
+	}
+	read, err := ReadBytes(ctx, backend, "/img.png")
+	if err != nil {
+		t.Fatalf("ReadBytes failed: %v", err)
+	}
+	if string(read) != string(data) {
+		t.Errorf("Expected %v, got %v", data, read)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/zhoucx/deepagents-go/pkg/internal/stringutil"
@@ -13,12 +14,14 @@ import (
 type StateBackend struct {
 	mu    sync.RWMutex
 	files map[string]string // path -> content
+	dirs  map[string]bool   // 显式创建的目录（MakeDir）
 }
 
 // NewStateBackend 创建状态后端
 func NewStateBackend() *StateBackend {
 	return &StateBackend{
 		files: make(map[string]string),
+		dirs:  make(map[string]bool),
 	}
 }
 
@@ -86,39 +89,17 @@ func (b *StateBackend) EditFile(ctx context.Context, path, oldStr, newStr string
 		return nil, fmt.Errorf("file not found: %s", path)
 	}
 
-	oldContent := content
-	var replacements int
-
-	if replaceAll {
-		// 替换所有匹配
-		newContent := ""
-		for {
-			idx := stringutil.IndexOf(content, oldStr)
-			if idx == -1 {
-				newContent += content
-				break
-			}
-			newContent += content[:idx] + newStr
-			content = content[idx+len(oldStr):]
-			replacements++
-		}
-		content = newContent
-	} else {
-		// 只替换第一个匹配
-		idx := stringutil.IndexOf(content, oldStr)
-		if idx == -1 {
-			return nil, fmt.Errorf("string not found: %s", oldStr)
-		}
-		content = content[:idx] + newStr + content[idx+len(oldStr):]
-		replacements = 1
+	newContent, replacements, err := applyEdit(content, oldStr, newStr, replaceAll)
+	if err != nil {
+		return nil, err
 	}
 
-	b.files[path] = content
+	b.files[path] = newContent
 	return &EditResult{
 		Path:         path,
 		Replacements: replacements,
-		OldContent:   oldContent,
-		NewContent:   content,
+		OldContent:   content,
+		NewContent:   newContent,
 	}, nil
 }
 
@@ -187,3 +168,149 @@ func (b *StateBackend) DeleteFile(ctx context.Context, path string) error {
 func (b *StateBackend) Execute(ctx context.Context, command string, timeout int) (*ExecuteResult, error) {
 	return nil, fmt.Errorf("execute not supported by StateBackend")
 }
+
+// Move 移动或重命名文件/目录
+func (b *StateBackend) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.transfer(src, dst, overwrite, true)
+}
+
+// Copy 复制文件/目录
+func (b *StateBackend) Copy(ctx context.Context, src, dst string, overwrite bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.transfer(src, dst, overwrite, false)
+}
+
+// transfer 复制文件或目录下的所有文件，remove 为 true 时删除源文件（调用方需持有写锁）
+func (b *StateBackend) transfer(src, dst string, overwrite, remove bool) error {
+	if src == dst {
+		return fmt.Errorf("source and destination are the same: %s", src)
+	}
+	if b.exists(dst) && !overwrite {
+		return fmt.Errorf("destination already exists: %s", dst)
+	}
+
+	// 单个文件
+	if content, ok := b.files[src]; ok {
+		b.files[dst] = content
+		if remove {
+			delete(b.files, src)
+		}
+		return nil
+	}
+
+	// 目录：目录由文件路径前缀隐式表示
+	if !b.isDir(src) {
+		return fmt.Errorf("file not found: %s", src)
+	}
+	srcPrefix, dstPrefix := dirPrefix(src), dirPrefix(dst)
+	if strings.HasPrefix(dstPrefix, srcPrefix) {
+		return fmt.Errorf("cannot move or copy a directory into itself: %s", dst)
+	}
+
+	moved := make(map[string]string)
+	for p, content := range b.files {
+		if strings.HasPrefix(p, srcPrefix) {
+			moved[dstPrefix+strings.TrimPrefix(p, srcPrefix)] = content
+			if remove {
+				delete(b.files, p)
+			}
+		}
+	}
+	for p, content := range moved {
+		b.files[p] = content
+	}
+
+	movedDirs := []string{dst}
+	for d := range b.dirs {
+		if strings.HasPrefix(d, srcPrefix) {
+			movedDirs = append(movedDirs, dstPrefix+strings.TrimPrefix(d, srcPrefix))
+			if remove {
+				delete(b.dirs, d)
+			}
+		}
+	}
+	if remove {
+		delete(b.dirs, src)
+	}
+	for _, d := range movedDirs {
+		b.dirs[d] = true
+	}
+	return nil
+}
+
+// MakeDir 创建目录
+func (b *StateBackend) MakeDir(ctx context.Context, path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.files[path]; ok {
+		return fmt.Errorf("file already exists: %s", path)
+	}
+	b.dirs[path] = true
+	return nil
+}
+
+// Stat 获取文件信息
+func (b *StateBackend) Stat(ctx context.Context, path string) (*FileInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if content, ok := b.files[path]; ok {
+		return &FileInfo{
+			Path: path,
+			Size: int64(len(content)),
+		}, nil
+	}
+	if b.isDir(path) {
+		return &FileInfo{
+			Path:  path,
+			IsDir: true,
+		}, nil
+	}
+	return nil, fmt.Errorf("file not found: %s", path)
+}
+
+// exists 判断文件或目录是否存在（调用方需持有锁）
+func (b *StateBackend) exists(path string) bool {
+	_, ok := b.files[path]
+	return ok || b.isDir(path)
+}
+
+// isDir 判断是否为目录：显式创建的目录，或存在以该路径为前缀的文件（调用方需持有锁）
+func (b *StateBackend) isDir(path string) bool {
+	if b.dirs[path] {
+		return true
+	}
+	prefix := dirPrefix(path)
+	for p := range b.files {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirPrefix 返回以 "/" 结尾的目录前缀
+func dirPrefix(path string) string {
+	return strings.TrimSuffix(path, "/") + "/"
+}
+
+// ReadBytes 读取文件的原始字节
+func (b *StateBackend) ReadBytes(ctx context.Context, path string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	content, ok := b.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return []byte(content), nil
+}
+
+// WriteBytes 写入原始字节（Go 字符串可以保存任意字节，内容原样存储）
+func (b *StateBackend) WriteBytes(ctx context.Context, path string, data []byte) (*WriteResult, error) {
+	return b.WriteFile(ctx, path, string(data))
+}
@@ -152,3 +152,86 @@ func TestStateBackend_ReadFileWithOffset(t *testing.T) {
 		t.Errorf("Expected %q, got %q", expected, result)
 	}
 }
+
+func TestStateBackend_MoveAndCopy(t *testing.T) {
+	backend := NewStateBackend()
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/a.txt", "A")
+	backend.WriteFile(ctx, "/src/x.go", "X")
+	backend.WriteFile(ctx, "/src/sub/y.go", "Y")
+
+	// 重命名文件
+	if err := backend.Move(ctx, "/a.txt", "/b.txt", false); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if _, err := backend.ReadFile(ctx, "/a.txt", 0, 0); err == nil {
+		t.Error("Expected source to be removed after move")
+	}
+	if content, _ := backend.ReadFile(ctx, "/b.txt", 0, 0); content != "A" {
+		t.Errorf("Expected %q, got %q", "A", content)
+	}
+
+	// 目标已存在
+	backend.WriteFile(ctx, "/c.txt", "C")
+	if err := backend.Copy(ctx, "/b.txt", "/c.txt", false); err == nil {
+		t.Error("Expected error when destination exists")
+	}
+	if err := backend.Copy(ctx, "/b.txt", "/c.txt", true); err != nil {
+		t.Fatalf("Copy with overwrite failed: %v", err)
+	}
+	if content, _ := backend.ReadFile(ctx, "/c.txt", 0, 0); content != "A" {
+		t.Errorf("Expected %q, got %q", "A", content)
+	}
+
+	// 移动目录
+	if err := backend.Move(ctx, "/src", "/lib", false); err != nil {
+		t.Fatalf("Move directory failed: %v", err)
+	}
+	if content, _ := backend.ReadFile(ctx, "/lib/sub/y.go", 0, 0); content != "Y" {
+		t.Errorf("Expected %q, got %q", "Y", content)
+	}
+	if _, err := backend.Stat(ctx, "/src"); err == nil {
+		t.Error("Expected /src to be gone after move")
+	}
+
+	// 不能移动到自身内部
+	if err := backend.Move(ctx, "/lib", "/lib/inner", false); err == nil {
+		t.Error("Expected error moving directory into itself")
+	}
+	if err := backend.Move(ctx, "/missing", "/other", false); err == nil {
+		t.Error("Expected error for missing source")
+	}
+}
+
+func TestStateBackend_MakeDirAndStat(t *testing.T) {
+	backend := NewStateBackend()
+	ctx := context.Background()
+
+	backend.WriteFile(ctx, "/docs/readme.md", "hello")
+	if err := backend.MakeDir(ctx, "/empty"); err != nil {
+		t.Fatalf("MakeDir failed: %v", err)
+	}
+
+	info, err := backend.Stat(ctx, "/docs/readme.md")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.IsDir || info.Size != 5 {
+		t.Errorf("Unexpected file info: %+v", info)
+	}
+
+	for _, dir := range []string{"/docs", "/empty"} {
+		info, err := backend.Stat(ctx, dir)
+		if err != nil || !info.IsDir {
+			t.Errorf("Expected %s to be a directory, got %+v, %v", dir, info, err)
+		}
+	}
+
+	if _, err := backend.Stat(ctx, "/nope"); err == nil {
+		t.Error("Expected error for nonexistent path")
+	}
+	if err := backend.MakeDir(ctx, "/docs/readme.md"); err == nil {
+		t.Error("Expected error creating directory over a file")
+	}
+}
@@ -43,10 +43,13 @@ func convertMessages(msgs []Message) []anthropic.MessageParam {
 			// 构建 assistant 消息的内容块
 			blocks := make([]anthropic.ContentBlockParamUnion, 0)
 
-			// 添加文本内容
+			// 添加文本内容和多模态内容块
 			if msg.Content != "" {
 				blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
 			}
+			for _, b := range msg.Blocks {
+				blocks = append(blocks, convertBlock(b))
+			}
 
 			// 添加工具调用
 			for _, tc := range msg.ToolCalls {
@@ -59,14 +62,17 @@ func convertMessages(msgs []Message) []anthropic.MessageParam {
 			// 构建 user 消息的内容块
 			blocks := make([]anthropic.ContentBlockParamUnion, 0)
 
-			// 添加文本内容
+			// 添加文本内容和多模态内容块
 			if msg.Content != "" {
 				blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
 			}
+			for _, b := range msg.Blocks {
+				blocks = append(blocks, convertBlock(b))
+			}
 
 			// 添加工具结果
 			for _, tr := range msg.ToolResults {
-				blocks = append(blocks, anthropic.NewToolResultBlock(tr.ToolCallID, tr.Content, tr.IsError))
+				blocks = append(blocks, convertToolResult(tr))
 			}
 
 			messages = append(messages, anthropic.NewUserMessage(blocks...))
@@ -75,6 +81,58 @@ func convertMessages(msgs []Message) []anthropic.MessageParam {
 	return messages
 }
 
+// convertBlock 将多模态内容块转换为 Anthropic 内容块
+func convertBlock(b ContentBlock) anthropic.ContentBlockParamUnion {
+	if b.Type == ContentBlockTypeText {
+		return anthropic.NewTextBlock(b.Text)
+	}
+	return anthropic.ContentBlockParam{
+		Type:   anthropic.F(anthropic.ContentBlockParamType(b.Type)),
+		Source: anthropic.F(any(anthropicSource(b))),
+	}
+}
+
+// convertToolResult 将工具结果转换为 Anthropic 内容块，结果附带内容块时以内容块列表作为结果内容
+func convertToolResult(tr ToolResult) anthropic.ContentBlockParamUnion {
+	if len(tr.Blocks) == 0 {
+		return anthropic.NewToolResultBlock(tr.ToolCallID, tr.Content, tr.IsError)
+	}
+
+	content := make([]map[string]any, 0, len(tr.Blocks)+1)
+	if tr.Content != "" {
+		content = append(content, anthropicBlockJSON(NewTextBlock(tr.Content)))
+	}
+	for _, b := range tr.Blocks {
+		content = append(content, anthropicBlockJSON(b))
+	}
+	return anthropic.ContentBlockParam{
+		Type:      anthropic.F(anthropic.ContentBlockParamTypeToolResult),
+		ToolUseID: anthropic.F(tr.ToolCallID),
+		IsError:   anthropic.F(tr.IsError),
+		Content:   anthropic.F(any(content)),
+	}
+}
+
+// anthropicBlockJSON 返回内容块的 Anthropic JSON 表示
+func anthropicBlockJSON(b ContentBlock) map[string]any {
+	if b.Type == ContentBlockTypeText {
+		return map[string]any{"type": "text", "text": b.Text}
+	}
+	block := map[string]any{"type": string(b.Type), "source": anthropicSource(b)}
+	if b.Type == ContentBlockTypeDocument && b.Name != "" {
+		block["title"] = b.Name
+	}
+	return block
+}
+
+// anthropicSource 返回图片或文档内容块的来源
+func anthropicSource(b ContentBlock) map[string]any {
+	if b.URL != "" {
+		return map[string]any{"type": "url", "url": b.URL}
+	}
+	return map[string]any{"type": "base64", "media_type": b.MediaType, "data": b.Data}
+}
+
 // Generate 生成响应
 func (c *AnthropicClient) Generate(ctx context.Context, req *ModelRequest) (*ModelResponse, error) {
 	// 记录请求信息
@@ -96,7 +154,8 @@ func (c *AnthropicClient) Generate(ctx context.Context, req *ModelRequest) (*Mod
 		})
 	}
 
-	if req.Temperature > 0 {
+	// 启用扩展思考时 API 不允许设置 temperature
+	if req.Temperature > 0 && !thinkingEnabled(req) {
 		params.Temperature = anthropic.F(req.Temperature)
 	}
 
@@ -114,7 +173,7 @@ func (c *AnthropicClient) Generate(ctx context.Context, req *ModelRequest) (*Mod
 	}
 
 	// 调用 API
-	message, err := c.client.Messages.New(ctx, params)
+	message, err := c.client.Messages.New(ctx, params, thinkingOptions(req)...)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic api error: %w", err)
 	}
@@ -128,6 +187,11 @@ func (c *AnthropicClient) Generate(ctx context.Context, req *ModelRequest) (*Mod
 		switch block.Type {
 		case anthropic.ContentBlockTypeText:
 			resp.Content += block.Text
+		case anthropicBlockThinking, anthropicBlockRedactedThinking:
+			// SDK 尚不支持思考块，从原始 JSON 中解析
+			var raw anthropicRawBlock
+			json.Unmarshal([]byte(block.JSON.RawJSON()), &raw)
+			resp.Reasoning = append(resp.Reasoning, raw.reasoning())
 		case anthropic.ContentBlockTypeToolUse:
 			// 解析工具调用
 			inputJSON, _ := json.Marshal(block.Input)
@@ -181,7 +245,8 @@ func (c *AnthropicClient) StreamGenerate(ctx context.Context, req *ModelRequest)
 		})
 	}
 
-	if req.Temperature > 0 {
+	// 启用扩展思考时 API 不允许设置 temperature
+	if req.Temperature > 0 && !thinkingEnabled(req) {
 		params.Temperature = anthropic.F(req.Temperature)
 	}
 
@@ -209,64 +274,75 @@ func (c *AnthropicClient) StreamGenerate(ctx context.Context, req *ModelRequest)
 		}
 
 		// 创建流式请求
-		stream := c.client.Messages.NewStreaming(ctx, params)
+		stream := c.client.Messages.NewStreaming(ctx, params, thinkingOptions(req)...)
 
-		// 累积文本内容和工具调用
-		var fullContent string
-		var toolCalls []ToolCall
+		// 累积当前的工具调用和思考块
 		var currentToolID string
 		var currentToolName string
 		var currentToolInput string
+		var currentReasoning *ReasoningBlock
 
 		// 处理流式事件
 		for stream.Next() {
 			event := stream.Current()
 
-			// 简化处理：仅处理最基本的事件类型
+			// 内容块和增量从原始 JSON 中解析（SDK 不支持思考块）
+			var raw anthropicRawStreamEvent
+			if event.Type == anthropic.MessageStreamEventTypeContentBlockStart || event.Type == anthropic.MessageStreamEventTypeContentBlockDelta {
+				json.Unmarshal([]byte(event.JSON.RawJSON()), &raw)
+			}
+
 			switch event.Type {
 			case anthropic.MessageStreamEventTypeContentBlockDelta:
-				// 尝试从 delta 中提取文本
-				// 由于类型是 interface{}，我们需要使用类型断言或反射
-				// 为了简化，我们先检查是否有文本字段
-				deltaJSON, _ := json.Marshal(event.Delta)
-				var deltaMap map[string]interface{}
-				if err := json.Unmarshal(deltaJSON, &deltaMap); err == nil {
-					// 检查是否是文本 delta
-					if text, ok := deltaMap["text"].(string); ok {
-						fullContent += text
-						eventChan <- StreamEvent{
-							Type:    StreamEventTypeText,
-							Content: text,
-							Done:    false,
-						}
+				switch raw.Delta.Type {
+				case "text_delta":
+					eventChan <- StreamEvent{
+						Type:    StreamEventTypeText,
+						Content: raw.Delta.Text,
+						Done:    false,
 					}
-					// 检查是否是工具调用参数 delta
-					if partialJSON, ok := deltaMap["partial_json"].(string); ok {
-						currentToolInput += partialJSON
+				case "input_json_delta":
+					// 工具调用参数
+					currentToolInput += raw.Delta.PartialJSON
+				case "thinking_delta":
+					if currentReasoning != nil {
+						currentReasoning.Text += raw.Delta.Thinking
+					}
+					eventChan <- StreamEvent{
+						Type:    StreamEventTypeThinking,
+						Content: raw.Delta.Thinking,
+						Done:    false,
+					}
+				case "signature_delta":
+					if currentReasoning != nil {
+						currentReasoning.Signature += raw.Delta.Signature
 					}
 				}
 
 			case anthropic.MessageStreamEventTypeContentBlockStart:
-				// 内容块开始 - 可能是文本或工具调用
-				if event.ContentBlock != nil {
-					blockJSON, _ := json.Marshal(event.ContentBlock)
-					var blockMap map[string]interface{}
-					if err := json.Unmarshal(blockJSON, &blockMap); err == nil {
-						if blockType, ok := blockMap["type"].(string); ok && blockType == "tool_use" {
-							// 工具调用开始
-							if id, ok := blockMap["id"].(string); ok {
-								currentToolID = id
-							}
-							if name, ok := blockMap["name"].(string); ok {
-								currentToolName = name
-							}
-							currentToolInput = ""
-						}
-					}
+				// 内容块开始 - 可能是文本、思考或工具调用
+				switch raw.ContentBlock.Type {
+				case "tool_use":
+					// 工具调用开始
+					currentToolID = raw.ContentBlock.ID
+					currentToolName = raw.ContentBlock.Name
+					currentToolInput = ""
+				case anthropicBlockThinking, anthropicBlockRedactedThinking:
+					reasoning := raw.ContentBlock.reasoning()
+					currentReasoning = &reasoning
 				}
 
 			case anthropic.MessageStreamEventTypeContentBlockStop:
 				// 内容块结束
+				if currentReasoning != nil {
+					// 发送完整的思考块
+					eventChan <- StreamEvent{
+						Type:      StreamEventTypeThinking,
+						Reasoning: currentReasoning,
+						Done:      false,
+					}
+					currentReasoning = nil
+				}
 				if currentToolID != "" {
 					// 解析工具调用参数
 					var input map[string]any
@@ -283,7 +359,6 @@ func (c *AnthropicClient) StreamGenerate(ctx context.Context, req *ModelRequest)
 						Name:  currentToolName,
 						Input: input,
 					}
-					toolCalls = append(toolCalls, toolCall)
 
 					// 发送工具调用事件
 					eventChan <- StreamEvent{
@@ -324,3 +399,86 @@ func (c *AnthropicClient) StreamGenerate(ctx context.Context, req *ModelRequest)
 
 	return eventChan, nil
 }
+
+// Anthropic 思考块类型
+const (
+	anthropicBlockThinking         = "thinking"
+	anthropicBlockRedactedThinking = "redacted_thinking"
+)
+
+// anthropicRawBlock 内容块的原始 JSON（用于 SDK 不支持的思考块）
+type anthropicRawBlock struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature"`
+	Data      string `json:"data"`
+}
+
+// reasoning 转换为思考块
+func (b anthropicRawBlock) reasoning() ReasoningBlock {
+	if b.Type == anthropicBlockRedactedThinking {
+		return ReasoningBlock{Redacted: b.Data}
+	}
+	return ReasoningBlock{Text: b.Thinking, Signature: b.Signature}
+}
+
+// anthropicRawStreamEvent 流式事件的原始 JSON
+type anthropicRawStreamEvent struct {
+	ContentBlock anthropicRawBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+	} `json:"delta"`
+}
+
+// thinkingEnabled 请求是否启用了扩展思考
+func thinkingEnabled(req *ModelRequest) bool {
+	return req.Thinking != nil && req.Thinking.BudgetTokens > 0
+}
+
+// thinkingOptions 返回扩展思考相关的请求选项
+// SDK 尚不支持思考，通过改写请求 JSON 设置思考预算，并把带思考块的 assistant 消息替换为完整的内容块列表
+// （convertMessages 与请求消息一一对应）
+func thinkingOptions(req *ModelRequest) []option.RequestOption {
+	var opts []option.RequestOption
+	if thinkingEnabled(req) {
+		opts = append(opts, option.WithJSONSet("thinking", map[string]any{
+			"type":          "enabled",
+			"budget_tokens": req.Thinking.BudgetTokens,
+		}))
+	}
+
+	for i, msg := range req.Messages {
+		if msg.Role != RoleAssistant || len(msg.Reasoning) == 0 {
+			continue
+		}
+		blocks := make([]map[string]any, 0, len(msg.Reasoning)+len(msg.Blocks)+len(msg.ToolCalls)+1)
+		for _, r := range msg.Reasoning {
+			if r.Redacted != "" {
+				blocks = append(blocks, map[string]any{"type": anthropicBlockRedactedThinking, "data": r.Redacted})
+				continue
+			}
+			blocks = append(blocks, map[string]any{"type": anthropicBlockThinking, "thinking": r.Text, "signature": r.Signature})
+		}
+		if msg.Content != "" {
+			blocks = append(blocks, map[string]any{"type": "text", "text": msg.Content})
+		}
+		for _, b := range msg.Blocks {
+			blocks = append(blocks, anthropicBlockJSON(b))
+		}
+		for _, tc := range msg.ToolCalls {
+			input := tc.Input
+			if input == nil {
+				input = map[string]any{}
+			}
+			blocks = append(blocks, map[string]any{"type": "tool_use", "id": tc.ID, "name": tc.Name, "input": input})
+		}
+		opts = append(opts, option.WithJSONSet(fmt.Sprintf("messages.%d.content", i), blocks))
+	}
+	return opts
+}
@@ -2,7 +2,14 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -342,3 +349,202 @@ func TestAnthropicClient_Generate_EmptyMessages(t *testing.T) {
 		t.Error("Expected error for empty messages")
 	}
 }
+
+// newRecordingServer 创建记录请求体并返回固定响应的测试服务器
+func newRecordingServer(t *testing.T, contentType, response string, body *map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, body)
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, response)
+	}))
+	t.Cleanup(server.Close)
+	// 请求日志写入当前目录
+	t.Cleanup(func() { os.RemoveAll(filepath.Join(".", "logs")) })
+	return server
+}
+
+// thinkingRequest 包含带签名思考块的多轮工具调用请求
+func thinkingRequest() *ModelRequest {
+	return &ModelRequest{
+		Messages: []Message{
+			{Role: RoleUser, Content: "列出文件"},
+			{
+				Role:      RoleAssistant,
+				Reasoning: []ReasoningBlock{{Text: "先看看目录", Signature: "sig-1"}, {Redacted: "opaque"}},
+				ToolCalls: []ToolCall{{ID: "call_1", Name: "ls", Input: map[string]any{"path": "/"}}},
+			},
+			{Role: RoleUser, ToolResults: []ToolResult{{ToolCallID: "call_1", Content: "a.txt"}}},
+		},
+		MaxTokens:   2048,
+		Temperature: 0.5,
+		Thinking:    &ThinkingConfig{BudgetTokens: 1024},
+	}
+}
+
+func TestAnthropicClient_Generate_Thinking(t *testing.T) {
+	var body map[string]any
+	server := newRecordingServer(t, "application/json", `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude", "stop_reason": "end_turn",
+		"content": [
+			{"type": "thinking", "thinking": "只有一个文件", "signature": "sig-2"},
+			{"type": "redacted_thinking", "data": "opaque-2"},
+			{"type": "text", "text": "a.txt"}
+		],
+		"usage": {"input_tokens": 1, "output_tokens": 1}
+	}`, &body)
+
+	client := NewAnthropicClient("test-key", "claude", server.URL+"/")
+	resp, err := client.Generate(context.Background(), thinkingRequest())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// 响应中的思考块被保留
+	wantReasoning := []ReasoningBlock{{Text: "只有一个文件", Signature: "sig-2"}, {Redacted: "opaque-2"}}
+	if !reflect.DeepEqual(resp.Reasoning, wantReasoning) || resp.Content != "a.txt" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// 请求携带思考预算，且不设置 temperature
+	thinking, _ := body["thinking"].(map[string]any)
+	if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(1024) {
+		t.Errorf("unexpected thinking config: %v", body["thinking"])
+	}
+	if _, ok := body["temperature"]; ok {
+		t.Error("temperature must not be set with extended thinking")
+	}
+
+	// 思考块（含签名）原样回传，位于工具调用之前
+	messages := body["messages"].([]any)
+	content := messages[1].(map[string]any)["content"].([]any)
+	if len(content) != 3 {
+		t.Fatalf("expected 3 content blocks, got %v", content)
+	}
+	want := []map[string]any{
+		{"type": "thinking", "thinking": "先看看目录", "signature": "sig-1"},
+		{"type": "redacted_thinking", "data": "opaque"},
+		{"type": "tool_use", "id": "call_1", "name": "ls", "input": map[string]any{"path": "/"}},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(content[i], any(want[i])) {
+			t.Errorf("content block %d = %v, want %v", i, content[i], want[i])
+		}
+	}
+}
+
+func TestAnthropicClient_StreamGenerate_Thinking(t *testing.T) {
+	sse := ""
+	for _, event := range []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"只有"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"一个文件"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-2"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"a.txt"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	} {
+		var typ struct{ Type string }
+		json.Unmarshal([]byte(event), &typ)
+		sse += fmt.Sprintf("event: %s\ndata: %s\n\n", typ.Type, event)
+	}
+	var body map[string]any
+	server := newRecordingServer(t, "text/event-stream", sse, &body)
+
+	client := NewAnthropicClient("test-key", "claude", server.URL+"/")
+	stream, err := client.StreamGenerate(context.Background(), thinkingRequest())
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+
+	var deltas, text string
+	var blocks []ReasoningBlock
+	for event := range stream {
+		switch event.Type {
+		case StreamEventTypeThinking:
+			if event.Reasoning != nil {
+				blocks = append(blocks, *event.Reasoning)
+			} else {
+				deltas += event.Content
+			}
+		case StreamEventTypeText:
+			text += event.Content
+		case StreamEventTypeError:
+			t.Fatalf("stream error: %v", event.Error)
+		}
+	}
+
+	if deltas != "只有一个文件" || text != "a.txt" {
+		t.Errorf("unexpected deltas %q / text %q", deltas, text)
+	}
+	if !reflect.DeepEqual(blocks, []ReasoningBlock{{Text: "只有一个文件", Signature: "sig-2"}}) {
+		t.Errorf("unexpected reasoning blocks: %+v", blocks)
+	}
+	if body["thinking"] == nil {
+		t.Error("expected thinking config in streaming request")
+	}
+}
+
+func TestConvertMessages_Multimodal(t *testing.T) {
+	msgs := []Message{
+		{
+			Role:    RoleUser,
+			Content: "看看这张图",
+			Blocks: []ContentBlock{
+				NewImageBlock("image/png", []byte("png")),
+				NewImageURLBlock("https://example.com/a.png"),
+				NewDocumentBlock("application/pdf", []byte("pdf"), "spec.pdf"),
+			},
+		},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "read_file", Input: map[string]any{}}}},
+		{
+			Role: RoleUser,
+			ToolResults: []ToolResult{
+				{ToolCallID: "call_1", Content: "Image attached.", Blocks: []ContentBlock{NewImageBlock("image/png", []byte("png"))}},
+			},
+		},
+	}
+
+	data, err := json.Marshal(convertMessages(msgs))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got []map[string]any
+	json.Unmarshal(data, &got)
+
+	content := got[0]["content"].([]any)
+	want := []map[string]any{
+		{"type": "text", "text": "看看这张图"},
+		{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "cG5n"}},
+		{"type": "image", "source": map[string]any{"type": "url", "url": "https://example.com/a.png"}},
+		{"type": "document", "source": map[string]any{"type": "base64", "media_type": "application/pdf", "data": "cGRm"}},
+	}
+	if len(content) != len(want) {
+		t.Fatalf("expected %d blocks, got %s", len(want), data)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(content[i], any(want[i])) {
+			t.Errorf("block %d = %v, want %v", i, content[i], want[i])
+		}
+	}
+
+	// 工具结果以内容块列表作为结果内容
+	result := got[2]["content"].([]any)[0].(map[string]any)
+	wantResult := map[string]any{
+		"type":        "tool_result",
+		"tool_use_id": "call_1",
+		"is_error":    false,
+		"content": []any{
+			map[string]any{"type": "text", "text": "Image attached."},
+			map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "cG5n"}},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("tool result = %v, want %v", result, wantResult)
+	}
+}
@@ -1,5 +1,7 @@
 package llm
 
+import "encoding/base64"
+
 // Role 定义消息角色
 type Role string
 
@@ -11,10 +13,66 @@ const (
 
 // Message 表示一条对话消息
 type Message struct {
-	Role        Role         `json:"role"`
-	Content     string       `json:"content"`
-	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`   // assistant 消息中的工具调用
-	ToolResults []ToolResult `json:"tool_results,omitempty"` // user 消息中的工具结果
+	Role        Role             `json:"role"`
+	Content     string           `json:"content"`
+	Reasoning   []ReasoningBlock `json:"reasoning,omitempty"`    // assistant 消息中的思考内容（位于文本和工具调用之前）
+	Blocks      []ContentBlock   `json:"blocks,omitempty"`       // 多模态内容块（位于 Content 之后）
+	ToolCalls   []ToolCall       `json:"tool_calls,omitempty"`   // assistant 消息中的工具调用
+	ToolResults []ToolResult     `json:"tool_results,omitempty"` // user 消息中的工具结果
+}
+
+// ContentBlockType 定义内容块类型
+type ContentBlockType string
+
+const (
+	ContentBlockTypeText     ContentBlockType = "text"     // 文本
+	ContentBlockTypeImage    ContentBlockType = "image"    // 图片
+	ContentBlockTypeDocument ContentBlockType = "document" // 文档（如 PDF）
+)
+
+// ContentBlock 表示消息或工具结果中的多模态内容块
+// 图片和文档的内容以 base64 编码保存在 Data 中，或通过 URL 引用（二者选其一）
+type ContentBlock struct {
+	Type      ContentBlockType `json:"type"`
+	Text      string           `json:"text,omitempty"`       // 文本内容
+	MediaType string           `json:"media_type,omitempty"` // MIME 类型，如 image/png、application/pdf
+	Data      string           `json:"data,omitempty"`       // base64 编码的内容
+	URL       string           `json:"url,omitempty"`        // 内容的 URL
+	Name      string           `json:"name,omitempty"`       // 文档名称
+}
+
+// NewTextBlock 创建文本内容块
+func NewTextBlock(text string) ContentBlock {
+	return ContentBlock{Type: ContentBlockTypeText, Text: text}
+}
+
+// NewImageBlock 创建图片内容块
+func NewImageBlock(mediaType string, data []byte) ContentBlock {
+	return ContentBlock{Type: ContentBlockTypeImage, MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data)}
+}
+
+// NewImageURLBlock 创建通过 URL 引用的图片内容块
+func NewImageURLBlock(url string) ContentBlock {
+	return ContentBlock{Type: ContentBlockTypeImage, URL: url}
+}
+
+// NewDocumentBlock 创建文档内容块
+func NewDocumentBlock(mediaType string, data []byte, name string) ContentBlock {
+	return ContentBlock{Type: ContentBlockTypeDocument, MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data), Name: name}
+}
+
+// ReasoningBlock 表示模型的思考（推理）内容
+// 使用扩展思考调用工具时，必须把思考块（含签名）原样放回后续请求的 assistant 消息中
+type ReasoningBlock struct {
+	Text      string `json:"text,omitempty"`      // 思考文本
+	Signature string `json:"signature,omitempty"` // 思考块签名（Anthropic）
+	Redacted  string `json:"redacted,omitempty"`  // 被加密的思考内容（Anthropic redacted_thinking 块）
+}
+
+// ThinkingConfig 扩展思考 / 推理配置
+type ThinkingConfig struct {
+	BudgetTokens int    `json:"budget_tokens,omitempty"` // 思考的 token 预算（Anthropic，需小于 MaxTokens）
+	Effort       string `json:"effort,omitempty"`        // 推理强度 low / medium / high（OpenAI 兼容的推理模型）
 }
 
 // ToolCall 表示工具调用请求
@@ -26,25 +84,28 @@ type ToolCall struct {
 
 // ToolResult 表示工具执行结果
 type ToolResult struct {
-	ToolCallID string `json:"tool_call_id"`
-	Content    string `json:"content"`
-	IsError    bool   `json:"is_error,omitempty"`
+	ToolCallID string         `json:"tool_call_id"`
+	Content    string         `json:"content"`
+	Blocks     []ContentBlock `json:"blocks,omitempty"` // 结果中附带的图片等内容块（位于 Content 之后）
+	IsError    bool           `json:"is_error,omitempty"`
 }
 
 // ModelRequest 表示发送给 LLM 的请求
 type ModelRequest struct {
-	Messages     []Message    `json:"messages"`
-	SystemPrompt string       `json:"system_prompt,omitempty"`
-	Tools        []ToolSchema `json:"tools,omitempty"`
-	MaxTokens    int          `json:"max_tokens,omitempty"`
-	Temperature  float64      `json:"temperature,omitempty"`
+	Messages     []Message       `json:"messages"`
+	SystemPrompt string          `json:"system_prompt,omitempty"`
+	Tools        []ToolSchema    `json:"tools,omitempty"`
+	MaxTokens    int             `json:"max_tokens,omitempty"`
+	Temperature  float64         `json:"temperature,omitempty"`
+	Thinking     *ThinkingConfig `json:"thinking,omitempty"` // 为 nil 时不启用扩展思考
 }
 
 // ModelResponse 表示 LLM 的响应
 type ModelResponse struct {
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	StopReason string     `json:"stop_reason"`
+	Content    string           `json:"content"`
+	Reasoning  []ReasoningBlock `json:"reasoning,omitempty"`
+	ToolCalls  []ToolCall       `json:"tool_calls,omitempty"`
+	StopReason string           `json:"stop_reason"`
 }
 
 // ToolSchema 定义工具的 JSON Schema
@@ -60,6 +121,7 @@ type StreamEventType string
 const (
 	StreamEventTypeStart      StreamEventType = "start"       // 开始生成
 	StreamEventTypeText       StreamEventType = "text"        // 文本内容
+	StreamEventTypeThinking   StreamEventType = "thinking"    // 思考内容
 	StreamEventTypeToolUse    StreamEventType = "tool_use"    // 工具调用
 	StreamEventTypeToolResult StreamEventType = "tool_result" // 工具结果
 	StreamEventTypeEnd        StreamEventType = "end"         // 生成结束
@@ -73,6 +135,7 @@ type StreamEvent struct {
 	Type       StreamEventType `json:"type"`
 	Content    string          `json:"content,omitempty"`     // 文本内容（增量）
 	ToolCall   *ToolCall       `json:"tool_call,omitempty"`   // 工具调用
+	Reasoning  *ReasoningBlock `json:"reasoning,omitempty"`   // 完整的思考块（思考块结束时发送，此时 Content 为空）
 	StopReason string          `json:"stop_reason,omitempty"` // 停止原因
 	Error      error           `json:"error,omitempty"`       // 错误信息
 	Metadata   map[string]any  `json:"metadata,omitempty"`    // 元数据
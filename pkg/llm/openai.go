@@ -2,8 +2,10 @@ package llm
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 )
@@ -39,76 +41,7 @@ func (c *OpenAIClient) Generate(ctx context.Context, req *ModelRequest) (*ModelR
 	logRequest("openai", c.model, req, false)
 
 	// 转换消息格式
-	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
-
-	// 如果有系统提示词，添加为第一条消息
-	if req.SystemPrompt != "" {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: req.SystemPrompt,
-		})
-	}
-
-	// 添加用户和助手消息
-	for _, msg := range req.Messages {
-		switch msg.Role {
-		case RoleAssistant:
-			// Assistant 消息：可能包含文本内容和工具调用
-			assistantMsg := openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleAssistant,
-				Content: msg.Content,
-			}
-
-			// 添加工具调用
-			if len(msg.ToolCalls) > 0 {
-				assistantMsg.ToolCalls = make([]openai.ToolCall, 0, len(msg.ToolCalls))
-				for _, tc := range msg.ToolCalls {
-					// 将参数转换为 JSON 字符串
-					argsJSON, _ := json.Marshal(tc.Input)
-					assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, openai.ToolCall{
-						ID:   tc.ID,
-						Type: openai.ToolTypeFunction,
-						Function: openai.FunctionCall{
-							Name:      tc.Name,
-							Arguments: string(argsJSON),
-						},
-					})
-				}
-			}
-
-			messages = append(messages, assistantMsg)
-
-		case RoleUser:
-			// User 消息：可能包含文本内容
-			if msg.Content != "" {
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:    openai.ChatMessageRoleUser,
-					Content: msg.Content,
-				})
-			}
-
-			// 添加工具结果（作为独立的 tool 消息）
-			for _, tr := range msg.ToolResults {
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:       openai.ChatMessageRoleTool,
-					Content:    tr.Content,
-					ToolCallID: tr.ToolCallID,
-				})
-			}
-
-		case RoleSystem:
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: msg.Content,
-			})
-
-		default:
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleUser,
-				Content: msg.Content,
-			})
-		}
-	}
+	messages := convertOpenAIMessages(req)
 
 	// 构建请求参数
 	chatReq := openai.ChatCompletionRequest{
@@ -124,6 +57,10 @@ func (c *OpenAIClient) Generate(ctx context.Context, req *ModelRequest) (*ModelR
 		chatReq.Temperature = float32(req.Temperature)
 	}
 
+	if req.Thinking != nil && req.Thinking.Effort != "" {
+		chatReq.ReasoningEffort = req.Thinking.Effort
+	}
+
 	// 添加工具定义
 	if len(req.Tools) > 0 {
 		tools := make([]openai.Tool, 0, len(req.Tools))
@@ -156,6 +93,9 @@ func (c *OpenAIClient) Generate(ctx context.Context, req *ModelRequest) (*ModelR
 		Content:    choice.Message.Content,
 		StopReason: string(choice.FinishReason),
 	}
+	if choice.Message.ReasoningContent != "" {
+		resp.Reasoning = []ReasoningBlock{{Text: choice.Message.ReasoningContent}}
+	}
 
 	// 解析工具调用
 	if len(choice.Message.ToolCalls) > 0 {
@@ -207,76 +147,7 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 	eventChan := make(chan StreamEvent, 10)
 
 	// 转换消息格式
-	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
-
-	// 如果有系统提示词，添加为第一条消息
-	if req.SystemPrompt != "" {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: req.SystemPrompt,
-		})
-	}
-
-	// 添加用户和助手消息
-	for _, msg := range req.Messages {
-		switch msg.Role {
-		case RoleAssistant:
-			// Assistant 消息：可能包含文本内容和工具调用
-			assistantMsg := openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleAssistant,
-				Content: msg.Content,
-			}
-
-			// 添加工具调用
-			if len(msg.ToolCalls) > 0 {
-				assistantMsg.ToolCalls = make([]openai.ToolCall, 0, len(msg.ToolCalls))
-				for _, tc := range msg.ToolCalls {
-					// 将参数转换为 JSON 字符串
-					argsJSON, _ := json.Marshal(tc.Input)
-					assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, openai.ToolCall{
-						ID:   tc.ID,
-						Type: openai.ToolTypeFunction,
-						Function: openai.FunctionCall{
-							Name:      tc.Name,
-							Arguments: string(argsJSON),
-						},
-					})
-				}
-			}
-
-			messages = append(messages, assistantMsg)
-
-		case RoleUser:
-			// User 消息：可能包含文本内容
-			if msg.Content != "" {
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:    openai.ChatMessageRoleUser,
-					Content: msg.Content,
-				})
-			}
-
-			// 添加工具结果（作为独立的 tool 消息）
-			for _, tr := range msg.ToolResults {
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:       openai.ChatMessageRoleTool,
-					Content:    tr.Content,
-					ToolCallID: tr.ToolCallID,
-				})
-			}
-
-		case RoleSystem:
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: msg.Content,
-			})
-
-		default:
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleUser,
-				Content: msg.Content,
-			})
-		}
-	}
+	messages := convertOpenAIMessages(req)
 
 	// 构建请求参数
 	chatReq := openai.ChatCompletionRequest{
@@ -293,6 +164,10 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 		chatReq.Temperature = float32(req.Temperature)
 	}
 
+	if req.Thinking != nil && req.Thinking.Effort != "" {
+		chatReq.ReasoningEffort = req.Thinking.Effort
+	}
+
 	// 添加工具定义
 	if len(req.Tools) > 0 {
 		tools := make([]openai.Tool, 0, len(req.Tools))
@@ -331,8 +206,21 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 		}
 		defer stream.Close()
 
-		// 用于累积工具调用信息
+		// 用于累积工具调用信息和思考内容
 		toolCallsMap := make(map[int]*ToolCall)
+		var reasoning string
+
+		// 发送完整的思考块
+		flushReasoning := func() {
+			if reasoning != "" {
+				eventChan <- StreamEvent{
+					Type:      StreamEventTypeThinking,
+					Reasoning: &ReasoningBlock{Text: reasoning},
+					Done:      false,
+				}
+				reasoning = ""
+			}
+		}
 
 		// 处理流式响应
 		for {
@@ -340,6 +228,7 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 			if err != nil {
 				// 流结束
 				if err.Error() == "EOF" {
+					flushReasoning()
 					eventChan <- StreamEvent{
 						Type: StreamEventTypeEnd,
 						Done: true,
@@ -360,6 +249,16 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 
 			choice := response.Choices[0]
 
+			// 处理思考内容
+			if choice.Delta.ReasoningContent != "" {
+				reasoning += choice.Delta.ReasoningContent
+				eventChan <- StreamEvent{
+					Type:    StreamEventTypeThinking,
+					Content: choice.Delta.ReasoningContent,
+					Done:    false,
+				}
+			}
+
 			// 处理文本内容
 			if choice.Delta.Content != "" {
 				eventChan <- StreamEvent{
@@ -399,6 +298,8 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 
 			// 检查是否结束
 			if choice.FinishReason != "" {
+				flushReasoning()
+
 				// 发送所有工具调用
 				for _, toolCall := range toolCallsMap {
 					// 解析完整的 JSON
@@ -431,3 +332,142 @@ func (c *OpenAIClient) StreamGenerate(ctx context.Context, req *ModelRequest) (<
 
 	return eventChan, nil
 }
+
+// convertOpenAIMessages 将内部消息格式转换为 OpenAI 消息格式
+func convertOpenAIMessages(req *ModelRequest) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
+
+	// 如果有系统提示词，添加为第一条消息
+	if req.SystemPrompt != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: req.SystemPrompt,
+		})
+	}
+
+	// 添加用户和助手消息
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case RoleAssistant:
+			// Assistant 消息：可能包含文本内容和工具调用
+			assistantMsg := openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: msg.Content,
+			}
+
+			// assistant 消息只支持文本内容块
+			for _, b := range msg.Blocks {
+				if b.Type == ContentBlockTypeText {
+					assistantMsg.Content += b.Text
+				}
+			}
+
+			// 回传思考内容（推理模型在多轮工具调用中需要）
+			for _, r := range msg.Reasoning {
+				assistantMsg.ReasoningContent += r.Text
+			}
+
+			// 添加工具调用
+			if len(msg.ToolCalls) > 0 {
+				assistantMsg.ToolCalls = make([]openai.ToolCall, 0, len(msg.ToolCalls))
+				for _, tc := range msg.ToolCalls {
+					// 将参数转换为 JSON 字符串
+					argsJSON, _ := json.Marshal(tc.Input)
+					assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, openai.ToolCall{
+						ID:   tc.ID,
+						Type: openai.ToolTypeFunction,
+						Function: openai.FunctionCall{
+							Name:      tc.Name,
+							Arguments: string(argsJSON),
+						},
+					})
+				}
+			}
+
+			messages = append(messages, assistantMsg)
+
+		case RoleUser:
+			// User 消息：可能包含文本内容和多模态内容块
+			if len(msg.Blocks) > 0 {
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:         openai.ChatMessageRoleUser,
+					MultiContent: convertOpenAIParts(msg.Content, msg.Blocks),
+				})
+			} else if msg.Content != "" {
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleUser,
+					Content: msg.Content,
+				})
+			}
+
+			// 添加工具结果（作为独立的 tool 消息）
+			// tool 消息只能包含文本，结果中的图片等内容块随后以一条 user 消息发送
+			var attachments []openai.ChatMessagePart
+			for _, tr := range msg.ToolResults {
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					Content:    tr.Content,
+					ToolCallID: tr.ToolCallID,
+				})
+				if len(tr.Blocks) > 0 {
+					header := fmt.Sprintf("工具调用 %s 返回的内容：", tr.ToolCallID)
+					attachments = append(attachments, convertOpenAIParts(header, tr.Blocks)...)
+				}
+			}
+			if len(attachments) > 0 {
+				messages = append(messages, openai.ChatCompletionMessage{
+					Role:         openai.ChatMessageRoleUser,
+					MultiContent: attachments,
+				})
+			}
+
+		case RoleSystem:
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: msg.Content,
+			})
+
+		default:
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: msg.Content,
+			})
+		}
+	}
+
+	return messages
+}
+
+// convertOpenAIParts 将文本和多模态内容块转换为 OpenAI 消息片段
+// 图片使用 image_url（内联内容转换为 data URL）；接口不支持文档，文本文档内联为文本，其余以说明文字代替
+func convertOpenAIParts(text string, blocks []ContentBlock) []openai.ChatMessagePart {
+	parts := make([]openai.ChatMessagePart, 0, len(blocks)+1)
+	if text != "" {
+		parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: text})
+	}
+
+	for _, b := range blocks {
+		switch b.Type {
+		case ContentBlockTypeImage:
+			url := b.URL
+			if url == "" {
+				url = fmt.Sprintf("data:%s;base64,%s", b.MediaType, b.Data)
+			}
+			parts = append(parts, openai.ChatMessagePart{
+				Type:     openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{URL: url},
+			})
+
+		case ContentBlockTypeDocument:
+			note := fmt.Sprintf("[文档 %s（%s）未发送：当前接口不支持该类型的文档]", b.Name, b.MediaType)
+			if data, err := base64.StdEncoding.DecodeString(b.Data); err == nil && strings.HasPrefix(b.MediaType, "text/") {
+				note = fmt.Sprintf("文档 %s：\n%s", b.Name, data)
+			}
+			parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: note})
+
+		default:
+			parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: b.Text})
+		}
+	}
+	return parts
+}
@@ -3,6 +3,8 @@ package llm
 import (
 	"context"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sashabaranov/go-openai"
@@ -290,3 +292,128 @@ func TestOpenAIClient_Generate_AllParameters(t *testing.T) {
 		t.Error("Expected error for invalid API key")
 	}
 }
+
+// reasoningRequest 包含思考内容的多轮工具调用请求
+func reasoningRequest() *ModelRequest {
+	req := thinkingRequest()
+	req.Messages[1].Reasoning = []ReasoningBlock{{Text: "先看看目录"}}
+	req.Thinking = &ThinkingConfig{Effort: "high"}
+	return req
+}
+
+func TestOpenAIClient_Generate_Reasoning(t *testing.T) {
+	var body map[string]any
+	server := newRecordingServer(t, "application/json", `{
+		"id": "chatcmpl-1", "object": "chat.completion", "model": "reasoner",
+		"choices": [{"index": 0, "finish_reason": "stop",
+			"message": {"role": "assistant", "content": "a.txt", "reasoning_content": "只有一个文件"}}]
+	}`, &body)
+
+	client := NewOpenAIClient("test-key", "reasoner", server.URL)
+	resp, err := client.Generate(context.Background(), reasoningRequest())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Reasoning, []ReasoningBlock{{Text: "只有一个文件"}}) || resp.Content != "a.txt" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// 请求携带推理强度，思考内容随 assistant 消息回传
+	if body["reasoning_effort"] != "high" {
+		t.Errorf("unexpected reasoning_effort: %v", body["reasoning_effort"])
+	}
+	messages := body["messages"].([]any)
+	if got := messages[1].(map[string]any)["reasoning_content"]; got != "先看看目录" {
+		t.Errorf("unexpected reasoning_content: %v", got)
+	}
+}
+
+func TestOpenAIClient_StreamGenerate_Reasoning(t *testing.T) {
+	chunks := []string{
+		`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"只有"}}]}`,
+		`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"reasoning_content":"一个文件"}}]}`,
+		`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"a.txt"}}]}`,
+		`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	}
+	sse := "data: " + strings.Join(chunks, "\n\ndata: ") + "\n\ndata: [DONE]\n\n"
+	var body map[string]any
+	server := newRecordingServer(t, "text/event-stream", sse, &body)
+
+	client := NewOpenAIClient("test-key", "reasoner", server.URL)
+	stream, err := client.StreamGenerate(context.Background(), reasoningRequest())
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+
+	var deltas, text string
+	var blocks []ReasoningBlock
+	for event := range stream {
+		switch event.Type {
+		case StreamEventTypeThinking:
+			if event.Reasoning != nil {
+				blocks = append(blocks, *event.Reasoning)
+			} else {
+				deltas += event.Content
+			}
+		case StreamEventTypeText:
+			text += event.Content
+		case StreamEventTypeError:
+			t.Fatalf("stream error: %v", event.Error)
+		}
+	}
+	if deltas != "只有一个文件" || text != "a.txt" {
+		t.Errorf("unexpected deltas %q / text %q", deltas, text)
+	}
+	if !reflect.DeepEqual(blocks, []ReasoningBlock{{Text: "只有一个文件"}}) {
+		t.Errorf("unexpected reasoning blocks: %+v", blocks)
+	}
+}
+
+func TestConvertOpenAIMessages_Multimodal(t *testing.T) {
+	req := &ModelRequest{
+		Messages: []Message{
+			{
+				Role:    RoleUser,
+				Content: "看看这张图",
+				Blocks: []ContentBlock{
+					NewImageBlock("image/png", []byte("png")),
+					NewDocumentBlock("text/plain", []byte("说明"), "README"),
+					NewDocumentBlock("application/pdf", []byte("pdf"), "spec.pdf"),
+				},
+			},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "read_file", Input: map[string]any{}}}},
+			{
+				Role: RoleUser,
+				ToolResults: []ToolResult{
+					{ToolCallID: "call_1", Content: "Image attached.", Blocks: []ContentBlock{NewImageURLBlock("https://example.com/a.png")}},
+				},
+			},
+		},
+	}
+
+	messages := convertOpenAIMessages(req)
+	if len(messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(messages))
+	}
+
+	parts := messages[0].MultiContent
+	if messages[0].Content != "" || len(parts) != 4 {
+		t.Fatalf("expected multi content user message, got %+v", messages[0])
+	}
+	if parts[0].Text != "看看这张图" || parts[1].ImageURL == nil || parts[1].ImageURL.URL != "data:image/png;base64,cG5n" {
+		t.Errorf("unexpected text/image parts: %+v", parts[:2])
+	}
+	if !strings.Contains(parts[2].Text, "说明") || !strings.Contains(parts[3].Text, "spec.pdf") {
+		t.Errorf("unexpected document parts: %+v", parts[2:])
+	}
+
+	// 工具结果中的图片在 tool 消息之后以 user 消息发送
+	if messages[2].Role != openai.ChatMessageRoleTool || messages[2].Content != "Image attached." {
+		t.Errorf("unexpected tool message: %+v", messages[2])
+	}
+	attachments := messages[3].MultiContent
+	if messages[3].Role != openai.ChatMessageRoleUser || len(attachments) != 2 ||
+		!strings.Contains(attachments[0].Text, "call_1") || attachments[1].ImageURL.URL != "https://example.com/a.png" {
+		t.Errorf("unexpected attachment message: %+v", messages[3])
+	}
+}
@@ -10,15 +10,16 @@ import (
 
 // RequestLog 记录请求的完整信息
 type RequestLog struct {
-	Timestamp    string       `json:"timestamp"`
-	Model        string       `json:"model"`
-	Provider     string       `json:"provider"` // "anthropic" 或 "openai"
-	Messages     []Message    `json:"messages"`
-	SystemPrompt string       `json:"system_prompt,omitempty"`
-	Tools        []ToolSchema `json:"tools,omitempty"`
-	MaxTokens    int          `json:"max_tokens,omitempty"`
-	Temperature  float64      `json:"temperature,omitempty"`
-	IsStreaming  bool         `json:"is_streaming"`
+	Timestamp    string          `json:"timestamp"`
+	Model        string          `json:"model"`
+	Provider     string          `json:"provider"` // "anthropic" 或 "openai"
+	Messages     []Message       `json:"messages"`
+	SystemPrompt string          `json:"system_prompt,omitempty"`
+	Tools        []ToolSchema    `json:"tools,omitempty"`
+	MaxTokens    int             `json:"max_tokens,omitempty"`
+	Temperature  float64         `json:"temperature,omitempty"`
+	Thinking     *ThinkingConfig `json:"thinking,omitempty"`
+	IsStreaming  bool            `json:"is_streaming"`
 }
 
 // logRequest 将请求信息记录到文件
@@ -44,6 +45,7 @@ func logRequest(provider, model string, req *ModelRequest, isStreaming bool) {
 		Tools:        req.Tools,
 		MaxTokens:    req.MaxTokens,
 		Temperature:  req.Temperature,
+		Thinking:     req.Thinking,
 		IsStreaming:  isStreaming,
 	}
 
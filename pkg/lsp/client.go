@@ -0,0 +1,446 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerConfig 语言服务器配置
+type ServerConfig struct {
+	Name                  string   // 名称（如 gopls）
+	Command               string   // 可执行文件
+	Args                  []string // 命令行参数
+	Env                   []string // 额外的环境变量（KEY=VALUE）
+	LanguageID            string   // 文档的语言 ID（如 go）
+	Extensions            []string // 负责的文件扩展名（如 .go）
+	InitializationOptions any      // initialize 请求的 initializationOptions
+}
+
+// GoplsConfig 返回 gopls 的默认配置
+func GoplsConfig() ServerConfig {
+	return ServerConfig{
+		Name:       "gopls",
+		Command:    "gopls",
+		Args:       []string{"serve"},
+		LanguageID: "go",
+		Extensions: []string{".go"},
+	}
+}
+
+// handles 判断服务器是否负责该文件
+func (c ServerConfig) handles(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range c.Extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// document 已打开的文档
+type document struct {
+	version int
+	content string
+	seq     int // 最近一次修改时的诊断发布次数
+}
+
+// diagnosticsEntry 某个文件最近一次发布的诊断
+type diagnosticsEntry struct {
+	diagnostics []protocolDiagnostic
+	seq         int // 发布次数
+}
+
+// client 单个语言服务器的客户端
+type client struct {
+	config  ServerConfig
+	rootDir string
+	cmd     *exec.Cmd
+	conn    *conn
+
+	syncMu      sync.Mutex
+	mu          sync.Mutex
+	docs        map[string]*document         // URI -> 文档
+	diagnostics map[string]*diagnosticsEntry // URI -> 诊断
+	diagChanged chan struct{}                // 诊断更新时关闭并替换
+}
+
+// startClient 启动语言服务器进程并完成初始化
+func startClient(ctx context.Context, config ServerConfig, rootDir string) (*client, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Dir = rootDir
+	cmd.Env = append(os.Environ(), config.Env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start language server %s: %w", config.Name, err)
+	}
+
+	c := newClient(config, rootDir, stdout, stdin)
+	c.cmd = cmd
+	if err := c.initialize(ctx); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newClient 基于已建立的读写流创建客户端（未初始化）
+func newClient(config ServerConfig, rootDir string, r io.Reader, w io.Writer) *client {
+	c := &client{
+		config:      config,
+		rootDir:     rootDir,
+		docs:        make(map[string]*document),
+		diagnostics: make(map[string]*diagnosticsEntry),
+		diagChanged: make(chan struct{}),
+	}
+	c.conn = newConn(r, w, c.handle)
+	return c
+}
+
+// initialize 发送 initialize 请求和 initialized 通知
+func (c *client) initialize(ctx context.Context) error {
+	rootURI := pathToURI(c.rootDir)
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"workspaceFolders": []map[string]any{
+			{"uri": rootURI, "name": filepath.Base(c.rootDir)},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": true},
+				"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+				"definition":         map[string]any{"linkSupport": true},
+				"references":         map[string]any{},
+				"documentSymbol":     map[string]any{"hierarchicalDocumentSymbolSupport": true},
+				"publishDiagnostics": map[string]any{"versionSupport": true},
+			},
+			"workspace": map[string]any{
+				"symbol":           map[string]any{},
+				"workspaceFolders": true,
+				"configuration":    true,
+			},
+		},
+	}
+	if c.config.InitializationOptions != nil {
+		params["initializationOptions"] = c.config.InitializationOptions
+	}
+
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return fmt.Errorf("failed to initialize language server %s: %w", c.config.Name, err)
+	}
+	return c.conn.Notify("initialized", map[string]any{})
+}
+
+// handle 处理服务器发来的请求和通知
+func (c *client) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err == nil {
+			c.mu.Lock()
+			entry, ok := c.diagnostics[p.URI]
+			if !ok {
+				entry = &diagnosticsEntry{}
+				c.diagnostics[p.URI] = entry
+			}
+			entry.diagnostics = p.Diagnostics
+			entry.seq++
+			close(c.diagChanged)
+			c.diagChanged = make(chan struct{})
+			c.mu.Unlock()
+		}
+	case "workspace/configuration":
+		// 所有配置项都使用服务器默认值
+		var p struct {
+			Items []any `json:"items"`
+		}
+		json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	}
+	// 其他请求（如 client/registerCapability、window/workDoneProgress/create）返回 null
+	return nil, nil
+}
+
+// syncDocument 将磁盘上的文件内容同步给服务器，返回文档 URI 和最近一次修改时的诊断发布次数
+// 首次访问时发送 didOpen，内容变化时发送全量 didChange，文件被删除时发送 didClose
+func (c *client) syncDocument(absPath string) (string, int, error) {
+	// syncMu 保证同一文档的版本按顺序发送；mu 只用于保护状态，发送消息时不持有，
+	// 避免与读取循环中的通知处理互相阻塞
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	uri := pathToURI(absPath)
+	data, readErr := os.ReadFile(absPath)
+	content := string(data)
+
+	c.mu.Lock()
+	seq := 0
+	if entry, ok := c.diagnostics[uri]; ok {
+		seq = entry.seq
+	}
+	doc, open := c.docs[uri]
+	var version int
+	switch {
+	case readErr != nil:
+		delete(c.docs, uri)
+	case !open:
+		version = 1
+		c.docs[uri] = &document{version: version, content: content, seq: seq}
+	case doc.content != content:
+		doc.version++
+		doc.content = content
+		doc.seq = seq
+		version = doc.version
+	default:
+		// 内容未变化：此后发布的诊断都对应当前内容
+		seq = doc.seq
+	}
+	c.mu.Unlock()
+
+	switch {
+	case readErr != nil:
+		if open {
+			c.conn.Notify("textDocument/didClose", map[string]any{
+				"textDocument": map[string]any{"uri": uri},
+			})
+		}
+		return uri, seq, readErr
+	case !open:
+		return uri, seq, c.conn.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        uri,
+				"languageId": c.config.LanguageID,
+				"version":    version,
+				"text":       content,
+			},
+		})
+	case version > 0:
+		return uri, seq, c.conn.Notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version},
+			"contentChanges": []map[string]any{{"text": content}},
+		})
+	}
+	return uri, seq, nil
+}
+
+// textDocumentPosition 构造 TextDocumentPositionParams
+func textDocumentPosition(uri string, pos position) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     pos,
+	}
+}
+
+// definition 查找定义
+func (c *client) definition(ctx context.Context, absPath string, pos position) ([]location, error) {
+	uri, _, err := c.syncDocument(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/definition", textDocumentPosition(uri, pos), &raw); err != nil {
+		return nil, err
+	}
+	return decodeLocations(raw), nil
+}
+
+// references 查找引用
+func (c *client) references(ctx context.Context, absPath string, pos position, includeDeclaration bool) ([]location, error) {
+	uri, _, err := c.syncDocument(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	params := textDocumentPosition(uri, pos)
+	params["context"] = map[string]any{"includeDeclaration": includeDeclaration}
+
+	var locations []location
+	if err := c.conn.Call(ctx, "textDocument/references", params, &locations); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// hover 获取类型和文档信息
+func (c *client) hover(ctx context.Context, absPath string, pos position) (string, error) {
+	uri, _, err := c.syncDocument(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	var result *hoverResult
+	if err := c.conn.Call(ctx, "textDocument/hover", textDocumentPosition(uri, pos), &result); err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", nil
+	}
+	return hoverText(result.Contents), nil
+}
+
+// documentSymbols 获取文档符号（层级结构或扁平列表）
+func (c *client) documentSymbols(ctx context.Context, absPath string) ([]documentSymbol, []symbolInformation, error) {
+	uri, _, err := c.syncDocument(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var raw []json.RawMessage
+	params := map[string]any{"textDocument": map[string]any{"uri": uri}}
+	if err := c.conn.Call(ctx, "textDocument/documentSymbol", params, &raw); err != nil {
+		return nil, nil, err
+	}
+	if len(raw) == 0 {
+		return nil, nil, nil
+	}
+
+	// 带 location 字段的是 SymbolInformation，否则为 DocumentSymbol
+	var probe struct {
+		Location *location `json:"location"`
+	}
+	json.Unmarshal(raw[0], &probe)
+	data, _ := json.Marshal(raw)
+	if probe.Location != nil {
+		var infos []symbolInformation
+		err := json.Unmarshal(data, &infos)
+		return nil, infos, err
+	}
+	var symbols []documentSymbol
+	err = json.Unmarshal(data, &symbols)
+	return symbols, nil, err
+}
+
+// workspaceSymbols 在工作区中搜索符号
+func (c *client) workspaceSymbols(ctx context.Context, query string) ([]symbolInformation, error) {
+	var symbols []symbolInformation
+	if err := c.conn.Call(ctx, "workspace/symbol", map[string]any{"query": query}, &symbols); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// fileDiagnostics 同步文件并等待服务器发布新的诊断，超时后返回最近一次的结果
+func (c *client) fileDiagnostics(ctx context.Context, absPath string, wait time.Duration) ([]protocolDiagnostic, error) {
+	uri, seq, err := c.syncDocument(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		entry, ok := c.diagnostics[uri]
+		changed := c.diagChanged
+		if ok && entry.seq > seq {
+			diagnostics := entry.diagnostics
+			c.mu.Unlock()
+			return diagnostics, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if entry, ok := c.diagnostics[uri]; ok {
+				return entry.diagnostics, nil
+			}
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// allDiagnostics 返回服务器发布过的所有诊断（URI -> 诊断）
+func (c *client) allDiagnostics() map[string][]protocolDiagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all := make(map[string][]protocolDiagnostic, len(c.diagnostics))
+	for uri, entry := range c.diagnostics {
+		if len(entry.diagnostics) > 0 {
+			all[uri] = entry.diagnostics
+		}
+	}
+	return all
+}
+
+// close 关闭服务器：发送 shutdown 和 exit，并等待进程退出
+func (c *client) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.conn.Call(ctx, "shutdown", nil, nil)
+	c.conn.Notify("exit", nil)
+	c.conn.close(nil)
+
+	if c.cmd == nil || c.cmd.Process == nil {
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		c.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// decodeLocations 解析 Location、Location[] 或 LocationLink[]
+func decodeLocations(raw json.RawMessage) []location {
+	var single location
+	if err := json.Unmarshal(raw, &single); err == nil && single.URI != "" {
+		return []location{single}
+	}
+
+	var links []locationLink
+	if err := json.Unmarshal(raw, &links); err == nil && len(links) > 0 && links[0].TargetURI != "" {
+		locations := make([]location, len(links))
+		for i, link := range links {
+			locations[i] = location{URI: link.TargetURI, Range: link.TargetSelectionRange}
+		}
+		return locations
+	}
+
+	var locations []location
+	json.Unmarshal(raw, &locations)
+	return locations
+}
+
+// pathToURI 将绝对路径转换为 file URI
+func pathToURI(absPath string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(absPath)}).String()
+}
+
+// uriToPath 将 file URI 转换为绝对路径
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,247 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("language server connection closed")
+
+// message JSON-RPC 2.0 消息（请求、响应和通知共用）
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// ResponseError JSON-RPC 错误响应
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("language server error %d: %s", e.Code, e.Message)
+}
+
+// Handler 处理服务器发来的请求和通知
+// 通知的返回值会被忽略；请求返回的结果作为响应发送给服务器
+type Handler func(method string, params json.RawMessage) (any, error)
+
+// conn 基于 Content-Length 分帧的 JSON-RPC 连接
+type conn struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	handler Handler
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *message
+	closed  chan struct{}
+	err     error
+}
+
+// newConn 创建连接并启动读取循环
+func newConn(r io.Reader, w io.Writer, handler Handler) *conn {
+	c := &conn{
+		reader:  bufio.NewReader(r),
+		writer:  w,
+		handler: handler,
+		pending: make(map[string]chan *message),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Call 发送请求并等待响应，result 为 nil 时忽略响应结果
+func (c *conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	c.nextID++
+	n := c.nextID
+	id := strconv.FormatInt(n, 10)
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(id)
+	if err := c.send(&message{ID: &rawID, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		// 通知服务器取消请求
+		c.Notify("$/cancelRequest", map[string]any{"id": n})
+		return ctx.Err()
+	case <-c.closed:
+		return c.closeErr()
+	}
+}
+
+// Notify 发送通知
+func (c *conn) Notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+// send 编码并写入一条消息
+func (c *conn) send(msg *message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode params: %w", err)
+		}
+		msg.Params = data
+	}
+	return c.write(msg)
+}
+
+// write 写入带 Content-Length 头的消息
+func (c *conn) write(msg *message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-c.closed:
+		return c.closeErr()
+	default:
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(body)
+	return err
+}
+
+// readLoop 持续读取服务器消息，直到连接关闭
+func (c *conn) readLoop() {
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			c.close(err)
+			return
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			go c.handleRequest(msg)
+		case msg.Method != "":
+			if c.handler != nil {
+				c.handler(msg.Method, msg.Params)
+			}
+		case msg.ID != nil:
+			c.mu.Lock()
+			ch, ok := c.pending[string(*msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+	}
+}
+
+// handleRequest 响应服务器发来的请求
+func (c *conn) handleRequest(msg *message) {
+	resp := &message{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage("null")}
+
+	var (
+		result any
+		err    error
+	)
+	if c.handler != nil {
+		result, err = c.handler(msg.Method, msg.Params)
+	}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = &ResponseError{Code: -32601, Message: err.Error()}
+	} else if result != nil {
+		data, encodeErr := json.Marshal(result)
+		if encodeErr == nil {
+			resp.Result = data
+		}
+	}
+	c.write(resp)
+}
+
+// readMessage 读取一条消息
+func (c *conn) readMessage() (*message, error) {
+	header, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return nil, err
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return &msg, nil
+}
+
+// close 关闭连接并唤醒所有等待中的请求
+func (c *conn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		err = ErrClosed
+	}
+	c.err = err
+	close(c.closed)
+}
+
+// closeErr 返回连接关闭的原因
+func (c *conn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
@@ -0,0 +1,281 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer 进程内的语言服务器，用于测试协议交互
+type fakeServer struct {
+	conn *conn
+	mu   sync.Mutex
+	log  []string // 收到的方法（didChange 附带版本号）
+}
+
+// newFakeClient 创建连接到 fakeServer 的客户端
+func newFakeClient(t *testing.T, rootDir string, handler func(s *fakeServer, method string, params json.RawMessage) (any, error)) (*client, *fakeServer) {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	t.Cleanup(func() {
+		clientR.Close()
+		serverR.Close()
+	})
+
+	s := &fakeServer{}
+	s.conn = newConn(serverR, serverW, func(method string, params json.RawMessage) (any, error) {
+		s.mu.Lock()
+		entry := method
+		if method == "textDocument/didChange" {
+			var p struct {
+				TextDocument struct{ Version int } `json:"textDocument"`
+			}
+			json.Unmarshal(params, &p)
+			entry += "@" + string(rune('0'+p.TextDocument.Version))
+		}
+		s.log = append(s.log, entry)
+		s.mu.Unlock()
+		return handler(s, method, params)
+	})
+
+	c := newClient(GoplsConfig(), rootDir, clientR, clientW)
+	if err := c.initialize(context.Background()); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	return c, s
+}
+
+func (s *fakeServer) methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.log...)
+}
+
+func TestConn_Call(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	newConn(serverR, serverW, func(method string, params json.RawMessage) (any, error) {
+		if method == "fail" {
+			return nil, errors.New("boom")
+		}
+		var p map[string]any
+		json.Unmarshal(params, &p)
+		return map[string]any{"echo": p["value"]}, nil
+	})
+	c := newConn(clientR, clientW, nil)
+
+	var result struct{ Echo string }
+	if err := c.Call(context.Background(), "echo", map[string]any{"value": "hi"}, &result); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if result.Echo != "hi" {
+		t.Errorf("Expected echo 'hi', got %q", result.Echo)
+	}
+
+	var respErr *ResponseError
+	if err := c.Call(context.Background(), "fail", nil, nil); !errors.As(err, &respErr) || respErr.Message != "boom" {
+		t.Errorf("Expected ResponseError, got %v", err)
+	}
+
+	// 服务器断开后，调用返回 ErrClosed
+	serverW.Close()
+	if err := c.Call(context.Background(), "echo", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestManager_FakeServer(t *testing.T) {
+	dir := t.TempDir()
+	// 第 2 行包含多字节字符，验证 UTF-16 列转换
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n// 注释 Foo\nfunc Foo() {}\n"), 0644)
+
+	c, s := newFakeClient(t, dir, func(s *fakeServer, method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "textDocument/definition":
+			return []map[string]any{{
+				"uri":   pathToURI(filepath.Join(dir, "a.go")),
+				"range": map[string]any{"start": map[string]any{"line": 1, "character": 6}, "end": map[string]any{"line": 1, "character": 9}},
+			}}, nil
+		case "textDocument/didOpen", "textDocument/didChange":
+			var p struct {
+				TextDocument struct{ URI string } `json:"textDocument"`
+			}
+			json.Unmarshal(params, &p)
+			go s.conn.Notify("textDocument/publishDiagnostics", map[string]any{
+				"uri": p.TextDocument.URI,
+				"diagnostics": []map[string]any{{
+					"range":    map[string]any{"start": map[string]any{"line": 2, "character": 5}, "end": map[string]any{"line": 2, "character": 8}},
+					"severity": 2,
+					"source":   "fake",
+					"message":  method,
+				}},
+			})
+		}
+		return nil, nil
+	})
+
+	m := NewManager(Config{RootDir: dir, VirtualPaths: true})
+	m.clients["gopls"] = c
+	ctx := context.Background()
+
+	locations, err := m.Definition(ctx, "/a.go", 3, 6)
+	if err != nil {
+		t.Fatalf("Definition failed: %v", err)
+	}
+	if len(locations) != 1 {
+		t.Fatalf("Expected 1 location, got %d", len(locations))
+	}
+	if got := locations[0]; got.Path != "/a.go" || got.Line != 2 || got.Column != 7 || got.Text != "// 注释 Foo" {
+		t.Errorf("Unexpected location: %+v", got)
+	}
+
+	diagnostics, err := m.Diagnostics(ctx, "/a.go")
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Severity != "warning" || diagnostics[0].Line != 3 || diagnostics[0].Column != 6 {
+		t.Errorf("Unexpected diagnostics: %+v", diagnostics)
+	}
+
+	// 文件修改后同步发送 didChange，诊断等待新的发布
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc Bar() {}\n"), 0644)
+	if err := m.Sync(ctx, "/a.go"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	diagnostics, _ = m.Diagnostics(ctx, "/a.go")
+	if len(diagnostics) != 1 || diagnostics[0].Message != "textDocument/didChange" {
+		t.Errorf("Expected diagnostics from didChange, got %+v", diagnostics)
+	}
+
+	got := strings.Join(s.methods(), ",")
+	want := "initialize,initialized,textDocument/didOpen,textDocument/definition,textDocument/didChange@2"
+	if got != want {
+		t.Errorf("Expected methods %s, got %s", want, got)
+	}
+
+	if _, err := m.Definition(ctx, "/../etc/passwd.go", 1, 1); err == nil {
+		t.Error("Expected error for path outside root")
+	}
+	if _, err := m.Hover(ctx, "/README.md", 1, 1); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Expected ErrNoServer, got %v", err)
+	}
+}
+
+func TestManager_FindColumn(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("var 名字, FooBar, Foo = 1, 2, 3\n"), 0644)
+	m := NewManager(Config{RootDir: dir})
+
+	tests := []struct {
+		symbol string
+		want   int
+	}{
+		{"Foo", 17}, // 跳过 FooBar 中的部分匹配
+		{"名字", 5},
+		{"FooBar", 9},
+	}
+	for _, tt := range tests {
+		got, err := m.FindColumn("a.go", 1, tt.symbol)
+		if err != nil || got != tt.want {
+			t.Errorf("FindColumn(%q) = %d, %v; want %d", tt.symbol, got, err, tt.want)
+		}
+	}
+	if _, err := m.FindColumn("a.go", 1, "missing"); err == nil {
+		t.Error("Expected error for missing symbol")
+	}
+}
+
+func TestHoverText(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`"plain"`, "plain"},
+		{`{"kind":"markdown","value":"**bold**"}`, "**bold**"},
+		{`{"language":"go","value":"func F()"}`, "```go\nfunc F()\n```"},
+		{`["a",{"language":"go","value":"b"}]`, "a\n\n```go\nb\n```"},
+	}
+	for _, tt := range tests {
+		if got := hoverText(json.RawMessage(tt.raw)); got != tt.want {
+			t.Errorf("hoverText(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestManager_Gopls(t *testing.T) {
+	if _, err := exec.LookPath("gopls"); err != nil {
+		t.Skip("gopls not installed")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/demo\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "greet.go"), []byte("package demo\n\n// Greet 返回问候语\nfunc Greet(name string) string {\n\treturn \"hello \" + name\n}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package demo\n\nfunc Run() string {\n\treturn Greet(\"world\")\n}\n"), 0644)
+
+	m := NewManager(Config{RootDir: dir, VirtualPaths: true, DiagnosticsWait: 10 * time.Second})
+	defer m.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	col, _ := m.FindColumn("/main.go", 4, "Greet")
+	locations, err := m.Definition(ctx, "/main.go", 4, col)
+	if err != nil {
+		t.Fatalf("Definition failed: %v", err)
+	}
+	if len(locations) != 1 || locations[0].Path != "/greet.go" || locations[0].Line != 4 {
+		t.Errorf("Unexpected definition: %+v", locations)
+	}
+
+	refs, err := m.References(ctx, "/greet.go", 4, 6, true)
+	if err != nil {
+		t.Fatalf("References failed: %v", err)
+	}
+	if len(refs) != 2 {
+		t.Errorf("Expected 2 references, got %+v", refs)
+	}
+
+	hover, err := m.Hover(ctx, "/greet.go", 4, 6)
+	if err != nil || !strings.Contains(hover, "func Greet(name string) string") {
+		t.Errorf("Unexpected hover: %q, %v", hover, err)
+	}
+
+	symbols, err := m.DocumentSymbols(ctx, "/greet.go")
+	if err != nil || len(symbols) != 1 || symbols[0].Name != "Greet" || symbols[0].Kind != "function" {
+		t.Errorf("Unexpected document symbols: %+v, %v", symbols, err)
+	}
+
+	workspace, err := m.WorkspaceSymbols(ctx, "Run")
+	if err != nil {
+		t.Fatalf("WorkspaceSymbols failed: %v", err)
+	}
+	found := false
+	for _, s := range workspace {
+		if s.Name == "Run" && s.Path == "/main.go" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected Run in workspace symbols, got %+v", workspace)
+	}
+
+	// 修改文件引入编译错误，同步后可以获取诊断
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package demo\n\nfunc Run() string {\n\treturn Greet(1)\n}\n"), 0644)
+	m.Sync(ctx, "/main.go")
+	diagnostics, err := m.Diagnostics(ctx, "/main.go")
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if len(diagnostics) == 0 || diagnostics[0].Severity != "error" || diagnostics[0].Line != 4 {
+		t.Errorf("Expected type error on line 4, got %+v", diagnostics)
+	}
+}
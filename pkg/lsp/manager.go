@@ -0,0 +1,527 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrNoServer 没有负责该文件的语言服务器
+var ErrNoServer = errors.New("no language server configured for this file type")
+
+// defaultDiagnosticsWait 等待服务器发布诊断的默认时长
+const defaultDiagnosticsWait = 3 * time.Second
+
+// Config 语言服务器管理器配置
+type Config struct {
+	RootDir         string         // 工作区根目录（如 FilesystemBackend 的根目录）
+	VirtualPaths    bool           // 路径是否为相对根目录的虚拟路径（与 FilesystemBackend 的虚拟模式一致）
+	Servers         []ServerConfig // 语言服务器，为空时默认使用 gopls
+	DiagnosticsWait time.Duration  // 等待诊断发布的时长，0 表示使用默认值
+}
+
+// Location 代码位置（路径与文件工具一致，行列从 1 开始，列按字符计数）
+type Location struct {
+	Path   string
+	Line   int
+	Column int
+	Text   string // 该行的内容（去除首尾空白）
+}
+
+// Symbol 代码符号
+type Symbol struct {
+	Name      string
+	Kind      string // 符号类型（如 function、struct、method）
+	Detail    string // 附加信息（如函数签名）
+	Container string // 所属的容器（如包名、类型名）
+	Path      string
+	Line      int
+	Column    int
+	Children  []Symbol
+}
+
+// Diagnostic 诊断信息
+type Diagnostic struct {
+	Path     string
+	Line     int
+	Column   int
+	Severity string // error、warning、info、hint
+	Source   string
+	Message  string
+}
+
+// Manager 语言服务器管理器
+// 按文件扩展名将请求路由到对应的语言服务器，服务器在首次使用时启动，在 Close 时关闭
+type Manager struct {
+	config  Config
+	mu      sync.Mutex
+	clients map[string]*client // 服务器名称 -> 客户端
+	failed  map[string]error   // 启动失败的服务器（不再重试）
+}
+
+// NewManager 创建语言服务器管理器
+func NewManager(cfg Config) *Manager {
+	if len(cfg.Servers) == 0 {
+		cfg.Servers = []ServerConfig{GoplsConfig()}
+	}
+	if cfg.DiagnosticsWait <= 0 {
+		cfg.DiagnosticsWait = defaultDiagnosticsWait
+	}
+	if abs, err := filepath.Abs(cfg.RootDir); err == nil {
+		cfg.RootDir = abs
+	}
+	return &Manager{
+		config:  cfg,
+		clients: make(map[string]*client),
+		failed:  make(map[string]error),
+	}
+}
+
+// Config 返回管理器配置
+func (m *Manager) Config() Config {
+	return m.config
+}
+
+// Handles 判断是否有语言服务器负责该文件
+func (m *Manager) Handles(path string) bool {
+	_, ok := m.serverFor(path)
+	return ok
+}
+
+// serverFor 返回负责该文件的服务器配置
+func (m *Manager) serverFor(path string) (ServerConfig, bool) {
+	for _, s := range m.config.Servers {
+		if s.handles(path) {
+			return s, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// clientFor 返回负责该文件的客户端（必要时启动服务器）和文件的绝对路径
+func (m *Manager) clientFor(ctx context.Context, path string) (*client, string, error) {
+	abs, err := m.absPath(path)
+	if err != nil {
+		return nil, "", err
+	}
+	server, ok := m.serverFor(abs)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrNoServer, path)
+	}
+	c, err := m.start(ctx, server)
+	return c, abs, err
+}
+
+// start 启动服务器（已启动时直接返回）
+func (m *Manager) start(ctx context.Context, server ServerConfig) (*client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.clients[server.Name]; ok {
+		return c, nil
+	}
+	if err, ok := m.failed[server.Name]; ok {
+		return nil, err
+	}
+
+	c, err := startClient(ctx, server, m.config.RootDir)
+	if err != nil {
+		m.failed[server.Name] = err
+		return nil, err
+	}
+	m.clients[server.Name] = c
+	return c, nil
+}
+
+// running 返回已启动的客户端
+func (m *Manager) running() []*client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := make([]*client, 0, len(m.clients))
+	for _, s := range m.config.Servers {
+		if c, ok := m.clients[s.Name]; ok {
+			clients = append(clients, c)
+		}
+	}
+	return clients
+}
+
+// Definition 查找符号的定义
+func (m *Manager) Definition(ctx context.Context, path string, line, column int) ([]Location, error) {
+	c, abs, err := m.clientFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := m.toPosition(abs, line, column)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := c.definition(ctx, abs, pos)
+	if err != nil {
+		return nil, err
+	}
+	return m.toLocations(locations), nil
+}
+
+// References 查找符号的所有引用
+func (m *Manager) References(ctx context.Context, path string, line, column int, includeDeclaration bool) ([]Location, error) {
+	c, abs, err := m.clientFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := m.toPosition(abs, line, column)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := c.references(ctx, abs, pos, includeDeclaration)
+	if err != nil {
+		return nil, err
+	}
+	return m.toLocations(locations), nil
+}
+
+// Hover 获取符号的类型和文档信息
+func (m *Manager) Hover(ctx context.Context, path string, line, column int) (string, error) {
+	c, abs, err := m.clientFor(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	pos, err := m.toPosition(abs, line, column)
+	if err != nil {
+		return "", err
+	}
+	return c.hover(ctx, abs, pos)
+}
+
+// DocumentSymbols 获取文件中的符号（层级结构）
+func (m *Manager) DocumentSymbols(ctx context.Context, path string) ([]Symbol, error) {
+	c, abs, err := m.clientFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols, infos, err := c.documentSymbols(ctx, abs)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newLineCache()
+	if infos != nil {
+		return m.fromSymbolInformation(infos, lines), nil
+	}
+
+	var convert func([]documentSymbol) []Symbol
+	convert = func(symbols []documentSymbol) []Symbol {
+		result := make([]Symbol, 0, len(symbols))
+		for _, s := range symbols {
+			line, column, _ := lines.fromPosition(abs, s.SelectionRange.Start)
+			result = append(result, Symbol{
+				Name:     s.Name,
+				Kind:     symbolKindName(s.Kind),
+				Detail:   s.Detail,
+				Path:     m.displayPath(abs),
+				Line:     line,
+				Column:   column,
+				Children: convert(s.Children),
+			})
+		}
+		return result
+	}
+	return convert(symbols), nil
+}
+
+// WorkspaceSymbols 在工作区中按名称搜索符号（查询所有配置的语言服务器）
+func (m *Manager) WorkspaceSymbols(ctx context.Context, query string) ([]Symbol, error) {
+	var (
+		symbols []Symbol
+		errs    []error
+	)
+	lines := newLineCache()
+	for _, server := range m.config.Servers {
+		c, err := m.start(ctx, server)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		infos, err := c.workspaceSymbols(ctx, query)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		symbols = append(symbols, m.fromSymbolInformation(infos, lines)...)
+	}
+
+	if len(symbols) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return symbols, nil
+}
+
+// Diagnostics 获取文件的诊断信息；path 为空时返回所有已打开文件的诊断
+func (m *Manager) Diagnostics(ctx context.Context, path string) ([]Diagnostic, error) {
+	lines := newLineCache()
+	if path == "" {
+		var result []Diagnostic
+		for _, c := range m.running() {
+			for uri, diagnostics := range c.allDiagnostics() {
+				result = append(result, m.toDiagnostics(uriToPath(uri), diagnostics, lines)...)
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			if result[i].Path != result[j].Path {
+				return result[i].Path < result[j].Path
+			}
+			return result[i].Line < result[j].Line
+		})
+		return result, nil
+	}
+
+	c, abs, err := m.clientFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics, err := c.fileDiagnostics(ctx, abs, m.config.DiagnosticsWait)
+	if err != nil {
+		return nil, err
+	}
+	return m.toDiagnostics(abs, diagnostics, lines), nil
+}
+
+// Sync 通知语言服务器文件已被修改（创建、编辑或删除）
+// 没有负责该文件的服务器或服务器尚未启动时不做任何事（服务器启动后会直接读取最新内容）
+func (m *Manager) Sync(ctx context.Context, path string) error {
+	abs, err := m.absPath(path)
+	if err != nil {
+		return err
+	}
+	server, ok := m.serverFor(abs)
+	if !ok {
+		return nil
+	}
+
+	m.mu.Lock()
+	c, ok := m.clients[server.Name]
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	_, _, err = c.syncDocument(abs)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// FindColumn 返回符号在指定行中首次出现的列号（从 1 开始），优先匹配完整的单词
+func (m *Manager) FindColumn(path string, line int, symbol string) (int, error) {
+	abs, err := m.absPath(path)
+	if err != nil {
+		return 0, err
+	}
+	text, err := newLineCache().line(abs, line)
+	if err != nil {
+		return 0, err
+	}
+
+	idx := -1
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], symbol)
+		if i == -1 {
+			break
+		}
+		i += offset
+		if idx == -1 {
+			idx = i
+		}
+		if isWordBoundary(text, i-1) && isWordBoundary(text, i+len(symbol)) {
+			idx = i
+			break
+		}
+		offset = i + 1
+	}
+	if idx == -1 {
+		return 0, fmt.Errorf("symbol %q not found on line %d of %s", symbol, line, path)
+	}
+	return utf8.RuneCountInString(text[:idx]) + 1, nil
+}
+
+// isWordBoundary 判断字节位置 i 是否不属于标识符
+func isWordBoundary(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// Close 关闭所有已启动的语言服务器
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*client)
+	m.mu.Unlock()
+
+	var errs []error
+	for _, c := range clients {
+		if err := c.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// absPath 将工具使用的路径转换为绝对路径
+func (m *Manager) absPath(path string) (string, error) {
+	root := m.config.RootDir
+	if m.config.VirtualPaths {
+		full := filepath.Join(root, strings.TrimPrefix(path, "/"))
+		if rel, err := filepath.Rel(root, full); err != nil || strings.HasPrefix(rel, "..") {
+			return "", fmt.Errorf("path outside root directory: %s", path)
+		}
+		return full, nil
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	return filepath.Join(root, path), nil
+}
+
+// displayPath 将绝对路径转换为工具使用的路径（工作区外的文件返回绝对路径）
+func (m *Manager) displayPath(abs string) string {
+	rel, err := filepath.Rel(m.config.RootDir, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return abs
+	}
+	if m.config.VirtualPaths {
+		return "/" + filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// toPosition 将从 1 开始的行列（列按字符计数）转换为协议位置
+func (m *Manager) toPosition(abs string, line, column int) (position, error) {
+	if line < 1 {
+		return position{}, fmt.Errorf("line must be >= 1, got %d", line)
+	}
+	text, err := newLineCache().line(abs, line)
+	if err != nil {
+		return position{}, err
+	}
+
+	runes := []rune(text)
+	column = min(max(column, 1), len(runes)+1)
+	return position{
+		Line:      line - 1,
+		Character: len(utf16.Encode(runes[:column-1])),
+	}, nil
+}
+
+// toLocations 转换协议位置
+func (m *Manager) toLocations(locations []location) []Location {
+	lines := newLineCache()
+	result := make([]Location, 0, len(locations))
+	for _, loc := range locations {
+		abs := uriToPath(loc.URI)
+		line, column, text := lines.fromPosition(abs, loc.Range.Start)
+		result = append(result, Location{
+			Path:   m.displayPath(abs),
+			Line:   line,
+			Column: column,
+			Text:   strings.TrimSpace(text),
+		})
+	}
+	return result
+}
+
+// fromSymbolInformation 转换扁平符号信息
+func (m *Manager) fromSymbolInformation(infos []symbolInformation, lines *lineCache) []Symbol {
+	symbols := make([]Symbol, 0, len(infos))
+	for _, info := range infos {
+		abs := uriToPath(info.Location.URI)
+		line, column, _ := lines.fromPosition(abs, info.Location.Range.Start)
+		symbols = append(symbols, Symbol{
+			Name:      info.Name,
+			Kind:      symbolKindName(info.Kind),
+			Container: info.ContainerName,
+			Path:      m.displayPath(abs),
+			Line:      line,
+			Column:    column,
+		})
+	}
+	return symbols
+}
+
+// toDiagnostics 转换协议诊断
+func (m *Manager) toDiagnostics(abs string, diagnostics []protocolDiagnostic, lines *lineCache) []Diagnostic {
+	result := make([]Diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		line, column, _ := lines.fromPosition(abs, d.Range.Start)
+		result = append(result, Diagnostic{
+			Path:     m.displayPath(abs),
+			Line:     line,
+			Column:   column,
+			Severity: d.Severity.String(),
+			Source:   d.Source,
+			Message:  d.Message,
+		})
+	}
+	return result
+}
+
+// lineCache 按需读取文件内容，用于行列转换
+type lineCache struct {
+	files map[string][]string
+}
+
+func newLineCache() *lineCache {
+	return &lineCache{files: make(map[string][]string)}
+}
+
+// line 返回文件的第 n 行（从 1 开始）
+func (c *lineCache) line(abs string, n int) (string, error) {
+	lines, ok := c.files[abs]
+	if !ok {
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return "", err
+		}
+		lines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		c.files[abs] = lines
+	}
+	if n < 1 || n > len(lines) {
+		return "", fmt.Errorf("line %d out of range (file has %d lines)", n, len(lines))
+	}
+	return lines[n-1], nil
+}
+
+// fromPosition 将协议位置转换为从 1 开始的行列和该行内容（文件不可读时列按 UTF-16 偏移估算）
+func (c *lineCache) fromPosition(abs string, pos position) (int, int, string) {
+	text, err := c.line(abs, pos.Line+1)
+	if err != nil {
+		return pos.Line + 1, pos.Character + 1, ""
+	}
+
+	units := 0
+	column := 1
+	for _, r := range text {
+		if units >= pos.Character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	return pos.Line + 1, column, text
+}
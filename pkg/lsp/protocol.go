@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// 以下为本包用到的 LSP 协议类型子集（参见 Language Server Protocol 3.17 规范）
+
+// position 文档中的位置（行和字符均从 0 开始，字符按 UTF-16 编码单元计数）
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspRange 文档中的范围
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// location 文档中的位置（URI + 范围）
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// locationLink 部分服务器对 definition 返回 LocationLink
+type locationLink struct {
+	TargetURI            string   `json:"targetUri"`
+	TargetRange          lspRange `json:"targetRange"`
+	TargetSelectionRange lspRange `json:"targetSelectionRange"`
+}
+
+// severity 诊断级别
+type severity int
+
+const (
+	severityError       severity = 1
+	severityWarning     severity = 2
+	severityInformation severity = 3
+	severityHint        severity = 4
+)
+
+// String 返回诊断级别名称
+func (s severity) String() string {
+	switch s {
+	case severityError:
+		return "error"
+	case severityWarning:
+		return "warning"
+	case severityInformation:
+		return "info"
+	case severityHint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// protocolDiagnostic 服务器发布的诊断
+type protocolDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity severity `json:"severity,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// publishDiagnosticsParams textDocument/publishDiagnostics 通知参数
+type publishDiagnosticsParams struct {
+	URI         string               `json:"uri"`
+	Version     *int                 `json:"version,omitempty"`
+	Diagnostics []protocolDiagnostic `json:"diagnostics"`
+}
+
+// documentSymbol 层级文档符号
+type documentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          lspRange         `json:"range"`
+	SelectionRange lspRange         `json:"selectionRange"`
+	Children       []documentSymbol `json:"children,omitempty"`
+}
+
+// symbolInformation 扁平符号信息（workspace/symbol 以及不支持层级结构的服务器）
+type symbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+// symbolKinds LSP SymbolKind 名称（下标为协议中的值）
+var symbolKinds = []string{
+	"", "file", "module", "namespace", "package", "class", "method", "property",
+	"field", "constructor", "enum", "interface", "function", "variable", "constant",
+	"string", "number", "boolean", "array", "object", "key", "null", "enum member",
+	"struct", "event", "operator", "type parameter",
+}
+
+// symbolKindName 返回符号类型名称
+func symbolKindName(kind int) string {
+	if kind > 0 && kind < len(symbolKinds) {
+		return symbolKinds[kind]
+	}
+	return "symbol"
+}
+
+// hoverResult textDocument/hover 响应
+type hoverResult struct {
+	Contents json.RawMessage `json:"contents"`
+}
+
+// hoverText 提取 hover 内容（MarkupContent、MarkedString 或 MarkedString 数组）
+func hoverText(raw json.RawMessage) string {
+	var markup struct {
+		Kind     string `json:"kind"`
+		Value    string `json:"value"`
+		Language string `json:"language"`
+	}
+	var text string
+	var list []json.RawMessage
+
+	switch {
+	case json.Unmarshal(raw, &text) == nil:
+		return text
+	case json.Unmarshal(raw, &list) == nil:
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			if s := hoverText(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	case json.Unmarshal(raw, &markup) == nil:
+		if markup.Language != "" {
+			return "```" + markup.Language + "\n" + markup.Value + "\n```"
+		}
+		return markup.Value
+	}
+	return ""
+}
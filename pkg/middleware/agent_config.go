@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/backend"
@@ -14,9 +15,10 @@ import (
 type AgentMemMiddleware struct {
 	*BaseMiddleware
 	backend       backend.Backend
-	configContent string // 缓存的配置内容
-	configPath    string // 找到的配置文件路径
-	loaded        bool   // 是否已加载
+	configContent string    // 缓存的配置内容
+	configPath    string    // 找到的配置文件路径
+	loaded        bool      // 是否已加载
+	loadOnce      sync.Once // 配置只在首次执行时加载（并发执行时只加载一次）
 }
 
 // NewAgentMemMiddleware 创建新的 AgentMemMiddleware
@@ -29,21 +31,15 @@ func NewAgentMemMiddleware(backend backend.Backend) *AgentMemMiddleware {
 
 // BeforeAgent 在 Agent 开始执行前加载配置文件
 func (m *AgentMemMiddleware) BeforeAgent(ctx context.Context, state *agent.State) error {
-	if m.loaded {
-		return nil // 已加载，跳过
-	}
-
-	// 只查找 AGENT.md 文件
-	content, err := m.backend.ReadFile(ctx, "AGENT.md", 0, 0)
-	if err == nil && content != "" {
-		m.configContent = content
-		m.configPath = "AGENT.md"
+	m.loadOnce.Do(func() {
+		// 只查找 AGENT.md 文件，不存在时静默跳过
+		content, err := m.backend.ReadFile(ctx, "AGENT.md", 0, 0)
+		if err == nil && content != "" {
+			m.configContent = content
+			m.configPath = "AGENT.md"
+		}
 		m.loaded = true
-		return nil
-	}
-
-	// 文件不存在，静默跳过
-	m.loaded = true
+	})
 	return nil
 }
 
@@ -0,0 +1,295 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// CheckpointMiddleware 文件快照中间件
+// 为 SnapshotBackend 提供每次执行的会话、轮次和迭代，并将变更记录与会话记录一起持久化，
+// 使工作区（以及可选的对话）可以回退到会话中任意一轮之前的状态
+// 位置保存在执行作用域中，变更记录按会话分别保存，同一实例可服务并发的多个会话；
+// 未指定 session_id 的嵌套执行（子 Agent）继承上级执行的位置，其变更归属于上级 Agent 当前的轮次
+type CheckpointMiddleware struct {
+	*BaseMiddleware
+	snapshot *backend.SnapshotBackend
+	store    *SessionStore
+	dateDirs map[string]string // 已加载的会话 ID -> 快照记录所在的日期目录
+	current  string            // 最近一次执行的会话 ID，供 REPL 的 Turns / Rewind 使用
+	direct   *checkpointRun    // 不属于任何执行的钩子调用（如直接调用中间件）使用的状态
+	mu       sync.Mutex        // 并发保护
+}
+
+// checkpointRun 一次执行的快照位置
+type checkpointRun struct {
+	runID     string // 所属执行的 ID
+	sessionID string // 会话 ID
+	turn      int    // 当前对话轮次
+	iteration int    // 当前迭代
+	persisted int    // 已持久化的变更数量
+}
+
+// TurnSummary 单个轮次的变更摘要
+type TurnSummary struct {
+	Turn    int      `json:"turn"`    // 对话轮次
+	Changes int      `json:"changes"` // 变更次数
+	Files   []string `json:"files"`   // 涉及的文件（去重、排序）
+}
+
+// NewCheckpointMiddleware 创建文件快照中间件
+// store 为 nil 时不持久化快照记录
+func NewCheckpointMiddleware(snapshot *backend.SnapshotBackend, store *SessionStore) *CheckpointMiddleware {
+	m := &CheckpointMiddleware{
+		BaseMiddleware: NewBaseMiddleware("checkpoint"),
+		snapshot:       snapshot,
+		store:          store,
+		dateDirs:       make(map[string]string),
+	}
+	snapshot.SetPositionFunc(m.position)
+	return m
+}
+
+// run 返回 ctx 对应的快照位置：本次执行的位置，嵌套执行继承的上级执行的位置，
+// 或不属于任何执行时直接调用钩子设置的位置；没有位置时返回 nil
+func (m *CheckpointMiddleware) run(ctx context.Context) *checkpointRun {
+	if r, ok := agent.LookupRunLocal[*checkpointRun](ctx, m); ok {
+		return r
+	}
+	if agent.RunIDFromContext(ctx) != "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.direct
+}
+
+// ownRun 返回属于本次执行的快照位置，嵌套执行继承的位置不返回（嵌套执行不推进位置，也不持久化）
+func (m *CheckpointMiddleware) ownRun(ctx context.Context) *checkpointRun {
+	r := m.run(ctx)
+	if r == nil || r.runID != agent.RunIDFromContext(ctx) {
+		return nil
+	}
+	return r
+}
+
+// position 返回 ctx 中发生的变更所属的位置（供 SnapshotBackend 调用）
+func (m *CheckpointMiddleware) position(ctx context.Context) (backend.SnapshotPosition, bool) {
+	r := m.run(ctx)
+	if r == nil {
+		return backend.SnapshotPosition{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return backend.SnapshotPosition{Session: r.sessionID, Turn: r.turn, Iteration: r.iteration}, true
+}
+
+// BeforeAgent 确定本次执行的会话和轮次，会话首次出现（首次运行、恢复会话或进程重启）时加载已有的快照记录
+func (m *CheckpointMiddleware) BeforeAgent(ctx context.Context, state *agent.State) error {
+	sessionID := ""
+	if v, ok := state.GetMetadata("session_id"); ok {
+		if sid, ok := v.(string); ok {
+			sessionID = sid
+		}
+	}
+	if sessionID == "" && agent.SubAgentInfoFromContext(ctx) != nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.dateDirs[sessionID]; !ok {
+		m.loadCheckpoints(ctx, sessionID)
+	}
+
+	r := &checkpointRun{
+		runID:     agent.RunIDFromContext(ctx),
+		sessionID: sessionID,
+		turn:      max(CountTurns(state.GetMessages()), 1),
+		persisted: len(m.snapshot.Changes(sessionID)),
+	}
+	if _, ok := agent.RunLocal(ctx, m, func() *checkpointRun { return r }); !ok {
+		m.direct = r
+	}
+	m.current = sessionID
+	return nil
+}
+
+// BeforeModel 每次调用模型前推进迭代编号
+func (m *CheckpointMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
+	r := m.ownRun(ctx)
+	if r == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r.iteration++
+	return nil
+}
+
+// AfterTool 工具执行后持久化新增的变更，保证进程中断时记录不丢失
+func (m *CheckpointMiddleware) AfterTool(ctx context.Context, result *llm.ToolResult, state *agent.State) error {
+	r := m.ownRun(ctx)
+	if r == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.snapshot.Changes(r.sessionID)) != r.persisted {
+		m.persistRun(ctx, r)
+	}
+	return nil
+}
+
+// AfterAgent 在 Agent 执行结束后持久化快照记录
+func (m *CheckpointMiddleware) AfterAgent(ctx context.Context, state *agent.State) error {
+	r := m.ownRun(ctx)
+	if r == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.persistRun(ctx, r)
+	return nil
+}
+
+// Turns 返回最近一次执行的会话中有文件变更的轮次摘要（按轮次升序）
+func (m *CheckpointMiddleware) Turns() []TurnSummary {
+	m.mu.Lock()
+	sessionID := m.current
+	m.mu.Unlock()
+
+	byTurn := make(map[int]*TurnSummary)
+	seen := make(map[int]map[string]bool)
+	var turns []int
+
+	for _, change := range m.snapshot.Changes(sessionID) {
+		summary, ok := byTurn[change.Turn]
+		if !ok {
+			summary = &TurnSummary{Turn: change.Turn}
+			byTurn[change.Turn] = summary
+			seen[change.Turn] = make(map[string]bool)
+			turns = append(turns, change.Turn)
+		}
+		summary.Changes++
+		if !seen[change.Turn][change.Path] {
+			seen[change.Turn][change.Path] = true
+			summary.Files = append(summary.Files, change.Path)
+		}
+	}
+
+	sort.Ints(turns)
+	result := make([]TurnSummary, 0, len(turns))
+	for _, turn := range turns {
+		sort.Strings(byTurn[turn].Files)
+		result = append(result, *byTurn[turn])
+	}
+	return result
+}
+
+// Rewind 将最近一次执行的会话修改过的文件回退到指定轮次开始之前的状态，并持久化剩余的快照记录
+func (m *CheckpointMiddleware) Rewind(ctx context.Context, turn int) ([]backend.FileChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if turn < 1 {
+		return nil, fmt.Errorf("invalid turn: %d", turn)
+	}
+
+	reverted, err := m.snapshot.Rewind(ctx, m.current, turn)
+	if _, persistErr := m.persist(ctx, m.current); persistErr != nil {
+		log.Printf("警告: 持久化快照记录失败: %v", persistErr)
+	}
+	return reverted, err
+}
+
+// loadCheckpoints 加载会话的快照记录，未找到时从空记录开始（调用方持有锁）
+func (m *CheckpointMiddleware) loadCheckpoints(ctx context.Context, sessionID string) {
+	m.dateDirs[sessionID] = time.Now().Format("2006-01-02")
+	m.snapshot.LoadChanges(sessionID, nil)
+
+	if m.store == nil || sessionID == "" {
+		return
+	}
+
+	record, dateDir, err := m.store.LoadCheckpoints(ctx, sessionID, 30)
+	if err != nil {
+		return
+	}
+
+	m.dateDirs[sessionID] = dateDir
+	m.snapshot.LoadChanges(sessionID, record.Changes)
+}
+
+// persistRun 持久化执行所属会话的快照记录，并更新已持久化的数量（调用方持有锁）
+func (m *CheckpointMiddleware) persistRun(ctx context.Context, r *checkpointRun) {
+	n, err := m.persist(ctx, r.sessionID)
+	if err != nil {
+		log.Printf("警告: 持久化快照记录失败: %v", err)
+		return
+	}
+	r.persisted = n
+}
+
+// persist 持久化会话的快照记录（使用会话加载时固定的 dateDir），返回已持久化的变更数量（调用方持有锁）
+func (m *CheckpointMiddleware) persist(ctx context.Context, sessionID string) (int, error) {
+	changes := m.snapshot.Changes(sessionID)
+	if m.store == nil || sessionID == "" {
+		return len(changes), nil
+	}
+
+	record := &CheckpointRecord{
+		SessionID: sessionID,
+		Changes:   changes,
+	}
+	if err := m.store.SaveCheckpoints(ctx, record, m.dateDirs[sessionID]); err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
+
+// CountTurns 统计对话轮次（不含工具结果的用户消息数量）
+func CountTurns(messages []llm.Message) int {
+	turns := 0
+	for _, msg := range messages {
+		if isTurnStart(msg) {
+			turns++
+		}
+	}
+	return turns
+}
+
+// MessagesBeforeTurn 返回指定轮次开始之前的消息（用于回退对话）
+func MessagesBeforeTurn(messages []llm.Message, turn int) []llm.Message {
+	turns := 0
+	for i, msg := range messages {
+		if isTurnStart(msg) {
+			turns++
+			if turns == turn {
+				return messages[:i]
+			}
+		}
+	}
+	return messages
+}
+
+// isTurnStart 判断消息是否为一轮对话的开始（用户输入而非工具结果）
+func isTurnStart(msg llm.Message) bool {
+	return msg.Role == llm.RoleUser && len(msg.ToolResults) == 0
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// newCheckpointState 创建包含指定用户轮次的状态
+func newCheckpointState(sessionID string, userTurns int) *agent.State {
+	state := agent.NewState()
+	state.SetMetadata("session_id", sessionID)
+	for i := 0; i < userTurns; i++ {
+		state.AddMessage(llm.Message{Role: llm.RoleUser, Content: "input"})
+		state.AddMessage(llm.Message{Role: llm.RoleAssistant, Content: "ok"})
+	}
+	// 最后一条为本轮的用户输入
+	state.Messages = state.Messages[:len(state.Messages)-1]
+	return state
+}
+
+func TestCheckpointMiddleware_RecordsTurnAndIteration(t *testing.T) {
+	ctx := context.Background()
+	workspace := backend.NewStateBackend()
+	snapshot := backend.NewSnapshotBackend(workspace)
+	m := NewCheckpointMiddleware(snapshot, nil)
+
+	if m.Name() != "checkpoint" {
+		t.Errorf("Expected name 'checkpoint', got %s", m.Name())
+	}
+
+	state := newCheckpointState("s1", 2)
+	m.BeforeAgent(ctx, state)
+	m.BeforeModel(ctx, &llm.ModelRequest{})
+	m.BeforeModel(ctx, &llm.ModelRequest{})
+	snapshot.WriteFile(ctx, "/a.txt", "hello")
+
+	changes := snapshot.Changes("s1")
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d", len(changes))
+	}
+	if changes[0].Turn != 2 || changes[0].Iteration != 2 {
+		t.Errorf("Expected position (2, 2), got (%d, %d)", changes[0].Turn, changes[0].Iteration)
+	}
+
+	turns := m.Turns()
+	if len(turns) != 1 || turns[0].Turn != 2 || turns[0].Files[0] != "/a.txt" {
+		t.Errorf("Unexpected turns: %+v", turns)
+	}
+}
+
+func TestCheckpointMiddleware_PersistAndRewindAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	store := NewSessionStore(backend.NewStateBackend())
+	workspace := backend.NewStateBackend()
+
+	// 第一个进程：两轮修改
+	snapshot := backend.NewSnapshotBackend(workspace)
+	m := NewCheckpointMiddleware(snapshot, store)
+
+	m.BeforeAgent(ctx, newCheckpointState("s1", 1))
+	m.BeforeModel(ctx, &llm.ModelRequest{})
+	snapshot.WriteFile(ctx, "/a.txt", "v1")
+	m.AfterTool(ctx, &llm.ToolResult{}, nil)
+	m.AfterAgent(ctx, nil)
+
+	m.BeforeAgent(ctx, newCheckpointState("s1", 2))
+	m.BeforeModel(ctx, &llm.ModelRequest{})
+	snapshot.EditFile(ctx, "/a.txt", "v1", "v2", false)
+	snapshot.WriteFile(ctx, "/b.txt", "new")
+	m.AfterAgent(ctx, nil)
+
+	// 第二个进程：从持久化记录恢复
+	restored := NewCheckpointMiddleware(backend.NewSnapshotBackend(workspace), store)
+	restored.BeforeAgent(ctx, newCheckpointState("s1", 3))
+
+	if len(restored.Turns()) != 2 {
+		t.Fatalf("Expected 2 turns after restore, got %d", len(restored.Turns()))
+	}
+
+	reverted, err := restored.Rewind(ctx, 2)
+	if err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if len(reverted) != 2 {
+		t.Errorf("Expected 2 reverted changes, got %d", len(reverted))
+	}
+
+	content, _ := workspace.ReadFile(ctx, "/a.txt", 0, 0)
+	if content != "v1" {
+		t.Errorf("Expected %q, got %q", "v1", content)
+	}
+	if _, err := workspace.ReadFile(ctx, "/b.txt", 0, 0); err == nil {
+		t.Error("Expected /b.txt to be removed")
+	}
+
+	// 回退结果同样应被持久化
+	record, _, err := store.LoadCheckpoints(ctx, "s1", 1)
+	if err != nil {
+		t.Fatalf("LoadCheckpoints failed: %v", err)
+	}
+	if len(record.Changes) != 1 {
+		t.Errorf("Expected 1 persisted change, got %d", len(record.Changes))
+	}
+}
+
+func TestCheckpointMiddleware_RewindInvalidTurn(t *testing.T) {
+	m := NewCheckpointMiddleware(backend.NewSnapshotBackend(backend.NewStateBackend()), nil)
+	if _, err := m.Rewind(context.Background(), 0); err == nil {
+		t.Error("Expected error for invalid turn")
+	}
+}
+
+func TestSessionStore_ListSessions_SkipsCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	fsBackend, err := backend.NewFilesystemBackend(t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("NewFilesystemBackend failed: %v", err)
+	}
+	store := NewSessionStore(fsBackend)
+	dateDir := "2026-01-01"
+
+	store.Save(ctx, &SessionRecord{SessionID: "abc"}, dateDir)
+	store.SaveCheckpoints(ctx, &CheckpointRecord{SessionID: "abc"}, dateDir)
+
+	files, _ := store.backend.ListFiles(ctx, "memory/sessions/"+dateDir)
+	count := 0
+	for _, f := range files {
+		if isSessionFile(f) {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 session file, got %d", count)
+	}
+}
+
+func TestMessagesBeforeTurn(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "first"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "1"}}},
+		{Role: llm.RoleUser, ToolResults: []llm.ToolResult{{ToolCallID: "1"}}},
+		{Role: llm.RoleAssistant, Content: "done"},
+		{Role: llm.RoleUser, Content: "second"},
+		{Role: llm.RoleAssistant, Content: "ok"},
+	}
+
+	if CountTurns(messages) != 2 {
+		t.Errorf("Expected 2 turns, got %d", CountTurns(messages))
+	}
+	if got := MessagesBeforeTurn(messages, 2); len(got) != 4 {
+		t.Errorf("Expected 4 messages before turn 2, got %d", len(got))
+	}
+	if got := MessagesBeforeTurn(messages, 1); len(got) != 0 {
+		t.Errorf("Expected 0 messages before turn 1, got %d", len(got))
+	}
+	if got := MessagesBeforeTurn(messages, 5); len(got) != len(messages) {
+		t.Errorf("Expected all messages for unknown turn, got %d", len(got))
+	}
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// TestMiddlewares_ConcurrentInvoke 多个会话并发使用同一个 Runnable，会话状态互不干扰（配合 -race 运行）
+func TestMiddlewares_ConcurrentInvoke(t *testing.T) {
+	fsBackend, err := backend.NewFilesystemBackend(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolRegistry := tools.NewRegistry()
+	store := NewSessionStore(fsBackend)
+	snapshot := backend.NewSnapshotBackend(fsBackend)
+	injector := NewContextInjectionMiddleware()
+	summarizer := NewSummarizationMiddleware(&SummarizationConfig{
+		MaxTokens: 1,
+		Enabled:   true,
+		LLMClient: &mockLLMClient{countTokensFunc: func([]llm.Message) int { return 100 }},
+	})
+	middlewares := []agent.Middleware{
+		NewFilesystemMiddleware(snapshot, toolRegistry),
+		injector,
+		NewHistoryMiddleware(store),
+		NewCheckpointMiddleware(snapshot, store),
+		NewTodoMiddlewareWithInjector(fsBackend, toolRegistry, injector),
+		summarizer,
+	}
+
+	// 首轮把任务写入 Todo 列表，并读取、编辑会话自己的文件，收到工具结果后结束
+	client := &mockLLMClient{generateFunc: func(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+		last := req.Messages[len(req.Messages)-1]
+		if len(last.ToolResults) > 0 {
+			for _, result := range last.ToolResults {
+				if result.IsError {
+					return nil, fmt.Errorf("tool %s failed: %s", result.ToolCallID, result.Content)
+				}
+			}
+			return &llm.ModelResponse{Content: "done", StopReason: "end_turn"}, nil
+		}
+		task, _, _ := strings.Cut(last.Content, "\n")
+		path := "/" + strings.TrimPrefix(task, "task for ") + ".txt"
+		return &llm.ModelResponse{
+			ToolCalls: []llm.ToolCall{
+				{ID: "call_1", Name: "write_todos", Input: map[string]any{
+					"todos": []any{map[string]any{"id": "1", "title": task, "status": "in_progress"}},
+				}},
+				{ID: "call_2", Name: "read_file", Input: map[string]any{"path": path}},
+				{ID: "call_3", Name: "edit_file", Input: map[string]any{"path": path, "old_string": "todo", "new_string": task}},
+			},
+			StopReason: "tool_use",
+		}, nil
+	}}
+	runnable := agent.NewRunnable(&agent.Config{
+		LLMClient:    client,
+		ToolRegistry: toolRegistry,
+		Middlewares:  middlewares,
+	})
+
+	const sessions = 8
+	ctx := context.Background()
+	for i := range sessions {
+		fsBackend.WriteFile(ctx, fmt.Sprintf("/session-%d.txt", i), "todo")
+	}
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sessionID := fmt.Sprintf("session-%d", i)
+			_, err := runnable.Invoke(ctx, &agent.InvokeInput{
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "task for " + sessionID}},
+				Metadata: map[string]any{"session_id": sessionID},
+			})
+			if err != nil {
+				t.Errorf("%s: %v", sessionID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range sessions {
+		sessionID := fmt.Sprintf("session-%d", i)
+
+		todos, err := fsBackend.ReadFile(ctx, "memory/todos/"+sessionID+".md", 0, 0)
+		if err != nil || !strings.Contains(todos, "task for "+sessionID) {
+			t.Errorf("%s: todo list not written to its own file: %q (%v)", sessionID, todos, err)
+		}
+		for j := range sessions {
+			if j != i && strings.Contains(todos, fmt.Sprintf("task for session-%d", j)) {
+				t.Errorf("%s: todo list contains another session's task", sessionID)
+			}
+		}
+
+		record, _, err := store.Load(ctx, sessionID, 1)
+		if err != nil {
+			t.Errorf("%s: session record not saved: %v", sessionID, err)
+			continue
+		}
+		if len(record.Messages) != 4 || record.Messages[0].Content != "task for "+sessionID {
+			t.Errorf("%s: unexpected session record: %+v", sessionID, record.Messages)
+		}
+
+		// 每个会话只记录自己的文件变更，位置为本会话的第 1 轮第 1 次迭代
+		changes := snapshot.Changes(sessionID)
+		if len(changes) != 1 || changes[0].Path != "/"+sessionID+".txt" || changes[0].Turn != 1 || changes[0].Iteration != 1 {
+			t.Errorf("%s: unexpected file changes: %+v", sessionID, changes)
+		}
+		checkpoints, _, err := store.LoadCheckpoints(ctx, sessionID, 1)
+		if err != nil || len(checkpoints.Changes) != 1 {
+			t.Errorf("%s: checkpoints not persisted: %+v (%v)", sessionID, checkpoints, err)
+		}
+	}
+
+	if got := summarizer.GetSummaryCount(); got != 2*sessions {
+		t.Errorf("expected %d summaries, got %d", 2*sessions, got)
+	}
+}
+
+// TestMiddlewares_InterleavedSessions 会话 B 在会话 A 读取文件之后、编辑之前开始执行，
+// A 的读取记录和快照位置不受 B 影响
+func TestMiddlewares_InterleavedSessions(t *testing.T) {
+	ctx := context.Background()
+	workspace := backend.NewStateBackend()
+	workspace.WriteFile(ctx, "/a.txt", "hello")
+	snapshot := backend.NewSnapshotBackend(workspace)
+	store := NewSessionStore(backend.NewStateBackend())
+	toolRegistry := tools.NewRegistry()
+
+	aRead := make(chan struct{})
+	bStarted := make(chan struct{})
+	client := &mockLLMClient{generateFunc: func(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+		last := req.Messages[len(req.Messages)-1]
+		for _, result := range last.ToolResults {
+			if result.IsError {
+				return nil, fmt.Errorf("tool %s failed: %s", result.ToolCallID, result.Content)
+			}
+		}
+
+		switch {
+		case req.Messages[0].Content == "b" && len(last.ToolResults) == 0:
+			close(bStarted)
+			return &llm.ModelResponse{ToolCalls: []llm.ToolCall{
+				{ID: "b_write", Name: "write_file", Input: map[string]any{"path": "/b.txt", "content": "b"}},
+			}, StopReason: "tool_use"}, nil
+		case req.Messages[0].Content == "a" && last.Content == "edit":
+			return &llm.ModelResponse{ToolCalls: []llm.ToolCall{
+				{ID: "a_read", Name: "read_file", Input: map[string]any{"path": "/a.txt"}},
+			}, StopReason: "tool_use"}, nil
+		case len(last.ToolResults) > 0 && last.ToolResults[0].ToolCallID == "a_read":
+			close(aRead)
+			<-bStarted
+			return &llm.ModelResponse{ToolCalls: []llm.ToolCall{
+				{ID: "a_edit", Name: "edit_file", Input: map[string]any{"path": "/a.txt", "old_string": "hello", "new_string": "hi"}},
+			}, StopReason: "tool_use"}, nil
+		}
+		return &llm.ModelResponse{Content: "done", StopReason: "end_turn"}, nil
+	}}
+	runnable := agent.NewRunnable(&agent.Config{
+		LLMClient:    client,
+		ToolRegistry: toolRegistry,
+		Middlewares: []agent.Middleware{
+			NewFilesystemMiddleware(snapshot, toolRegistry),
+			NewCheckpointMiddleware(snapshot, store),
+		},
+	})
+
+	invoke := func(sessionID string, messages ...string) error {
+		input := &agent.InvokeInput{Metadata: map[string]any{"session_id": sessionID}}
+		for i, content := range messages {
+			if i > 0 {
+				input.Messages = append(input.Messages, llm.Message{Role: llm.RoleAssistant, Content: "ok"})
+			}
+			input.Messages = append(input.Messages, llm.Message{Role: llm.RoleUser, Content: content})
+		}
+		_, err := runnable.Invoke(ctx, input)
+		return err
+	}
+
+	// A 处于第 3 轮，B 处于第 1 轮
+	errA := make(chan error, 1)
+	go func() { errA <- invoke("session-a", "a", "continue", "edit") }()
+	<-aRead
+	if err := invoke("session-b", "b"); err != nil {
+		t.Fatalf("session b: %v", err)
+	}
+	if err := <-errA; err != nil {
+		t.Fatalf("session a: %v", err)
+	}
+
+	changes := snapshot.Changes("session-a")
+	if len(changes) != 1 || changes[0].Path != "/a.txt" || changes[0].Turn != 3 || changes[0].Iteration != 2 {
+		t.Errorf("unexpected changes for session a: %+v", changes)
+	}
+	changes = snapshot.Changes("session-b")
+	if len(changes) != 1 || changes[0].Path != "/b.txt" || changes[0].Turn != 1 {
+		t.Errorf("unexpected changes for session b: %+v", changes)
+	}
+}
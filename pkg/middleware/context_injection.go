@@ -7,11 +7,13 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
 )
 
 // ContextInjectionMiddleware 上下文注入中间件
 // 用于在调用模型前隐形注入提醒和上下文信息
+// 执行期间通过 ForRun 添加的上下文块只注入到本次执行；直接添加的上下文块由下一次模型调用注入
 type ContextInjectionMiddleware struct {
 	*BaseMiddleware
 	mu            sync.Mutex
@@ -26,6 +28,17 @@ func NewContextInjectionMiddleware() *ContextInjectionMiddleware {
 	}
 }
 
+// ForRun 返回当前执行的上下文注入器，ctx 不属于任何执行时返回自身
+func (m *ContextInjectionMiddleware) ForRun(ctx context.Context) *ContextInjectionMiddleware {
+	if m == nil {
+		return nil
+	}
+	if scoped, ok := agent.RunLocal(ctx, m, NewContextInjectionMiddleware); ok {
+		return scoped
+	}
+	return m
+}
+
 // EnsureContextBlock 添加上下文块（去重）
 // 添加的内容会在下次调用模型时注入到系统提示词中
 func (m *ContextInjectionMiddleware) EnsureContextBlock(text string) {
@@ -71,10 +84,15 @@ func (m *ContextInjectionMiddleware) GetPendingBlocks() []string {
 
 // BeforeModel 在调用模型前注入上下文
 func (m *ContextInjectionMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if len(m.pendingBlocks) == 0 {
+	blocks := m.takePendingBlocks()
+	if scoped := m.ForRun(ctx); scoped != m {
+		for _, block := range scoped.takePendingBlocks() {
+			if !slices.Contains(blocks, block) {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+	if len(blocks) == 0 {
 		return nil
 	}
 
@@ -82,15 +100,22 @@ func (m *ContextInjectionMiddleware) BeforeModel(ctx context.Context, req *llm.M
 	var injection strings.Builder
 	injection.WriteString("\n\n")
 
-	for _, block := range m.pendingBlocks {
+	for _, block := range blocks {
 		fmt.Fprintf(&injection, "<system-reminder>\n%s\n</system-reminder>\n\n", block)
 	}
 
 	// 注入到最后一条消息末尾（靠近模型当前注意力焦点）
 	appendToLastMessage(req, injection.String())
 
-	// 清空 pending blocks（一次性注入）
-	m.pendingBlocks = make([]string, 0)
-
 	return nil
 }
+
+// takePendingBlocks 取出并清空待注入的上下文块（一次性注入）
+func (m *ContextInjectionMiddleware) takePendingBlocks() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blocks := m.pendingBlocks
+	m.pendingBlocks = make([]string, 0)
+	return blocks
+}
@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// maxDiagnostics 追加到工具结果中的最大诊断条数
+const maxDiagnostics = 20
+
+// defaultLintTimeout 单条诊断命令的默认超时（毫秒）
+const defaultLintTimeout = 60000
+
+// Diagnostic 一条诊断信息
+type Diagnostic struct {
+	Source  string // 诊断来源（如 gofmt、build、vet）
+	Message string // 诊断内容（通常包含 文件:行:列）
+}
+
+// Linter 语言诊断器，通过 backend.ExecuteArgs 按参数列表执行检查命令
+// 实现此接口即可为其他语言或工具接入编辑后诊断
+type Linter interface {
+	// Name 诊断器名称
+	Name() string
+	// Match 判断是否需要检查该文件
+	Match(path string) bool
+	// Lint 检查被修改的文件（路径为相对于命令执行目录的路径），无问题时返回空列表
+	Lint(ctx context.Context, b backend.Backend, paths []string) ([]Diagnostic, error)
+}
+
+// DiagnosticsMiddleware 编辑后诊断中间件
+// 文件修改类工具执行成功后，对被修改的文件运行匹配的诊断器，并将诊断结果追加到工具结果中，
+// 使模型在同一轮迭代中就能发现格式、编译和静态检查问题
+type DiagnosticsMiddleware struct {
+	*BaseMiddleware
+	backend backend.Backend
+	linters []Linter
+	pending map[string][]string // 工具调用 ID -> 被修改的文件
+	warned  map[string]bool     // 已输出过执行失败警告的诊断器
+	mu      sync.Mutex
+}
+
+// NewDiagnosticsMiddleware 创建编辑后诊断中间件，未指定诊断器时默认使用 Go 诊断器
+// 诊断命令通过 backend.ExecuteArgs 按参数列表执行（如 SandboxBackend 需要在 AllowedCommands 中允许 gofmt 和 go），
+// 后端未实现 backend.CommandExecutor 时降级为 Execute，此时路径不能包含空白
+func NewDiagnosticsMiddleware(b backend.Backend, linters ...Linter) *DiagnosticsMiddleware {
+	if len(linters) == 0 {
+		linters = []Linter{NewGoLinter()}
+	}
+	return &DiagnosticsMiddleware{
+		BaseMiddleware: NewBaseMiddleware("diagnostics"),
+		backend:        b,
+		linters:        linters,
+		pending:        make(map[string][]string),
+		warned:         make(map[string]bool),
+	}
+}
+
+// AddLinter 添加诊断器
+func (m *DiagnosticsMiddleware) AddLinter(l Linter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.linters = append(m.linters, l)
+}
+
+// bindBackend 创建作用于其他后端的诊断中间件（工作树隔离的子 Agent 使用）
+func (m *DiagnosticsMiddleware) bindBackend(b *backend.FilesystemBackend, _ *tools.Registry) agent.Middleware {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return NewDiagnosticsMiddleware(b, slices.Clone(m.linters)...)
+}
+
+// BeforeTool 记录文件修改类工具将要修改的文件
+func (m *DiagnosticsMiddleware) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *agent.State) error {
+	paths := editedPaths(toolCall)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[toolCall.ID] = paths
+	return nil
+}
+
+// AfterTool 工具执行成功后运行诊断，并将结果追加到工具结果中
+func (m *DiagnosticsMiddleware) AfterTool(ctx context.Context, result *llm.ToolResult, state *agent.State) error {
+	m.mu.Lock()
+	paths, ok := m.pending[result.ToolCallID]
+	delete(m.pending, result.ToolCallID)
+	linters := slices.Clone(m.linters)
+	m.mu.Unlock()
+
+	if !ok || result.IsError {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	for _, l := range linters {
+		var matched []string
+		for _, p := range paths {
+			if l.Match(p) {
+				matched = append(matched, lintPath(p))
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		found, err := l.Lint(ctx, m.backend, matched)
+		if err != nil {
+			m.warnOnce(l.Name(), err)
+			continue
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+
+	if len(diagnostics) > 0 {
+		result.Content += "\n\n" + formatDiagnostics(diagnostics)
+	}
+	return nil
+}
+
+// warnOnce 诊断器执行失败时（如后端不支持命令执行）只警告一次
+func (m *DiagnosticsMiddleware) warnOnce(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.warned[name] {
+		return
+	}
+	m.warned[name] = true
+	log.Printf("警告: 诊断器 %s 执行失败: %v", name, err)
+}
+
+// editedPaths 返回文件修改类工具调用涉及的文件
+func editedPaths(toolCall *llm.ToolCall) []string {
+	switch toolCall.Name {
+	case "write_file", "edit_file", "multi_edit":
+		if p, ok := toolCall.Input["path"].(string); ok && p != "" {
+			return []string{p}
+		}
+	case "apply_patch":
+		patch, _ := toolCall.Input["patch"].(string)
+		var paths []string
+		for _, line := range strings.Split(patch, "\n") {
+			if !strings.HasPrefix(line, "+++ ") {
+				continue
+			}
+			p := strings.TrimSpace(line[4:])
+			if idx := strings.IndexByte(p, '\t'); idx != -1 {
+				p = p[:idx]
+			}
+			if p == "/dev/null" {
+				continue
+			}
+			paths = append(paths, strings.TrimPrefix(p, "b/"))
+		}
+		return paths
+	}
+	return nil
+}
+
+// lintPath 将工具使用的路径转换为相对于命令执行目录的路径
+func lintPath(p string) string {
+	p = path.Clean(strings.TrimPrefix(p, "/"))
+	if p == "." || strings.HasPrefix(p, "../") {
+		return p
+	}
+	return "./" + p
+}
+
+// formatDiagnostics 格式化诊断结果（超出上限的部分省略）
+func formatDiagnostics(diagnostics []Diagnostic) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Diagnostics (%d issue(s) found, fix them before continuing):\n", len(diagnostics))
+	for i, d := range diagnostics {
+		if i == maxDiagnostics {
+			fmt.Fprintf(&sb, "... and %d more\n", len(diagnostics)-maxDiagnostics)
+			break
+		}
+		fmt.Fprintf(&sb, "[%s] %s\n", d.Source, d.Message)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// GoLinter Go 诊断器：依次运行 gofmt、go build 和 go vet
+type GoLinter struct {
+	Timeout int // 单条命令超时（毫秒），0 表示使用默认值
+}
+
+// NewGoLinter 创建 Go 诊断器
+func NewGoLinter() *GoLinter {
+	return &GoLinter{Timeout: defaultLintTimeout}
+}
+
+// Name 诊断器名称
+func (l *GoLinter) Name() string {
+	return "go"
+}
+
+// Match 只检查 .go 文件
+func (l *GoLinter) Match(p string) bool {
+	return strings.HasSuffix(p, ".go")
+}
+
+// Lint 检查格式和语法，通过后编译所在的包，编译通过后再运行 go vet
+func (l *GoLinter) Lint(ctx context.Context, b backend.Backend, paths []string) ([]Diagnostic, error) {
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = defaultLintTimeout
+	}
+
+	// 按参数列表执行，路径中可能包含空白
+	result, err := backend.ExecuteArgs(ctx, b, append([]string{"gofmt", "-l", "-e"}, paths...), timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	syntaxError := false
+	for _, line := range outputLines(result) {
+		if slices.Contains(paths, line) || slices.Contains(paths, "./"+line) {
+			diagnostics = append(diagnostics, Diagnostic{Source: "gofmt", Message: line + " is not gofmt-formatted"})
+		} else {
+			syntaxError = true
+			diagnostics = append(diagnostics, Diagnostic{Source: "gofmt", Message: line})
+		}
+	}
+	// 语法错误时编译和 vet 只会重复报告同样的问题
+	if syntaxError {
+		return diagnostics, nil
+	}
+
+	var pkgs []string
+	for _, p := range paths {
+		if dir := lintPath(path.Dir(p)); !slices.Contains(pkgs, dir) {
+			pkgs = append(pkgs, dir)
+		}
+	}
+
+	// 编译结果写入空设备，main 包不会在工作区留下可执行文件
+	steps := [][]string{
+		{"go", "build", "-o", os.DevNull},
+		{"go", "vet"},
+	}
+	for _, args := range steps {
+		step := args[1]
+		result, err := backend.ExecuteArgs(ctx, b, append(args, pkgs...), timeout)
+		if err != nil {
+			return nil, err
+		}
+		if result.ExitCode == 0 {
+			continue
+		}
+		for _, line := range outputLines(result) {
+			// 跳过 "# package" 标题行
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{Source: step, Message: line})
+		}
+		// 编译失败时 vet 会重复报告同样的错误
+		break
+	}
+	return diagnostics, nil
+}
+
+// outputLines 合并命令的 stdout 和 stderr 并按行拆分（去重、去除空行）
+func outputLines(result *backend.ExecuteResult) []string {
+	output := result.Stdout
+	if result.Stderr != "" && result.Stderr != result.Stdout {
+		output += "\n" + result.Stderr
+	}
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !slices.Contains(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// scriptedBackend 按命令前缀返回预设输出的后端
+type scriptedBackend struct {
+	*backend.StateBackend
+	outputs  map[string]*backend.ExecuteResult
+	commands []string
+}
+
+func (b *scriptedBackend) ExecuteArgs(ctx context.Context, args []string, timeout int) (*backend.ExecuteResult, error) {
+	command := strings.Join(args, " ")
+	b.commands = append(b.commands, command)
+	for prefix, result := range b.outputs {
+		if strings.HasPrefix(command, prefix) {
+			return result, nil
+		}
+	}
+	return &backend.ExecuteResult{}, nil
+}
+
+// runTool 模拟一次工具调用经过中间件的过程
+func runTool(t *testing.T, m *DiagnosticsMiddleware, call llm.ToolCall, content string) *llm.ToolResult {
+	t.Helper()
+	ctx := context.Background()
+	state := agent.NewState()
+	if err := m.BeforeTool(ctx, &call, state); err != nil {
+		t.Fatalf("BeforeTool failed: %v", err)
+	}
+	result := &llm.ToolResult{ToolCallID: call.ID, Content: content}
+	if err := m.AfterTool(ctx, result, state); err != nil {
+		t.Fatalf("AfterTool failed: %v", err)
+	}
+	return result
+}
+
+func TestDiagnosticsMiddleware_GoLinter(t *testing.T) {
+	b := &scriptedBackend{
+		StateBackend: backend.NewStateBackend(),
+		outputs: map[string]*backend.ExecuteResult{
+			"gofmt": {Stdout: "pkg/a/a.go\n", ExitCode: 0},
+			"go build": {
+				Stdout:   "# example.com/pkg/a\npkg/a/a.go:3:2: undefined: x\n",
+				ExitCode: 1,
+			},
+		},
+	}
+	m := NewDiagnosticsMiddleware(b)
+
+	result := runTool(t, m, llm.ToolCall{
+		ID:    "call_1",
+		Name:  "edit_file",
+		Input: map[string]any{"path": "/pkg/a/a.go"},
+	}, "Successfully replaced 1 occurrence(s)")
+
+	want := "Successfully replaced 1 occurrence(s)\n\nDiagnostics (2 issue(s) found, fix them before continuing):\n" +
+		"[gofmt] pkg/a/a.go is not gofmt-formatted\n" +
+		"[build] pkg/a/a.go:3:2: undefined: x"
+	if result.Content != want {
+		t.Errorf("Unexpected content:\n%s", result.Content)
+	}
+
+	// 编译失败时不再运行 vet
+	wantCommands := []string{"gofmt -l -e ./pkg/a/a.go", "go build -o " + os.DevNull + " ./pkg/a"}
+	if strings.Join(b.commands, "|") != strings.Join(wantCommands, "|") {
+		t.Errorf("Expected commands %v, got %v", wantCommands, b.commands)
+	}
+}
+
+func TestDiagnosticsMiddleware_SkipsUnrelated(t *testing.T) {
+	b := &scriptedBackend{StateBackend: backend.NewStateBackend()}
+	m := NewDiagnosticsMiddleware(b)
+
+	// 非 Go 文件、非编辑工具、执行失败的工具都不触发诊断
+	runTool(t, m, llm.ToolCall{ID: "1", Name: "write_file", Input: map[string]any{"path": "/README.md"}}, "ok")
+	runTool(t, m, llm.ToolCall{ID: "2", Name: "read_file", Input: map[string]any{"path": "/main.go"}}, "ok")
+
+	call := llm.ToolCall{ID: "3", Name: "write_file", Input: map[string]any{"path": "/main.go"}}
+	m.BeforeTool(context.Background(), &call, agent.NewState())
+	m.AfterTool(context.Background(), &llm.ToolResult{ToolCallID: "3", Content: "failed", IsError: true}, agent.NewState())
+
+	if len(b.commands) != 0 {
+		t.Errorf("Expected no commands, got %v", b.commands)
+	}
+
+	// 检查全部通过时不修改工具结果
+	result := runTool(t, m, llm.ToolCall{ID: "4", Name: "write_file", Input: map[string]any{"path": "main.go"}}, "ok")
+	if result.Content != "ok" {
+		t.Errorf("Expected unchanged content, got %q", result.Content)
+	}
+	if len(b.commands) != 3 {
+		t.Errorf("Expected gofmt, build and vet, got %v", b.commands)
+	}
+}
+
+func TestEditedPaths_ApplyPatch(t *testing.T) {
+	call := &llm.ToolCall{Name: "apply_patch", Input: map[string]any{"patch": "--- a/x.go\n+++ b/x.go\n@@ -1 +1 @@\n-a\n+b\n--- a/old.go\n+++ /dev/null\n--- /dev/null\n+++ b/new.go\t2024-01-01\n"}}
+	got := editedPaths(call)
+	if strings.Join(got, ",") != "x.go,new.go" {
+		t.Errorf("Unexpected paths: %v", got)
+	}
+}
+
+// customLinter 测试用的自定义诊断器
+type customLinter struct{}
+
+func (customLinter) Name() string           { return "yaml" }
+func (customLinter) Match(path string) bool { return strings.HasSuffix(path, ".yaml") }
+func (customLinter) Lint(ctx context.Context, b backend.Backend, paths []string) ([]Diagnostic, error) {
+	return []Diagnostic{{Source: "yamllint", Message: paths[0] + ": bad indentation"}}, nil
+}
+
+func TestDiagnosticsMiddleware_CustomLinter(t *testing.T) {
+	m := NewDiagnosticsMiddleware(&scriptedBackend{StateBackend: backend.NewStateBackend()})
+	m.AddLinter(customLinter{})
+
+	result := runTool(t, m, llm.ToolCall{ID: "1", Name: "multi_edit", Input: map[string]any{"path": "/config.yaml"}}, "ok")
+	if !strings.Contains(result.Content, "[yamllint] ./config.yaml: bad indentation") {
+		t.Errorf("Expected custom diagnostic, got %q", result.Content)
+	}
+}
+
+func TestDiagnosticsMiddleware_RealToolchain(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/diag\n\ngo 1.21\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "pkg"), 0755)
+	os.WriteFile(filepath.Join(dir, "pkg", "a.go"), []byte("package pkg\n\nfunc A() int {\n\treturn undefinedName\n}\n"), 0644)
+
+	cfg := backend.DefaultSandboxConfig(dir)
+	cfg.AllowedCommands = []string{"gofmt", "go"}
+	cfg.EnableAuditLog = false
+	sandbox, err := backend.NewSandboxBackend(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sandbox: %v", err)
+	}
+	m := NewDiagnosticsMiddleware(sandbox)
+
+	result := runTool(t, m, llm.ToolCall{ID: "1", Name: "write_file", Input: map[string]any{"path": "/pkg/a.go"}}, "ok")
+	if !strings.Contains(result.Content, "[build]") || !strings.Contains(result.Content, "undefinedName") {
+		t.Errorf("Expected build diagnostic, got:\n%s", result.Content)
+	}
+}
+
+func TestGoLinter_PathWithSpaces(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/diag\n\ngo 1.21\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "my pkg"), 0755)
+	os.WriteFile(filepath.Join(dir, "my pkg", "a.go"), []byte("package pkg\n\nfunc A() int {\n\treturn 1\n}\n"), 0644)
+
+	b, err := backend.NewFilesystemBackend(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, err := NewGoLinter().Lint(context.Background(), b, []string{"./my pkg/a.go"})
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	// 路径不会被拆分为多个参数，gofmt 不报告语法错误（go build 会如实报告包路径不合法）
+	for _, d := range diagnostics {
+		if d.Source == "gofmt" {
+			t.Errorf("Unexpected gofmt diagnostic for a clean file in a directory with spaces: %s", d.Message)
+		}
+	}
+}
+
+func TestGoLinter_MainPackageLeavesNoBinary(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/diag\n\ngo 1.21\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "cmd", "foo"), 0755)
+	os.WriteFile(filepath.Join(dir, "cmd", "foo", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+
+	b, err := backend.NewFilesystemBackend(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewDiagnosticsMiddleware(b)
+	result := runTool(t, m, llm.ToolCall{ID: "1", Name: "write_file", Input: map[string]any{"path": "/cmd/foo/main.go"}}, "ok")
+	if result.Content != "ok" {
+		t.Errorf("Expected no diagnostics, got:\n%s", result.Content)
+	}
+
+	// 编译 main 包不应在工作区留下可执行文件
+	var files []string
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if len(files) != 2 {
+		t.Errorf("Expected only go.mod and cmd/foo/main.go, got %v", files)
+	}
+}
@@ -16,6 +16,7 @@ type FilesystemMiddleware struct {
 	*BaseMiddleware
 	backend      backend.Backend
 	toolRegistry *tools.Registry
+	tracker      *tools.FileTracker // 按执行记录已读取的文件，防止过期写入
 }
 
 // NewFilesystemMiddleware 创建文件系统中间件
@@ -25,6 +26,7 @@ func NewFilesystemMiddleware(backend backend.Backend, toolRegistry *tools.Regist
 		backend:        backend,
 		toolRegistry:   toolRegistry,
 	}
+	m.tracker = tools.NewScopedFileTracker(m.runTracker)
 
 	// 注册文件系统工具
 	m.registerTools()
@@ -36,12 +38,47 @@ func NewFilesystemMiddleware(backend backend.Backend, toolRegistry *tools.Regist
 func (m *FilesystemMiddleware) registerTools() {
 	// 注册基础工具
 	m.toolRegistry.Register(tools.NewListFilesTool(m.backend))
-	m.toolRegistry.Register(tools.NewReadFileTool(m.backend))
-	m.toolRegistry.Register(tools.NewWriteFileTool(m.backend))
-	m.toolRegistry.Register(tools.NewEditFileTool(m.backend))
+	m.toolRegistry.Register(tools.NewReadFileToolWithTracker(m.backend, m.tracker))
+	m.toolRegistry.Register(tools.NewWriteFileToolWithTracker(m.backend, m.tracker))
+	m.toolRegistry.Register(tools.NewEditFileToolWithTracker(m.backend, m.tracker))
+	m.toolRegistry.Register(tools.NewMultiEditToolWithTracker(m.backend, m.tracker))
+	m.toolRegistry.Register(tools.NewApplyPatchToolWithTracker(m.backend, m.tracker))
 	m.toolRegistry.Register(tools.NewGrepTool(m.backend))
 	m.toolRegistry.Register(tools.NewGlobTool(m.backend))
+	m.toolRegistry.Register(tools.NewMoveFileToolWithTracker(m.backend, m.tracker))
+	m.toolRegistry.Register(tools.NewCopyFileToolWithTracker(m.backend, m.tracker))
+	m.toolRegistry.Register(tools.NewStatTool(m.backend))
+
+	// 创建目录需要后端原生支持
+	if _, ok := m.backend.(backend.FileManager); ok {
+		m.toolRegistry.Register(tools.NewMakeDirTool(m.backend))
+	}
 	m.toolRegistry.Register(tools.NewBashTool())
+
+	// 运行测试需要后端能按参数列表执行命令
+	if _, ok := m.backend.(backend.CommandExecutor); ok {
+		m.toolRegistry.Register(tools.NewTestTool(m.backend))
+	}
+}
+
+// bindBackend 创建作用于其他后端的文件系统中间件（工作树隔离的子 Agent 使用）
+func (m *FilesystemMiddleware) bindBackend(b *backend.FilesystemBackend, toolRegistry *tools.Registry) agent.Middleware {
+	return NewFilesystemMiddleware(b, toolRegistry)
+}
+
+// runTracker 返回 ctx 所属执行的文件读取记录，不属于任何执行时返回 nil
+func (m *FilesystemMiddleware) runTracker(ctx context.Context) *tools.FileTracker {
+	tracker, _ := agent.LookupRunLocal[*tools.FileTracker](ctx, m)
+	return tracker
+}
+
+// BeforeAgent 每次执行开始时创建新的文件读取记录
+// 子 Agent 复用主 Agent 的中间件时继承上级执行的记录，不单独创建
+func (m *FilesystemMiddleware) BeforeAgent(ctx context.Context, state *agent.State) error {
+	if _, ok := agent.LookupRunLocal[*tools.FileTracker](ctx, m); !ok {
+		agent.RunLocal(ctx, m, tools.NewFileTracker)
+	}
+	return nil
 }
 
 // AfterTool 在工具执行后检查结果大小
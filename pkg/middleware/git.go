@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// GitMiddleware Git 中间件
+// 注册结构化的 git 工具，命令在后端根目录下执行，破坏性操作需显式 override
+// 后端为 SnapshotBackend 时，修改工作区的操作（switch、stash push/pop/apply）会记录文件变更以支持回退
+type GitMiddleware struct {
+	*BaseMiddleware
+	backend      backend.Backend
+	toolRegistry *tools.Registry
+}
+
+// NewGitMiddleware 创建 Git 中间件
+func NewGitMiddleware(backend backend.Backend, toolRegistry *tools.Registry) *GitMiddleware {
+	m := &GitMiddleware{
+		BaseMiddleware: NewBaseMiddleware("git"),
+		backend:        backend,
+		toolRegistry:   toolRegistry,
+	}
+	m.registerTools()
+	return m
+}
+
+// registerTools 注册 git 工具
+func (m *GitMiddleware) registerTools() {
+	for _, tool := range tools.NewGitTools(m.backend) {
+		m.toolRegistry.Register(tool)
+	}
+}
+
+// bindBackend 创建作用于其他后端的 Git 中间件（工作树隔离的子 Agent 使用）
+func (m *GitMiddleware) bindBackend(b *backend.FilesystemBackend, toolRegistry *tools.Registry) agent.Middleware {
+	return NewGitMiddleware(b, toolRegistry)
+}
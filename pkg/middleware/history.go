@@ -12,16 +12,27 @@ import (
 )
 
 // HistoryMiddleware 自动记录对话历史到 JSON 文件
+// 按会话 ID 分别记录，同一实例可服务并发的多个会话；未指定 session_id 的嵌套执行（子 Agent）不记录
 type HistoryMiddleware struct {
 	*BaseMiddleware
-	store            *SessionStore
-	sessionID        string         // 会话 ID（UUID）
+	store    *SessionStore
+	sessions map[string]*historySession // 会话 ID -> 会话记录状态
+	current  string                     // 最近一次执行（或 SetResumeMode 指定）的会话 ID，供 REPL 命令使用
+	resumeID string                     // 待恢复的会话 ID
+	mu       sync.Mutex                 // 并发保护
+}
+
+// historySession 单个会话的记录状态
+type historySession struct {
 	sessionRecord    *SessionRecord // 当前会话记录
 	dateDir          string         // 会话创建时固定的日期目录（修复跨午夜漂移）
 	lastMessageCount int            // 上次记录的消息数量（用于跟踪新增消息）
 	pendingToolCalls int            // 待完成的工具调用数量
-	resumeMode       bool           // 是否为恢复模式
-	mu               sync.Mutex     // 并发保护
+}
+
+// historyRun 一次执行记录到的会话（为 nil 表示本次执行不记录）
+type historyRun struct {
+	session *historySession
 }
 
 // NewHistoryMiddleware 创建会话记录中间件
@@ -29,6 +40,7 @@ func NewHistoryMiddleware(store *SessionStore) *HistoryMiddleware {
 	return &HistoryMiddleware{
 		BaseMiddleware: NewBaseMiddleware("memory"),
 		store:          store,
+		sessions:       make(map[string]*historySession),
 	}
 }
 
@@ -37,41 +49,63 @@ func (m *HistoryMiddleware) GetSessionStore() *SessionStore {
 	return m.store
 }
 
+// session 返回本次执行记录到的会话（调用方持有锁）
+// 不属于任何执行时使用最近一次执行的会话
+func (m *HistoryMiddleware) session(ctx context.Context) *historySession {
+	if r, ok := agent.RunLocal(ctx, m, func() *historyRun { return &historyRun{} }); ok {
+		return r.session
+	}
+	return m.sessions[m.current]
+}
+
 // BeforeAgent 在 Agent 开始执行前初始化会话记录
 func (m *HistoryMiddleware) BeforeAgent(ctx context.Context, state *agent.State) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// 1. 从 state.Metadata 获取 session_id（由 CLI 传入）
-	if sessionID, ok := state.GetMetadata("session_id"); ok {
-		if sid, ok := sessionID.(string); ok {
-			m.sessionID = sid
+	sessionID := ""
+	if v, ok := state.GetMetadata("session_id"); ok {
+		sessionID, _ = v.(string)
+	}
+
+	if sessionID == "" {
+		// 子 Agent 的执行不记录到会话历史
+		if agent.SubAgentInfoFromContext(ctx) != nil {
+			return nil
 		}
+		// 如果没有 session_id，生成新的 UUID
+		sessionID = fmt.Sprintf("%d", time.Now().UnixNano())
+		state.SetMetadata("session_id", sessionID)
 	}
+	m.current = sessionID
 
-	// 如果没有 session_id，生成新的 UUID
-	if m.sessionID == "" {
-		m.sessionID = fmt.Sprintf("%d", time.Now().UnixNano())
-		state.SetMetadata("session_id", m.sessionID)
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		session = &historySession{}
+		m.sessions[sessionID] = session
+	}
+	if r, ok := agent.RunLocal(ctx, m, func() *historyRun { return &historyRun{} }); ok {
+		r.session = session
 	}
 
 	// 2. 如果是恢复模式，尝试加载已有会话
-	if m.resumeMode {
-		m.resumeMode = false // 无论成功与否，只执行一次
-		if m.loadExistingSession(ctx) {
+	if m.resumeID == sessionID {
+		m.resumeID = "" // 无论成功与否，只执行一次
+		if m.loadExistingSession(ctx, sessionID, session) {
 			return nil
 		}
 	}
 
 	// 3. 如果已有会话记录（非首次调用），跳过初始化
-	if m.sessionRecord != nil {
+	if session.sessionRecord != nil {
 		return nil
 	}
 
 	// 4. 初始化新会话，固定 dateDir
-	m.dateDir = time.Now().Format("2006-01-02")
-	m.sessionRecord = &SessionRecord{
-		SessionID: m.sessionID,
+	session.dateDir = time.Now().Format("2006-01-02")
+	session.sessionRecord = &SessionRecord{
+		SessionID: sessionID,
 		StartTime: time.Now().UTC().Format(time.RFC3339),
 		Messages:  make([]MessageLog, 0),
 	}
@@ -84,8 +118,13 @@ func (m *HistoryMiddleware) BeforeModel(ctx context.Context, req *llm.ModelReque
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	session := m.session(ctx)
+	if session == nil || session.sessionRecord == nil {
+		return nil
+	}
+
 	// 记录所有新增的 user 消息
-	for i := m.lastMessageCount; i < len(req.Messages); i++ {
+	for i := session.lastMessageCount; i < len(req.Messages); i++ {
 		msg := req.Messages[i]
 		if msg.Role == "user" {
 			msgLog := MessageLog{
@@ -103,12 +142,12 @@ func (m *HistoryMiddleware) BeforeModel(ctx context.Context, req *llm.ModelReque
 				})
 			}
 
-			m.sessionRecord.Messages = append(m.sessionRecord.Messages, msgLog)
+			session.sessionRecord.Messages = append(session.sessionRecord.Messages, msgLog)
 		}
 	}
 
 	// 更新消息计数
-	m.lastMessageCount = len(req.Messages)
+	session.lastMessageCount = len(req.Messages)
 
 	return nil
 }
@@ -118,6 +157,11 @@ func (m *HistoryMiddleware) AfterModel(ctx context.Context, resp *llm.ModelRespo
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	session := m.session(ctx)
+	if session == nil || session.sessionRecord == nil {
+		return nil
+	}
+
 	// 1. 创建 assistant 消息
 	assistantMsg := MessageLog{
 		Role:       "assistant",
@@ -139,14 +183,14 @@ func (m *HistoryMiddleware) AfterModel(ctx context.Context, resp *llm.ModelRespo
 	}
 
 	// 3. 添加到消息列表
-	m.sessionRecord.Messages = append(m.sessionRecord.Messages, assistantMsg)
+	session.sessionRecord.Messages = append(session.sessionRecord.Messages, assistantMsg)
 
 	// 4. 设置待完成的工具调用数量
-	m.pendingToolCalls = len(resp.ToolCalls)
+	session.pendingToolCalls = len(resp.ToolCalls)
 
 	// 5. 如果没有工具调用，立即持久化（不重置 lastMessageCount）
-	if m.pendingToolCalls == 0 {
-		if err := m.persistSession(ctx); err != nil {
+	if session.pendingToolCalls == 0 {
+		if err := m.persistSession(ctx, session); err != nil {
 			log.Printf("警告: 持久化会话记录失败: %v", err)
 		}
 	}
@@ -159,15 +203,16 @@ func (m *HistoryMiddleware) AfterTool(ctx context.Context, result *llm.ToolResul
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if len(m.sessionRecord.Messages) == 0 {
+	session := m.session(ctx)
+	if session == nil || session.sessionRecord == nil || len(session.sessionRecord.Messages) == 0 {
 		return nil
 	}
 
 	// 1. 找到最后一条 assistant 消息（包含工具调用）
 	var lastAssistantMsg *MessageLog
-	for i := len(m.sessionRecord.Messages) - 1; i >= 0; i-- {
-		if m.sessionRecord.Messages[i].Role == "assistant" {
-			lastAssistantMsg = &m.sessionRecord.Messages[i]
+	for i := len(session.sessionRecord.Messages) - 1; i >= 0; i-- {
+		if session.sessionRecord.Messages[i].Role == "assistant" {
+			lastAssistantMsg = &session.sessionRecord.Messages[i]
 			break
 		}
 	}
@@ -187,11 +232,11 @@ func (m *HistoryMiddleware) AfterTool(ctx context.Context, result *llm.ToolResul
 	}
 
 	// 3. 减少待完成的工具调用数量
-	m.pendingToolCalls--
+	session.pendingToolCalls--
 
 	// 4. 如果所有工具都执行完毕，持久化（不重置 lastMessageCount）
-	if m.pendingToolCalls <= 0 {
-		if err := m.persistSession(ctx); err != nil {
+	if session.pendingToolCalls <= 0 {
+		if err := m.persistSession(ctx, session); err != nil {
 			log.Printf("警告: 持久化会话记录失败: %v", err)
 		}
 	}
@@ -200,31 +245,31 @@ func (m *HistoryMiddleware) AfterTool(ctx context.Context, result *llm.ToolResul
 }
 
 // persistSession 持久化会话记录到文件（使用固定的 dateDir）
-func (m *HistoryMiddleware) persistSession(ctx context.Context) error {
-	m.sessionRecord.EndTime = time.Now().UTC().Format(time.RFC3339)
-	return m.store.Save(ctx, m.sessionRecord, m.dateDir)
+func (m *HistoryMiddleware) persistSession(ctx context.Context, session *historySession) error {
+	session.sessionRecord.EndTime = time.Now().UTC().Format(time.RFC3339)
+	return m.store.Save(ctx, session.sessionRecord, session.dateDir)
 }
 
 // loadExistingSession 尝试加载已有的会话记录
-func (m *HistoryMiddleware) loadExistingSession(ctx context.Context) bool {
-	record, dateDir, err := m.store.Load(ctx, m.sessionID, 30)
+func (m *HistoryMiddleware) loadExistingSession(ctx context.Context, sessionID string, session *historySession) bool {
+	record, dateDir, err := m.store.Load(ctx, sessionID, 30)
 	if err != nil {
 		return false
 	}
 
-	m.sessionRecord = record
-	m.dateDir = dateDir
-	m.lastMessageCount = len(record.Messages)
+	session.sessionRecord = record
+	session.dateDir = dateDir
+	session.lastMessageCount = len(record.Messages)
 	return true
 }
 
-// SetResumeMode 设置为恢复模式（由 REPL 调用）
+// SetResumeMode 设置为恢复模式（由 REPL 调用），下次以该会话 ID 执行时加载已有会话
 func (m *HistoryMiddleware) SetResumeMode(sessionID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.sessionID = sessionID
-	m.resumeMode = true
+	m.current = sessionID
+	m.resumeID = sessionID
 }
 
 // ClearSession 清除当前会话状态（由 REPL /clear 调用）
@@ -232,12 +277,28 @@ func (m *HistoryMiddleware) ClearSession() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.sessionRecord = nil
-	m.sessionID = ""
-	m.dateDir = ""
-	m.lastMessageCount = 0
-	m.pendingToolCalls = 0
-	m.resumeMode = false
+	delete(m.sessions, m.current)
+	m.current = ""
+	m.resumeID = ""
+}
+
+// TruncateSession 将当前会话记录截断为前 messageCount 条消息并持久化（由 REPL /rewind 调用）
+func (m *HistoryMiddleware) TruncateSession(ctx context.Context, messageCount int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session := m.sessions[m.current]
+	if session == nil || session.sessionRecord == nil {
+		return nil
+	}
+
+	if messageCount < len(session.sessionRecord.Messages) {
+		session.sessionRecord.Messages = session.sessionRecord.Messages[:messageCount]
+	}
+	session.lastMessageCount = messageCount
+	session.pendingToolCalls = 0
+
+	return m.persistSession(ctx, session)
 }
 
 // LoadSessionMessages 加载会话的历史消息（用于恢复对话上下文）
@@ -246,19 +307,24 @@ func (m *HistoryMiddleware) LoadSessionMessages(ctx context.Context) ([]llm.Mess
 	defer m.mu.Unlock()
 
 	// 如果 sessionRecord 尚未加载，主动从文件加载
-	if m.sessionRecord == nil {
-		if m.sessionID == "" {
-			return nil, fmt.Errorf("会话 ID 未设置")
-		}
-		if !m.loadExistingSession(ctx) {
-			return nil, fmt.Errorf("未找到会话: %s", m.sessionID)
+	if m.current == "" {
+		return nil, fmt.Errorf("会话 ID 未设置")
+	}
+	session, ok := m.sessions[m.current]
+	if !ok {
+		session = &historySession{}
+	}
+	if session.sessionRecord == nil {
+		if !m.loadExistingSession(ctx, m.current, session) {
+			return nil, fmt.Errorf("未找到会话: %s", m.current)
 		}
+		m.sessions[m.current] = session
 	}
 
-	messages := make([]llm.Message, 0, len(m.sessionRecord.Messages))
+	messages := make([]llm.Message, 0, len(session.sessionRecord.Messages))
 
 	// 从 SessionRecord 重建消息列表
-	for _, msg := range m.sessionRecord.Messages {
+	for _, msg := range session.sessionRecord.Messages {
 		var role llm.Role
 		switch msg.Role {
 		case "user":
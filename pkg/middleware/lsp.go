@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/lsp"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// LSPMiddleware 语言服务器中间件
+// 注册代码导航工具（定义、引用、类型信息、符号、诊断），并在文件工具修改文件后同步给语言服务器
+type LSPMiddleware struct {
+	*BaseMiddleware
+	manager      *lsp.Manager
+	toolRegistry *tools.Registry
+	pending      map[string][]string // 工具调用 ID -> 被修改的文件
+	mu           sync.Mutex
+}
+
+// NewLSPMiddleware 创建语言服务器中间件
+func NewLSPMiddleware(manager *lsp.Manager, toolRegistry *tools.Registry) *LSPMiddleware {
+	m := &LSPMiddleware{
+		BaseMiddleware: NewBaseMiddleware("lsp"),
+		manager:        manager,
+		toolRegistry:   toolRegistry,
+		pending:        make(map[string][]string),
+	}
+	m.registerTools()
+	return m
+}
+
+// registerTools 注册代码导航工具
+func (m *LSPMiddleware) registerTools() {
+	m.toolRegistry.Register(tools.NewLSPDefinitionTool(m.manager))
+	m.toolRegistry.Register(tools.NewLSPReferencesTool(m.manager))
+	m.toolRegistry.Register(tools.NewLSPHoverTool(m.manager))
+	m.toolRegistry.Register(tools.NewLSPDocumentSymbolsTool(m.manager))
+	m.toolRegistry.Register(tools.NewLSPWorkspaceSymbolsTool(m.manager))
+	m.toolRegistry.Register(tools.NewLSPDiagnosticsTool(m.manager))
+}
+
+// bindBackend 创建以其他后端根目录为工作区的语言服务器中间件（工作树隔离的子 Agent 使用）
+// 新中间件启动独立的语言服务器，使用完毕后需调用 Close
+func (m *LSPMiddleware) bindBackend(b *backend.FilesystemBackend, toolRegistry *tools.Registry) agent.Middleware {
+	cfg := m.manager.Config()
+	cfg.RootDir = b.RootDir()
+	cfg.VirtualPaths = b.VirtualMode()
+	return NewLSPMiddleware(lsp.NewManager(cfg), toolRegistry)
+}
+
+// BeforeTool 记录将要被修改的文件
+func (m *LSPMiddleware) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *agent.State) error {
+	paths := editedPaths(toolCall)
+	if toolCall.Name == "move_file" || toolCall.Name == "copy_file" {
+		for _, key := range []string{"source", "destination"} {
+			if p, ok := toolCall.Input[key].(string); ok && p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[toolCall.ID] = paths
+	return nil
+}
+
+// AfterTool 将被修改的文件同步给语言服务器，使后续查询和诊断基于最新内容
+func (m *LSPMiddleware) AfterTool(ctx context.Context, result *llm.ToolResult, state *agent.State) error {
+	m.mu.Lock()
+	paths, ok := m.pending[result.ToolCallID]
+	delete(m.pending, result.ToolCallID)
+	m.mu.Unlock()
+
+	if !ok || result.IsError {
+		return nil
+	}
+	for _, p := range paths {
+		m.manager.Sync(ctx, p)
+	}
+	return nil
+}
+
+// Close 关闭所有语言服务器
+func (m *LSPMiddleware) Close() error {
+	return m.manager.Close()
+}
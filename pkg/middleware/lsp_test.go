@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/lsp"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+func TestLSPMiddleware_RegistersTools(t *testing.T) {
+	registry := tools.NewRegistry()
+	m := NewLSPMiddleware(lsp.NewManager(lsp.Config{RootDir: t.TempDir()}), registry)
+	defer m.Close()
+
+	for _, name := range []string{
+		"lsp_definition", "lsp_references", "lsp_hover",
+		"lsp_document_symbols", "lsp_workspace_symbols", "lsp_diagnostics",
+	} {
+		if _, ok := registry.Get(name); !ok {
+			t.Errorf("expected tool %s to be registered", name)
+		}
+	}
+}
+
+func TestLSPMiddleware_TracksEditedFiles(t *testing.T) {
+	m := NewLSPMiddleware(lsp.NewManager(lsp.Config{RootDir: t.TempDir()}), tools.NewRegistry())
+	defer m.Close()
+	ctx := context.Background()
+
+	calls := []*llm.ToolCall{
+		{ID: "1", Name: "write_file", Input: map[string]any{"path": "/a.go"}},
+		{ID: "2", Name: "move_file", Input: map[string]any{"source": "/b.go", "destination": "/c.go"}},
+		{ID: "3", Name: "read_file", Input: map[string]any{"path": "/a.go"}},
+	}
+	for _, call := range calls {
+		if err := m.BeforeTool(ctx, call, nil); err != nil {
+			t.Fatalf("BeforeTool failed: %v", err)
+		}
+	}
+
+	if got := m.pending["2"]; len(got) != 2 || got[0] != "/b.go" || got[1] != "/c.go" {
+		t.Errorf("unexpected pending paths for move_file: %v", got)
+	}
+	if _, ok := m.pending["3"]; ok {
+		t.Error("read_file should not be tracked")
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if err := m.AfterTool(ctx, &llm.ToolResult{ToolCallID: id}, nil); err != nil {
+			t.Fatalf("AfterTool failed: %v", err)
+		}
+	}
+	if len(m.pending) != 0 {
+		t.Errorf("expected pending to be drained, got %v", m.pending)
+	}
+}
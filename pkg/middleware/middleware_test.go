@@ -26,7 +26,7 @@ func TestFilesystemMiddleware_RegistersTools(t *testing.T) {
 	NewFilesystemMiddleware(backend, toolRegistry)
 
 	// 验证工具已注册
-	expectedTools := []string{"ls", "read_file", "write_file", "edit_file", "grep", "glob"}
+	expectedTools := []string{"ls", "read_file", "write_file", "edit_file", "multi_edit", "apply_patch", "grep", "glob", "bash"}
 
 	for _, toolName := range expectedTools {
 		_, ok := toolRegistry.Get(toolName)
@@ -36,6 +36,109 @@ func TestFilesystemMiddleware_RegistersTools(t *testing.T) {
 	}
 }
 
+func TestFilesystemMiddleware_RegistersTestToolForCommandBackends(t *testing.T) {
+	// StateBackend 不能执行命令，不注册 run_tests
+	toolRegistry := tools.NewRegistry()
+	NewFilesystemMiddleware(backend.NewStateBackend(), toolRegistry)
+	if _, ok := toolRegistry.Get("run_tests"); ok {
+		t.Error("Expected run_tests not to be registered for backend without CommandExecutor")
+	}
+
+	fsBackend, err := backend.NewFilesystemBackend(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolRegistry = tools.NewRegistry()
+	NewFilesystemMiddleware(fsBackend, toolRegistry)
+	if _, ok := toolRegistry.Get("run_tests"); !ok {
+		t.Error("Expected run_tests to be registered for FilesystemBackend")
+	}
+}
+
+func TestFilesystemMiddleware_RegistersFileManagementTools(t *testing.T) {
+	// StateBackend 实现了 FileManager，注册全部文件管理工具
+	toolRegistry := tools.NewRegistry()
+	NewFilesystemMiddleware(backend.NewStateBackend(), toolRegistry)
+	for _, toolName := range []string{"move_file", "copy_file", "stat", "make_dir"} {
+		if _, ok := toolRegistry.Get(toolName); !ok {
+			t.Errorf("Expected tool %q to be registered", toolName)
+		}
+	}
+
+	// SQLiteBackend 不支持创建目录
+	sqliteBackend, err := backend.NewSQLiteBackend(":memory:", "test")
+	if err != nil {
+		t.Fatalf("NewSQLiteBackend failed: %v", err)
+	}
+	defer sqliteBackend.Close()
+
+	toolRegistry = tools.NewRegistry()
+	NewFilesystemMiddleware(sqliteBackend, toolRegistry)
+	if _, ok := toolRegistry.Get("make_dir"); ok {
+		t.Error("Expected make_dir not to be registered for backend without FileManager")
+	}
+	if _, ok := toolRegistry.Get("move_file"); !ok {
+		t.Error("Expected move_file to be registered")
+	}
+}
+
+// scriptedToolClient 按首条用户消息选择脚本，依次发出脚本中每一步的工具调用，全部完成后结束
+func scriptedToolClient(scripts map[string][][]llm.ToolCall) *mockLLMClient {
+	return &mockLLMClient{generateFunc: func(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+		step := 0
+		for _, msg := range req.Messages {
+			if len(msg.ToolResults) > 0 {
+				step++
+			}
+		}
+		script := scripts[req.Messages[0].Content]
+		if step >= len(script) {
+			return &llm.ModelResponse{Content: "done", StopReason: "end_turn"}, nil
+		}
+		return &llm.ModelResponse{ToolCalls: script[step], StopReason: "tool_use"}, nil
+	}}
+}
+
+func TestFilesystemMiddleware_ReadTrackingPerRun(t *testing.T) {
+	fsBackend := backend.NewStateBackend()
+	toolRegistry := tools.NewRegistry()
+	ctx := context.Background()
+	fsBackend.WriteFile(ctx, "/a.txt", "hello")
+
+	edit := func(id, oldStr, newStr string) llm.ToolCall {
+		return llm.ToolCall{ID: id, Name: "edit_file", Input: map[string]any{"path": "/a.txt", "old_string": oldStr, "new_string": newStr}}
+	}
+	runnable := agent.NewRunnable(&agent.Config{
+		LLMClient: scriptedToolClient(map[string][][]llm.ToolCall{
+			"read and edit": {
+				{{ID: "read", Name: "read_file", Input: map[string]any{"path": "/a.txt"}}},
+				{edit("edit", "hello", "hi")},
+			},
+			"edit only": {{edit("edit", "hi", "hey")}},
+		}),
+		ToolRegistry: toolRegistry,
+		Middlewares:  []agent.Middleware{NewFilesystemMiddleware(fsBackend, toolRegistry)},
+	})
+
+	lastResult := func(task string) llm.ToolResult {
+		output, err := runnable.Invoke(ctx, &agent.InvokeInput{Messages: []llm.Message{{Role: llm.RoleUser, Content: task}}})
+		if err != nil {
+			t.Fatalf("Invoke failed: %v", err)
+		}
+		results := output.Messages[len(output.Messages)-2].ToolResults
+		return results[len(results)-1]
+	}
+
+	if result := lastResult("read and edit"); result.IsError {
+		t.Fatalf("Expected edit to succeed within the same run: %s", result.Content)
+	}
+
+	// 新的执行需要重新读取
+	if result := lastResult("edit only"); !result.IsError {
+		t.Error("Expected edit to fail in a new run without reading")
+	}
+}
+
 func TestFilesystemMiddleware_AfterTool_SmallResult(t *testing.T) {
 	backend := backend.NewStateBackend()
 	toolRegistry := tools.NewRegistry()
@@ -129,3 +232,14 @@ func TestBaseMiddleware_DefaultImplementations(t *testing.T) {
 		t.Errorf("AfterTool should return nil, got %v", err)
 	}
 }
+
+func TestGitMiddleware_RegistersTools(t *testing.T) {
+	toolRegistry := tools.NewRegistry()
+	NewGitMiddleware(backend.NewStateBackend(), toolRegistry)
+
+	for _, name := range []string{"git_status", "git_diff", "git_log", "git_show", "git_blame", "git_branch", "git_add", "git_commit", "git_stash", "git_worktree"} {
+		if _, ok := toolRegistry.Get(name); !ok {
+			t.Errorf("Expected tool %q to be registered", name)
+		}
+	}
+}
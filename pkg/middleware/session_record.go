@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
 )
 
 // SessionRecord 会话记录（顶层结构）
@@ -66,6 +68,12 @@ type ToolResultLog struct {
 	IsError    bool   `json:"is_error"`     // 是否错误
 }
 
+// CheckpointRecord 文件快照记录（与会话记录一同持久化，用于回退工作区）
+type CheckpointRecord struct {
+	SessionID string               `json:"session_id"` // 对应的会话 ID
+	Changes   []backend.FileChange `json:"changes"`    // 按发生顺序排列的文件变更
+}
+
 // ParseSessionRecord 从 JSON 字符串解析会话记录
 func ParseSessionRecord(data string) (*SessionRecord, error) {
 	var record SessionRecord
@@ -96,7 +96,7 @@ func (s *SessionStore) ListSessions(ctx context.Context, days int) ([]*SessionIn
 		}
 
 		for _, file := range files {
-			if file.IsDir || !strings.HasSuffix(file.Path, ".json") {
+			if !isSessionFile(file) {
 				continue
 			}
 
@@ -131,7 +131,7 @@ func (s *SessionStore) FindSession(ctx context.Context, idPrefix string, days in
 		}
 
 		for _, file := range files {
-			if file.IsDir || !strings.HasSuffix(file.Path, ".json") {
+			if !isSessionFile(file) {
 				continue
 			}
 
@@ -159,3 +159,55 @@ func (s *SessionStore) FindSession(ctx context.Context, idPrefix string, days in
 
 	return matched, nil
 }
+
+// checkpointSuffix 文件快照记录的文件名后缀（与会话记录存放在同一目录）
+const checkpointSuffix = ".checkpoints.json"
+
+// isSessionFile 判断是否为会话记录文件（排除快照记录）
+func isSessionFile(file backend.FileInfo) bool {
+	if file.IsDir || !strings.HasSuffix(file.Path, ".json") {
+		return false
+	}
+	return !strings.HasSuffix(file.Path, checkpointSuffix)
+}
+
+// SaveCheckpoints 保存文件快照记录（与会话记录存放在同一目录）
+func (s *SessionStore) SaveCheckpoints(ctx context.Context, record *CheckpointRecord, dateDir string) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化快照记录失败: %w", err)
+	}
+
+	filePath := fmt.Sprintf("memory/sessions/%s/%s%s", dateDir, record.SessionID, checkpointSuffix)
+
+	_, err = s.backend.WriteFile(ctx, filePath, string(data))
+	if err != nil {
+		return fmt.Errorf("写入快照记录失败: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCheckpoints 按会话 ID 加载文件快照记录，搜索最近 searchDays 天的目录
+// 返回快照记录和所在的 dateDir
+func (s *SessionStore) LoadCheckpoints(ctx context.Context, sessionID string, searchDays int) (*CheckpointRecord, string, error) {
+	now := time.Now()
+	for i := 0; i < searchDays; i++ {
+		dateDir := now.AddDate(0, 0, -i).Format("2006-01-02")
+		filePath := fmt.Sprintf("memory/sessions/%s/%s%s", dateDir, sessionID, checkpointSuffix)
+
+		content, err := s.backend.ReadFile(ctx, filePath, 0, 0)
+		if err != nil {
+			continue
+		}
+
+		var record CheckpointRecord
+		if err := json.Unmarshal([]byte(content), &record); err != nil {
+			continue
+		}
+
+		return &record, dateDir, nil
+	}
+
+	return nil, "", fmt.Errorf("未找到快照记录: %s", sessionID)
+}
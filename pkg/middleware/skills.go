@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
@@ -22,6 +24,8 @@ type SkillsMiddleware struct {
 	skillPaths   map[string]string // 技能名称 -> 技能目录路径
 	toolRegistry *tools.Registry
 	loadedSkills map[string]bool // 已加载完整内容的技能
+	defaultOnce  sync.Once       // 默认技能目录只在首次执行时加载
+	mu           sync.RWMutex    // 保护技能列表（执行期间可能并发访问）
 }
 
 // Skill 表示一个技能（对齐 Claude Code 格式）
@@ -79,14 +83,16 @@ func (m *SkillsMiddleware) registerSkillTool() {
 			if skill == nil {
 				var available strings.Builder
 				fmt.Fprintf(&available, "技能 '%s' 不存在。\n\n可用技能：\n", skillName)
-				for _, s := range m.skills {
+				for _, s := range m.GetSkills() {
 					fmt.Fprintf(&available, "- %s: %s\n", s.Name, s.Description)
 				}
 				return available.String(), nil
 			}
 
 			// 标记技能已加载
+			m.mu.Lock()
 			m.loadedSkills[skillName] = true
+			m.mu.Unlock()
 
 			// 返回技能内容和基础路径
 			var result strings.Builder
@@ -101,6 +107,9 @@ func (m *SkillsMiddleware) registerSkillTool() {
 
 // buildSkillToolDescription 构建 Skill 工具的描述（包含可用技能列表）
 func (m *SkillsMiddleware) buildSkillToolDescription() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	var desc strings.Builder
 	desc.WriteString(`激活一个技能，获取该技能的详细指令和领域知识。
 
@@ -154,8 +163,10 @@ func (m *SkillsMiddleware) LoadFromDirectory(dir string) error {
 			continue // 跳过无效的技能
 		}
 
+		m.mu.Lock()
 		m.skills = append(m.skills, *skill)
 		m.skillPaths[skill.Name] = skillDir
+		m.mu.Unlock()
 	}
 
 	// 更新工具描述
@@ -215,10 +226,12 @@ func (m *SkillsMiddleware) parseSkill(content, basePath string) (*Skill, error)
 
 // AddSkill 添加技能
 func (m *SkillsMiddleware) AddSkill(skill Skill) {
+	m.mu.Lock()
 	m.skills = append(m.skills, skill)
 	if skill.BasePath != "" {
 		m.skillPaths[skill.Name] = skill.BasePath
 	}
+	m.mu.Unlock()
 
 	// 更新工具描述
 	if m.toolRegistry != nil {
@@ -229,14 +242,19 @@ func (m *SkillsMiddleware) AddSkill(skill Skill) {
 
 // GetSkills 获取所有技能
 func (m *SkillsMiddleware) GetSkills() []Skill {
-	return m.skills
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.skills)
 }
 
 // GetSkillByName 根据名称获取技能
 func (m *SkillsMiddleware) GetSkillByName(name string) *Skill {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for i := range m.skills {
 		if m.skills[i].Name == name {
-			return &m.skills[i]
+			skill := m.skills[i]
+			return &skill
 		}
 	}
 	return nil
@@ -244,30 +262,35 @@ func (m *SkillsMiddleware) GetSkillByName(name string) *Skill {
 
 // IsSkillLoaded 检查技能是否已加载完整内容
 func (m *SkillsMiddleware) IsSkillLoaded(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.loadedSkills[name]
 }
 
-// BeforeAgent 在 Agent 开始前加载默认技能
+// BeforeAgent 在首次执行前加载默认技能
 func (m *SkillsMiddleware) BeforeAgent(ctx context.Context, state *agent.State) error {
-	// 按优先级加载技能目录
-	// 1. 项目级技能（最高优先级）
-	// 2. 用户级技能
-	skillDirs := []string{
-		"skills",
-	}
+	m.defaultOnce.Do(func() {
+		// 按优先级加载技能目录
+		// 1. 项目级技能（最高优先级）
+		// 2. 用户级技能
+		skillDirs := []string{
+			"skills",
+		}
 
-	for _, dir := range skillDirs {
-		if _, err := os.Stat(dir); err == nil {
-			m.LoadFromDirectory(dir)
+		for _, dir := range skillDirs {
+			if _, err := os.Stat(dir); err == nil {
+				m.LoadFromDirectory(dir)
+			}
 		}
-	}
+	})
 
 	return nil
 }
 
 // BeforeModel 在调用模型前注入轻量技能列表到系统提示
 func (m *SkillsMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
-	if len(m.skills) == 0 {
+	skills := m.GetSkills()
+	if len(skills) == 0 {
 		return nil
 	}
 
@@ -276,7 +299,7 @@ func (m *SkillsMiddleware) BeforeModel(ctx context.Context, req *llm.ModelReques
 	skillsList.WriteString("\n\n## 可用技能\n\n")
 	skillsList.WriteString("以下技能可通过 `Skill` 工具激活，获取详细指令和领域知识：\n\n")
 
-	for _, skill := range m.skills {
+	for _, skill := range skills {
 		fmt.Fprintf(&skillsList, "- **%s**: %s\n", skill.Name, skill.Description)
 	}
 
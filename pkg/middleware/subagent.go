@@ -3,6 +3,12 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"log"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/zhoucx/deepagents-go/pkg/agent"
 	"github.com/zhoucx/deepagents-go/pkg/llm"
@@ -11,7 +17,30 @@ import (
 
 // SubAgentConfig 子Agent配置
 type SubAgentConfig struct {
-	MaxDepth int // 最大递归深度，0表示不限制
+	MaxDepth       int                  // 最大递归深度，0表示不限制
+	Worktree       *WorktreeConfig      // 非 nil 时每个子Agent在独立的 git 工作树中工作
+	SubAgents      []SubAgentDefinition // 命名子Agent类型
+	MaxConcurrency int                  // delegate_tasks 同时运行的子Agent数，0 表示默认值 4
+	TaskTimeout    time.Duration        // delegate_tasks 中每个任务的超时时间，0 表示不限制
+}
+
+// generalPurposeSubAgent 默认子Agent类型（沿用主Agent的提示词和全部工具）
+const generalPurposeSubAgent = "general-purpose"
+
+// defaultSubAgentIterations 子Agent默认的迭代次数限制
+const defaultSubAgentIterations = 10
+
+// SubAgentDefinition 命名子Agent定义
+// 未设置的字段沿用主Agent的配置
+type SubAgentDefinition struct {
+	Name          string             // 类型名称（delegate_to_subagent 的 subagent_type 参数）
+	Description   string             // 用途说明，渲染到工具描述中供模型选择
+	SystemPrompt  string             // 系统提示词，为空时使用主Agent的提示词
+	Tools         []string           // 允许使用的工具（支持 * 通配，如 git_*），为空表示全部工具
+	LLMClient     llm.Client         // 使用的模型客户端，为空时使用主Agent的客户端
+	Model         string             // 模型名称（Markdown 定义使用），由构建方解析为 LLMClient
+	MaxIterations int                // 迭代次数限制，0 表示默认值 10
+	Middlewares   []agent.Middleware // 追加在主Agent中间件之后的中间件
 }
 
 // SubAgentMiddleware 子Agent中间件
@@ -25,6 +54,8 @@ type SubAgentMiddleware struct {
 	systemPrompt string
 	maxTokens    int
 	temperature  float64
+	subAgents    []SubAgentDefinition
+	mu           sync.RWMutex
 }
 
 // NewSubAgentMiddleware 创建子Agent中间件
@@ -54,49 +85,160 @@ func NewSubAgentMiddleware(
 		temperature:    temperature,
 	}
 
-	// 注册 delegate_to_subagent 工具
+	for _, def := range config.SubAgents {
+		if err := m.validateSubAgent(def); err != nil {
+			log.Printf("警告: 忽略子Agent类型 %q: %v\n", def.Name, err)
+			continue
+		}
+		m.subAgents = append(m.subAgents, def)
+	}
+
+	// 注册 delegate_to_subagent 和 delegate_tasks 工具
 	m.registerTools()
 
 	return m
 }
 
-// registerTools 注册工具
+// RegisterSubAgent 注册命名子Agent类型，并更新 delegate_to_subagent 工具的描述
+func (m *SubAgentMiddleware) RegisterSubAgent(def SubAgentDefinition) error {
+	m.mu.Lock()
+	if err := m.validateSubAgent(def); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.subAgents = append(m.subAgents, def)
+	m.mu.Unlock()
+
+	m.registerTools()
+	return nil
+}
+
+// SubAgents 返回已注册的命名子Agent类型
+func (m *SubAgentMiddleware) SubAgents() []SubAgentDefinition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.subAgents)
+}
+
+// validateSubAgent 检查子Agent定义（调用方持有锁）
+func (m *SubAgentMiddleware) validateSubAgent(def SubAgentDefinition) error {
+	if def.Name == "" {
+		return fmt.Errorf("subagent name is required")
+	}
+	if def.Name == generalPurposeSubAgent || m.findSubAgent(def.Name) != nil {
+		return fmt.Errorf("subagent %q already registered", def.Name)
+	}
+	return nil
+}
+
+// findSubAgent 按名称查找子Agent定义（调用方持有锁）
+func (m *SubAgentMiddleware) findSubAgent(name string) *SubAgentDefinition {
+	for i := range m.subAgents {
+		if m.subAgents[i].Name == name {
+			return &m.subAgents[i]
+		}
+	}
+	return nil
+}
+
+// registerTools 注册工具（子Agent类型变化时重新注册以更新描述）
 func (m *SubAgentMiddleware) registerTools() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sb strings.Builder
+	sb.WriteString("将复杂任务委派给子Agent处理。子Agent有独立的状态和上下文，适合处理需要多步骤的子任务。")
+	if m.config.Worktree != nil {
+		sb.WriteString("\n\n子Agent在独立的 git 工作树和分支中工作（从最新提交开始，看不到未提交的修改），不会修改当前工作区。" +
+			"完成后返回其分支名和 diff，由你决定合并、检查或丢弃。")
+	}
+
+	types := []string{generalPurposeSubAgent}
+	properties := map[string]any{
+		"task": map[string]any{
+			"type":        "string",
+			"description": "要委派给子Agent的任务描述",
+		},
+		"context": map[string]any{
+			"type":        "string",
+			"description": "传递给子Agent的上下文信息（可选）",
+		},
+	}
+	if len(m.subAgents) > 0 {
+		sb.WriteString("\n\n可用的子Agent类型（subagent_type）：\n- " + generalPurposeSubAgent + ": 通用子Agent，拥有与你相同的工具")
+		for _, def := range m.subAgents {
+			fmt.Fprintf(&sb, "\n- %s: %s", def.Name, def.Description)
+			types = append(types, def.Name)
+		}
+		properties["subagent_type"] = map[string]any{
+			"type":        "string",
+			"enum":        types,
+			"description": "子Agent类型（默认 " + generalPurposeSubAgent + "）",
+		}
+	}
+
+	m.toolRegistry.Remove("delegate_to_subagent")
 	m.toolRegistry.Register(tools.NewBaseTool(
 		"delegate_to_subagent",
-		"将复杂任务委派给子Agent处理。子Agent有独立的状态和上下文，适合处理需要多步骤的子任务。",
+		sb.String(),
 		map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"task": map[string]any{
-					"type":        "string",
-					"description": "要委派给子Agent的任务描述",
-				},
-				"context": map[string]any{
-					"type":        "string",
-					"description": "传递给子Agent的上下文信息（可选）",
-				},
-			},
-			"required": []string{"task"},
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"task"},
 		},
 		func(ctx context.Context, args map[string]any) (string, error) {
 			return m.executeSubAgent(ctx, args)
 		},
 	))
+	m.toolRegistry.Remove("delegate_tasks")
+	m.toolRegistry.Register(m.newBatchTool(types))
+}
+
+// subAgentTask 一次委派的任务
+type subAgentTask struct {
+	Task    string // 任务描述
+	Context string // 上下文信息（可选）
+	Type    string // 子Agent类型，为空表示 general-purpose
+}
+
+// parseSubAgentTask 从工具参数解析委派任务
+func parseSubAgentTask(args map[string]any) (subAgentTask, error) {
+	task, ok := args["task"].(string)
+	if !ok || task == "" {
+		return subAgentTask{}, fmt.Errorf("task must be a non-empty string")
+	}
+	return subAgentTask{
+		Task:    task,
+		Context: tools.GetStringArg(args, "context", ""),
+		Type:    tools.GetStringArg(args, "subagent_type", ""),
+	}, nil
 }
 
 // executeSubAgent 执行子Agent
 func (m *SubAgentMiddleware) executeSubAgent(ctx context.Context, args map[string]any) (string, error) {
-	// 获取任务描述
-	task, ok := args["task"].(string)
-	if !ok {
-		return "", fmt.Errorf("task must be a string")
+	t, err := parseSubAgentTask(args)
+	if err != nil {
+		return "", err
 	}
+	return m.runSubAgent(ctx, t)
+}
 
-	// 获取上下文（可选）
-	contextInfo := ""
-	if ctx, ok := args["context"].(string); ok {
-		contextInfo = ctx
+// runSubAgent 以独立的状态运行一个子Agent，返回其最终响应
+func (m *SubAgentMiddleware) runSubAgent(ctx context.Context, t subAgentTask) (string, error) {
+	task, contextInfo := t.Task, t.Context
+
+	// 解析子Agent类型
+	def := SubAgentDefinition{Name: generalPurposeSubAgent}
+	if name := t.Type; name != "" && name != generalPurposeSubAgent {
+		m.mu.RLock()
+		found := m.findSubAgent(name)
+		if found != nil {
+			def = *found
+		}
+		m.mu.RUnlock()
+		if found == nil {
+			return "", fmt.Errorf("unknown subagent_type %q", name)
+		}
 	}
 
 	// 检查递归深度
@@ -132,12 +274,50 @@ func (m *SubAgentMiddleware) executeSubAgent(ctx context.Context, args map[strin
 		}
 	}
 
+	// 沿用调用方本次执行的工具注册表
+	parentTools := m.toolRegistry
+	if runTools := agent.ToolsFromContext(ctx); runTools != nil {
+		parentTools = runTools
+	}
+
+	// 工作树隔离：文件、git 等工具作用于独立的工作树
+	toolRegistry := parentTools
+	var wt *worktree
+	if m.config.Worktree != nil {
+		var err error
+		wt, err = m.config.Worktree.createWorktree(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create worktree for subagent: %w", err)
+		}
+		var release func()
+		toolRegistry, subMiddlewares, release = bindMiddlewares(wt, subMiddlewares, parentTools)
+		defer release()
+	}
+
+	// 按子Agent定义覆盖配置
+	subMiddlewares = append(subMiddlewares, def.Middlewares...)
+	if len(def.Tools) > 0 {
+		toolRegistry = filterTools(toolRegistry, def.Tools)
+	}
+	llmClient := m.llmClient
+	if def.LLMClient != nil {
+		llmClient = def.LLMClient
+	}
+	systemPrompt := m.systemPrompt
+	if def.SystemPrompt != "" {
+		systemPrompt = def.SystemPrompt
+	}
+	maxIterations := defaultSubAgentIterations
+	if def.MaxIterations > 0 {
+		maxIterations = def.MaxIterations
+	}
+
 	subAgentConfig := &agent.Config{
-		LLMClient:     m.llmClient,
-		ToolRegistry:  m.toolRegistry,
+		LLMClient:     llmClient,
+		ToolRegistry:  toolRegistry,
 		Middlewares:   subMiddlewares,
-		SystemPrompt:  m.systemPrompt,
-		MaxIterations: 10, // 子Agent的迭代次数限制
+		SystemPrompt:  systemPrompt,
+		MaxIterations: maxIterations,
 		MaxTokens:     m.maxTokens,
 		Temperature:   m.temperature,
 	}
@@ -148,11 +328,22 @@ func (m *SubAgentMiddleware) executeSubAgent(ctx context.Context, args map[strin
 	// 创建带深度信息的上下文
 	subCtx := m.withDepth(ctx, depth+1)
 
-	// 执行子Agent
-	output, err := subExecutor.Invoke(subCtx, &agent.InvokeInput{
+	// 执行子Agent（调用方流式执行时，子Agent的事件转发到调用方的事件流）
+	output, err := agent.InvokeNested(subCtx, subExecutor, &agent.InvokeInput{
 		Messages: messages,
+	}, agent.SubAgentInfo{
+		Name:  def.Name,
+		Depth: depth + 1,
+		Task:  taskTitle(task),
 	})
+	var worktreeReport string
+	if wt != nil {
+		worktreeReport = m.config.Worktree.finishWorktree(ctx, wt, commitMessage(task))
+	}
 	if err != nil {
+		if worktreeReport != "" {
+			return "", fmt.Errorf("subagent execution failed: %w\n\n%s", err, worktreeReport)
+		}
 		return "", fmt.Errorf("subagent execution failed: %w", err)
 	}
 
@@ -174,11 +365,34 @@ func (m *SubAgentMiddleware) executeSubAgent(ctx context.Context, args map[strin
 		return "SubAgent completed but returned no assistant message", nil
 	}
 
-	return fmt.Sprintf("SubAgent completed successfully.\n\nResult:\n%s", lastResponse), nil
+	result := fmt.Sprintf("SubAgent completed successfully.\n\nResult:\n%s", lastResponse)
+	if worktreeReport != "" {
+		result += "\n\nWorktree:\n" + worktreeReport
+	}
+	return result, nil
+}
+
+// filterTools 创建只包含允许工具的注册表（支持 * 通配）
+func filterTools(registry *tools.Registry, allowed []string) *tools.Registry {
+	filtered := tools.NewRegistry()
+	for _, tool := range registry.List() {
+		for _, pattern := range allowed {
+			if ok, _ := path.Match(pattern, tool.Name()); ok {
+				filtered.Register(tool)
+				break
+			}
+		}
+	}
+	return filtered
 }
 
 // getDepth 从上下文中获取当前递归深度
 func (m *SubAgentMiddleware) getDepth(ctx context.Context) int {
+	return subAgentDepth(ctx)
+}
+
+// subAgentDepth 从上下文中获取子 Agent 递归深度（主 Agent 为 0）
+func subAgentDepth(ctx context.Context) int {
 	if depth, ok := ctx.Value(subAgentDepthKey{}).(int); ok {
 		return depth
 	}
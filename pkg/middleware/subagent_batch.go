@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+const (
+	// defaultSubAgentConcurrency 并发委派默认的最大并发数
+	defaultSubAgentConcurrency = 4
+	// maxBatchTasks 单次并发委派的最大任务数
+	maxBatchTasks = 16
+)
+
+// batchTask 并发委派中的一个任务
+type batchTask struct {
+	subAgentTask
+	Label string // 结果标签
+}
+
+// batchResult 并发委派中一个任务的结果
+type batchResult struct {
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+// newBatchTool 创建 delegate_tasks 工具（types 为可选的子Agent类型列表）
+func (m *SubAgentMiddleware) newBatchTool(types []string) tools.Tool {
+	taskProperties := map[string]any{
+		"task": map[string]any{
+			"type":        "string",
+			"description": "子任务描述（需自包含，子Agent看不到当前对话）",
+		},
+		"context": map[string]any{
+			"type":        "string",
+			"description": "传递给子Agent的上下文信息（可选）",
+		},
+		"label": map[string]any{
+			"type":        "string",
+			"description": "结果标签，用于区分各任务的结果（可选）",
+		},
+	}
+	if len(types) > 1 {
+		taskProperties["subagent_type"] = map[string]any{
+			"type":        "string",
+			"enum":        types,
+			"description": "子Agent类型（默认 " + generalPurposeSubAgent + "）",
+		}
+	}
+
+	return tools.NewBaseTool(
+		"delegate_tasks",
+		fmt.Sprintf("将多个相互独立的子任务同时委派给多个子Agent并发执行（最多 %d 个任务，同时运行 %d 个），"+
+			"各子Agent状态相互隔离。全部完成后按任务顺序返回带标签的结果，单个任务失败或超时不影响其他任务。"+
+			"子任务之间有依赖时请使用 delegate_to_subagent 逐个委派。", maxBatchTasks, m.concurrency()),
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"tasks": map[string]any{
+					"type":     "array",
+					"minItems": 1,
+					"maxItems": maxBatchTasks,
+					"items": map[string]any{
+						"type":       "object",
+						"properties": taskProperties,
+						"required":   []string{"task"},
+					},
+				},
+			},
+			"required": []string{"tasks"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			return m.executeBatch(ctx, args)
+		},
+	)
+}
+
+// concurrency 返回并发委派的最大并发数
+func (m *SubAgentMiddleware) concurrency() int {
+	if m.config.MaxConcurrency > 0 {
+		return m.config.MaxConcurrency
+	}
+	return defaultSubAgentConcurrency
+}
+
+// parseBatchTasks 从工具参数解析任务列表
+func parseBatchTasks(args map[string]any) ([]batchTask, error) {
+	items, ok := args["tasks"].([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("tasks must be a non-empty array")
+	}
+	if len(items) > maxBatchTasks {
+		return nil, fmt.Errorf("too many tasks: %d (max %d)", len(items), maxBatchTasks)
+	}
+
+	tasks := make([]batchTask, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("tasks[%d] must be an object", i)
+		}
+		t, err := parseSubAgentTask(obj)
+		if err != nil {
+			return nil, fmt.Errorf("tasks[%d]: %w", i, err)
+		}
+		tasks = append(tasks, batchTask{subAgentTask: t, Label: tools.GetStringArg(obj, "label", "")})
+	}
+	return tasks, nil
+}
+
+// executeBatch 并发执行多个子Agent，汇总各任务的结果
+func (m *SubAgentMiddleware) executeBatch(ctx context.Context, args map[string]any) (string, error) {
+	tasks, err := parseBatchTasks(args)
+	if err != nil {
+		return "", err
+	}
+
+	results := make([]batchResult, len(tasks))
+	sem := make(chan struct{}, m.concurrency())
+	var wg sync.WaitGroup
+	for i := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				return
+			}
+			results[i] = m.runBatchTask(ctx, tasks[i].subAgentTask)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return formatBatchResults(tasks, results), nil
+}
+
+// runBatchTask 在超时限制内运行一个子Agent，panic 视为该任务失败
+func (m *SubAgentMiddleware) runBatchTask(ctx context.Context, t subAgentTask) (result batchResult) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("subagent panicked: %v", r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	if m.config.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.TaskTimeout)
+		defer cancel()
+	}
+
+	result.Output, result.Err = m.runSubAgent(ctx, t)
+	if result.Err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Err = fmt.Errorf("timed out after %s: %w", m.config.TaskTimeout, result.Err)
+	}
+	return result
+}
+
+// formatBatchResults 按任务顺序格式化结果
+func formatBatchResults(tasks []batchTask, results []batchResult) string {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Delegated %d tasks: %d succeeded, %d failed.", len(tasks), len(tasks)-failed, failed)
+	for i, t := range tasks {
+		label := t.Label
+		if label == "" {
+			label = taskTitle(t.Task)
+		}
+		fmt.Fprintf(&sb, "\n\n## [%d] %s", i+1, label)
+		if t.Type != "" {
+			fmt.Fprintf(&sb, " (%s)", t.Type)
+		}
+
+		r := results[i]
+		if r.Err != nil {
+			fmt.Fprintf(&sb, "\nStatus: failed after %s\nError: %v", r.Duration.Round(time.Millisecond), r.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "\nStatus: succeeded in %s\n\n%s", r.Duration.Round(time.Millisecond), r.Output)
+	}
+	return sb.String()
+}
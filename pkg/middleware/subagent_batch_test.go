@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// concurrentLLMClient 并发安全的模拟客户端，按任务内容返回结果并记录最大并发数
+type concurrentLLMClient struct {
+	subAgentMockLLMClient
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (c *concurrentLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.peak = max(c.peak, c.inFlight)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	task := req.Messages[len(req.Messages)-1].Content
+	switch {
+	case strings.Contains(task, "fail"):
+		return nil, fmt.Errorf("model unavailable")
+	case strings.Contains(task, "hang"):
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(20 * time.Millisecond)
+	return &llm.ModelResponse{Content: "answer to " + task, StopReason: "end_turn"}, nil
+}
+
+func (c *concurrentLLMClient) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	eventChan := make(chan llm.StreamEvent, 3)
+	go func() {
+		defer close(eventChan)
+		resp, err := c.Generate(ctx, req)
+		if err != nil {
+			eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeError, Error: err, Done: true}
+			return
+		}
+		eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeText, Content: resp.Content}
+		eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeEnd, StopReason: resp.StopReason, Done: true}
+	}()
+	return eventChan, nil
+}
+
+func TestSubAgentMiddleware_DelegateTasks(t *testing.T) {
+	toolRegistry := tools.NewRegistry()
+	client := &concurrentLLMClient{}
+	NewSubAgentMiddleware(&SubAgentConfig{
+		MaxDepth:       3,
+		MaxConcurrency: 2,
+		TaskTimeout:    200 * time.Millisecond,
+	}, client, toolRegistry, nil, "", 4096, 0.7)
+
+	tool, ok := toolRegistry.Get("delegate_tasks")
+	if !ok {
+		t.Fatal("delegate_tasks tool not registered")
+	}
+
+	tasks := []any{
+		map[string]any{"task": "question one", "label": "q1"},
+		map[string]any{"task": "please fail"},
+		map[string]any{"task": "question three"},
+		map[string]any{"task": "hang forever", "label": "slow"},
+		map[string]any{"task": "question five"},
+	}
+	result, err := tool.Execute(context.Background(), map[string]any{"tasks": tasks})
+	if err != nil {
+		t.Fatalf("delegate_tasks failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"Delegated 5 tasks: 3 succeeded, 2 failed.",
+		"## [1] q1\nStatus: succeeded",
+		"answer to question one",
+		"## [2] please fail\nStatus: failed",
+		"model unavailable",
+		"## [4] slow\nStatus: failed",
+		"timed out after 200ms",
+		"answer to question five",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in result:\n%s", want, result)
+		}
+	}
+	if strings.Index(result, "[3]") > strings.Index(result, "[4]") {
+		t.Error("results should keep task order")
+	}
+	if client.peak != 2 {
+		t.Errorf("expected 2 concurrent subagents, got %d", client.peak)
+	}
+}
+
+func TestSubAgentMiddleware_DelegateTasksInvalidArgs(t *testing.T) {
+	toolRegistry := tools.NewRegistry()
+	NewSubAgentMiddleware(nil, &subAgentMockLLMClient{}, toolRegistry, nil, "", 4096, 0.7)
+	tool, _ := toolRegistry.Get("delegate_tasks")
+
+	tooMany := make([]any, maxBatchTasks+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]any{"task": "x"}
+	}
+	for _, args := range []map[string]any{
+		{},
+		{"tasks": []any{}},
+		{"tasks": []any{"not an object"}},
+		{"tasks": []any{map[string]any{"label": "missing task"}}},
+		{"tasks": tooMany},
+	} {
+		if _, err := tool.Execute(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+// writeThenHangLLMClient 先写入文件，之后一直等待直到上下文结束
+type writeThenHangLLMClient struct {
+	subAgentMockLLMClient
+}
+
+func (c *writeThenHangLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	if len(req.Messages[len(req.Messages)-1].ToolResults) > 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &llm.ModelResponse{
+		ToolCalls: []llm.ToolCall{{
+			ID:    "call_1",
+			Name:  "write_file",
+			Input: map[string]any{"path": "/partial.txt", "content": "partial work\n"},
+		}},
+		StopReason: "tool_use",
+	}, nil
+}
+
+func TestSubAgentMiddleware_DelegateTasksWorktreeTimeout(t *testing.T) {
+	_, repo := newWorktreeRepo(t)
+	toolRegistry := tools.NewRegistry()
+	NewSubAgentMiddleware(&SubAgentConfig{
+		MaxDepth:    3,
+		TaskTimeout: 300 * time.Millisecond,
+		Worktree:    &WorktreeConfig{Repo: repo},
+	}, &writeThenHangLLMClient{}, toolRegistry, []agent.Middleware{
+		NewFilesystemMiddleware(repo, toolRegistry),
+	}, "", 4096, 0.7)
+
+	tool, _ := toolRegistry.Get("delegate_tasks")
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"tasks": []any{map[string]any{"task": "write something slowly"}},
+	})
+	if err != nil {
+		t.Fatalf("delegate_tasks failed: %v", err)
+	}
+
+	// 超时任务的变更仍被提交到分支，工作树被清理
+	for _, want := range []string{"timed out after 300ms", "Changes were committed to branch deepagents/subagent-", "+partial work"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in result:\n%s", want, result)
+		}
+	}
+	worktrees, _ := tools.RunGit(context.Background(), repo, "worktree", "list")
+	if strings.Count(strings.TrimSpace(worktrees), "\n") != 0 {
+		t.Errorf("expected timed-out worktree to be removed, got:\n%s", worktrees)
+	}
+	branches, _ := tools.RunGit(context.Background(), repo, "branch", "--list", "deepagents/*", "--format=%(refname:short)")
+	if content, err := tools.RunGit(context.Background(), repo, "show", strings.TrimSpace(branches)+":partial.txt"); err != nil || content != "partial work\n" {
+		t.Errorf("expected partial work on branch %q, got %q (err=%v)", branches, content, err)
+	}
+}
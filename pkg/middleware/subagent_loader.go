@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// subAgentFrontmatter Markdown 子Agent定义的 YAML frontmatter
+type subAgentFrontmatter struct {
+	Name          string   `yaml:"name"`           // 类型名称
+	Description   string   `yaml:"description"`    // 用途说明
+	Tools         toolList `yaml:"tools"`          // 允许使用的工具（列表或逗号分隔，可选）
+	Model         string   `yaml:"model"`          // 模型名称（可选，inherit 表示沿用主Agent）
+	MaxIterations int      `yaml:"max-iterations"` // 迭代次数限制（可选）
+}
+
+// toolList 工具列表，兼容 YAML 列表和逗号分隔的字符串两种写法
+type toolList []string
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+func (l *toolList) UnmarshalYAML(value *yaml.Node) error {
+	var items []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		items = strings.Split(value.Value, ",")
+	case yaml.SequenceNode:
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("tools 必须是列表或逗号分隔的字符串")
+	}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// ParseSubAgentDefinition 解析 Markdown 子Agent定义（YAML frontmatter + Markdown 正文）
+// 正文作为子Agent的系统提示词，示例：
+//
+//	---
+//	name: test-writer
+//	description: 为指定代码补充单元测试
+//	tools: read_file, write_file, edit_file, run_tests
+//	model: claude-haiku-4-5
+//	max-iterations: 15
+//	---
+//	你是测试工程师……
+func ParseSubAgentDefinition(content string) (*SubAgentDefinition, error) {
+	// 检查是否有 YAML frontmatter
+	if !strings.HasPrefix(content, "---") {
+		return nil, fmt.Errorf("子Agent定义缺少 YAML frontmatter")
+	}
+
+	// 分离 frontmatter 和 body
+	parts := strings.SplitN(content[3:], "---", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("子Agent定义格式错误：缺少 frontmatter 结束标记")
+	}
+
+	var fm subAgentFrontmatter
+	if err := yaml.Unmarshal([]byte(strings.TrimSpace(parts[0])), &fm); err != nil {
+		return nil, fmt.Errorf("解析 frontmatter 失败: %w", err)
+	}
+
+	// 验证必需字段
+	if fm.Name == "" {
+		return nil, fmt.Errorf("子Agent定义缺少 name 字段")
+	}
+	if strings.ContainsAny(fm.Name, " \t\n") || fm.Name == generalPurposeSubAgent {
+		return nil, fmt.Errorf("子Agent名称无效: %q", fm.Name)
+	}
+	if fm.Description == "" {
+		return nil, fmt.Errorf("子Agent定义缺少 description 字段")
+	}
+	if fm.MaxIterations < 0 {
+		return nil, fmt.Errorf("max-iterations 不能为负数")
+	}
+
+	def := &SubAgentDefinition{
+		Name:          fm.Name,
+		Description:   fm.Description,
+		SystemPrompt:  strings.TrimSpace(parts[1]),
+		Tools:         fm.Tools,
+		MaxIterations: fm.MaxIterations,
+	}
+	if fm.Model != "inherit" {
+		def.Model = fm.Model
+	}
+	return def, nil
+}
+
+// LoadSubAgentDefinitions 从目录加载所有 *.md 子Agent定义（按文件名排序）
+// 无效的文件被跳过，其错误合并后与有效定义一起返回
+func LoadSubAgentDefinitions(dir string) ([]SubAgentDefinition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取子Agent目录失败: %w", err)
+	}
+
+	var defs []SubAgentDefinition
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		def, err := ParseSubAgentDefinition(string(content))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		defs = append(defs, *def)
+	}
+
+	return defs, errors.Join(errs...)
+}
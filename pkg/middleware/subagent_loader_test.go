@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseSubAgentDefinition(t *testing.T) {
+	def, err := ParseSubAgentDefinition(`---
+name: test-writer
+description: 为指定代码补充单元测试
+tools: read_file, write_file , run_tests
+model: claude-haiku-4-5
+max-iterations: 15
+---
+
+你是测试工程师。
+`)
+	if err != nil {
+		t.Fatalf("ParseSubAgentDefinition() error = %v", err)
+	}
+	if def.Name != "test-writer" || def.Description != "为指定代码补充单元测试" {
+		t.Errorf("unexpected name/description: %q %q", def.Name, def.Description)
+	}
+	if !slices.Equal(def.Tools, []string{"read_file", "write_file", "run_tests"}) {
+		t.Errorf("Tools = %v", def.Tools)
+	}
+	if def.Model != "claude-haiku-4-5" || def.MaxIterations != 15 {
+		t.Errorf("Model = %q, MaxIterations = %d", def.Model, def.MaxIterations)
+	}
+	if def.SystemPrompt != "你是测试工程师。" {
+		t.Errorf("SystemPrompt = %q", def.SystemPrompt)
+	}
+
+	// 列表写法和 inherit 模型
+	def, err = ParseSubAgentDefinition("---\nname: a\ndescription: b\ntools:\n  - git_*\n  - read_file\nmodel: inherit\n---\nprompt")
+	if err != nil {
+		t.Fatalf("ParseSubAgentDefinition() error = %v", err)
+	}
+	if !slices.Equal(def.Tools, []string{"git_*", "read_file"}) || def.Model != "" {
+		t.Errorf("Tools = %v, Model = %q", def.Tools, def.Model)
+	}
+}
+
+func TestParseSubAgentDefinition_Errors(t *testing.T) {
+	tests := map[string]string{
+		"no frontmatter":      "name: a",
+		"unterminated":        "---\nname: a\ndescription: b\n",
+		"missing name":        "---\ndescription: b\n---\n",
+		"missing description": "---\nname: a\n---\n",
+		"invalid name":        "---\nname: general-purpose\ndescription: b\n---\n",
+		"invalid tools":       "---\nname: a\ndescription: b\ntools: {x: 1}\n---\n",
+	}
+	for name, content := range tests {
+		if _, err := ParseSubAgentDefinition(content); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadSubAgentDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.md":      "---\nname: b\ndescription: second\n---\nB",
+		"a.md":      "---\nname: a\ndescription: first\n---\nA",
+		"broken.md": "---\nname: broken\n---\n",
+		"notes.txt": "---\nname: ignored\ndescription: x\n---\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defs, err := LoadSubAgentDefinitions(dir)
+	if err == nil {
+		t.Error("expected error for broken.md")
+	}
+	if len(defs) != 2 || defs[0].Name != "a" || defs[1].Name != "b" {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+
+	if _, err := LoadSubAgentDefinitions(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/zhoucx/deepagents-go/pkg/agent"
@@ -443,3 +445,168 @@ func TestSubAgentMiddleware_NoInfiniteRecursion(t *testing.T) {
 		t.Error("Expected non-empty result")
 	}
 }
+
+// recordingLLMClient 记录请求的模拟LLM客户端
+type recordingLLMClient struct {
+	subAgentMockLLMClient
+	requests []*llm.ModelRequest
+}
+
+func (c *recordingLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	c.requests = append(c.requests, req)
+	return c.subAgentMockLLMClient.Generate(ctx, req)
+}
+
+func (c *recordingLLMClient) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	c.requests = append(c.requests, req)
+	return c.subAgentMockLLMClient.StreamGenerate(ctx, req)
+}
+
+func TestSubAgentMiddleware_NamedTypes(t *testing.T) {
+	toolRegistry := tools.NewRegistry()
+	fsMiddleware := NewFilesystemMiddleware(backend.NewStateBackend(), toolRegistry)
+	reviewerClient := &recordingLLMClient{}
+
+	m := NewSubAgentMiddleware(
+		&SubAgentConfig{
+			MaxDepth: 3,
+			SubAgents: []SubAgentDefinition{
+				ResearcherSubAgent(),
+				{
+					Name:         "reviewer",
+					Description:  "Reviews code",
+					SystemPrompt: "You review code.",
+					Tools:        []string{"read_file", "g*"},
+					LLMClient:    reviewerClient,
+				},
+			},
+		},
+		&subAgentMockLLMClient{},
+		toolRegistry,
+		[]agent.Middleware{fsMiddleware},
+		"Parent prompt",
+		4096,
+		0.7,
+	)
+
+	tool, _ := toolRegistry.Get("delegate_to_subagent")
+	for _, s := range []string{"- general-purpose:", "- researcher: 只读调研", "- reviewer: Reviews code"} {
+		if !strings.Contains(tool.Description(), s) {
+			t.Errorf("expected %q in tool description:\n%s", s, tool.Description())
+		}
+	}
+	enum := tool.Parameters()["properties"].(map[string]any)["subagent_type"].(map[string]any)["enum"].([]string)
+	if len(enum) != 3 {
+		t.Errorf("expected 3 subagent types, got %v", enum)
+	}
+
+	_, err := m.executeSubAgent(context.Background(), map[string]any{"task": "Review the parser", "subagent_type": "reviewer"})
+	if err != nil {
+		t.Fatalf("executeSubAgent failed: %v", err)
+	}
+	if len(reviewerClient.requests) == 0 {
+		t.Fatal("expected the subagent's own LLM client to be used")
+	}
+	req := reviewerClient.requests[0]
+	if req.SystemPrompt != "You review code." {
+		t.Errorf("expected subagent system prompt, got %q", req.SystemPrompt)
+	}
+	var names []string
+	for _, s := range req.Tools {
+		names = append(names, s.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"glob", "grep", "read_file"}) {
+		t.Errorf("expected only allowed tools, got %v", names)
+	}
+
+	if _, err := m.executeSubAgent(context.Background(), map[string]any{"task": "x", "subagent_type": "missing"}); err == nil {
+		t.Error("expected error for unknown subagent_type")
+	}
+}
+
+func TestSubAgentMiddleware_RegisterSubAgent(t *testing.T) {
+	toolRegistry := tools.NewRegistry()
+	m := NewSubAgentMiddleware(nil, &subAgentMockLLMClient{}, toolRegistry, nil, "", 4096, 0.7)
+
+	tool, _ := toolRegistry.Get("delegate_to_subagent")
+	if _, ok := tool.Parameters()["properties"].(map[string]any)["subagent_type"]; ok {
+		t.Error("subagent_type should not be offered when no types are registered")
+	}
+
+	if err := m.RegisterSubAgent(CodeReviewerSubAgent()); err != nil {
+		t.Fatalf("RegisterSubAgent failed: %v", err)
+	}
+	tool, _ = toolRegistry.Get("delegate_to_subagent")
+	if !strings.Contains(tool.Description(), "- code-reviewer:") {
+		t.Errorf("expected description to list code-reviewer:\n%s", tool.Description())
+	}
+
+	for _, def := range []SubAgentDefinition{{}, {Name: "general-purpose"}, CodeReviewerSubAgent()} {
+		if err := m.RegisterSubAgent(def); err == nil {
+			t.Errorf("expected error registering %q", def.Name)
+		}
+	}
+	if got := len(m.SubAgents()); got != 1 {
+		t.Errorf("expected 1 registered subagent, got %d", got)
+	}
+}
+
+func TestSubAgentMiddleware_StreamsEvents(t *testing.T) {
+	toolRegistry := tools.NewRegistry()
+	fsMiddleware := NewFilesystemMiddleware(backend.NewStateBackend(), toolRegistry)
+	client := &subAgentMockLLMClient{
+		responses: []*llm.ModelResponse{
+			{
+				ToolCalls:  []llm.ToolCall{{ID: "call_1", Name: "ls", Input: map[string]any{"path": "/"}}},
+				StopReason: "tool_use",
+			},
+			{Content: "Found nothing", StopReason: "end_turn"},
+		},
+	}
+	m := NewSubAgentMiddleware(&SubAgentConfig{MaxDepth: 3}, client, toolRegistry, []agent.Middleware{fsMiddleware}, "", 4096, 0.7)
+
+	var events []agent.AgentEvent
+	parent := &agent.SubAgentInfo{ID: "sub-parent", Name: "general-purpose", Depth: 1}
+	ctx := agent.WithSubAgentInfo(m.withDepth(context.Background(), 1), parent)
+	ctx = agent.WithEventEmitter(ctx, func(event agent.AgentEvent) {
+		events = append(events, event)
+	})
+
+	result, err := m.executeSubAgent(ctx, map[string]any{"task": "List files\nin the root"})
+	if err != nil {
+		t.Fatalf("executeSubAgent failed: %v", err)
+	}
+	if !strings.Contains(result, "Found nothing") {
+		t.Errorf("unexpected result: %s", result)
+	}
+
+	if len(events) == 0 {
+		t.Fatal("expected subagent events to be forwarded")
+	}
+	if events[0].Type != agent.AgentEventTypeStart || events[len(events)-1].Type != agent.AgentEventTypeEnd {
+		t.Errorf("expected start...end, got %s...%s", events[0].Type, events[len(events)-1].Type)
+	}
+	var toolStarts int
+	for _, event := range events {
+		info := event.SubAgent
+		if info == nil || info.Name != "general-purpose" || info.Depth != 2 || info.ParentID != "sub-parent" || info.Task != "List files" {
+			t.Fatalf("unexpected subagent info: %+v", info)
+		}
+		if info.ID != events[0].SubAgent.ID {
+			t.Errorf("expected a stable subagent ID, got %s and %s", info.ID, events[0].SubAgent.ID)
+		}
+		if event.Done {
+			t.Error("forwarded events must not be marked done")
+		}
+		if event.Type == agent.AgentEventTypeToolStart {
+			toolStarts++
+		}
+	}
+	if toolStarts != 1 {
+		t.Errorf("expected 1 tool start event, got %d", toolStarts)
+	}
+	if events[len(events)-1].Metadata != nil {
+		t.Error("final messages should not be forwarded with the end event")
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+// readOnlyTools 只读工具（不修改文件和仓库）
+var readOnlyTools = []string{
+	"ls", "read_file", "grep", "glob", "stat",
+	"git_status", "git_diff", "git_log", "git_show", "git_blame",
+	"lsp_*",
+}
+
+// ResearcherSubAgent 返回只读调研子Agent定义
+// 用于在代码库和网络中查找信息，不修改任何文件
+func ResearcherSubAgent() SubAgentDefinition {
+	return SubAgentDefinition{
+		Name:        "researcher",
+		Description: "只读调研：在代码库和网络中查找信息、梳理调用关系、回答问题，不修改任何文件",
+		SystemPrompt: `你是一个只读的调研助手。你的任务是收集信息并给出准确、有依据的结论。
+
+**工作方式**：
+- 先用 glob、grep 定位相关文件，再用 read_file 阅读关键代码
+- 需要时用 git_log、git_blame 了解代码的演变，用 web_search、web_fetch 查阅外部资料
+- 你不能修改任何文件，也不要建议需要你自己执行的修改
+
+**输出**：
+- 直接回答问题，引用具体的 文件:行号 作为依据
+- 区分确认的事实和推测
+- 保持简洁，只包含与任务相关的信息`,
+		Tools: append([]string{"web_search", "web_fetch"}, readOnlyTools...),
+	}
+}
+
+// CodeReviewerSubAgent 返回代码评审子Agent定义
+// 检查变更的正确性和风险，可以运行测试，但不修改文件
+func CodeReviewerSubAgent() SubAgentDefinition {
+	return SubAgentDefinition{
+		Name:        "code-reviewer",
+		Description: "代码评审：检查当前变更（或指定的文件、分支）中的缺陷、风险和遗漏的测试，可以运行测试但不修改文件",
+		SystemPrompt: `你是一个严谨的代码评审者。你的任务是找出变更中真实存在的问题。
+
+**工作方式**：
+- 用 git_status、git_diff 查看变更，用 read_file 阅读变更周围的代码以理解上下文
+- 需要时用 run_tests 运行相关测试验证判断
+- 你不能修改任何文件
+
+**关注点**（按优先级）：
+1. 正确性：逻辑错误、边界条件、错误处理、并发问题
+2. 安全：注入、越权、敏感信息泄露
+3. 兼容性：破坏现有 API 或行为
+4. 测试：缺少对新行为的测试
+
+**输出**：
+- 每个问题给出 文件:行号、问题描述和修改建议，按严重程度排序
+- 不报告纯风格问题，不确定的问题明确标注
+- 没有发现问题时直接说明`,
+		Tools: append([]string{"run_tests"}, readOnlyTools...),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/zhoucx/deepagents-go/pkg/llm"
 )
@@ -15,8 +16,9 @@ type SummarizationMiddleware struct {
 	targetTokens    int // 摘要后的目标 token 数
 	llmClient       llm.Client
 	enabled         bool
-	summaryCount    int // 已生成的摘要数量
-	lastSummarySize int // 上次摘要的大小
+	summaryCount    int        // 已生成的摘要数量（所有执行累计）
+	lastSummarySize int        // 上次摘要的大小
+	mu              sync.Mutex // 保护摘要统计
 }
 
 // SummarizationConfig 摘要配置
@@ -66,11 +68,15 @@ func (m *SummarizationMiddleware) IsEnabled() bool {
 
 // GetSummaryCount 获取已生成的摘要数量
 func (m *SummarizationMiddleware) GetSummaryCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.summaryCount
 }
 
 // GetLastSummarySize 获取上次摘要的大小
 func (m *SummarizationMiddleware) GetLastSummarySize() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.lastSummarySize
 }
 
@@ -97,8 +103,10 @@ func (m *SummarizationMiddleware) BeforeModel(ctx context.Context, req *llm.Mode
 
 	// 替换消息为摘要
 	m.replaceWithSummary(req, summary)
+	m.mu.Lock()
 	m.summaryCount++
 	m.lastSummarySize = len(summary)
+	m.mu.Unlock()
 
 	return nil
 }
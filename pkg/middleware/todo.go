@@ -11,14 +11,24 @@ import (
 	"github.com/zhoucx/deepagents-go/pkg/tools"
 )
 
+// defaultTodoWarning 未使用 Todo 的默认提醒
+const defaultTodoWarning = "提醒：你已经连续多轮未使用 write_todos 工具。对于复杂任务（3步以上），建议使用 Todo 列表进行规划和跟踪进度。"
+
 // TodoMiddleware Todo 列表中间件
+// 会话 ID 和轮次计数保存在执行作用域中，同一实例可服务并发的多个会话
 type TodoMiddleware struct {
 	*BaseMiddleware
 	backend         backend.Backend
 	toolRegistry    *tools.Registry
 	contextInjector *ContextInjectionMiddleware // 上下文注入器
-	roundCounter    *RoundCounter               // 轮次计数器
-	sessionID       string                      // 会话 ID，用于隔离 todo 文件
+	maxWarning      int                         // 触发提醒的轮次阈值
+	fallback        *todoRun                    // 不属于任何执行时（如直接调用钩子）使用的状态
+}
+
+// todoRun 一次执行中的 Todo 状态
+type todoRun struct {
+	sessionID    string        // 会话 ID，用于隔离 todo 文件
+	roundCounter *RoundCounter // 轮次计数器
 }
 
 // NewTodoMiddleware 创建 Todo 中间件
@@ -33,12 +43,9 @@ func NewTodoMiddlewareWithInjector(backend backend.Backend, toolRegistry *tools.
 		backend:         backend,
 		toolRegistry:    toolRegistry,
 		contextInjector: contextInjector,
-		roundCounter: NewRoundCounter(
-			3, // 默认 3 轮未使用 Todo 后提醒
-			contextInjector,
-			"提醒：你已经连续多轮未使用 write_todos 工具。对于复杂任务（3步以上），建议使用 Todo 列表进行规划和跟踪进度。",
-		),
+		maxWarning:      3, // 默认 3 轮未使用 Todo 后提醒
 	}
+	m.fallback = &todoRun{roundCounter: NewRoundCounter(m.maxWarning, contextInjector, defaultTodoWarning)}
 
 	// 注册 write_todos 工具
 	m.registerTools()
@@ -46,6 +53,17 @@ func NewTodoMiddlewareWithInjector(backend backend.Backend, toolRegistry *tools.
 	return m
 }
 
+// run 返回当前执行的 Todo 状态
+func (m *TodoMiddleware) run(ctx context.Context) *todoRun {
+	r, ok := agent.RunLocal(ctx, m, func() *todoRun {
+		return &todoRun{roundCounter: NewRoundCounter(m.maxWarning, m.contextInjector.ForRun(ctx), defaultTodoWarning)}
+	})
+	if !ok {
+		return m.fallback
+	}
+	return r
+}
+
 // registerTools 注册工具
 func (m *TodoMiddleware) registerTools() {
 	m.toolRegistry.Register(tools.NewBaseTool(
@@ -153,19 +171,19 @@ func (m *TodoMiddleware) registerTools() {
 
 			// 全部完成：删除 todo 文件
 			if allCompleted {
-				_ = m.backend.DeleteFile(ctx, m.todoPath())
-				m.roundCounter.Reset()
+				_ = m.backend.DeleteFile(ctx, m.todoPath(ctx))
+				m.run(ctx).roundCounter.Reset()
 				return fmt.Sprintf("All %d todo items completed, todo list cleaned up", len(todosRaw)), nil
 			}
 
 			// 保存到会话级 todo 文件
-			_, err := m.backend.WriteFile(ctx, m.todoPath(), content.String())
+			_, err := m.backend.WriteFile(ctx, m.todoPath(ctx), content.String())
 			if err != nil {
 				return "", fmt.Errorf("failed to write todos: %w", err)
 			}
 
 			// 重置轮次计数器
-			m.roundCounter.Reset()
+			m.run(ctx).roundCounter.Reset()
 
 			return fmt.Sprintf("Successfully updated %d todo items", len(todosRaw)), nil
 		},
@@ -174,7 +192,7 @@ func (m *TodoMiddleware) registerTools() {
 
 // readGoalFromFile 从现有 todo 文件中提取 goal
 func (m *TodoMiddleware) readGoalFromFile(ctx context.Context) string {
-	content, err := m.backend.ReadFile(ctx, m.todoPath(), 0, 0)
+	content, err := m.backend.ReadFile(ctx, m.todoPath(ctx), 0, 0)
 	if err != nil {
 		return ""
 	}
@@ -191,9 +209,9 @@ func (m *TodoMiddleware) readGoalFromFile(ctx context.Context) string {
 }
 
 // todoPath 返回当前会话的 todo 文件路径
-func (m *TodoMiddleware) todoPath() string {
-	if m.sessionID != "" {
-		return fmt.Sprintf("memory/todos/%s.md", m.sessionID)
+func (m *TodoMiddleware) todoPath(ctx context.Context) string {
+	if sessionID := m.run(ctx).sessionID; sessionID != "" {
+		return fmt.Sprintf("memory/todos/%s.md", sessionID)
 	}
 	return "todos.md"
 }
@@ -203,7 +221,7 @@ func (m *TodoMiddleware) BeforeAgent(ctx context.Context, state *agent.State) er
 	// 从 state 获取 session_id
 	if sid, ok := state.GetMetadata("session_id"); ok {
 		if s, ok := sid.(string); ok && s != "" {
-			m.sessionID = s
+			m.run(ctx).sessionID = s
 		}
 	}
 
@@ -218,7 +236,7 @@ func (m *TodoMiddleware) BeforeAgent(ctx context.Context, state *agent.State) er
 	}
 
 	// 尝试读取现有的 Todo 列表
-	content, err := m.backend.ReadFile(ctx, m.todoPath(), 0, 0)
+	content, err := m.backend.ReadFile(ctx, m.todoPath(ctx), 0, 0)
 	if err != nil {
 		// 文件不存在，忽略错误
 		return nil
@@ -232,7 +250,7 @@ func (m *TodoMiddleware) BeforeAgent(ctx context.Context, state *agent.State) er
 
 // BeforeModel 在调用 LLM 前注入 Todo 列表到系统提示
 func (m *TodoMiddleware) BeforeModel(ctx context.Context, req *llm.ModelRequest) error {
-	content, err := m.backend.ReadFile(ctx, m.todoPath(), 0, 0)
+	content, err := m.backend.ReadFile(ctx, m.todoPath(ctx), 0, 0)
 	if err != nil {
 		// 没有 Todo 列表，跳过
 		return nil
@@ -306,6 +324,8 @@ func (m *TodoMiddleware) AfterModel(ctx context.Context, resp *llm.ModelResponse
 		}
 	}
 
+	counter := m.run(ctx).roundCounter
+
 	// 动态更新警告消息，包含原始需求
 	if !usedTodo {
 		originalRequest := ""
@@ -319,7 +339,7 @@ func (m *TodoMiddleware) AfterModel(ctx context.Context, resp *llm.ModelResponse
 		}
 
 		if originalRequest != "" {
-			m.roundCounter.SetWarningMessage(fmt.Sprintf(
+			counter.SetWarningMessage(fmt.Sprintf(
 				"提醒：你已经连续多轮未使用 write_todos 工具。用户的原始需求是：「%s」。请围绕该需求使用 Todo 列表规划和跟踪进度。",
 				originalRequest,
 			))
@@ -327,22 +347,35 @@ func (m *TodoMiddleware) AfterModel(ctx context.Context, resp *llm.ModelResponse
 	}
 
 	// 使用轮次计数器跟踪
-	m.roundCounter.Track(usedTodo)
+	counter.Track(usedTodo)
 
 	return nil
 }
 
-// SetMaxRoundsWarning 设置触发警告的阈值
+// AfterAgent 在 Agent 执行后刷新状态中的 Todo 列表（供多轮对话线程读取最新的 todos）
+func (m *TodoMiddleware) AfterAgent(ctx context.Context, state *agent.State) error {
+	content, err := m.backend.ReadFile(ctx, m.todoPath(ctx), 0, 0)
+	if err != nil {
+		// 列表已清理或不存在
+		state.SetMetadata("todos", "")
+		return nil
+	}
+	state.SetMetadata("todos", content)
+	return nil
+}
+
+// SetMaxRoundsWarning 设置触发警告的阈值（在执行开始前调用）
 func (m *TodoMiddleware) SetMaxRoundsWarning(rounds int) {
-	m.roundCounter.SetMaxWarning(rounds)
+	m.maxWarning = rounds
+	m.fallback.roundCounter.SetMaxWarning(rounds)
 }
 
-// GetRoundsWithoutTodo 获取未使用 Todo 的轮次（用于测试）
+// GetRoundsWithoutTodo 获取未使用 Todo 的轮次（不属于任何执行时的计数，用于测试）
 func (m *TodoMiddleware) GetRoundsWithoutTodo() int {
-	return m.roundCounter.GetCount()
+	return m.fallback.roundCounter.GetCount()
 }
 
-// ResetRoundsCounter 重置轮次计数器
+// ResetRoundsCounter 重置轮次计数器（不属于任何执行时的计数）
 func (m *TodoMiddleware) ResetRoundsCounter() {
-	m.roundCounter.Reset()
+	m.fallback.roundCounter.Reset()
 }
@@ -307,6 +307,33 @@ func TestTodoMiddleware_BeforeAgent(t *testing.T) {
 	}
 }
 
+func TestTodoMiddleware_AfterAgentRefreshesTodos(t *testing.T) {
+	backend := backend.NewStateBackend()
+	toolRegistry := tools.NewRegistry()
+	middleware := NewTodoMiddleware(backend, toolRegistry)
+
+	ctx := context.Background()
+	state := agent.NewState()
+	state.SetMetadata("todos", "# Todo List\n\n## ⏳ Old [1]\n\n")
+
+	// 执行期间更新了 Todo 列表
+	todoContent := "# Todo List\n\n## ✅ New [1]\n\n"
+	backend.WriteFile(ctx, "todos.md", todoContent)
+	if err := middleware.AfterAgent(ctx, state); err != nil {
+		t.Fatalf("AfterAgent failed: %v", err)
+	}
+	if todos, _ := state.GetMetadata("todos"); todos != todoContent {
+		t.Errorf("Expected todos content %q, got %q", todoContent, todos)
+	}
+
+	// 列表被清理后状态中的 todos 为空
+	backend.DeleteFile(ctx, "todos.md")
+	middleware.AfterAgent(ctx, state)
+	if todos, _ := state.GetMetadata("todos"); todos != "" {
+		t.Errorf("Expected empty todos, got %q", todos)
+	}
+}
+
 func TestTodoMiddleware_BeforeAgentCapturesOriginalRequest(t *testing.T) {
 	be := backend.NewStateBackend()
 	toolRegistry := tools.NewRegistry()
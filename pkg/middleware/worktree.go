@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// maxWorktreeDiffLines 返回给主 Agent 的 diff 最大行数
+const maxWorktreeDiffLines = 200
+
+// worktreeCleanupTimeout 子 Agent 结束后提交变更、清理工作树的超时时间
+const worktreeCleanupTimeout = time.Minute
+
+// WorktreeConfig 子 Agent 工作树隔离配置
+// 启用后每个子 Agent 在独立的 git 工作树和分支中工作，完成后将变更提交到该分支，
+// 并向主 Agent 返回分支名和 diff，由主 Agent 决定合并、检查或丢弃
+type WorktreeConfig struct {
+	Repo         *backend.FilesystemBackend // 主仓库所在的后端（根目录需在 git 仓库中）
+	Dir          string                     // 工作树存放目录（相对于仓库根目录），默认 .deepagents/worktrees
+	BranchPrefix string                     // 分支名前缀，默认 deepagents/
+	BaseRef      string                     // 工作树的起点，默认 HEAD
+
+	mu sync.Mutex // 串行化对主仓库的 git 操作
+}
+
+// worktree 一个子 Agent 的工作树
+type worktree struct {
+	name    string
+	branch  string
+	path    string // 真实路径
+	base    string // 起点提交
+	backend *backend.FilesystemBackend
+}
+
+// backendBinder 可绑定到其他后端的中间件
+// 工作树隔离的子 Agent 通过它获得作用于工作树（而非主工作区）的中间件和工具
+type backendBinder interface {
+	bindBackend(b *backend.FilesystemBackend, toolRegistry *tools.Registry) agent.Middleware
+}
+
+// createWorktree 从 BaseRef 创建新分支和工作树
+func (c *WorktreeConfig) createWorktree(ctx context.Context) (*worktree, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir := c.Dir
+	if dir == "" {
+		dir = ".deepagents/worktrees"
+	}
+	prefix := c.BranchPrefix
+	if prefix == "" {
+		prefix = "deepagents/"
+	}
+	baseRef := c.BaseRef
+	if baseRef == "" {
+		baseRef = "HEAD"
+	}
+
+	base, err := tools.RunGit(ctx, c.Repo, "rev-parse", "--verify", baseRef+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("worktree isolation requires a git repository with at least one commit: %w", err)
+	}
+
+	// 工作树目录内放置忽略全部内容的 .gitignore，避免主工作区出现未跟踪文件
+	ignoreFile := "/" + filepath.ToSlash(filepath.Join(dir, ".gitignore"))
+	if _, err := backend.Stat(ctx, c.Repo, ignoreFile); err != nil {
+		if _, err := c.Repo.WriteFile(ctx, ignoreFile, "*\n"); err != nil {
+			return nil, fmt.Errorf("failed to prepare worktree directory: %w", err)
+		}
+	}
+
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	name := "subagent-" + time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+	wt := &worktree{
+		name:   name,
+		branch: prefix + name,
+		path:   filepath.Join(c.Repo.RootDir(), dir, name),
+		base:   strings.TrimSpace(base),
+	}
+
+	if _, err := tools.RunGit(ctx, c.Repo, "worktree", "add", "-b", wt.branch, wt.path, wt.base); err != nil {
+		return nil, err
+	}
+	wt.backend, err = backend.NewFilesystemBackend(wt.path, true)
+	if err != nil {
+		c.removeWorktree(ctx, wt, true)
+		return nil, err
+	}
+	return wt, nil
+}
+
+// finishWorktree 提交子 Agent 的变更并生成报告
+// 有变更时保留分支并删除工作树目录；无变更时同时删除分支；提交失败时保留工作树供检查
+// 子 Agent 超时或被取消后仍需完成清理，因此不继承 ctx 的取消，使用单独的超时
+func (c *WorktreeConfig) finishWorktree(ctx context.Context, wt *worktree, message string) string {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), worktreeCleanupTimeout)
+	defer cancel()
+
+	status, err := tools.RunGit(ctx, wt.backend, "status", "--porcelain")
+	if err != nil {
+		return fmt.Sprintf("Failed to inspect worktree %s: %v", wt.path, err)
+	}
+	if strings.TrimSpace(status) != "" {
+		if _, err := tools.RunGit(ctx, wt.backend, "add", "--all"); err == nil {
+			_, err = tools.RunGit(ctx, wt.backend, "commit", "-m", message)
+		}
+		if err != nil {
+			return fmt.Sprintf("Failed to commit subagent changes: %v\nThe worktree was kept at %s (branch %s) for manual inspection.", err, wt.path, wt.branch)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count, err := tools.RunGit(ctx, c.Repo, "rev-list", "--count", wt.base+".."+wt.branch)
+	if err != nil {
+		return fmt.Sprintf("Failed to inspect branch %s: %v", wt.branch, err)
+	}
+	if n, _ := strconv.Atoi(strings.TrimSpace(count)); n == 0 {
+		c.removeWorktree(ctx, wt, true)
+		return "The subagent made no file changes (its worktree and branch were removed)."
+	}
+
+	stat, _ := tools.RunGit(ctx, c.Repo, "diff", "--stat", wt.base, wt.branch)
+	diff, _ := tools.RunGit(ctx, c.Repo, "diff", wt.base, wt.branch)
+	c.removeWorktree(ctx, wt, false)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Changes were committed to branch %s (based on %s):\n", wt.branch, shortHash(wt.base))
+	sb.WriteString(strings.TrimRight(stat, "\n"))
+	sb.WriteString("\n\nDiff:\n")
+	sb.WriteString(truncateLines(strings.TrimRight(diff, "\n"), maxWorktreeDiffLines))
+	fmt.Fprintf(&sb, "\n\nThe working tree was NOT modified. Review with git_diff (ref %q), merge with `git merge %s`, or discard with git_branch (action delete, override true).",
+		shortHash(wt.base)+"..."+wt.branch, wt.branch)
+	return sb.String()
+}
+
+// removeWorktree 删除工作树目录，deleteBranch 为 true 时同时删除分支
+func (c *WorktreeConfig) removeWorktree(ctx context.Context, wt *worktree, deleteBranch bool) {
+	tools.RunGit(ctx, c.Repo, "worktree", "remove", "--force", wt.path)
+	if deleteBranch {
+		tools.RunGit(ctx, c.Repo, "branch", "-D", wt.branch)
+	}
+}
+
+// bindMiddlewares 为工作树创建工具注册表和中间件
+// 可绑定的中间件（文件系统、git、诊断等）重新创建并作用于工作树，其余中间件和工具与主 Agent 共享
+func bindMiddlewares(wt *worktree, middlewares []agent.Middleware, parent *tools.Registry) (*tools.Registry, []agent.Middleware, func()) {
+	registry := tools.NewRegistry()
+	// bash 在工作树中执行（先注册，文件系统中间件注册的同名工具会被忽略）
+	registry.Register(tools.NewBashToolWithDir(wt.path))
+
+	bound := make([]agent.Middleware, 0, len(middlewares))
+	var closers []io.Closer
+	for _, mw := range middlewares {
+		if binder, ok := mw.(backendBinder); ok {
+			mw = binder.bindBackend(wt.backend, registry)
+			if closer, ok := mw.(io.Closer); ok {
+				closers = append(closers, closer)
+			}
+		}
+		bound = append(bound, mw)
+	}
+
+	// 其余工具沿用主 Agent 的实例（同名工具已注册时忽略）
+	for _, tool := range parent.List() {
+		registry.Register(tool)
+	}
+
+	return registry, bound, func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}
+}
+
+// commitMessage 根据任务生成提交信息
+func commitMessage(task string) string {
+	return "subagent: " + taskTitle(task)
+}
+
+// taskTitle 取任务描述的第一行作为标题（最多 72 个字符）
+func taskTitle(task string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(task), "\n")
+	if r := []rune(title); len(r) > 72 {
+		title = string(r[:69]) + "..."
+	}
+	return title
+}
+
+// shortHash 返回提交哈希的短格式
+func shortHash(hash string) string {
+	if len(hash) > 10 {
+		return hash[:10]
+	}
+	return hash
+}
+
+// truncateLines 保留前 max 行并注明省略的行数
+func truncateLines(text string, max int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= max {
+		return text
+	}
+	return strings.Join(lines[:max], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-max)
+}
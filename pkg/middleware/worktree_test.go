@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// newWorktreeRepo 创建包含一个提交的临时 git 仓库
+func newWorktreeRepo(t *testing.T) (string, *backend.FilesystemBackend) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"add", "README.md"},
+		{"commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	b, err := backend.NewFilesystemBackend(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, b
+}
+
+// newWorktreeSubAgent 创建启用工作树隔离的子Agent中间件
+func newWorktreeSubAgent(repo *backend.FilesystemBackend, responses ...*llm.ModelResponse) *SubAgentMiddleware {
+	toolRegistry := tools.NewRegistry()
+	middlewares := []agent.Middleware{
+		NewFilesystemMiddleware(repo, toolRegistry),
+		NewGitMiddleware(repo, toolRegistry),
+	}
+	return NewSubAgentMiddleware(
+		&SubAgentConfig{MaxDepth: 3, Worktree: &WorktreeConfig{Repo: repo}},
+		&subAgentMockLLMClient{responses: responses},
+		toolRegistry, middlewares, "", 4096, 0.7,
+	)
+}
+
+func TestSubAgentMiddleware_WorktreeIsolation(t *testing.T) {
+	dir, repo := newWorktreeRepo(t)
+	m := newWorktreeSubAgent(repo,
+		&llm.ModelResponse{
+			ToolCalls: []llm.ToolCall{{
+				ID:    "call_1",
+				Name:  "write_file",
+				Input: map[string]any{"path": "/feature.txt", "content": "hello from subagent\n"},
+			}},
+			StopReason: "tool_use",
+		},
+		&llm.ModelResponse{Content: "Added feature.txt", StopReason: "end_turn"},
+	)
+
+	result, err := m.executeSubAgent(context.Background(), map[string]any{"task": "Add a feature file"})
+	if err != nil {
+		t.Fatalf("executeSubAgent failed: %v", err)
+	}
+	for _, s := range []string{"Added feature.txt", "Changes were committed to branch deepagents/subagent-", "feature.txt | 1 +", "+hello from subagent"} {
+		if !strings.Contains(result, s) {
+			t.Errorf("expected %q in result:\n%s", s, result)
+		}
+	}
+
+	// 主工作区不受影响
+	if _, err := os.Stat(filepath.Join(dir, "feature.txt")); !os.IsNotExist(err) {
+		t.Errorf("subagent changes leaked into the main working tree: %v", err)
+	}
+	status, err := tools.RunGit(context.Background(), repo, "status", "--porcelain")
+	if err != nil || status != "" {
+		t.Errorf("expected clean main working tree, got %q (err=%v)", status, err)
+	}
+
+	// 变更提交到了子Agent的分支，工作树目录已删除
+	branches, _ := tools.RunGit(context.Background(), repo, "branch", "--list", "deepagents/*", "--format=%(refname:short)")
+	branch := strings.TrimSpace(branches)
+	content, err := tools.RunGit(context.Background(), repo, "show", branch+":feature.txt")
+	if err != nil || content != "hello from subagent\n" {
+		t.Errorf("expected file on branch %q, got %q (err=%v)", branch, content, err)
+	}
+	worktrees, _ := tools.RunGit(context.Background(), repo, "worktree", "list")
+	if strings.Count(strings.TrimSpace(worktrees), "\n") != 0 {
+		t.Errorf("expected subagent worktree to be removed, got:\n%s", worktrees)
+	}
+}
+
+func TestSubAgentMiddleware_WorktreeWithoutChanges(t *testing.T) {
+	_, repo := newWorktreeRepo(t)
+	m := newWorktreeSubAgent(repo, &llm.ModelResponse{Content: "Nothing to do", StopReason: "end_turn"})
+
+	result, err := m.executeSubAgent(context.Background(), map[string]any{"task": "Inspect only"})
+	if err != nil {
+		t.Fatalf("executeSubAgent failed: %v", err)
+	}
+	if !strings.Contains(result, "made no file changes") {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+	if branches, _ := tools.RunGit(context.Background(), repo, "branch", "--list", "deepagents/*"); branches != "" {
+		t.Errorf("expected subagent branch to be deleted, got %q", branches)
+	}
+}
+
+func TestSubAgentMiddleware_WorktreeRequiresRepository(t *testing.T) {
+	repo, err := backend.NewFilesystemBackend(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newWorktreeSubAgent(repo)
+
+	if _, err := m.executeSubAgent(context.Background(), map[string]any{"task": "x"}); err == nil || !strings.Contains(err.Error(), "git repository") {
+		t.Errorf("expected repository error, got %v", err)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	if got := commitMessage("  Fix the parser\nwith details"); got != "subagent: Fix the parser" {
+		t.Errorf("unexpected message: %q", got)
+	}
+	if got := commitMessage(strings.Repeat("x", 100)); len(got) != len("subagent: ")+72 {
+		t.Errorf("expected title truncated to 72 characters, got %d", len(got))
+	}
+}
@@ -0,0 +1,113 @@
+// Package team 将多个 Agent 组合为团队：把 Agent 包装为工具，或由路由 Agent 将对话移交给其他 Agent
+package team
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// AgentToolConfig Agent 工具配置
+type AgentToolConfig struct {
+	Name          string                                                // 工具名称
+	Description   string                                                // 工具描述
+	Agent         agent.Agent                                           // 被包装的 Agent
+	InputSchema   map[string]any                                        // 参数的 JSON Schema，默认包含 task 和 context
+	BuildInput    func(args map[string]any) (*agent.InvokeInput, error) // 将参数转换为 Agent 输入，默认以 task 作为用户消息
+	ExtractOutput func(output *agent.InvokeOutput) (string, error)      // 从 Agent 输出提取工具结果，默认取最后一条助手消息
+}
+
+// NewAgentTool 将 Agent 包装为工具
+// 调用方流式执行时，被包装 Agent 的事件以子 Agent 事件的形式转发到调用方的事件流
+func NewAgentTool(cfg AgentToolConfig) tools.Tool {
+	schema := cfg.InputSchema
+	if schema == nil {
+		schema = map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"task": map[string]any{
+					"type":        "string",
+					"description": "交给该 Agent 的任务描述（需自包含）",
+				},
+				"context": map[string]any{
+					"type":        "string",
+					"description": "任务相关的上下文信息（可选）",
+				},
+			},
+			"required": []string{"task"},
+		}
+	}
+	buildInput := cfg.BuildInput
+	if buildInput == nil {
+		buildInput = taskInput
+	}
+	extractOutput := cfg.ExtractOutput
+	if extractOutput == nil {
+		extractOutput = lastAssistantOutput
+	}
+
+	return tools.NewBaseTool(cfg.Name, cfg.Description, schema,
+		func(ctx context.Context, args map[string]any) (string, error) {
+			if cfg.Agent == nil {
+				return "", fmt.Errorf("agent tool %s has no agent configured", cfg.Name)
+			}
+			input, err := buildInput(args)
+			if err != nil {
+				return "", err
+			}
+			output, err := agent.InvokeNested(ctx, cfg.Agent, input, agent.SubAgentInfo{
+				Name: cfg.Name,
+				Task: inputTitle(input),
+			})
+			if err != nil {
+				return "", fmt.Errorf("agent %s failed: %w", cfg.Name, err)
+			}
+			return extractOutput(output)
+		},
+	)
+}
+
+// taskInput 默认的输入转换：task（及可选的 context）作为一条用户消息
+func taskInput(args map[string]any) (*agent.InvokeInput, error) {
+	task := tools.GetStringArg(args, "task", "")
+	if task == "" {
+		return nil, fmt.Errorf("task must be a non-empty string")
+	}
+	if contextInfo := tools.GetStringArg(args, "context", ""); contextInfo != "" {
+		task = fmt.Sprintf("Context: %s\n\nTask: %s", contextInfo, task)
+	}
+	return &agent.InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: task}},
+	}, nil
+}
+
+// lastAssistantOutput 默认的输出提取：最后一条助手消息
+func lastAssistantOutput(output *agent.InvokeOutput) (string, error) {
+	if content := agent.LastAssistantMessage(output); content != "" {
+		return content, nil
+	}
+	return "", fmt.Errorf("agent returned no assistant message")
+}
+
+// inputTitle 取最后一条用户消息的第一行作为任务标题
+func inputTitle(input *agent.InvokeInput) string {
+	for i := len(input.Messages) - 1; i >= 0; i-- {
+		if input.Messages[i].Role == llm.RoleUser && input.Messages[i].Content != "" {
+			return title(input.Messages[i].Content)
+		}
+	}
+	return ""
+}
+
+// title 取文本的第一行（最多 72 个字符）
+func title(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if r := []rune(line); len(r) > 72 {
+		line = string(r[:69]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,125 @@
+package team
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// fakeAgent 记录输入并返回固定回答的 Agent
+type fakeAgent struct {
+	answer string
+	inputs [][]llm.Message
+}
+
+func (a *fakeAgent) Invoke(ctx context.Context, input *agent.InvokeInput) (*agent.InvokeOutput, error) {
+	a.inputs = append(a.inputs, input.Messages)
+	messages := append(append([]llm.Message{}, input.Messages...), llm.Message{Role: llm.RoleAssistant, Content: a.answer})
+	return &agent.InvokeOutput{Messages: messages}, nil
+}
+
+func (a *fakeAgent) InvokeStream(ctx context.Context, input *agent.InvokeInput) (<-chan agent.AgentEvent, error) {
+	output, _ := a.Invoke(ctx, input)
+	events := make(chan agent.AgentEvent, 3)
+	events <- agent.AgentEvent{Type: agent.AgentEventTypeStart}
+	events <- agent.AgentEvent{Type: agent.AgentEventTypeLLMText, Content: a.answer}
+	events <- agent.AgentEvent{Type: agent.AgentEventTypeEnd, Metadata: map[string]any{"messages": output.Messages}, Done: true}
+	close(events)
+	return events, nil
+}
+
+// scriptedLLM 按顺序返回预设响应的 LLM 客户端
+type scriptedLLM struct {
+	responses []*llm.ModelResponse
+	calls     int
+}
+
+func (c *scriptedLLM) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	if c.calls >= len(c.responses) {
+		return &llm.ModelResponse{Content: "Done", StopReason: "end_turn"}, nil
+	}
+	c.calls++
+	return c.responses[c.calls-1], nil
+}
+
+func (c *scriptedLLM) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	resp, _ := c.Generate(ctx, req)
+	events := make(chan llm.StreamEvent, len(resp.ToolCalls)+2)
+	if resp.Content != "" {
+		events <- llm.StreamEvent{Type: llm.StreamEventTypeText, Content: resp.Content}
+	}
+	for i := range resp.ToolCalls {
+		events <- llm.StreamEvent{Type: llm.StreamEventTypeToolUse, ToolCall: &resp.ToolCalls[i]}
+	}
+	events <- llm.StreamEvent{Type: llm.StreamEventTypeEnd, StopReason: resp.StopReason, Done: true}
+	close(events)
+	return events, nil
+}
+
+func (c *scriptedLLM) CountTokens(messages []llm.Message) int {
+	return 0
+}
+
+func TestAgentTool_Defaults(t *testing.T) {
+	inner := &fakeAgent{answer: "42"}
+	tool := NewAgentTool(AgentToolConfig{Name: "calculator", Description: "算数", Agent: inner})
+
+	if required := tool.Parameters()["required"].([]string); len(required) != 1 || required[0] != "task" {
+		t.Errorf("unexpected default schema: %v", tool.Parameters())
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]any{"task": "6*7", "context": "integers"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != "42" {
+		t.Errorf("expected last assistant message, got %q", result)
+	}
+	if got := inner.inputs[0][0].Content; got != "Context: integers\n\nTask: 6*7" {
+		t.Errorf("unexpected input: %q", got)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]any{}); err == nil {
+		t.Error("expected error for missing task")
+	}
+}
+
+func TestAgentTool_CustomInputOutput(t *testing.T) {
+	inner := &fakeAgent{answer: "bonjour"}
+	tool := NewAgentTool(AgentToolConfig{
+		Name:        "translator",
+		Agent:       inner,
+		InputSchema: map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}},
+		BuildInput: func(args map[string]any) (*agent.InvokeInput, error) {
+			return &agent.InvokeInput{Messages: []llm.Message{{Role: llm.RoleUser, Content: "Translate: " + args["text"].(string)}}}, nil
+		},
+		ExtractOutput: func(output *agent.InvokeOutput) (string, error) {
+			return strings.ToUpper(agent.LastAssistantMessage(output)), nil
+		},
+	})
+
+	var events []agent.AgentEvent
+	ctx := agent.WithEventEmitter(context.Background(), func(event agent.AgentEvent) {
+		events = append(events, event)
+	})
+	result, err := tool.Execute(ctx, map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != "BONJOUR" || inner.inputs[0][0].Content != "Translate: hello" {
+		t.Errorf("unexpected result %q for input %q", result, inner.inputs[0][0].Content)
+	}
+
+	// 流式执行时内部事件标记为子 Agent 事件
+	if len(events) != 3 {
+		t.Fatalf("expected 3 forwarded events, got %d", len(events))
+	}
+	for _, event := range events {
+		if event.SubAgent == nil || event.SubAgent.Name != "translator" || event.SubAgent.Depth != 1 || event.Done {
+			t.Errorf("unexpected forwarded event: %+v", event)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package team
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/middleware"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+// Peer 可接手对话的 Agent
+type Peer struct {
+	Name        string      // 名称（handoff 工具的 agent 参数）
+	Description string      // 擅长的工作，渲染到工具描述中供路由 Agent 选择
+	Agent       agent.Agent // Agent 实例
+}
+
+// Supervisor 路由 Agent 的包装
+// 运行期间路由 Agent 可通过 handoff 工具将完整对话移交给其他 Agent，对方完成后控制权交回路由 Agent
+type Supervisor struct {
+	name   string
+	router agent.Agent
+}
+
+// NewSupervisor 创建 Supervisor
+// router 的工具中应包含 NewHandoffTool 创建的工具，中间件中应包含 NewHandoffMiddleware 创建的中间件
+func NewSupervisor(name string, router agent.Agent) *Supervisor {
+	return &Supervisor{name: name, router: router}
+}
+
+// Invoke 执行路由 Agent（非流式）
+func (s *Supervisor) Invoke(ctx context.Context, input *agent.InvokeInput) (*agent.InvokeOutput, error) {
+	return s.router.Invoke(s.withConversation(ctx), input)
+}
+
+// InvokeStream 执行路由 Agent（流式），移交过程以 handoff / handoff_end 事件呈现
+func (s *Supervisor) InvokeStream(ctx context.Context, input *agent.InvokeInput) (<-chan agent.AgentEvent, error) {
+	return s.router.InvokeStream(s.withConversation(ctx), input)
+}
+
+// withConversation 为本次运行创建对话记录
+func (s *Supervisor) withConversation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conversationKey{}, &conversation{
+		supervisor: s.name,
+		answers:    make(map[string]string),
+	})
+}
+
+// HandoffMiddleware 在路由 Agent 调用 handoff 工具前记录当前的完整对话
+// 移交的对话因此包含本次运行中路由 Agent 已产生的消息和之前各 Agent 的回答
+type HandoffMiddleware struct {
+	*middleware.BaseMiddleware
+}
+
+// NewHandoffMiddleware 创建 handoff 中间件
+func NewHandoffMiddleware() *HandoffMiddleware {
+	return &HandoffMiddleware{BaseMiddleware: middleware.NewBaseMiddleware("handoff")}
+}
+
+// BeforeTool 记录 handoff 调用时路由 Agent 的消息
+func (m *HandoffMiddleware) BeforeTool(ctx context.Context, toolCall *llm.ToolCall, state *agent.State) error {
+	if toolCall.Name != "handoff" {
+		return nil
+	}
+	if conv, ok := ctx.Value(conversationKey{}).(*conversation); ok && conv != nil {
+		conv.capture(toolCall.ID, state.GetMessages())
+	}
+	return nil
+}
+
+// conversationKey 用于在上下文中存储本次运行的对话记录
+type conversationKey struct{}
+
+// conversation 一次 Supervisor 运行中的对话记录
+type conversation struct {
+	supervisor string
+	mu         sync.Mutex
+	messages   []llm.Message     // 最近一次 handoff 调用时路由 Agent 的消息
+	callID     string            // 最近一次 handoff 调用的 ID
+	answers    map[string]string // handoff 调用 ID -> 对方的回答
+}
+
+// capture 记录 handoff 调用时路由 Agent 的消息
+func (c *conversation) capture(callID string, messages []llm.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callID = callID
+	c.messages = messages
+}
+
+// handoffInput 构造移交给其他 Agent 的对话：完整对话加上交接说明，保证以用户消息结尾
+func (c *conversation) handoffInput(note string) ([]llm.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.messages == nil {
+		return nil, fmt.Errorf("handoff requires the router to use the middleware created by NewHandoffMiddleware")
+	}
+	messages := transcript(c.messages, c.answers)
+	if note != "" {
+		note = fmt.Sprintf("[%s 的交接说明] %s", c.supervisor, note)
+	}
+	if n := len(messages); n > 0 && messages[n-1].Role == llm.RoleUser {
+		if note != "" {
+			messages[n-1].Content += "\n\n" + note
+		}
+		return messages, nil
+	}
+	if note == "" {
+		note = "请继续处理上面的对话。"
+	}
+	return append(messages, llm.Message{Role: llm.RoleUser, Content: note}), nil
+}
+
+// record 记录最近一次移交的回答，之后的移交可以看到之前各 Agent 的回答
+func (c *conversation) record(answer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.answers[c.callID] = answer
+}
+
+// transcript 提取对话中的文本消息，相邻同角色消息合并
+// 工具结果中只保留 handoff 的回答（作为助手消息），其余工具调用和结果省略
+func transcript(messages []llm.Message, answers map[string]string) []llm.Message {
+	var result []llm.Message
+	add := func(role llm.Role, content string) {
+		content = strings.TrimSpace(content)
+		if content == "" {
+			return
+		}
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content += "\n\n" + content
+			return
+		}
+		result = append(result, llm.Message{Role: role, Content: content})
+	}
+
+	for _, msg := range messages {
+		if msg.Role != llm.RoleUser && msg.Role != llm.RoleAssistant {
+			continue
+		}
+		for _, tr := range msg.ToolResults {
+			if answer, ok := answers[tr.ToolCallID]; ok {
+				add(llm.RoleAssistant, answer)
+			}
+		}
+		add(msg.Role, msg.Content)
+	}
+	return result
+}
+
+// NewHandoffTool 创建 handoff 工具，路由 Agent 通过它将完整对话移交给 peers 中的 Agent
+// 工具需在 Supervisor 中运行且路由 Agent 使用 NewHandoffMiddleware；对方完成后其回答作为工具结果返回，控制权交回路由 Agent
+func NewHandoffTool(peers ...Peer) tools.Tool {
+	names := make([]string, 0, len(peers))
+	var desc strings.Builder
+	desc.WriteString("将当前完整对话移交给更合适的 Agent 处理。对方能看到完整对话，完成后其回答作为结果返回，由你决定直接回复用户、继续移交或补充处理。\n\n可移交的 Agent：")
+	for _, peer := range peers {
+		names = append(names, peer.Name)
+		fmt.Fprintf(&desc, "\n- %s: %s", peer.Name, peer.Description)
+	}
+
+	return tools.NewBaseTool(
+		"handoff",
+		desc.String(),
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"agent": map[string]any{
+					"type":        "string",
+					"enum":        names,
+					"description": "接手对话的 Agent",
+				},
+				"note": map[string]any{
+					"type":        "string",
+					"description": "交接说明：需要对方完成什么、已经知道的信息（可选）",
+				},
+			},
+			"required": []string{"agent"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			conv, ok := ctx.Value(conversationKey{}).(*conversation)
+			if !ok || conv == nil {
+				return "", fmt.Errorf("handoff is only available when running under a Supervisor")
+			}
+			name := tools.GetStringArg(args, "agent", "")
+			var peer *Peer
+			for i := range peers {
+				if peers[i].Name == name {
+					peer = &peers[i]
+					break
+				}
+			}
+			if peer == nil {
+				return "", fmt.Errorf("unknown agent %q, available: %s", name, strings.Join(names, ", "))
+			}
+			note := tools.GetStringArg(args, "note", "")
+			return handoff(ctx, conv, peer, note)
+		},
+	)
+}
+
+// handoff 执行一次移交并发送 handoff / handoff_end 事件
+func handoff(ctx context.Context, conv *conversation, peer *Peer, note string) (string, error) {
+	emit := agent.EventEmitterFromContext(ctx)
+	if emit != nil {
+		emit(agent.AgentEvent{
+			Type:     agent.AgentEventTypeHandoff,
+			Content:  note,
+			Metadata: map[string]any{"from": conv.supervisor, "to": peer.Name},
+		})
+	}
+
+	input, err := conv.handoffInput(note)
+	if err != nil {
+		return "", err
+	}
+	// 对方不能再使用本次运行的对话记录移交
+	peerCtx := context.WithValue(ctx, conversationKey{}, (*conversation)(nil))
+	output, err := agent.InvokeNested(peerCtx, peer.Agent, &agent.InvokeInput{Messages: input}, agent.SubAgentInfo{
+		Name: peer.Name,
+		Task: title(input[len(input)-1].Content),
+	})
+	answer := agent.LastAssistantMessage(output)
+
+	if emit != nil {
+		emit(agent.AgentEvent{
+			Type:     agent.AgentEventTypeHandoffEnd,
+			Content:  answer,
+			Metadata: map[string]any{"from": peer.Name, "to": conv.supervisor},
+			Error:    err,
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("agent %s failed: %w", peer.Name, err)
+	}
+
+	conv.record(answer)
+	return fmt.Sprintf("%s finished and handed control back to you.\n\nResponse:\n%s", peer.Name, answer), nil
+}
@@ -0,0 +1,174 @@
+package team
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+func TestSupervisor_Handoff(t *testing.T) {
+	coder := &fakeAgent{answer: "patched main.go"}
+	handoffTool := NewHandoffTool(
+		Peer{Name: "coder", Description: "写代码", Agent: coder},
+		Peer{Name: "writer", Description: "写文档", Agent: &fakeAgent{answer: "docs"}},
+	)
+	if !strings.Contains(handoffTool.Description(), "- coder: 写代码") {
+		t.Errorf("expected peers in description:\n%s", handoffTool.Description())
+	}
+
+	registry := tools.NewRegistry()
+	registry.Register(handoffTool)
+	router := agent.NewRunnable(&agent.Config{
+		LLMClient: &scriptedLLM{responses: []*llm.ModelResponse{
+			{
+				ToolCalls:  []llm.ToolCall{{ID: "call_1", Name: "handoff", Input: map[string]any{"agent": "coder", "note": "修复空指针"}}},
+				StopReason: "tool_use",
+			},
+			{Content: "coder 已修复", StopReason: "end_turn"},
+		}},
+		ToolRegistry: registry,
+		Middlewares:  []agent.Middleware{NewHandoffMiddleware()},
+	})
+	supervisor := NewSupervisor("router", router)
+
+	stream, err := supervisor.InvokeStream(context.Background(), &agent.InvokeInput{
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: "你好"},
+			{Role: llm.RoleAssistant, Content: "你好，需要什么帮助？", ToolCalls: []llm.ToolCall{{ID: "old", Name: "ls"}}},
+			{Role: llm.RoleUser, ToolResults: []llm.ToolResult{{ToolCallID: "old", Content: "a.go"}}},
+			{Role: llm.RoleUser, Content: "main.go 崩溃了"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	var handoffs, returns, peerEvents int
+	var final []llm.Message
+	for event := range stream {
+		switch {
+		case event.SubAgent != nil:
+			peerEvents++
+			if event.SubAgent.Name != "coder" {
+				t.Errorf("unexpected peer event source: %+v", event.SubAgent)
+			}
+		case event.Type == agent.AgentEventTypeHandoff:
+			handoffs++
+			if event.Metadata["from"] != "router" || event.Metadata["to"] != "coder" || event.Content != "修复空指针" {
+				t.Errorf("unexpected handoff event: %+v", event)
+			}
+		case event.Type == agent.AgentEventTypeHandoffEnd:
+			returns++
+			if event.Metadata["from"] != "coder" || event.Content != "patched main.go" {
+				t.Errorf("unexpected handoff_end event: %+v", event)
+			}
+		case event.Type == agent.AgentEventTypeEnd:
+			final, _ = event.Metadata["messages"].([]llm.Message)
+		case event.Type == agent.AgentEventTypeError:
+			t.Fatalf("unexpected error: %v", event.Error)
+		}
+	}
+	if handoffs != 1 || returns != 1 || peerEvents == 0 {
+		t.Errorf("handoffs=%d returns=%d peerEvents=%d", handoffs, returns, peerEvents)
+	}
+
+	// 对方收到完整的文本对话和交接说明
+	input := coder.inputs[0]
+	if len(input) != 3 || input[0].Content != "你好" || input[1].Role != llm.RoleAssistant ||
+		input[2].Content != "main.go 崩溃了\n\n[router 的交接说明] 修复空指针" {
+		t.Errorf("unexpected handoff input: %+v", input)
+	}
+
+	// 控制权交回路由 Agent，由其给出最终回答
+	if len(final) == 0 || final[len(final)-1].Content != "coder 已修复" {
+		t.Fatalf("expected router to finish the run, got %+v", final)
+	}
+	toolResult := final[len(final)-2].ToolResults[0]
+	if toolResult.IsError || !strings.Contains(toolResult.Content, "patched main.go") {
+		t.Errorf("unexpected handoff result: %+v", toolResult)
+	}
+}
+
+func TestSupervisor_SequentialHandoffs(t *testing.T) {
+	coder := &fakeAgent{answer: "func f() {}"}
+	writer := &fakeAgent{answer: "docs"}
+	registry := tools.NewRegistry()
+	registry.Register(NewHandoffTool(Peer{Name: "coder", Agent: coder}, Peer{Name: "writer", Agent: writer}))
+	router := agent.NewRunnable(&agent.Config{
+		LLMClient: &scriptedLLM{responses: []*llm.ModelResponse{
+			{
+				Content:    "先让 coder 实现",
+				ToolCalls:  []llm.ToolCall{{ID: "call_1", Name: "handoff", Input: map[string]any{"agent": "coder"}}},
+				StopReason: "tool_use",
+			},
+			{
+				Content:    "实现好了，再补文档",
+				ToolCalls:  []llm.ToolCall{{ID: "call_2", Name: "handoff", Input: map[string]any{"agent": "writer", "note": "补充文档"}}},
+				StopReason: "tool_use",
+			},
+			{Content: "完成", StopReason: "end_turn"},
+		}},
+		ToolRegistry: registry,
+		Middlewares:  []agent.Middleware{NewHandoffMiddleware()},
+	})
+
+	_, err := NewSupervisor("router", router).Invoke(context.Background(), &agent.InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "写个函数"}},
+	})
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	// 移交的对话包含本次运行中路由 Agent 的消息
+	first := coder.inputs[0]
+	if len(first) != 3 || first[1].Content != "先让 coder 实现" || first[2].Content != "请继续处理上面的对话。" {
+		t.Errorf("unexpected first handoff input: %+v", first)
+	}
+
+	// 之后的移交包含之前 Agent 的回答
+	second := writer.inputs[0]
+	if len(second) != 3 || second[1].Role != llm.RoleAssistant ||
+		second[1].Content != "先让 coder 实现\n\nfunc f() {}\n\n实现好了，再补文档" ||
+		second[2].Content != "[router 的交接说明] 补充文档" {
+		t.Errorf("unexpected second handoff input: %+v", second)
+	}
+}
+
+func TestHandoffTool_RequiresMiddleware(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(NewHandoffTool(Peer{Name: "coder", Agent: &fakeAgent{}}))
+	router := agent.NewRunnable(&agent.Config{
+		LLMClient: &scriptedLLM{responses: []*llm.ModelResponse{{
+			ToolCalls:  []llm.ToolCall{{ID: "call_1", Name: "handoff", Input: map[string]any{"agent": "coder"}}},
+			StopReason: "tool_use",
+		}}},
+		ToolRegistry: registry,
+	})
+
+	output, err := NewSupervisor("router", router).Invoke(context.Background(), &agent.InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "写个函数"}},
+	})
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	result := output.Messages[2].ToolResults[0]
+	if !result.IsError || !strings.Contains(result.Content, "NewHandoffMiddleware") {
+		t.Errorf("expected missing middleware error, got %+v", result)
+	}
+}
+
+func TestHandoffTool_RequiresSupervisor(t *testing.T) {
+	tool := NewHandoffTool(Peer{Name: "coder", Agent: &fakeAgent{}})
+	if _, err := tool.Execute(context.Background(), map[string]any{"agent": "coder"}); err == nil {
+		t.Error("expected error outside of a Supervisor")
+	}
+
+	ctx := context.WithValue(context.Background(), conversationKey{}, &conversation{supervisor: "router"})
+	if _, err := tool.Execute(ctx, map[string]any{"agent": "missing"}); err == nil {
+		t.Error("expected error for unknown agent")
+	}
+}
@@ -26,6 +26,21 @@ func GetBoolArg(args map[string]any, key string, defaultVal bool) bool {
 	return defaultVal
 }
 
+// GetStringSliceArg 从参数中获取字符串数组（忽略非字符串和空字符串元素）
+func GetStringSliceArg(args map[string]any, key string) []string {
+	list, ok := args[key].([]any)
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
 // ValidateStringArg 验证必需的字符串参数
 func ValidateStringArg(args map[string]any, key string) (string, error) {
 	v, ok := args[key].(string)
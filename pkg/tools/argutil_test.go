@@ -156,6 +156,22 @@ func TestGetBoolArg(t *testing.T) {
 	}
 }
 
+func TestGetStringSliceArg(t *testing.T) {
+	args := map[string]any{
+		"paths": []any{"a.go", "", 1, "b.go"},
+		"bad":   "a.go",
+	}
+	if got := GetStringSliceArg(args, "paths"); !reflect.DeepEqual(got, []string{"a.go", "b.go"}) {
+		t.Errorf("expected [a.go b.go], got %v", got)
+	}
+	if got := GetStringSliceArg(args, "bad"); got != nil {
+		t.Errorf("expected nil for non-array value, got %v", got)
+	}
+	if got := GetStringSliceArg(args, "missing"); got != nil {
+		t.Errorf("expected nil for missing key, got %v", got)
+	}
+}
+
 func TestValidateStringArg(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// attachmentsKey 用于在上下文中存储工具结果的附件
+type attachmentsKey struct{}
+
+// Attachments 收集一次工具调用附加到结果中的内容块（如图片）
+type Attachments struct {
+	mu     sync.Mutex
+	blocks []llm.ContentBlock
+}
+
+// WithAttachments 返回可以收集附件的上下文，执行器在调用工具前创建，调用后把附件放入工具结果
+func WithAttachments(ctx context.Context) (context.Context, *Attachments) {
+	a := &Attachments{}
+	return context.WithValue(ctx, attachmentsKey{}, a), a
+}
+
+// Blocks 返回已附加的内容块
+func (a *Attachments) Blocks() []llm.ContentBlock {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]llm.ContentBlock(nil), a.blocks...)
+}
+
+// Attach 把内容块附加到当前工具调用的结果中
+// ctx 不支持附件（如直接调用工具）时返回 false，工具应改为返回文本描述
+func Attach(ctx context.Context, blocks ...llm.ContentBlock) bool {
+	a, _ := ctx.Value(attachmentsKey{}).(*Attachments)
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.blocks = append(a.blocks, blocks...)
+	return true
+}
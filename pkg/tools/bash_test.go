@@ -81,3 +81,12 @@ func TestBashTool(t *testing.T) {
 		}
 	})
 }
+
+func TestBashTool_BlocksUnsafeGit(t *testing.T) {
+	tool := NewBashTool()
+
+	_, err := tool.Execute(context.Background(), map[string]any{"command": "git reset --hard HEAD"})
+	if err == nil || !strings.Contains(err.Error(), "override") {
+		t.Errorf("expected unsafe git command to be blocked, got %v", err)
+	}
+}
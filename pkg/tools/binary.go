@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器（用于读取图片尺寸）
+	_ "image/jpeg" // 注册 JPEG 解码器
+	_ "image/png"  // 注册 PNG 解码器
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/internal/stringutil"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// maxBase64ReadSize read_file 以 base64 返回内容时允许的最大文件大小
+const maxBase64ReadSize = 5 << 20
+
+// maxImageAttachSize read_file 以图片形式提供给模型的最大文件大小
+const maxImageAttachSize = 5 << 20
+
+// attachableImageTypes 可以作为图片提供给模型的 MIME 类型
+var attachableImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// readFileContent 读取文件内容供模型查看
+// 合法 UTF-8 文本直接返回；非 UTF-8 文本解码为 UTF-8；二进制文件返回描述信息，
+// 其中不超过大小限制的图片同时作为图片内容块附加到工具结果中
+func readFileContent(ctx context.Context, b backend.Backend, path string, offset, limit int) (string, error) {
+	content, err := b.ReadFile(ctx, path, offset, limit)
+	if err != nil {
+		return "", err
+	}
+
+	// 快速路径：普通 UTF-8 文本
+	if utf8.ValidString(content) && strings.IndexByte(content[:min(len(content), 8192)], 0) == -1 {
+		return content, nil
+	}
+
+	data, err := backend.ReadBytes(ctx, b, path)
+	if err != nil {
+		return "", err
+	}
+
+	info := backend.DetectContent(data)
+	if info.Binary {
+		attached := attachableImageTypes[info.MIMEType] && len(data) <= maxImageAttachSize &&
+			Attach(ctx, llm.NewImageBlock(info.MIMEType, data))
+		return describeBinary(path, data, info, attached), nil
+	}
+
+	text, err := backend.DecodeText(data, info.Encoding)
+	if err != nil {
+		return "", err
+	}
+
+	if offset > 0 || limit > 0 {
+		lines := stringutil.SplitLines(text)
+		if offset >= len(lines) {
+			return "", nil
+		}
+		end := len(lines)
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		return stringutil.JoinLines(lines[offset:end]), nil
+	}
+	return text, nil
+}
+
+// readFileBase64 以 base64 返回文件的原始字节
+func readFileBase64(ctx context.Context, b backend.Backend, path string) (string, error) {
+	data, err := backend.ReadBytes(ctx, b, path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxBase64ReadSize {
+		return "", fmt.Errorf("file too large for base64 output: %d bytes (max %d)", len(data), maxBase64ReadSize)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// writeFileBase64 解码 base64 内容并写入原始字节
+func writeFileBase64(ctx context.Context, b backend.Backend, path, content string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 content: %w", err)
+	}
+
+	result, err := backend.WriteBytes(ctx, b, path, data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Successfully wrote %d bytes to %s", result.BytesWritten, result.Path), nil
+}
+
+// describeBinary 生成二进制文件的描述（类型、大小，图片附带尺寸），attached 表示图片已附加到结果中
+func describeBinary(path string, data []byte, info backend.ContentInfo, attached bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Binary file: %s\n", path)
+	fmt.Fprintf(&sb, "Type: %s\n", info.MIMEType)
+	fmt.Fprintf(&sb, "Size: %s\n", formatSize(len(data)))
+
+	if cfg, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		fmt.Fprintf(&sb, "Dimensions: %dx%d (%s)\n", cfg.Width, cfg.Height, format)
+	}
+
+	if attached {
+		sb.WriteString("\nImage attached.")
+	} else {
+		sb.WriteString("\nContent not shown. Use encoding=\"base64\" to read the raw bytes.")
+	}
+	return sb.String()
+}
+
+// formatSize 格式化文件大小
+func formatSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB (%d bytes)", float64(n)/(1<<20), n)
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB (%d bytes)", float64(n)/(1<<10), n)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// encodeTestPNG 生成指定尺寸的 PNG 图片
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadFileTool_BinarySummary(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteBytes(ctx, "/logo.png", encodeTestPNG(t, 64, 32))
+
+	tool := NewReadFileTool(b)
+	result, err := tool.Execute(ctx, map[string]any{"path": "/logo.png"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for _, want := range []string{"Binary file: /logo.png", "Type: image/png", "Dimensions: 64x32 (png)"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected result to contain %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestReadFileTool_AttachesImage(t *testing.T) {
+	b := backend.NewStateBackend()
+	data := encodeTestPNG(t, 64, 32)
+	b.WriteBytes(context.Background(), "/logo.png", data)
+	b.WriteBytes(context.Background(), "/data.bin", []byte{0x00, 0x01, 0x02, 0xff})
+
+	tool := NewReadFileTool(b)
+	ctx, attachments := WithAttachments(context.Background())
+	result, err := tool.Execute(ctx, map[string]any{"path": "/logo.png"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(result, "Dimensions: 64x32 (png)") || !strings.Contains(result, "Image attached.") {
+		t.Errorf("Unexpected description:\n%s", result)
+	}
+	blocks := attachments.Blocks()
+	if len(blocks) != 1 || blocks[0].Type != llm.ContentBlockTypeImage || blocks[0].MediaType != "image/png" ||
+		blocks[0].Data != base64.StdEncoding.EncodeToString(data) {
+		t.Fatalf("Expected attached png block, got %+v", blocks)
+	}
+
+	// 非图片的二进制文件不附加
+	ctx, attachments = WithAttachments(context.Background())
+	if _, err := tool.Execute(ctx, map[string]any{"path": "/data.bin"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(attachments.Blocks()) != 0 {
+		t.Errorf("Expected no attachments for binary data, got %+v", attachments.Blocks())
+	}
+}
+
+func TestReadFileTool_Base64(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	data := encodeTestPNG(t, 2, 2)
+	b.WriteBytes(ctx, "/tiny.png", data)
+
+	tool := NewReadFileTool(b)
+	result, err := tool.Execute(ctx, map[string]any{"path": "/tiny.png", "encoding": "base64"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("Unexpected base64 output: %s", result)
+	}
+}
+
+func TestReadFileTool_Latin1(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteBytes(ctx, "/legacy.txt", []byte("line 1\ncaf\xe9\nline 3"))
+
+	tool := NewReadFileTool(b)
+	result, err := tool.Execute(ctx, map[string]any{"path": "/legacy.txt"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != "line 1\ncafé\nline 3" {
+		t.Errorf("Expected decoded text, got %q", result)
+	}
+
+	result, _ = tool.Execute(ctx, map[string]any{"path": "/legacy.txt", "offset": float64(1), "limit": float64(1)})
+	if result != "café" {
+		t.Errorf("Expected %q, got %q", "café", result)
+	}
+}
+
+func TestWriteFileTool_Base64(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	data := []byte{0x00, 0x01, 0xff}
+
+	tool := NewWriteFileTool(b)
+	if _, err := tool.Execute(ctx, map[string]any{
+		"path":     "/blob.bin",
+		"content":  base64.StdEncoding.EncodeToString(data),
+		"encoding": "base64",
+	}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	read, _ := b.ReadBytes(ctx, "/blob.bin")
+	if !bytes.Equal(read, data) {
+		t.Errorf("Expected %v, got %v", data, read)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]any{
+		"path":     "/blob.bin",
+		"content":  "not base64!",
+		"encoding": "base64",
+	}); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+}
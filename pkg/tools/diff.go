@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 3       // 差异上下文行数
+	maxDiffLines     = 200     // 返回给模型的差异最大行数
+	maxDiffMatrix    = 4 << 20 // LCS 矩阵的最大规模，超出时将差异区整体视为删除+插入
+)
+
+// diffOp 行级差异操作
+type diffOp struct {
+	kind byte // ' ' 相同，'-' 删除，'+' 插入
+	line string
+}
+
+// splitText 将文本按行拆分，返回行列表和是否以换行结尾
+func splitText(text string) ([]string, bool) {
+	if text == "" {
+		return nil, false
+	}
+	trailingNewline := strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), trailingNewline
+}
+
+// joinText 将行列表拼接为文本
+func joinText(lines []string, trailingNewline bool) string {
+	text := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		text += "\n"
+	}
+	return text
+}
+
+// diffLines 计算两组行之间的差异（先去除公共前后缀，再对中间部分做 LCS）
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// diffMiddle 使用 LCS 计算差异，规模过大时退化为整体替换
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffMatrix {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff 生成紧凑的统一格式差异，内容相同时返回空字符串
+func unifiedDiff(path, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	oldLines, _ := splitText(oldText)
+	newLines, _ := splitText(newText)
+	ops := diffLines(oldLines, newLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", strings.TrimPrefix(path, "/"), strings.TrimPrefix(path, "/"))
+
+	for start := 0; start < len(ops); {
+		// 找到下一处变更
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// 间隔不超过两倍上下文的变更合并为同一个 hunk
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				if k-last > 2*diffContextLines {
+					break
+				}
+				last = k
+			}
+		}
+
+		from := max(start, first-diffContextLines)
+		to := min(len(ops), last+diffContextLines+1)
+		writeHunk(&sb, ops, from, to)
+		start = to
+	}
+
+	return sb.String()
+}
+
+// writeHunk 输出 ops[from:to] 对应的 hunk
+func writeHunk(sb *strings.Builder, ops []diffOp, from, to int) {
+	oldStart, newStart := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldStart++
+		}
+		if op.kind != '-' {
+			newStart++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	// 统一格式约定：空范围的起始行号指向其前一行
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, op := range ops[from:to] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// truncateDiff 限制差异的行数，避免占用过多上下文
+func truncateDiff(diff string) string {
+	lines, _ := splitText(diff)
+	if len(lines) <= maxDiffLines {
+		return diff
+	}
+	return joinText(lines[:maxDiffLines], true) + fmt.Sprintf("... (%d more diff lines omitted)\n", len(lines)-maxDiffLines)
+}
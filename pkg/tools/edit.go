@@ -0,0 +1,463 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// textFile 解码后的文本文件
+// 内容统一为 UTF-8 和 LF 行尾，写回时恢复原有的编码和行尾风格
+type textFile struct {
+	text string
+	info backend.ContentInfo
+	raw  []byte // 原始字节（新文件为 nil）
+}
+
+// newTextFile 创建新文本文件（UTF-8、LF 行尾）
+func newTextFile() *textFile {
+	return &textFile{
+		info: backend.ContentInfo{Encoding: backend.EncodingUTF8, LineEnding: backend.LineEndingLF},
+	}
+}
+
+// readTextFile 读取并解码文本文件，二进制文件返回错误
+func readTextFile(ctx context.Context, b backend.Backend, path string) (*textFile, error) {
+	data, err := backend.ReadBytes(ctx, b, path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := backend.DetectContent(data)
+	if info.Binary {
+		return nil, fmt.Errorf("cannot edit binary file %s (%s)", path, info.MIMEType)
+	}
+
+	text, err := backend.DecodeText(data, info.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if info.LineEnding == backend.LineEndingCRLF {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	return &textFile{text: text, info: info, raw: data}, nil
+}
+
+// encode 按文件原有的编码和行尾风格编码文本
+func (f *textFile) encode(text string) ([]byte, error) {
+	if f.info.LineEnding == backend.LineEndingCRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return backend.EncodeText(text, f.info.Encoding)
+}
+
+// matchLevel 匹配策略，按置信度从高到低排列
+type matchLevel int
+
+const (
+	matchExact          matchLevel = iota // 完全匹配
+	matchTrailingSpace                    // 忽略行尾空白
+	matchIndentation                      // 忽略缩进
+	matchWhitespaceRuns                   // 忽略所有空白差异
+)
+
+// matchStrategy 模糊匹配策略
+type matchStrategy struct {
+	level      matchLevel
+	name       string
+	confidence float64
+	normalize  func(line string) string
+}
+
+// fuzzyStrategies 按顺序尝试的模糊匹配策略（逐行比较规范化后的内容）
+var fuzzyStrategies = []matchStrategy{
+	{matchTrailingSpace, "ignoring trailing whitespace", 0.95, func(line string) string {
+		return strings.TrimRight(line, " \t")
+	}},
+	{matchIndentation, "ignoring indentation", 0.85, func(line string) string {
+		return strings.TrimSpace(line)
+	}},
+	{matchWhitespaceRuns, "ignoring whitespace", 0.7, func(line string) string {
+		return strings.Join(strings.Fields(line), " ")
+	}},
+}
+
+// editMatch old_string 在文本中的一处匹配
+type editMatch struct {
+	start, end int // 字节范围
+	line       int // 起始行号（从 1 开始）
+	indent     string
+}
+
+// editOperation 一次字符串替换
+type editOperation struct {
+	oldString  string
+	newString  string
+	replaceAll bool
+}
+
+// editReport 一次替换的匹配结果
+type editReport struct {
+	replacements int
+	line         int     // 第一处匹配的行号
+	strategy     string  // 匹配策略（完全匹配时为空）
+	confidence   float64 // 匹配置信度（完全匹配为 1）
+}
+
+// applyEdits 依次对文本执行替换，任一替换失败则返回错误
+// fuzzy 为 true 时，完全匹配失败后依次尝试忽略空白差异的模糊匹配
+func applyEdits(text string, edits []editOperation, fuzzy bool) (string, []editReport, error) {
+	reports := make([]editReport, 0, len(edits))
+	for i, edit := range edits {
+		if edit.oldString == "" {
+			return "", nil, fmt.Errorf("edit %d: old_string must not be empty", i+1)
+		}
+		if edit.oldString == edit.newString {
+			return "", nil, fmt.Errorf("edit %d: old_string and new_string are identical", i+1)
+		}
+
+		var (
+			report editReport
+			err    error
+		)
+		text, report, err = applyEdit(text, edit, fuzzy)
+		if err != nil {
+			return "", nil, fmt.Errorf("edit %d: %w", i+1, err)
+		}
+		reports = append(reports, report)
+	}
+	return text, reports, nil
+}
+
+// applyEdit 执行一次替换
+func applyEdit(text string, edit editOperation, fuzzy bool) (string, editReport, error) {
+	oldStr := strings.ReplaceAll(edit.oldString, "\r\n", "\n")
+	newStr := strings.ReplaceAll(edit.newString, "\r\n", "\n")
+
+	report := editReport{confidence: 1}
+	matches := findExact(text, oldStr)
+	reindent := false
+
+	if len(matches) == 0 && fuzzy {
+		for _, strategy := range fuzzyStrategies {
+			if matches = findFuzzy(text, oldStr, strategy.normalize); len(matches) > 0 {
+				report.strategy = strategy.name
+				report.confidence = strategy.confidence
+				reindent = strategy.level >= matchIndentation
+				break
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		hint := ""
+		if !fuzzy {
+			hint = " (set fuzzy to true to tolerate whitespace differences)"
+		}
+		return "", report, fmt.Errorf("old_string not found%s: %s", hint, truncateForError(oldStr))
+	}
+	if len(matches) > 1 && !edit.replaceAll {
+		return "", report, fmt.Errorf("old_string matches %d locations (lines %s); include more context to make it unique or set replace_all",
+			len(matches), matchLines(matches))
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		replacement := newStr
+		if reindent {
+			replacement = reindentText(newStr, leadingIndent(firstNonBlankLine(oldStr)), m.indent)
+		}
+		sb.WriteString(text[last:m.start])
+		sb.WriteString(replacement)
+		last = m.end
+	}
+	sb.WriteString(text[last:])
+
+	report.replacements = len(matches)
+	report.line = matches[0].line
+	return sb.String(), report, nil
+}
+
+// findExact 查找所有不重叠的完全匹配
+func findExact(text, oldStr string) []editMatch {
+	var matches []editMatch
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], oldStr)
+		if idx == -1 {
+			return matches
+		}
+		start := offset + idx
+		matches = append(matches, editMatch{
+			start: start,
+			end:   start + len(oldStr),
+			line:  strings.Count(text[:start], "\n") + 1,
+		})
+		offset = start + len(oldStr)
+	}
+}
+
+// findFuzzy 逐行比较规范化后的内容，查找所有不重叠的匹配（匹配范围为完整的行）
+func findFuzzy(text, oldStr string, normalize func(string) string) []editMatch {
+	oldLines, oldTrailingNewline := splitText(oldStr)
+	if len(oldLines) == 0 {
+		return nil
+	}
+	want := make([]string, len(oldLines))
+	for i, line := range oldLines {
+		want[i] = normalize(line)
+	}
+
+	lines := strings.Split(text, "\n")
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line) + 1
+	}
+
+	var matches []editMatch
+	for i := 0; i+len(want) <= len(lines); {
+		matched := true
+		for j := range want {
+			if normalize(lines[i+j]) != want[j] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			i++
+			continue
+		}
+
+		last := i + len(want) - 1
+		end := offsets[last] + len(lines[last])
+		// old_string 以换行结尾时，匹配范围包含最后一行的换行符
+		if oldTrailingNewline && last < len(lines)-1 {
+			end++
+		}
+		matches = append(matches, editMatch{
+			start:  offsets[i],
+			end:    end,
+			line:   i + 1,
+			indent: leadingIndent(firstNonBlankLine(strings.Join(lines[i:last+1], "\n"))),
+		})
+		i += len(want)
+	}
+	return matches
+}
+
+// reindentText 将 text 中以 from 开头的缩进替换为 to
+func reindentText(text, from, to string) string {
+	if from == to {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, from) {
+			lines[i] = to + line[len(from):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leadingIndent 返回行首的空白
+func leadingIndent(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// firstNonBlankLine 返回第一个非空白行
+func firstNonBlankLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// matchLines 格式化匹配的行号列表
+func matchLines(matches []editMatch) string {
+	lines := make([]string, 0, len(matches))
+	for _, m := range matches {
+		lines = append(lines, fmt.Sprint(m.line))
+	}
+	return strings.Join(lines, ", ")
+}
+
+// truncateForError 截断错误信息中过长的字符串
+func truncateForError(s string) string {
+	const maxLen = 200
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+// NewMultiEditTool 创建批量编辑工具
+func NewMultiEditTool(b backend.Backend) Tool {
+	return NewMultiEditToolWithTracker(b, nil)
+}
+
+// NewMultiEditToolWithTracker 创建批量编辑工具，tracker 不为 nil 时要求文件已读取且未被外部修改
+func NewMultiEditToolWithTracker(b backend.Backend, tracker *FileTracker) Tool {
+	return NewBaseTool(
+		"multi_edit",
+		`对同一个文件按顺序执行多处字符串替换（原子操作）。
+
+**使用场景**：
+- 需要对同一文件做多处修改时，优先使用此工具而非多次调用 edit_file
+- 缩进或空白可能与文件不一致时，开启 fuzzy 匹配
+
+**执行规则**：
+- 按 edits 的顺序依次执行，后面的替换基于前面替换后的内容
+- 任一替换失败则整个文件保持不变
+- 每个 old_string 必须唯一匹配，除非设置 replace_all
+- 必须先用 read_file 读取文件
+
+**模糊匹配**（fuzzy: true）：
+- 完全匹配失败时，依次尝试忽略行尾空白、忽略缩进、忽略所有空白差异的逐行匹配
+- 忽略缩进匹配时，new_string 会按文件中的实际缩进自动调整
+- 结果中会报告使用的匹配策略和置信度
+
+**参数说明**：
+- path: 文件路径
+- edits: 替换列表，每项包含 old_string、new_string、replace_all（可选）
+- fuzzy: 是否启用空白容错匹配（默认 false）
+
+**输出**：
+- 成功时返回修改内容的统一格式差异`,
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "文件路径",
+				},
+				"edits": map[string]any{
+					"type":        "array",
+					"description": "按顺序执行的替换列表",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"old_string": map[string]any{
+								"type":        "string",
+								"description": "要替换的字符串",
+							},
+							"new_string": map[string]any{
+								"type":        "string",
+								"description": "新字符串",
+							},
+							"replace_all": map[string]any{
+								"type":        "boolean",
+								"description": "是否替换所有匹配（默认 false）",
+							},
+						},
+						"required": []string{"old_string", "new_string"},
+					},
+				},
+				"fuzzy": map[string]any{
+					"type":        "boolean",
+					"description": "是否启用空白/缩进容错匹配（默认 false）",
+				},
+			},
+			"required": []string{"path", "edits"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok {
+				return "", fmt.Errorf("path must be a string")
+			}
+
+			edits, err := parseEditOperations(args["edits"])
+			if err != nil {
+				return "", err
+			}
+			fuzzy := GetBoolArg(args, "fuzzy", false)
+
+			if tracker != nil {
+				if err := tracker.CheckEdit(ctx, b, path); err != nil {
+					return "", err
+				}
+			}
+
+			file, err := readTextFile(ctx, b, path)
+			if err != nil {
+				return "", err
+			}
+
+			newText, reports, err := applyEdits(file.text, edits, fuzzy)
+			if err != nil {
+				return "", fmt.Errorf("%w (no changes were made to %s)", err, path)
+			}
+
+			data, err := file.encode(newText)
+			if err != nil {
+				return "", err
+			}
+			if _, err := backend.WriteBytes(ctx, b, path, data); err != nil {
+				return "", err
+			}
+
+			if tracker != nil {
+				tracker.Record(ctx, b, path)
+			}
+			return formatEditResult(path, file.text, newText, reports), nil
+		},
+	)
+}
+
+// parseEditOperations 解析 edits 参数
+func parseEditOperations(raw any) ([]editOperation, error) {
+	items, ok := raw.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("edits must be a non-empty array")
+	}
+
+	edits := make([]editOperation, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("edit %d: must be an object", i+1)
+		}
+		oldStr, ok := m["old_string"].(string)
+		if !ok {
+			return nil, fmt.Errorf("edit %d: old_string must be a string", i+1)
+		}
+		newStr, ok := m["new_string"].(string)
+		if !ok {
+			return nil, fmt.Errorf("edit %d: new_string must be a string", i+1)
+		}
+		edits = append(edits, editOperation{
+			oldString:  oldStr,
+			newString:  newStr,
+			replaceAll: GetBoolArg(m, "replace_all", false),
+		})
+	}
+	return edits, nil
+}
+
+// formatEditResult 格式化批量编辑结果：替换次数、模糊匹配报告和差异
+func formatEditResult(path, oldText, newText string, reports []editReport) string {
+	total := 0
+	for _, r := range reports {
+		total += r.replacements
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Applied %d edit(s) (%d replacement(s)) to %s\n", len(reports), total, path)
+	for i, r := range reports {
+		if r.strategy != "" {
+			fmt.Fprintf(&sb, "edit %d: matched %s at line %d (confidence %.2f)\n", i+1, r.strategy, r.line, r.confidence)
+		}
+	}
+
+	if diff := unifiedDiff(path, oldText, newText); diff != "" {
+		sb.WriteString("\n")
+		sb.WriteString(truncateDiff(diff))
+	}
+	return sb.String()
+}
@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+func TestApplyEdits_Ordered(t *testing.T) {
+	text := "a := 1\nb := 2\n"
+	got, reports, err := applyEdits(text, []editOperation{
+		{oldString: "a := 1", newString: "a := 10"},
+		{oldString: "a := 10\nb", newString: "a := 10\nc"}, // 基于前一次替换的结果
+	}, false)
+	if err != nil {
+		t.Fatalf("applyEdits failed: %v", err)
+	}
+	if got != "a := 10\nc := 2\n" {
+		t.Errorf("Unexpected result: %q", got)
+	}
+	if len(reports) != 2 || reports[1].line != 1 || reports[1].strategy != "" {
+		t.Errorf("Unexpected reports: %+v", reports)
+	}
+}
+
+func TestApplyEdits_Errors(t *testing.T) {
+	text := "x\nx\n"
+	tests := []struct {
+		name  string
+		edits []editOperation
+		want  string
+	}{
+		{"empty old", []editOperation{{oldString: "", newString: "y"}}, "must not be empty"},
+		{"identical", []editOperation{{oldString: "x", newString: "x"}}, "identical"},
+		{"not unique", []editOperation{{oldString: "x", newString: "y"}}, "matches 2 locations (lines 1, 2)"},
+		{"not found", []editOperation{{oldString: "z", newString: "y"}}, "edit 1: old_string not found"},
+		{"second fails", []editOperation{{oldString: "x\nx", newString: "y"}, {oldString: "x", newString: "z"}}, "edit 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := applyEdits(text, tt.edits, false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	got, reports, err := applyEdits(text, []editOperation{{oldString: "x", newString: "y", replaceAll: true}}, false)
+	if err != nil || got != "y\ny\n" || reports[0].replacements != 2 {
+		t.Errorf("replace_all: got %q, %+v, %v", got, reports, err)
+	}
+}
+
+func TestApplyEdits_Fuzzy(t *testing.T) {
+	text := "func main() {\n\tif ok {\n\t\trun()  \n\t}\n}\n"
+
+	tests := []struct {
+		name       string
+		old, new   string
+		want       string
+		strategy   string
+		confidence float64
+	}{
+		{
+			name:       "trailing whitespace",
+			old:        "\t\trun()\n",
+			new:        "\t\tstart()\n",
+			want:       "func main() {\n\tif ok {\n\t\tstart()\n\t}\n}\n",
+			strategy:   "ignoring trailing whitespace",
+			confidence: 0.95,
+		},
+		{
+			// 模型用空格缩进，替换内容按文件中的 Tab 缩进调整
+			name:       "indentation",
+			old:        "if ok {\n    run()\n}",
+			new:        "if ok {\n    run()\n    stop()\n}",
+			want:       "func main() {\n\tif ok {\n\t    run()\n\t    stop()\n\t}\n}\n",
+			strategy:   "ignoring indentation",
+			confidence: 0.85,
+		},
+		{
+			name:       "whitespace runs",
+			old:        "if  ok  {",
+			new:        "if !ok {",
+			want:       "func main() {\n\tif !ok {\n\t\trun()  \n\t}\n}\n",
+			strategy:   "ignoring whitespace",
+			confidence: 0.7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := applyEdits(text, []editOperation{{oldString: tt.old, newString: tt.new}}, false); err == nil {
+				t.Fatal("Expected exact matching to fail")
+			}
+
+			got, reports, err := applyEdits(text, []editOperation{{oldString: tt.old, newString: tt.new}}, true)
+			if err != nil {
+				t.Fatalf("Fuzzy edit failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if reports[0].strategy != tt.strategy || reports[0].confidence != tt.confidence {
+				t.Errorf("Unexpected report: %+v", reports[0])
+			}
+		})
+	}
+}
+
+func TestMultiEditTool(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/main.go", "package main\r\n\r\nfunc a() {}\r\nfunc b() {}\r\n")
+	tool := NewMultiEditTool(b)
+
+	result, err := tool.Execute(ctx, map[string]any{
+		"path": "/main.go",
+		"edits": []any{
+			map[string]any{"old_string": "func a() {}", "new_string": "func a() { b() }"},
+			map[string]any{"old_string": "func b() {}\n", "new_string": "func b() {}\nfunc c() {}\n"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("multi_edit failed: %v", err)
+	}
+	if !strings.Contains(result, "Applied 2 edit(s)") || !strings.Contains(result, "+func c() {}") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+
+	// 保留 CRLF 行尾
+	content, _ := b.ReadFile(ctx, "/main.go", 0, 0)
+	if content != "package main\r\n\r\nfunc a() { b() }\r\nfunc b() {}\r\nfunc c() {}\r\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+
+	// 任一替换失败时文件保持不变
+	_, err = tool.Execute(ctx, map[string]any{
+		"path": "/main.go",
+		"edits": []any{
+			map[string]any{"old_string": "package main", "new_string": "package app"},
+			map[string]any{"old_string": "missing", "new_string": "x"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "no changes were made") {
+		t.Fatalf("Expected atomic failure, got %v", err)
+	}
+	if after, _ := b.ReadFile(ctx, "/main.go", 0, 0); after != content {
+		t.Error("File should be unchanged after a failed multi_edit")
+	}
+}
+
+func TestMultiEditTool_RequiresRead(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/a.txt", "hello")
+	tracker := NewFileTracker()
+	tool := NewMultiEditToolWithTracker(b, tracker)
+
+	args := map[string]any{
+		"path":  "/a.txt",
+		"edits": []any{map[string]any{"old_string": "hello", "new_string": "hi"}},
+	}
+	if _, err := tool.Execute(ctx, args); err == nil {
+		t.Fatal("Expected error editing unread file")
+	}
+
+	tracker.Record(ctx, b, "/a.txt")
+	if _, err := tool.Execute(ctx, args); err != nil {
+		t.Fatalf("multi_edit after read failed: %v", err)
+	}
+}
@@ -52,6 +52,11 @@ func NewListFilesTool(backend backend.Backend) Tool {
 
 // NewReadFileTool 创建 read_file 工具
 func NewReadFileTool(backend backend.Backend) Tool {
+	return NewReadFileToolWithTracker(backend, nil)
+}
+
+// NewReadFileToolWithTracker 创建 read_file 工具，读取成功后将文件版本记录到 tracker
+func NewReadFileToolWithTracker(backend backend.Backend, tracker *FileTracker) Tool {
 	return NewBaseTool(
 		"read_file",
 		`读取文件内容。
@@ -70,6 +75,12 @@ func NewReadFileTool(backend backend.Backend) Tool {
 - 每行格式：[空格][行号][Tab][实际内容]
 - 示例：    42→    func example() {
 
+**非文本文件**：
+- 非 UTF-8 编码的文本（如 Latin-1、UTF-16）会自动转换为 UTF-8 显示
+- 二进制文件（图片、PDF、压缩包等）返回类型、大小、图片尺寸等描述
+- 图片文件（PNG、JPEG、GIF、WebP，不超过 5 MB）同时以图片形式提供，可以直接查看图片内容
+- 需要原始内容时设置 encoding 为 "base64"
+
 **注意**：
 - 使用 edit_file 前必须先调用此工具
 - 对于大文件，可使用 offset 和 limit 分页读取`,
@@ -88,6 +99,11 @@ func NewReadFileTool(backend backend.Backend) Tool {
 					"type":        "integer",
 					"description": "读取行数（可选）",
 				},
+				"encoding": map[string]any{
+					"type":        "string",
+					"enum":        []string{"text", "base64"},
+					"description": "返回格式（可选，默认 text；base64 返回原始字节）",
+				},
 			},
 			"required": []string{"path"},
 		},
@@ -107,11 +123,20 @@ func NewReadFileTool(backend backend.Backend) Tool {
 				limit = int(v)
 			}
 
-			content, err := backend.ReadFile(ctx, path, offset, limit)
+			var content string
+			var err error
+			if GetStringArg(args, "encoding", "text") == "base64" {
+				content, err = readFileBase64(ctx, backend, path)
+			} else {
+				content, err = readFileContent(ctx, backend, path, offset, limit)
+			}
 			if err != nil {
 				return "", err
 			}
 
+			if tracker != nil {
+				tracker.Record(ctx, backend, path)
+			}
 			return content, nil
 		},
 	)
@@ -119,6 +144,11 @@ func NewReadFileTool(backend backend.Backend) Tool {
 
 // NewWriteFileTool 创建 write_file 工具
 func NewWriteFileTool(backend backend.Backend) Tool {
+	return NewWriteFileToolWithTracker(backend, nil)
+}
+
+// NewWriteFileToolWithTracker 创建 write_file 工具，覆盖已有文件前通过 tracker 检查是否已读取且未过期
+func NewWriteFileToolWithTracker(backend backend.Backend, tracker *FileTracker) Tool {
 	return NewBaseTool(
 		"write_file",
 		`写入文件内容（创建或覆盖）。
@@ -134,7 +164,8 @@ func NewWriteFileTool(backend backend.Backend) Tool {
 
 **参数说明**：
 - path: 文件路径
-- content: 要写入的完整内容`,
+- content: 要写入的完整内容
+- encoding: 内容编码（可选，默认 text；写入二进制文件时使用 base64）`,
 		map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -146,6 +177,11 @@ func NewWriteFileTool(backend backend.Backend) Tool {
 					"type":        "string",
 					"description": "文件内容",
 				},
+				"encoding": map[string]any{
+					"type":        "string",
+					"enum":        []string{"text", "base64"},
+					"description": "内容编码（可选，默认 text）",
+				},
 			},
 			"required": []string{"path", "content"},
 		},
@@ -160,18 +196,42 @@ func NewWriteFileTool(backend backend.Backend) Tool {
 				return "", fmt.Errorf("content must be a string")
 			}
 
-			result, err := backend.WriteFile(ctx, path, content)
-			if err != nil {
-				return "", err
+			if tracker != nil {
+				if err := tracker.CheckWrite(ctx, backend, path); err != nil {
+					return "", err
+				}
+			}
+
+			var message string
+			if GetStringArg(args, "encoding", "text") == "base64" {
+				msg, err := writeFileBase64(ctx, backend, path, content)
+				if err != nil {
+					return "", err
+				}
+				message = msg
+			} else {
+				result, err := backend.WriteFile(ctx, path, content)
+				if err != nil {
+					return "", err
+				}
+				message = fmt.Sprintf("Successfully wrote %d bytes to %s", result.BytesWritten, result.Path)
 			}
 
-			return fmt.Sprintf("Successfully wrote %d bytes to %s", result.BytesWritten, result.Path), nil
+			if tracker != nil {
+				tracker.Record(ctx, backend, path)
+			}
+			return message, nil
 		},
 	)
 }
 
 // NewEditFileTool 创建 edit_file 工具
 func NewEditFileTool(backend backend.Backend) Tool {
+	return NewEditFileToolWithTracker(backend, nil)
+}
+
+// NewEditFileToolWithTracker 创建 edit_file 工具，编辑前通过 tracker 检查文件是否已读取且未过期
+func NewEditFileToolWithTracker(backend backend.Backend, tracker *FileTracker) Tool {
 	return NewBaseTool(
 		"edit_file",
 		`编辑文件（字符串替换）。
@@ -192,7 +252,7 @@ func NewEditFileTool(backend backend.Backend) Tool {
 - replace_all: 是否替换所有匹配（默认 false）
 
 **常见错误**：
-- 未先读取文件
+- 未先读取文件，或文件在读取后被外部修改（需重新读取）
 - old_string 不唯一
 - 缩进不匹配（Tab vs 空格）
 - 包含了行号前缀
@@ -244,11 +304,20 @@ new_string: "func a() {\n    x := 2"`,
 				replaceAll = v
 			}
 
+			if tracker != nil {
+				if err := tracker.CheckEdit(ctx, backend, path); err != nil {
+					return "", err
+				}
+			}
+
 			result, err := backend.EditFile(ctx, path, oldStr, newStr, replaceAll)
 			if err != nil {
 				return "", err
 			}
 
+			if tracker != nil {
+				tracker.Record(ctx, backend, path)
+			}
 			return fmt.Sprintf("Successfully replaced %d occurrence(s) in %s", result.Replacements, result.Path), nil
 		},
 	)
@@ -406,33 +475,248 @@ func NewGlobTool(backend backend.Backend) Tool {
 	)
 }
 
-// NewBashTool 创建 bash 工具
+// NewMoveFileTool 创建 move_file 工具
+func NewMoveFileTool(b backend.Backend) Tool {
+	return NewMoveFileToolWithTracker(b, nil)
+}
+
+// NewMoveFileToolWithTracker 创建 move_file 工具，移动前通过 tracker 检查源文件和被覆盖的目标文件是否已读取且未过期
+func NewMoveFileToolWithTracker(b backend.Backend, tracker *FileTracker) Tool {
+	return NewBaseTool(
+		"move_file",
+		`移动或重命名文件/目录。
+
+**使用场景**：
+- 重命名文件
+- 将文件移动到其他目录（自动创建父目录）
+
+**参数说明**：
+- source: 源路径
+- destination: 目标路径
+- overwrite: 目标已存在时是否覆盖（默认 false）
+
+**注意**：
+- 优先使用此工具而非 bash mv
+- 移动文件前必须先用 read_file 读取源文件，覆盖已有文件前必须先读取目标文件
+- 跨存储位置只能移动单个文件，不能移动目录`,
+		transferToolSchema(),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			src, dst, err := transferToolArgs(args)
+			if err != nil {
+				return "", err
+			}
+
+			overwrite := GetBoolArg(args, "overwrite", false)
+			if tracker != nil {
+				if err := checkTransfer(ctx, b, tracker, src, dst, overwrite, true); err != nil {
+					return "", err
+				}
+			}
+
+			if err := backend.Move(ctx, b, src, dst, overwrite); err != nil {
+				return "", err
+			}
+			if tracker != nil {
+				tracker.Record(ctx, b, dst)
+			}
+			return fmt.Sprintf("Successfully moved %s to %s", src, dst), nil
+		},
+	)
+}
+
+// NewCopyFileTool 创建 copy_file 工具
+func NewCopyFileTool(b backend.Backend) Tool {
+	return NewCopyFileToolWithTracker(b, nil)
+}
+
+// NewCopyFileToolWithTracker 创建 copy_file 工具，覆盖已有文件前通过 tracker 检查目标文件是否已读取且未过期
+func NewCopyFileToolWithTracker(b backend.Backend, tracker *FileTracker) Tool {
+	return NewBaseTool(
+		"copy_file",
+		`复制文件/目录（目录递归复制）。
+
+**参数说明**：
+- source: 源路径
+- destination: 目标路径
+- overwrite: 目标已存在时是否覆盖（默认 false）
+
+**注意**：
+- 优先使用此工具而非 bash cp
+- 覆盖已有文件前必须先用 read_file 读取目标文件
+- 跨存储位置只能复制单个文件，不能复制目录`,
+		transferToolSchema(),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			src, dst, err := transferToolArgs(args)
+			if err != nil {
+				return "", err
+			}
+
+			overwrite := GetBoolArg(args, "overwrite", false)
+			if tracker != nil {
+				if err := checkTransfer(ctx, b, tracker, src, dst, overwrite, false); err != nil {
+					return "", err
+				}
+			}
+
+			if err := backend.Copy(ctx, b, src, dst, overwrite); err != nil {
+				return "", err
+			}
+			if tracker != nil {
+				tracker.Record(ctx, b, dst)
+			}
+			return fmt.Sprintf("Successfully copied %s to %s", src, dst), nil
+		},
+	)
+}
+
+// checkTransfer 移动或复制前的过期写入检查
+// 覆盖时目标文件必须已读取且未过期；移动时源文件同样会被修改（删除），也需要检查
+func checkTransfer(ctx context.Context, b backend.Backend, tracker *FileTracker, src, dst string, overwrite, move bool) error {
+	if move {
+		if err := tracker.CheckWrite(ctx, b, src); err != nil {
+			return err
+		}
+	}
+	if overwrite {
+		return tracker.CheckWrite(ctx, b, dst)
+	}
+	return nil
+}
+
+// transferToolSchema move_file 和 copy_file 共用的参数定义
+func transferToolSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"source": map[string]any{
+				"type":        "string",
+				"description": "源路径",
+			},
+			"destination": map[string]any{
+				"type":        "string",
+				"description": "目标路径",
+			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": "目标已存在时是否覆盖（默认 false）",
+			},
+		},
+		"required": []string{"source", "destination"},
+	}
+}
+
+// transferToolArgs 解析 source 和 destination 参数
+func transferToolArgs(args map[string]any) (string, string, error) {
+	src, err := ValidateStringArg(args, "source")
+	if err != nil {
+		return "", "", err
+	}
+	dst, err := ValidateStringArg(args, "destination")
+	if err != nil {
+		return "", "", err
+	}
+	return src, dst, nil
+}
+
+// NewMakeDirTool 创建 make_dir 工具
+func NewMakeDirTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"make_dir",
+		"创建目录（包括所有父目录，目录已存在时不报错）",
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "目录路径",
+				},
+			},
+			"required": []string{"path"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok {
+				return "", fmt.Errorf("path must be a string")
+			}
+
+			if err := backend.MakeDir(ctx, b, path); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Successfully created directory %s", path), nil
+		},
+	)
+}
+
+// NewStatTool 创建 stat 工具
+func NewStatTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"stat",
+		"获取单个文件或目录的信息（类型、大小、修改时间），用于确认文件是否存在",
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "文件或目录路径",
+				},
+			},
+			"required": []string{"path"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok {
+				return "", fmt.Errorf("path must be a string")
+			}
+
+			info, err := backend.Stat(ctx, b, path)
+			if err != nil {
+				return "", err
+			}
+
+			if info.IsDir {
+				return fmt.Sprintf("%s: directory", info.Path), nil
+			}
+			result := fmt.Sprintf("%s: file, %d bytes", info.Path, info.Size)
+			if info.ModTime > 0 {
+				result += ", modified " + time.Unix(info.ModTime, 0).Format(time.RFC3339)
+			}
+			return result, nil
+		},
+	)
+}
+
+// NewBashTool 创建 bash 工具（在当前进程工作目录下执行）
 func NewBashTool() Tool {
+	return NewBashToolWithDir("")
+}
+
+// NewBashToolWithDir 创建在指定目录下执行命令的 bash 工具
+func NewBashToolWithDir(dir string) Tool {
 	return NewBaseTool(
 		"bash",
 		`执行 bash 命令。
 
 **正确用途**：
-- git 操作（git status, git commit, git push 等）
-- 构建命令（go build, go test, npm install 等）
+- 构建命令（go build, npm install 等；运行 Go 测试优先用 run_tests）
 - 系统命令（docker, curl 等）
 
 **禁止用途**：
 - 文件读写（用 read_file, write_file, edit_file）
 - 文件搜索（用 grep, glob）
 - 不要用 cat, head, tail, sed, awk, find, ls
+- git 操作优先用 git_status, git_diff, git_commit 等 git 工具
 
 **命令组合**：
 - 独立命令：可并行调用多次 bash
-- 依赖命令：用 && 串联（如 git add && git commit）
+- 依赖命令：用 && 串联（如 go mod tidy && go build）
 
-**Git 安全规则**：
-- 禁止 --force, --no-verify, reset --hard（除非用户明确要求）
-- 优先 git add 具体文件而非 git add -A
+**Git 安全规则**（git 工具未覆盖的操作，如 push）：
+- --force, --no-verify, reset --hard, clean -f 默认被拒绝，仅在用户明确要求时设置 override=true
 
 **参数说明**：
 - command: 要执行的命令
-- timeout: 超时时间（秒），默认 30 秒`,
+- timeout: 超时时间（秒），默认 30 秒
+- override: 允许破坏性 git 操作（仅在用户明确要求时设为 true）`,
 		map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -444,6 +728,7 @@ func NewBashTool() Tool {
 					"type":        "integer",
 					"description": "超时时间（秒），默认 30 秒",
 				},
+				"override": overrideProperty(),
 			},
 			"required": []string{"command"},
 		},
@@ -452,6 +737,9 @@ func NewBashTool() Tool {
 			if !ok {
 				return "", fmt.Errorf("command must be a string")
 			}
+			if err := CheckGitSafety(command, GetBoolArg(args, "override", false)); err != nil {
+				return "", err
+			}
 
 			// 获取超时时间，默认 30 秒
 			timeout := 30
@@ -465,6 +753,7 @@ func NewBashTool() Tool {
 
 			// 执行命令
 			cmd := exec.CommandContext(execCtx, "bash", "-c", command)
+			cmd.Dir = dir
 
 			var stdout, stderr bytes.Buffer
 			cmd.Stdout = &stdout
@@ -469,3 +469,92 @@ func TestNewGlobTool_InvalidPattern(t *testing.T) {
 		t.Error("Expected error for invalid pattern type")
 	}
 }
+
+func TestNewMoveFileTool(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/old.txt", "content")
+
+	tool := NewMoveFileTool(b)
+	if tool.Name() != "move_file" {
+		t.Errorf("Expected name 'move_file', got %q", tool.Name())
+	}
+
+	result, err := tool.Execute(ctx, map[string]any{
+		"source":      "/old.txt",
+		"destination": "/new.txt",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(result, "/new.txt") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+	if content, _ := b.ReadFile(ctx, "/new.txt", 0, 0); content != "content" {
+		t.Errorf("Expected moved content, got %q", content)
+	}
+
+	// 缺少参数
+	if _, err := tool.Execute(ctx, map[string]any{"source": "/new.txt"}); err == nil {
+		t.Error("Expected error for missing destination")
+	}
+}
+
+func TestNewCopyFileTool_Overwrite(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/a.txt", "A")
+	b.WriteFile(ctx, "/b.txt", "B")
+
+	tool := NewCopyFileTool(b)
+	args := map[string]any{"source": "/a.txt", "destination": "/b.txt"}
+	if _, err := tool.Execute(ctx, args); err == nil {
+		t.Error("Expected error when destination exists")
+	}
+
+	args["overwrite"] = true
+	if _, err := tool.Execute(ctx, args); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if content, _ := b.ReadFile(ctx, "/b.txt", 0, 0); content != "A" {
+		t.Errorf("Expected %q, got %q", "A", content)
+	}
+}
+
+func TestNewStatTool(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/dir/file.txt", "hello")
+
+	tool := NewStatTool(b)
+
+	result, err := tool.Execute(ctx, map[string]any{"path": "/dir/file.txt"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(result, "file, 5 bytes") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+
+	result, _ = tool.Execute(ctx, map[string]any{"path": "/dir"})
+	if !strings.Contains(result, "directory") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]any{"path": "/missing"}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestNewMakeDirTool(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+
+	tool := NewMakeDirTool(b)
+	if _, err := tool.Execute(ctx, map[string]any{"path": "/build/out"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if info, err := b.Stat(ctx, "/build/out"); err != nil || !info.IsDir {
+		t.Errorf("Expected directory, got %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,972 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+const (
+	// gitTimeout git 命令超时（毫秒）
+	gitTimeout = 60000
+	// maxGitOutputLines git 工具输出的最大行数
+	maxGitOutputLines = 400
+	// maxGitOutputBytes git 工具输出的最大字节数
+	maxGitOutputBytes = 50000
+)
+
+// RunGit 在后端根目录下执行 git 命令，非零退出码作为错误返回
+func RunGit(ctx context.Context, b backend.Backend, args ...string) (string, error) {
+	argv := append([]string{"git", "--no-pager", "-c", "core.quotepath=off", "-c", "color.ui=false"}, args...)
+	result, err := backend.ExecuteArgs(ctx, b, argv, gitTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to run git: %w", err)
+	}
+	if result.ExitCode != 0 {
+		msg := strings.TrimSpace(result.Stderr)
+		if msg == "" {
+			msg = strings.TrimSpace(result.Stdout)
+		}
+		return "", fmt.Errorf("git %s failed (exit code %d): %s", args[0], result.ExitCode, msg)
+	}
+	return result.Stdout, nil
+}
+
+// runTrackedGit 执行会修改工作区的 git 命令（如 switch、stash pop）
+// 后端实现 backend.ChangeRecorder 时记录受影响文件的变更以支持快照回退（只回退文件内容，不回退分支和 stash 等引用）；
+// 受影响文件为工作区中未提交的文件，加上 queries 中各 git 命令（-z 输出仓库相对路径）列出的文件
+func runTrackedGit(ctx context.Context, b backend.Backend, queries [][]string, args ...string) (string, error) {
+	if _, ok := b.(backend.ChangeRecorder); !ok {
+		return RunGit(ctx, b, args...)
+	}
+
+	prefix, err := RunGit(ctx, b, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	status, err := RunGit(ctx, b, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return "", err
+	}
+	repoPaths := gitStatusPaths(status)
+	for _, query := range queries {
+		// 查询失败（如引用不存在）时由 git 命令本身报告错误
+		if output, err := RunGit(ctx, b, query...); err == nil {
+			repoPaths = append(repoPaths, strings.Split(output, "\x00")...)
+		}
+	}
+
+	// 仓库相对路径转换为后端路径，忽略后端根目录之外的文件
+	prefix = strings.TrimSpace(prefix)
+	var paths []string
+	for _, p := range repoPaths {
+		if rel, ok := strings.CutPrefix(p, prefix); ok && rel != "" {
+			paths = append(paths, "/"+rel)
+		}
+	}
+
+	var output string
+	err = backend.TrackChanges(ctx, b, paths, func() error {
+		var runErr error
+		output, runErr = RunGit(ctx, b, args...)
+		return runErr
+	})
+	return output, err
+}
+
+// gitStatusPaths 解析 git status --porcelain=v1 -z 输出中的路径（重命名和复制同时包含原路径）
+func gitStatusPaths(output string) []string {
+	var paths []string
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths = append(paths, entry[3:])
+		if (entry[0] == 'R' || entry[0] == 'C') && i+1 < len(entries) {
+			i++
+			paths = append(paths, entries[i])
+		}
+	}
+	return paths
+}
+
+// boundGitOutput 限制输出大小，超出部分提示缩小范围
+func boundGitOutput(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	total := len(lines)
+	if total > maxGitOutputLines {
+		lines = lines[:maxGitOutputLines]
+	}
+	output = strings.Join(lines, "\n")
+	if len(output) > maxGitOutputBytes {
+		output = output[:maxGitOutputBytes]
+		if i := strings.LastIndexByte(output, '\n'); i > 0 {
+			output = output[:i]
+		}
+	}
+	if kept := strings.Count(output, "\n") + 1; kept < total {
+		output += fmt.Sprintf("\n... (%d more lines truncated, narrow the request with paths or limits)", total-kept)
+	}
+	return output
+}
+
+// gitPathspecs 将工具使用的路径转换为相对于仓库根目录的 pathspec
+func gitPathspecs(paths []string) []string {
+	specs := make([]string, len(paths))
+	for i, p := range paths {
+		specs[i] = path.Clean(strings.TrimPrefix(p, "/"))
+	}
+	return specs
+}
+
+// gitRefArg 读取引用类参数（分支、提交等），拒绝以 - 开头的值以防被当作选项
+func gitRefArg(args map[string]any, key string) (string, error) {
+	ref := GetStringArg(args, key, "")
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid %s %q", key, ref)
+	}
+	return ref, nil
+}
+
+// requireOverride 破坏性操作默认拒绝，需显式设置 override
+func requireOverride(args map[string]any, action string) error {
+	if GetBoolArg(args, "override", false) {
+		return nil
+	}
+	return fmt.Errorf("%s is blocked by default because it can discard work or rewrite history; set override=true only if the user explicitly asked for it", action)
+}
+
+// unsafeGitPatterns 通过 bash 执行时需要显式 override 的 git 命令
+var unsafeGitPatterns = []struct {
+	pattern *regexp.Regexp
+	action  string
+}{
+	{regexp.MustCompile(`\bgit\b[^;&|]*\bpush\b[^;&|]*(\s--force\b|\s--force-with-lease\b|\s-[a-zA-Z]*f\b|\s\+\S)`), "force push"},
+	{regexp.MustCompile(`\bgit\b[^;&|]*\breset\b[^;&|]*\s--hard\b`), "git reset --hard"},
+	{regexp.MustCompile(`\bgit\b[^;&|]*\s--no-verify\b`), "skipping hooks (--no-verify)"},
+	{regexp.MustCompile(`\bgit\b[^;&|]*\bclean\b[^;&|]*\s-[a-zA-Z]*f`), "git clean -f"},
+}
+
+// CheckGitSafety 检查 shell 命令中的破坏性 git 操作，override 为 false 时拒绝
+func CheckGitSafety(command string, override bool) error {
+	if override {
+		return nil
+	}
+	for _, p := range unsafeGitPatterns {
+		if p.pattern.MatchString(command) {
+			return fmt.Errorf("%s is blocked by default because it can discard work or rewrite history; set override=true only if the user explicitly asked for it", p.action)
+		}
+	}
+	return nil
+}
+
+// gitSchema 构造 git 工具参数 schema
+func gitSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// pathsProperty 路径过滤参数
+func pathsProperty(description string) map[string]any {
+	return map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"description": description,
+	}
+}
+
+// overrideProperty 破坏性操作的显式确认参数
+func overrideProperty() map[string]any {
+	return map[string]any{
+		"type":        "boolean",
+		"description": "允许破坏性操作（仅在用户明确要求时设为 true）",
+	}
+}
+
+// gitStatusNames porcelain 状态码对应的说明
+var gitStatusNames = map[byte]string{
+	'M': "modified",
+	'T': "type changed",
+	'A': "new file",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+}
+
+// formatGitStatus 将 git status --porcelain=v1 -z --branch 输出整理为分组结果
+func formatGitStatus(output string) string {
+	var branch string
+	var staged, unstaged, untracked, conflicts []string
+
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 3 {
+			continue
+		}
+		if strings.HasPrefix(entry, "## ") {
+			branch = entry[3:]
+			continue
+		}
+		x, y, file := entry[0], entry[1], entry[3:]
+		// 重命名和复制的下一项为原路径
+		if (x == 'R' || x == 'C') && i+1 < len(entries) {
+			i++
+			file = entries[i] + " -> " + file
+		}
+		switch {
+		case x == '?' && y == '?':
+			untracked = append(untracked, file)
+		case x == '!' && y == '!':
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			conflicts = append(conflicts, file)
+		default:
+			if name, ok := gitStatusNames[x]; ok {
+				staged = append(staged, fmt.Sprintf("%s: %s", name, file))
+			}
+			if name, ok := gitStatusNames[y]; ok {
+				unstaged = append(unstaged, fmt.Sprintf("%s: %s", name, file))
+			}
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Branch: %s\n", branch)
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n%s (%d):\n", title, len(items))
+		for _, item := range items {
+			fmt.Fprintf(&sb, "  %s\n", item)
+		}
+	}
+	section("Conflicts", conflicts)
+	section("Staged", staged)
+	section("Unstaged", unstaged)
+	section("Untracked", untracked)
+	if len(staged)+len(unstaged)+len(untracked)+len(conflicts) == 0 {
+		sb.WriteString("\nWorking tree clean\n")
+	}
+	return boundGitOutput(sb.String())
+}
+
+// NewGitStatusTool 创建 git status 工具
+func NewGitStatusTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_status",
+		`查看仓库状态：当前分支、与上游的差异，以及冲突、已暂存、未暂存和未跟踪的文件。
+
+**参数说明**：
+- paths: 只查看这些路径（可选）`,
+		gitSchema(map[string]any{
+			"paths": pathsProperty("只查看这些路径（可选）"),
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			gitArgs := []string{"status", "--porcelain=v1", "-z", "--branch"}
+			if paths := GetStringSliceArg(args, "paths"); len(paths) > 0 {
+				gitArgs = append(append(gitArgs, "--"), gitPathspecs(paths)...)
+			}
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			return formatGitStatus(output), nil
+		},
+	)
+}
+
+// NewGitDiffTool 创建 git diff 工具
+func NewGitDiffTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_diff",
+		`查看变更内容（unified diff）。
+
+**参数说明**：
+- paths: 只查看这些路径（可选，大型变更时建议指定）
+- staged: 查看已暂存的变更（默认查看未暂存的变更）
+- ref: 与指定提交比较（如 HEAD~1、main、main...HEAD）
+- stat: 只显示每个文件的变更行数统计`,
+		gitSchema(map[string]any{
+			"paths": pathsProperty("只查看这些路径（可选）"),
+			"staged": map[string]any{
+				"type":        "boolean",
+				"description": "查看已暂存的变更",
+			},
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "与指定提交比较",
+			},
+			"stat": map[string]any{
+				"type":        "boolean",
+				"description": "只显示变更统计",
+			},
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			ref, err := gitRefArg(args, "ref")
+			if err != nil {
+				return "", err
+			}
+
+			gitArgs := []string{"diff"}
+			if GetBoolArg(args, "staged", false) {
+				gitArgs = append(gitArgs, "--cached")
+			}
+			if GetBoolArg(args, "stat", false) {
+				gitArgs = append(gitArgs, "--stat")
+			}
+			if ref != "" {
+				gitArgs = append(gitArgs, ref)
+			}
+			gitArgs = append(append(gitArgs, "--"), gitPathspecs(GetStringSliceArg(args, "paths"))...)
+
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(output) == "" {
+				return "No changes", nil
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitLogTool 创建 git log 工具
+func NewGitLogTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_log",
+		`查看提交历史（每行一个提交：短哈希、日期、作者、标题）。
+
+**参数说明**：
+- ref: 起始提交或分支（默认 HEAD，可用 main..HEAD 表示范围）
+- paths: 只查看修改了这些路径的提交（可选）
+- max_count: 最多返回的提交数（默认 20，最大 200）`,
+		gitSchema(map[string]any{
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "起始提交、分支或范围",
+			},
+			"paths": pathsProperty("只查看修改了这些路径的提交（可选）"),
+			"max_count": map[string]any{
+				"type":        "integer",
+				"description": "最多返回的提交数（默认 20）",
+			},
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			ref, err := gitRefArg(args, "ref")
+			if err != nil {
+				return "", err
+			}
+
+			maxCount := Clamp(GetIntArg(args, "max_count", 20), 1, 200)
+			gitArgs := []string{"log", "-n", strconv.Itoa(maxCount), "--date=short", "--format=%h %ad %an: %s"}
+			if ref != "" {
+				gitArgs = append(gitArgs, ref)
+			}
+			gitArgs = append(append(gitArgs, "--"), gitPathspecs(GetStringSliceArg(args, "paths"))...)
+
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(output) == "" {
+				return "No commits found", nil
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitShowTool 创建 git show 工具
+func NewGitShowTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_show",
+		`查看提交详情（提交信息和变更），或查看文件在某个提交中的内容。
+
+**参数说明**：
+- ref: 提交（默认 HEAD）
+- path: 查看该文件在 ref 中的内容（可选）
+- stat: 只显示变更统计，不显示 diff`,
+		gitSchema(map[string]any{
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "提交（默认 HEAD）",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "查看该文件在 ref 中的内容",
+			},
+			"stat": map[string]any{
+				"type":        "boolean",
+				"description": "只显示变更统计",
+			},
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			ref, err := gitRefArg(args, "ref")
+			if err != nil {
+				return "", err
+			}
+			if ref == "" {
+				ref = "HEAD"
+			}
+
+			var gitArgs []string
+			if p := GetStringArg(args, "path", ""); p != "" {
+				gitArgs = []string{"show", ref + ":" + gitPathspecs([]string{p})[0]}
+			} else if GetBoolArg(args, "stat", false) {
+				gitArgs = []string{"show", "--stat", ref, "--"}
+			} else {
+				gitArgs = []string{"show", ref, "--"}
+			}
+
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// formatGitBlame 将 git blame --line-porcelain 输出整理为 行号 提交 (作者 日期) 内容
+func formatGitBlame(output string) string {
+	var sb strings.Builder
+	var hash, line, author, date string
+
+	for _, l := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(l, "\t"):
+			fmt.Fprintf(&sb, "%5s %s (%s %s) %s\n", line, hash, author, date, l[1:])
+		case strings.HasPrefix(l, "author "):
+			author = strings.TrimPrefix(l, "author ")
+		case strings.HasPrefix(l, "author-time "):
+			if t, err := strconv.ParseInt(strings.TrimPrefix(l, "author-time "), 10, 64); err == nil {
+				date = time.Unix(t, 0).UTC().Format("2006-01-02")
+			}
+		default:
+			// 提交行：<40 位哈希> <原行号> <当前行号> [<行数>]
+			fields := strings.Fields(l)
+			if len(fields) >= 3 && len(fields[0]) == 40 {
+				hash, line = fields[0][:8], fields[2]
+			}
+		}
+	}
+	return boundGitOutput(sb.String())
+}
+
+// NewGitBlameTool 创建 git blame 工具
+func NewGitBlameTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_blame",
+		`查看文件每一行最后由哪个提交修改（行号、提交、作者、日期、内容）。
+
+**参数说明**：
+- path: 文件路径
+- start_line: 起始行号（可选，从 1 开始）
+- end_line: 结束行号（可选）
+- ref: 查看指定提交中的版本（可选）`,
+		gitSchema(map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "文件路径",
+			},
+			"start_line": map[string]any{
+				"type":        "integer",
+				"description": "起始行号",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "结束行号",
+			},
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "查看指定提交中的版本",
+			},
+		}, "path"),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			p, err := ValidateStringArg(args, "path")
+			if err != nil {
+				return "", err
+			}
+			ref, err := gitRefArg(args, "ref")
+			if err != nil {
+				return "", err
+			}
+
+			gitArgs := []string{"blame", "--line-porcelain"}
+			start, end := GetIntArg(args, "start_line", 0), GetIntArg(args, "end_line", 0)
+			if start > 0 || end > 0 {
+				lineRange := strconv.Itoa(max(start, 1)) + ","
+				if end > 0 {
+					lineRange += strconv.Itoa(end)
+				}
+				gitArgs = append(gitArgs, "-L", lineRange)
+			}
+			if ref != "" {
+				gitArgs = append(gitArgs, ref)
+			}
+			gitArgs = append(append(gitArgs, "--"), gitPathspecs([]string{p})...)
+
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			return formatGitBlame(output), nil
+		},
+	)
+}
+
+// NewGitBranchTool 创建 git branch 工具
+func NewGitBranchTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_branch",
+		`管理分支。
+
+**操作**：
+- list: 列出分支（默认，* 表示当前分支）
+- create: 创建分支（不切换）
+- switch: 切换分支（有未提交的变更冲突时失败；override=true 时丢弃这些变更）
+- delete: 删除已合并的分支（override=true 时强制删除未合并的分支）
+
+**参数说明**：
+- action: list、create、switch 或 delete
+- name: 分支名
+- start_point: 创建分支的起点（默认 HEAD）
+- all: list 时包含远程分支
+- override: 允许破坏性操作（仅在用户明确要求时设为 true）`,
+		gitSchema(map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "create", "switch", "delete"},
+				"description": "操作（默认 list）",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "分支名",
+			},
+			"start_point": map[string]any{
+				"type":        "string",
+				"description": "创建分支的起点",
+			},
+			"all": map[string]any{
+				"type":        "boolean",
+				"description": "list 时包含远程分支",
+			},
+			"override": overrideProperty(),
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			action := GetStringArg(args, "action", "list")
+			name, err := gitRefArg(args, "name")
+			if err != nil {
+				return "", err
+			}
+			if action != "list" && name == "" {
+				return "", fmt.Errorf("name is required for %s", action)
+			}
+
+			var gitArgs []string
+			switch action {
+			case "list":
+				gitArgs = []string{"branch", "--format=%(HEAD) %(refname:short) %(objectname:short) %(upstream:track) %(contents:subject)"}
+				if GetBoolArg(args, "all", false) {
+					gitArgs = append(gitArgs, "--all")
+				}
+			case "create":
+				start, err := gitRefArg(args, "start_point")
+				if err != nil {
+					return "", err
+				}
+				gitArgs = []string{"branch", name}
+				if start != "" {
+					gitArgs = append(gitArgs, start)
+				}
+			case "switch":
+				gitArgs = []string{"switch", name}
+				if GetBoolArg(args, "override", false) {
+					gitArgs = []string{"switch", "--discard-changes", name}
+				}
+			case "delete":
+				gitArgs = []string{"branch", "-d", name}
+				if GetBoolArg(args, "override", false) {
+					gitArgs = []string{"branch", "-D", name}
+				}
+			default:
+				return "", fmt.Errorf("unknown action %q", action)
+			}
+
+			var output string
+			if action == "switch" {
+				diff := []string{"diff", "--name-only", "-z", "HEAD", name, "--"}
+				output, err = runTrackedGit(ctx, b, [][]string{diff}, gitArgs...)
+			} else {
+				output, err = RunGit(ctx, b, gitArgs...)
+			}
+			if err != nil {
+				return "", err
+			}
+			switch action {
+			case "create":
+				return fmt.Sprintf("Created branch %s", name), nil
+			case "switch":
+				return fmt.Sprintf("Switched to branch %s", name), nil
+			case "delete":
+				return strings.TrimSpace(output), nil
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitAddTool 创建 git add 工具
+func NewGitAddTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_add",
+		`暂存文件以便提交。优先暂存具体文件，避免误提交无关文件（如密钥、构建产物）。
+
+**参数说明**：
+- paths: 要暂存的文件或目录
+- all: 暂存所有变更（包括删除和未跟踪文件，谨慎使用）`,
+		gitSchema(map[string]any{
+			"paths": pathsProperty("要暂存的文件或目录"),
+			"all": map[string]any{
+				"type":        "boolean",
+				"description": "暂存所有变更",
+			},
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			paths := GetStringSliceArg(args, "paths")
+			gitArgs := []string{"add"}
+			switch {
+			case GetBoolArg(args, "all", false):
+				gitArgs = append(gitArgs, "--all")
+			case len(paths) > 0:
+				gitArgs = append(append(gitArgs, "--"), gitPathspecs(paths)...)
+			default:
+				return "", fmt.Errorf("paths is required unless all is true")
+			}
+
+			if _, err := RunGit(ctx, b, gitArgs...); err != nil {
+				return "", err
+			}
+			output, err := RunGit(ctx, b, "diff", "--cached", "--name-status")
+			if err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(output) == "" {
+				return "Nothing staged", nil
+			}
+			return "Staged changes:\n" + boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitCommitTool 创建 git commit 工具
+func NewGitCommitTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_commit",
+		`提交已暂存的变更（先用 git_add 暂存）。
+
+**规则**：
+- 提交信息简洁说明变更内容和原因
+- 不跳过 hooks，hooks 失败时修复问题后重新提交
+- 不修改已有提交，除非用户明确要求
+
+**参数说明**：
+- message: 提交信息
+- allow_empty: 允许空提交
+- amend: 修改上一个提交（需要 override=true）
+- no_verify: 跳过 pre-commit 和 commit-msg hooks（需要 override=true）
+- override: 允许破坏性操作（仅在用户明确要求时设为 true）`,
+		gitSchema(map[string]any{
+			"message": map[string]any{
+				"type":        "string",
+				"description": "提交信息",
+			},
+			"allow_empty": map[string]any{
+				"type":        "boolean",
+				"description": "允许空提交",
+			},
+			"amend": map[string]any{
+				"type":        "boolean",
+				"description": "修改上一个提交",
+			},
+			"no_verify": map[string]any{
+				"type":        "boolean",
+				"description": "跳过 hooks",
+			},
+			"override": overrideProperty(),
+		}, "message"),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			message, err := ValidateStringArg(args, "message")
+			if err != nil {
+				return "", err
+			}
+
+			gitArgs := []string{"commit", "-m", message}
+			if GetBoolArg(args, "allow_empty", false) {
+				gitArgs = append(gitArgs, "--allow-empty")
+			}
+			if GetBoolArg(args, "amend", false) {
+				if err := requireOverride(args, "amending a commit"); err != nil {
+					return "", err
+				}
+				gitArgs = append(gitArgs, "--amend")
+			}
+			if GetBoolArg(args, "no_verify", false) {
+				if err := requireOverride(args, "skipping hooks (--no-verify)"); err != nil {
+					return "", err
+				}
+				gitArgs = append(gitArgs, "--no-verify")
+			}
+
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitStashTool 创建 git stash 工具
+func NewGitStashTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_stash",
+		`暂存和恢复工作区的未提交变更。
+
+**操作**：
+- list: 列出 stash（默认）
+- push: 保存当前变更并还原工作区
+- pop: 恢复并删除 stash
+- apply: 恢复但保留 stash
+- show: 查看 stash 的变更
+- drop: 删除 stash（需要 override=true）
+
+**参数说明**：
+- action: 操作
+- message: push 时的说明
+- paths: push 时只保存这些路径
+- include_untracked: push 时包含未跟踪文件
+- ref: stash 引用（默认 stash@{0}）
+- override: 允许破坏性操作（仅在用户明确要求时设为 true）`,
+		gitSchema(map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "push", "pop", "apply", "show", "drop"},
+				"description": "操作（默认 list）",
+			},
+			"message": map[string]any{
+				"type":        "string",
+				"description": "push 时的说明",
+			},
+			"paths": pathsProperty("push 时只保存这些路径"),
+			"include_untracked": map[string]any{
+				"type":        "boolean",
+				"description": "push 时包含未跟踪文件",
+			},
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "stash 引用（默认 stash@{0}）",
+			},
+			"override": overrideProperty(),
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			ref, err := gitRefArg(args, "ref")
+			if err != nil {
+				return "", err
+			}
+
+			action := GetStringArg(args, "action", "list")
+			var gitArgs []string
+			switch action {
+			case "list":
+				gitArgs = []string{"stash", "list"}
+			case "push":
+				gitArgs = []string{"stash", "push"}
+				if GetBoolArg(args, "include_untracked", false) {
+					gitArgs = append(gitArgs, "--include-untracked")
+				}
+				if msg := GetStringArg(args, "message", ""); msg != "" {
+					gitArgs = append(gitArgs, "-m", msg)
+				}
+				if paths := GetStringSliceArg(args, "paths"); len(paths) > 0 {
+					gitArgs = append(append(gitArgs, "--"), gitPathspecs(paths)...)
+				}
+			case "pop", "apply", "show", "drop":
+				if action == "drop" {
+					if err := requireOverride(args, "dropping a stash"); err != nil {
+						return "", err
+					}
+				}
+				gitArgs = []string{"stash", action}
+				if action == "show" {
+					gitArgs = append(gitArgs, "-p")
+				}
+				if ref != "" {
+					gitArgs = append(gitArgs, ref)
+				}
+			default:
+				return "", fmt.Errorf("unknown action %q", action)
+			}
+
+			var output string
+			switch action {
+			case "push":
+				output, err = runTrackedGit(ctx, b, nil, gitArgs...)
+			case "pop", "apply":
+				// stash 中的已跟踪文件变更和未跟踪文件（第三个父提交）
+				stash := ref
+				if stash == "" {
+					stash = "stash@{0}"
+				}
+				queries := [][]string{
+					{"diff", "--name-only", "-z", stash + "^1", stash, "--"},
+					{"ls-tree", "-r", "--name-only", "--full-name", "-z", stash + "^3"},
+				}
+				output, err = runTrackedGit(ctx, b, queries, gitArgs...)
+			default:
+				output, err = RunGit(ctx, b, gitArgs...)
+			}
+			if err != nil {
+				return "", err
+			}
+			if strings.TrimSpace(output) == "" {
+				if action == "list" {
+					return "No stashes", nil
+				}
+				return fmt.Sprintf("git stash %s completed", action), nil
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitWorktreeTool 创建 git worktree 工具
+func NewGitWorktreeTool(b backend.Backend) Tool {
+	return NewBaseTool(
+		"git_worktree",
+		`管理工作树（同一仓库的多个检出目录，可在不影响当前目录的情况下处理其他分支）。
+
+**操作**：
+- list: 列出工作树（默认）
+- add: 在 path 创建工作树（branch 指定时创建新分支，否则检出 ref）
+- remove: 删除工作树（有未提交的变更时需要 override=true）
+- prune: 清理已不存在的工作树记录
+
+**参数说明**：
+- action: 操作
+- path: 工作树目录（相对于仓库根目录）
+- branch: add 时创建的新分支名
+- ref: add 时检出的提交或分支（默认 HEAD）
+- override: 允许破坏性操作（仅在用户明确要求时设为 true）`,
+		gitSchema(map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "add", "remove", "prune"},
+				"description": "操作（默认 list）",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "工作树目录",
+			},
+			"branch": map[string]any{
+				"type":        "string",
+				"description": "add 时创建的新分支名",
+			},
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "add 时检出的提交或分支",
+			},
+			"override": overrideProperty(),
+		}),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			action := GetStringArg(args, "action", "list")
+			p := GetStringArg(args, "path", "")
+			if action == "add" || action == "remove" {
+				if p == "" {
+					return "", fmt.Errorf("path is required for %s", action)
+				}
+				p = gitPathspecs([]string{p})[0]
+			}
+			branch, err := gitRefArg(args, "branch")
+			if err != nil {
+				return "", err
+			}
+			ref, err := gitRefArg(args, "ref")
+			if err != nil {
+				return "", err
+			}
+
+			var gitArgs []string
+			switch action {
+			case "list":
+				gitArgs = []string{"worktree", "list"}
+			case "add":
+				gitArgs = []string{"worktree", "add"}
+				if branch != "" {
+					gitArgs = append(gitArgs, "-b", branch)
+				}
+				gitArgs = append(gitArgs, "--", p)
+				if ref != "" {
+					gitArgs = append(gitArgs, ref)
+				}
+			case "remove":
+				gitArgs = []string{"worktree", "remove"}
+				if GetBoolArg(args, "override", false) {
+					gitArgs = append(gitArgs, "--force")
+				}
+				gitArgs = append(gitArgs, "--", p)
+			case "prune":
+				gitArgs = []string{"worktree", "prune", "-v"}
+			default:
+				return "", fmt.Errorf("unknown action %q", action)
+			}
+
+			output, err := RunGit(ctx, b, gitArgs...)
+			if err != nil {
+				return "", err
+			}
+			switch action {
+			case "add":
+				return fmt.Sprintf("Created worktree at %s", p), nil
+			case "remove":
+				return fmt.Sprintf("Removed worktree at %s", p), nil
+			}
+			if strings.TrimSpace(output) == "" {
+				return "Nothing to prune", nil
+			}
+			return boundGitOutput(output), nil
+		},
+	)
+}
+
+// NewGitTools 创建全部 git 工具
+func NewGitTools(b backend.Backend) []Tool {
+	return []Tool{
+		NewGitStatusTool(b),
+		NewGitDiffTool(b),
+		NewGitLogTool(b),
+		NewGitShowTool(b),
+		NewGitBlameTool(b),
+		NewGitBranchTool(b),
+		NewGitAddTool(b),
+		NewGitCommitTool(b),
+		NewGitStashTool(b),
+		NewGitWorktreeTool(b),
+	}
+}
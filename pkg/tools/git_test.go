@@ -0,0 +1,318 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// newGitRepo 创建临时 git 仓库并返回以其为根目录的后端
+func newGitRepo(t *testing.T) (string, backend.Backend) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	b, err := backend.NewFilesystemBackend(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, b
+}
+
+// runGitTool 执行 git 工具并在失败时终止测试
+func runGitTool(t *testing.T, tool Tool, args map[string]any) string {
+	t.Helper()
+	result, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("%s failed: %v", tool.Name(), err)
+	}
+	return result
+}
+
+func TestGitTools_Workflow(t *testing.T) {
+	dir, b := newGitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "my file.txt"), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status := runGitTool(t, NewGitStatusTool(b), map[string]any{})
+	if !strings.Contains(status, "Untracked (1):\n  my file.txt") {
+		t.Errorf("unexpected status:\n%s", status)
+	}
+
+	if _, err := NewGitAddTool(b).Execute(context.Background(), map[string]any{}); err == nil {
+		t.Error("expected error when neither paths nor all is given")
+	}
+	added := runGitTool(t, NewGitAddTool(b), map[string]any{"paths": []any{"/my file.txt"}})
+	if !strings.Contains(added, "A\tmy file.txt") {
+		t.Errorf("unexpected add result: %s", added)
+	}
+
+	committed := runGitTool(t, NewGitCommitTool(b), map[string]any{"message": "Add my file"})
+	if !strings.Contains(committed, "Add my file") {
+		t.Errorf("unexpected commit result: %s", committed)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "my file.txt"), []byte("one\nTWO\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status = runGitTool(t, NewGitStatusTool(b), map[string]any{})
+	if !strings.Contains(status, "Branch: main") || !strings.Contains(status, "Unstaged (1):\n  modified: my file.txt") {
+		t.Errorf("unexpected status:\n%s", status)
+	}
+
+	diff := runGitTool(t, NewGitDiffTool(b), map[string]any{"paths": []any{"my file.txt"}})
+	if !strings.Contains(diff, "-two") || !strings.Contains(diff, "+TWO") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	if diff := runGitTool(t, NewGitDiffTool(b), map[string]any{"staged": true}); diff != "No changes" {
+		t.Errorf("expected no staged changes, got %q", diff)
+	}
+
+	log := runGitTool(t, NewGitLogTool(b), map[string]any{})
+	if !strings.Contains(log, "Test User: Add my file") {
+		t.Errorf("unexpected log: %s", log)
+	}
+
+	blame := runGitTool(t, NewGitBlameTool(b), map[string]any{"path": "my file.txt", "start_line": float64(2), "end_line": float64(2), "ref": "HEAD"})
+	if !strings.Contains(blame, "    2 ") || !strings.Contains(blame, "(Test User ") || !strings.HasSuffix(blame, ") two") {
+		t.Errorf("unexpected blame: %q", blame)
+	}
+
+	show := runGitTool(t, NewGitShowTool(b), map[string]any{"path": "my file.txt"})
+	if show != "one\ntwo" {
+		t.Errorf("unexpected show: %q", show)
+	}
+
+	stashed := runGitTool(t, NewGitStashTool(b), map[string]any{"action": "push", "message": "wip change"})
+	if !strings.Contains(stashed, "wip change") {
+		t.Errorf("unexpected stash result: %s", stashed)
+	}
+	if list := runGitTool(t, NewGitStashTool(b), map[string]any{}); !strings.Contains(list, "stash@{0}") {
+		t.Errorf("unexpected stash list: %s", list)
+	}
+	if _, err := NewGitStashTool(b).Execute(context.Background(), map[string]any{"action": "drop"}); err == nil || !strings.Contains(err.Error(), "override") {
+		t.Errorf("expected stash drop to require override, got %v", err)
+	}
+	runGitTool(t, NewGitStashTool(b), map[string]any{"action": "pop"})
+}
+
+func TestGitTools_SafetyRules(t *testing.T) {
+	dir, b := newGitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitTool(t, NewGitAddTool(b), map[string]any{"all": true})
+	runGitTool(t, NewGitCommitTool(b), map[string]any{"message": "init"})
+
+	commit := NewGitCommitTool(b)
+	for _, args := range []map[string]any{
+		{"message": "x", "amend": true},
+		{"message": "x", "no_verify": true, "allow_empty": true},
+	} {
+		if _, err := commit.Execute(context.Background(), args); err == nil || !strings.Contains(err.Error(), "override") {
+			t.Errorf("expected override error for %v, got %v", args, err)
+		}
+	}
+	runGitTool(t, commit, map[string]any{"message": "init (amended)", "amend": true, "override": true})
+
+	// 未合并的分支默认不能删除
+	branch := NewGitBranchTool(b)
+	runGitTool(t, branch, map[string]any{"action": "create", "name": "feature"})
+	runGitTool(t, branch, map[string]any{"action": "switch", "name": "feature"})
+	runGitTool(t, commit, map[string]any{"message": "feature work", "allow_empty": true})
+	runGitTool(t, branch, map[string]any{"action": "switch", "name": "main"})
+	if _, err := branch.Execute(context.Background(), map[string]any{"action": "delete", "name": "feature"}); err == nil {
+		t.Error("expected deleting an unmerged branch to fail without override")
+	}
+	runGitTool(t, branch, map[string]any{"action": "delete", "name": "feature", "override": true})
+
+	list := runGitTool(t, branch, map[string]any{})
+	if !strings.HasPrefix(list, "* main ") || strings.Contains(list, "feature") {
+		t.Errorf("unexpected branch list: %q", list)
+	}
+
+	// 以 - 开头的引用会被当作选项
+	if _, err := NewGitLogTool(b).Execute(context.Background(), map[string]any{"ref": "--output=/tmp/x"}); err == nil {
+		t.Error("expected error for option-like ref")
+	}
+}
+
+func TestCheckGitSafety(t *testing.T) {
+	blocked := []string{
+		"git push --force origin main",
+		"git push -f",
+		"git push origin +main",
+		"cd repo && git reset --hard HEAD~1",
+		"git commit --no-verify -m wip",
+		"git clean -fd",
+	}
+	for _, cmd := range blocked {
+		if err := CheckGitSafety(cmd, false); err == nil {
+			t.Errorf("expected %q to be blocked", cmd)
+		}
+		if err := CheckGitSafety(cmd, true); err != nil {
+			t.Errorf("expected %q to be allowed with override, got %v", cmd, err)
+		}
+	}
+
+	allowed := []string{
+		"git push origin main",
+		"git reset --soft HEAD~1",
+		"git status && rm -f build.log",
+		"go test -failfast ./...",
+	}
+	for _, cmd := range allowed {
+		if err := CheckGitSafety(cmd, false); err != nil {
+			t.Errorf("expected %q to be allowed, got %v", cmd, err)
+		}
+	}
+}
+
+func TestBoundGitOutput(t *testing.T) {
+	lines := make([]string, maxGitOutputLines+10)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	out := boundGitOutput(strings.Join(lines, "\n") + "\n")
+	if !strings.HasSuffix(out, "(10 more lines truncated, narrow the request with paths or limits)") {
+		t.Errorf("unexpected truncation note: %q", out[len(out)-80:])
+	}
+	if boundGitOutput("a\nb\n") != "a\nb" {
+		t.Error("short output should be returned unchanged")
+	}
+}
+
+func TestFormatGitStatus(t *testing.T) {
+	output := "## main...origin/main [ahead 1]\x00R  new.go\x00old.go\x00UU conflict.go\x00MM both.go\x00 D gone.go\x00?? notes.txt\x00"
+	want := `Branch: main...origin/main [ahead 1]
+
+Conflicts (1):
+  conflict.go
+
+Staged (2):
+  renamed: old.go -> new.go
+  modified: both.go
+
+Unstaged (2):
+  modified: both.go
+  deleted: gone.go
+
+Untracked (1):
+  notes.txt`
+	if got := formatGitStatus(output); got != want {
+		t.Errorf("unexpected status:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGitWorktreeTool(t *testing.T) {
+	dir, b := newGitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitTool(t, NewGitAddTool(b), map[string]any{"paths": []any{"a.txt"}})
+	runGitTool(t, NewGitCommitTool(b), map[string]any{"message": "init"})
+
+	worktree := NewGitWorktreeTool(b)
+	runGitTool(t, worktree, map[string]any{"action": "add", "path": "../wt", "branch": "task"})
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "wt", "a.txt")); err != nil {
+		t.Fatalf("expected worktree checkout: %v", err)
+	}
+	if list := runGitTool(t, worktree, map[string]any{}); !strings.Contains(list, "[task]") {
+		t.Errorf("unexpected worktree list: %s", list)
+	}
+
+	// 有未提交的变更时默认不能删除
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "wt", "a.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Execute(context.Background(), map[string]any{"action": "remove", "path": "../wt"}); err == nil {
+		t.Error("expected removing a dirty worktree to fail without override")
+	}
+	runGitTool(t, worktree, map[string]any{"action": "remove", "path": "../wt", "override": true})
+}
+
+func TestGitTools_SnapshotRecordsWorkingTreeChanges(t *testing.T) {
+	dir, fs := newGitRepo(t)
+	b := backend.NewSnapshotBackend(fs)
+	ctx := context.Background()
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readFile := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	writeFile("a.txt", "main\n")
+	runGitTool(t, NewGitAddTool(b), map[string]any{"all": true})
+	runGitTool(t, NewGitCommitTool(b), map[string]any{"message": "init"})
+	branch := NewGitBranchTool(b)
+	runGitTool(t, branch, map[string]any{"action": "create", "name": "feature"})
+	runGitTool(t, branch, map[string]any{"action": "switch", "name": "feature"})
+	writeFile("a.txt", "feature\n")
+	writeFile("b.txt", "new\n")
+	runGitTool(t, NewGitAddTool(b), map[string]any{"all": true})
+	runGitTool(t, NewGitCommitTool(b), map[string]any{"message": "feature"})
+
+	// 第 1 轮切换分支，第 2 轮暂存未提交的修改
+	b.SetPosition(1, 1)
+	runGitTool(t, branch, map[string]any{"action": "switch", "name": "main"})
+	if readFile("a.txt") != "main\n" || readFile("b.txt") != "<missing>" {
+		t.Fatalf("unexpected checkout: a=%q b=%q", readFile("a.txt"), readFile("b.txt"))
+	}
+	writeFile("a.txt", "dirty\n")
+	writeFile("c.txt", "untracked\n")
+	b.SetPosition(2, 1)
+	runGitTool(t, NewGitStashTool(b), map[string]any{"action": "push", "include_untracked": true})
+	if readFile("a.txt") != "main\n" || readFile("c.txt") != "<missing>" {
+		t.Fatalf("unexpected stash result: a=%q c=%q", readFile("a.txt"), readFile("c.txt"))
+	}
+	b.SetPosition(3, 1)
+	runGitTool(t, NewGitStashTool(b), map[string]any{"action": "pop"})
+
+	if _, err := b.Rewind(ctx, "", 3); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if readFile("a.txt") != "main\n" || readFile("c.txt") != "<missing>" {
+		t.Errorf("expected stash pop reverted: a=%q c=%q", readFile("a.txt"), readFile("c.txt"))
+	}
+	if _, err := b.Rewind(ctx, "", 2); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if readFile("a.txt") != "dirty\n" || readFile("c.txt") != "untracked\n" {
+		t.Errorf("expected stash push reverted: a=%q c=%q", readFile("a.txt"), readFile("c.txt"))
+	}
+	if _, err := b.Rewind(ctx, "", 1); err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if readFile("a.txt") != "feature\n" || readFile("b.txt") != "new\n" {
+		t.Errorf("expected switch reverted: a=%q b=%q", readFile("a.txt"), readFile("b.txt"))
+	}
+}
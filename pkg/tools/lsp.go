@@ -0,0 +1,317 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhoucx/deepagents-go/pkg/lsp"
+)
+
+// maxLSPResults 返回给模型的最大结果条数
+const maxLSPResults = 100
+
+// positionSchema 需要定位到代码位置的工具参数
+func positionSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "文件路径",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "行号（从 1 开始）",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "该行中的符号名（推荐，自动定位列号）",
+			},
+			"column": map[string]any{
+				"type":        "integer",
+				"description": "列号（从 1 开始，未指定 symbol 时使用）",
+			},
+		},
+		"required": []string{"path", "line"},
+	}
+}
+
+// positionArgs 解析代码位置参数，指定 symbol 时自动计算列号
+func positionArgs(m *lsp.Manager, args map[string]any) (string, int, int, error) {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return "", 0, 0, fmt.Errorf("path must be a non-empty string")
+	}
+	line := GetIntArg(args, "line", 0)
+	if line < 1 {
+		return "", 0, 0, fmt.Errorf("line must be a positive integer")
+	}
+
+	if symbol := GetStringArg(args, "symbol", ""); symbol != "" {
+		column, err := m.FindColumn(path, line, symbol)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		return path, line, column, nil
+	}
+	return path, line, GetIntArg(args, "column", 1), nil
+}
+
+// formatLocations 格式化代码位置列表
+func formatLocations(locations []lsp.Location) string {
+	var sb strings.Builder
+	for i, loc := range locations {
+		if i == maxLSPResults {
+			fmt.Fprintf(&sb, "... and %d more\n", len(locations)-maxLSPResults)
+			break
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d: %s\n", loc.Path, loc.Line, loc.Column, loc.Text)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// NewLSPDefinitionTool 创建跳转到定义工具
+func NewLSPDefinitionTool(m *lsp.Manager) Tool {
+	return NewBaseTool(
+		"lsp_definition",
+		`通过语言服务器查找符号的定义位置（比 grep 更准确）。
+
+**参数说明**：
+- path: 符号所在的文件
+- line: 符号所在的行号（从 1 开始）
+- symbol: 该行中的符号名（推荐，自动定位列号）
+- column: 列号（从 1 开始，未指定 symbol 时使用）
+
+**输出**：定义所在的 文件:行:列 和该行内容`,
+		positionSchema(),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, line, column, err := positionArgs(m, args)
+			if err != nil {
+				return "", err
+			}
+
+			locations, err := m.Definition(ctx, path, line, column)
+			if err != nil {
+				return "", err
+			}
+			if len(locations) == 0 {
+				return "No definition found", nil
+			}
+			return formatLocations(locations), nil
+		},
+	)
+}
+
+// NewLSPReferencesTool 创建查找引用工具
+func NewLSPReferencesTool(m *lsp.Manager) Tool {
+	schema := positionSchema()
+	schema["properties"].(map[string]any)["include_declaration"] = map[string]any{
+		"type":        "boolean",
+		"description": "是否包含声明本身（默认 true）",
+	}
+
+	return NewBaseTool(
+		"lsp_references",
+		`通过语言服务器查找符号的所有引用（重构、重命名前确认影响范围）。
+
+**参数说明**：
+- path: 符号所在的文件
+- line: 符号所在的行号（从 1 开始）
+- symbol: 该行中的符号名（推荐，自动定位列号）
+- column: 列号（从 1 开始，未指定 symbol 时使用）
+- include_declaration: 是否包含声明本身（默认 true）`,
+		schema,
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, line, column, err := positionArgs(m, args)
+			if err != nil {
+				return "", err
+			}
+
+			locations, err := m.References(ctx, path, line, column, GetBoolArg(args, "include_declaration", true))
+			if err != nil {
+				return "", err
+			}
+			if len(locations) == 0 {
+				return "No references found", nil
+			}
+			return fmt.Sprintf("Found %d reference(s):\n%s", len(locations), formatLocations(locations)), nil
+		},
+	)
+}
+
+// NewLSPHoverTool 创建类型信息工具
+func NewLSPHoverTool(m *lsp.Manager) Tool {
+	return NewBaseTool(
+		"lsp_hover",
+		`通过语言服务器获取符号的类型、签名和文档注释。
+
+**参数说明**：
+- path: 符号所在的文件
+- line: 符号所在的行号（从 1 开始）
+- symbol: 该行中的符号名（推荐，自动定位列号）
+- column: 列号（从 1 开始，未指定 symbol 时使用）`,
+		positionSchema(),
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, line, column, err := positionArgs(m, args)
+			if err != nil {
+				return "", err
+			}
+
+			text, err := m.Hover(ctx, path, line, column)
+			if err != nil {
+				return "", err
+			}
+			if text == "" {
+				return "No hover information available", nil
+			}
+			return text, nil
+		},
+	)
+}
+
+// NewLSPDocumentSymbolsTool 创建文档符号工具
+func NewLSPDocumentSymbolsTool(m *lsp.Manager) Tool {
+	return NewBaseTool(
+		"lsp_document_symbols",
+		`通过语言服务器列出文件中的符号（类型、函数、方法、字段等）及其行号，快速了解文件结构。
+
+**参数说明**：
+- path: 文件路径`,
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "文件路径",
+				},
+			},
+			"required": []string{"path"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			path, err := ValidateStringArg(args, "path")
+			if err != nil {
+				return "", err
+			}
+
+			symbols, err := m.DocumentSymbols(ctx, path)
+			if err != nil {
+				return "", err
+			}
+			if len(symbols) == 0 {
+				return "No symbols found", nil
+			}
+
+			var sb strings.Builder
+			writeSymbolTree(&sb, symbols, 0)
+			return strings.TrimSuffix(sb.String(), "\n"), nil
+		},
+	)
+}
+
+// writeSymbolTree 按层级缩进输出符号
+func writeSymbolTree(sb *strings.Builder, symbols []lsp.Symbol, depth int) {
+	for _, s := range symbols {
+		fmt.Fprintf(sb, "%s%s %s", strings.Repeat("  ", depth), s.Kind, s.Name)
+		if s.Detail != "" {
+			fmt.Fprintf(sb, " %s", s.Detail)
+		}
+		fmt.Fprintf(sb, " (line %d)\n", s.Line)
+		writeSymbolTree(sb, s.Children, depth+1)
+	}
+}
+
+// NewLSPWorkspaceSymbolsTool 创建工作区符号搜索工具
+func NewLSPWorkspaceSymbolsTool(m *lsp.Manager) Tool {
+	return NewBaseTool(
+		"lsp_workspace_symbols",
+		`通过语言服务器在整个工作区中按名称搜索符号（支持模糊匹配）。
+
+**使用场景**：
+- 知道类型或函数名，但不知道定义在哪个文件
+
+**参数说明**：
+- query: 符号名称或名称片段`,
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "符号名称或名称片段",
+				},
+			},
+			"required": []string{"query"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			query, err := ValidateStringArg(args, "query")
+			if err != nil {
+				return "", err
+			}
+
+			symbols, err := m.WorkspaceSymbols(ctx, query)
+			if err != nil {
+				return "", err
+			}
+			if len(symbols) == 0 {
+				return fmt.Sprintf("No symbols matching %q", query), nil
+			}
+
+			var sb strings.Builder
+			for i, s := range symbols {
+				if i == maxLSPResults {
+					fmt.Fprintf(&sb, "... and %d more\n", len(symbols)-maxLSPResults)
+					break
+				}
+				fmt.Fprintf(&sb, "%s %s - %s:%d", s.Kind, s.Name, s.Path, s.Line)
+				if s.Container != "" {
+					fmt.Fprintf(&sb, " (in %s)", s.Container)
+				}
+				sb.WriteString("\n")
+			}
+			return strings.TrimSuffix(sb.String(), "\n"), nil
+		},
+	)
+}
+
+// NewLSPDiagnosticsTool 创建诊断工具
+func NewLSPDiagnosticsTool(m *lsp.Manager) Tool {
+	return NewBaseTool(
+		"lsp_diagnostics",
+		`通过语言服务器获取编译错误、类型错误和警告。
+
+**参数说明**：
+- path: 文件路径（可选，省略时返回所有已打开文件的诊断）`,
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "文件路径（可选）",
+				},
+			},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			diagnostics, err := m.Diagnostics(ctx, GetStringArg(args, "path", ""))
+			if err != nil {
+				return "", err
+			}
+			if len(diagnostics) == 0 {
+				return "No diagnostics", nil
+			}
+
+			var sb strings.Builder
+			for i, d := range diagnostics {
+				if i == maxLSPResults {
+					fmt.Fprintf(&sb, "... and %d more\n", len(diagnostics)-maxLSPResults)
+					break
+				}
+				fmt.Fprintf(&sb, "%s:%d:%d: %s: %s", d.Path, d.Line, d.Column, d.Severity, d.Message)
+				if d.Source != "" {
+					fmt.Fprintf(&sb, " [%s]", d.Source)
+				}
+				sb.WriteString("\n")
+			}
+			return strings.TrimSuffix(sb.String(), "\n"), nil
+		},
+	)
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/lsp"
+)
+
+func TestPositionArgs(t *testing.T) {
+	dir := t.TempDir()
+	src := "package demo\n\nfunc Hello() string { return hello }\n"
+	if err := os.WriteFile(filepath.Join(dir, "demo.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := lsp.NewManager(lsp.Config{RootDir: dir, VirtualPaths: true})
+	defer m.Close()
+
+	if _, _, _, err := positionArgs(m, map[string]any{"line": 1.0}); err == nil {
+		t.Error("expected error for missing path")
+	}
+	if _, _, _, err := positionArgs(m, map[string]any{"path": "/demo.go", "line": 0.0}); err == nil {
+		t.Error("expected error for invalid line")
+	}
+
+	_, line, column, err := positionArgs(m, map[string]any{"path": "/demo.go", "line": 3.0, "symbol": "hello"})
+	if err != nil {
+		t.Fatalf("positionArgs failed: %v", err)
+	}
+	if line != 3 || column != 30 {
+		t.Errorf("expected 3:30, got %d:%d", line, column)
+	}
+
+	_, _, column, err = positionArgs(m, map[string]any{"path": "/demo.go", "line": 3.0, "column": 6.0})
+	if err != nil || column != 6 {
+		t.Errorf("expected explicit column 6, got %d (err=%v)", column, err)
+	}
+
+	if _, _, _, err := positionArgs(m, map[string]any{"path": "/demo.go", "line": 3.0, "symbol": "missing"}); err == nil {
+		t.Error("expected error for unknown symbol")
+	}
+}
+
+func TestFormatLocations_Truncates(t *testing.T) {
+	locations := make([]lsp.Location, maxLSPResults+5)
+	for i := range locations {
+		locations[i] = lsp.Location{Path: "/a.go", Line: i + 1, Column: 1, Text: "x"}
+	}
+
+	out := formatLocations(locations)
+	if !strings.HasPrefix(out, "/a.go:1:1: x\n") {
+		t.Errorf("unexpected output prefix: %q", out[:20])
+	}
+	if !strings.HasSuffix(out, "... and 5 more") {
+		t.Errorf("expected truncation note, got %q", out[len(out)-30:])
+	}
+}
@@ -0,0 +1,457 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// devNull 统一格式差异中表示文件不存在的路径
+const devNull = "/dev/null"
+
+// hunkHeaderRe 匹配 hunk 头，如 "@@ -1,3 +1,4 @@"（行号可省略）
+var hunkHeaderRe = regexp.MustCompile(`^@@(?: -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))?)? @@`)
+
+// filePatch 单个文件的补丁
+type filePatch struct {
+	oldPath string // 源路径（新建文件时为空）
+	newPath string // 目标路径（删除文件时为空）
+	hunks   []patchHunk
+}
+
+// patchHunk 补丁中的一个 hunk
+type patchHunk struct {
+	oldStart  int      // 源文件起始行号（从 1 开始，0 表示未指定）
+	lines     []string // 带前缀的行（' '、'-'、'+'）
+	noNewline bool     // 修改后的文件末尾没有换行符
+}
+
+// parsePatch 解析统一格式差异（支持多文件、新建和删除）
+// 容忍模型常见的格式问题：hunk 头的行数不准确、行号缺失、空上下文行丢失了前导空格
+func parsePatch(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var patches []filePatch
+	for i := 0; i < len(lines); {
+		if !isFileHeader(lines, i) {
+			// 跳过 "diff --git"、"index" 等头部信息
+			i++
+			continue
+		}
+
+		fp := filePatch{
+			oldPath: patchPath(lines[i][4:]),
+			newPath: patchPath(lines[i+1][4:]),
+		}
+		if fp.oldPath == "" && fp.newPath == "" {
+			return nil, fmt.Errorf("line %d: both paths are %s", i+1, devNull)
+		}
+		i += 2
+
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			if fp.oldPath == "" {
+				// 新建文件没有上下文，丢失前缀的行视为新增
+				for k, line := range hunk.lines {
+					if line[0] == ' ' {
+						hunk.lines[k] = "+" + line[1:]
+					}
+				}
+			}
+			fp.hunks = append(fp.hunks, hunk)
+			i = next
+		}
+		if len(fp.hunks) == 0 && fp.oldPath != "" && fp.newPath != "" && fp.oldPath == fp.newPath {
+			return nil, fmt.Errorf("no hunks found for %s", fp.newPath)
+		}
+		patches = append(patches, fp)
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file patches found: expected '--- <path>' and '+++ <path>' headers")
+	}
+	return patches, nil
+}
+
+// isFileHeader 判断第 i 行是否为文件头（"--- " 后紧跟 "+++ "）
+func isFileHeader(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.HasPrefix(lines[i], "--- ") && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// parseHunk 解析从第 i 行开始的 hunk，返回 hunk 和下一行的位置
+func parseHunk(lines []string, i int) (patchHunk, int, error) {
+	m := hunkHeaderRe.FindStringSubmatch(lines[i])
+	if m == nil {
+		return patchHunk{}, 0, fmt.Errorf("line %d: invalid hunk header: %s", i+1, lines[i])
+	}
+
+	var hunk patchHunk
+	if m[1] != "" {
+		hunk.oldStart, _ = strconv.Atoi(m[1])
+	}
+
+	blankTail := 0 // 末尾由空行补全的上下文行数
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || isFileHeader(lines, i) || strings.HasPrefix(line, "diff ") {
+			break
+		}
+		switch {
+		case line == "":
+			// 空上下文行常被去掉前导空格
+			hunk.lines = append(hunk.lines, " ")
+			blankTail++
+			continue
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunk.lines = append(hunk.lines, line)
+		case line[0] == '\\':
+			// "\ No newline at end of file" 紧跟在修改后文件的最后一行之后
+			if n := len(hunk.lines); n > 0 && hunk.lines[n-1][0] != '-' {
+				hunk.noNewline = true
+			}
+		default:
+			return patchHunk{}, 0, fmt.Errorf("line %d: unexpected line in hunk: %s", i+1, line)
+		}
+		blankTail = 0
+	}
+	// hunk 末尾的空行通常是文件之间的分隔或补丁结尾，不作为上下文
+	hunk.lines = hunk.lines[:len(hunk.lines)-blankTail]
+
+	if len(hunk.lines) == 0 {
+		return patchHunk{}, 0, fmt.Errorf("line %d: empty hunk", i+1)
+	}
+	return hunk, i, nil
+}
+
+// patchPath 从文件头中提取路径（去除时间戳和 a/、b/ 前缀）
+func patchPath(header string) string {
+	path := strings.TrimSpace(header)
+	if idx := strings.IndexByte(path, '\t'); idx != -1 {
+		path = path[:idx]
+	}
+	if path == devNull {
+		return ""
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		path = path[2:]
+	}
+	return path
+}
+
+// applyHunks 将 hunk 依次应用到文本上
+// 先在预期位置附近查找完全匹配的上下文，找不到时依次尝试忽略行尾空白和忽略空白的匹配
+func applyHunks(text string, hunks []patchHunk) (string, []string, error) {
+	lines, trailingNewline := splitText(text)
+	if text == "" {
+		trailingNewline = true
+	}
+
+	var (
+		notes  []string
+		offset int // 已应用的 hunk 造成的行号偏移
+		minPos int // 下一个 hunk 的最小起始位置（hunk 不能重叠）
+	)
+	for n, hunk := range hunks {
+		var oldSeg []string
+		for _, line := range hunk.lines {
+			if line[0] != '+' {
+				oldSeg = append(oldSeg, line[1:])
+			}
+		}
+
+		expected := max(hunk.oldStart-1, 0) + offset
+		if len(oldSeg) == 0 && hunk.oldStart > 0 {
+			// 纯插入的 hunk 中，起始行号指向插入位置的前一行
+			expected = hunk.oldStart + offset
+		}
+
+		pos, strategy := locateHunk(lines, oldSeg, expected, minPos)
+		if pos == -1 {
+			return "", nil, fmt.Errorf("hunk %d does not apply: context not found near line %d:\n%s",
+				n+1, hunk.oldStart, truncateForError(strings.Join(oldSeg, "\n")))
+		}
+		if strategy != "" {
+			notes = append(notes, fmt.Sprintf("hunk %d: matched %s at line %d", n+1, strategy, pos+1))
+		} else if hunk.oldStart > 0 && pos != expected {
+			notes = append(notes, fmt.Sprintf("hunk %d: applied at line %d (offset %+d lines)", n+1, pos+1, pos-expected))
+		}
+
+		// 上下文行保留文件中的实际内容，只替换删除和插入的行
+		var replacement []string
+		k := pos
+		for _, line := range hunk.lines {
+			switch line[0] {
+			case ' ':
+				replacement = append(replacement, lines[k])
+				k++
+			case '-':
+				k++
+			case '+':
+				replacement = append(replacement, line[1:])
+			}
+		}
+
+		lines = append(lines[:pos:pos], append(replacement, lines[pos+len(oldSeg):]...)...)
+		offset += len(replacement) - len(oldSeg)
+		minPos = pos + len(replacement)
+		if hunk.noNewline {
+			trailingNewline = false
+		}
+	}
+
+	return joinText(lines, trailingNewline), notes, nil
+}
+
+// locateHunk 查找 hunk 上下文的位置，返回行索引和使用的模糊策略（完全匹配时为空）
+func locateHunk(lines, oldSeg []string, expected, minPos int) (int, string) {
+	if len(oldSeg) == 0 {
+		return min(max(expected, minPos), len(lines)), ""
+	}
+
+	if pos := searchLines(lines, oldSeg, expected, minPos, func(s string) string { return s }); pos != -1 {
+		return pos, ""
+	}
+	for _, strategy := range fuzzyStrategies {
+		if pos := searchLines(lines, oldSeg, expected, minPos, strategy.normalize); pos != -1 {
+			return pos, strategy.name
+		}
+	}
+	return -1, ""
+}
+
+// searchLines 从 expected 开始向两侧交替查找匹配 want 的位置（不早于 minPos）
+func searchLines(lines, want []string, expected, minPos int, normalize func(string) string) int {
+	matchAt := func(pos int) bool {
+		if pos < minPos || pos+len(want) > len(lines) {
+			return false
+		}
+		for j := range want {
+			if normalize(lines[pos+j]) != normalize(want[j]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for delta := 0; expected-delta >= minPos || expected+delta < len(lines); delta++ {
+		if matchAt(expected + delta) {
+			return expected + delta
+		}
+		if delta > 0 && matchAt(expected-delta) {
+			return expected - delta
+		}
+	}
+	return -1
+}
+
+// patchResult 单个文件的补丁应用结果
+type patchResult struct {
+	patch   filePatch
+	file    *textFile // 源文件（新建时为空文件）
+	newText string
+	notes   []string
+}
+
+// NewApplyPatchTool 创建补丁应用工具
+func NewApplyPatchTool(b backend.Backend) Tool {
+	return NewApplyPatchToolWithTracker(b, nil)
+}
+
+// NewApplyPatchToolWithTracker 创建补丁应用工具，tracker 不为 nil 时要求被修改的文件已读取且未被外部修改
+func NewApplyPatchToolWithTracker(b backend.Backend, tracker *FileTracker) Tool {
+	return NewBaseTool(
+		"apply_patch",
+		`应用统一格式差异（unified diff）补丁，可同时修改、新建、删除多个文件。
+
+**使用场景**：
+- 一次性修改多个文件或同一文件的多处
+- 已有 git diff / diff -u 格式的修改内容
+
+**格式要求**：
+- 每个文件以 "--- a/<路径>" 和 "+++ b/<路径>" 开头
+- 新建文件使用 "--- /dev/null"，删除文件使用 "+++ /dev/null"
+- hunk 以 "@@ -旧起始行,行数 +新起始行,行数 @@" 开头，行号不准确时会在附近自动查找上下文
+- 上下文行以空格开头，删除行以 "-" 开头，新增行以 "+" 开头
+
+**执行规则**：
+- 所有文件的补丁都能应用时才会写入，否则不修改任何文件
+- 被修改或删除的文件必须先用 read_file 读取
+- 上下文完全匹配失败时会尝试忽略空白差异，并在结果中报告
+
+**参数说明**：
+- patch: 补丁内容
+
+**输出**：
+- 成功时返回实际修改内容的统一格式差异`,
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"patch": map[string]any{
+					"type":        "string",
+					"description": "统一格式差异补丁内容",
+				},
+			},
+			"required": []string{"patch"},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			patch, err := ValidateStringArg(args, "patch")
+			if err != nil {
+				return "", err
+			}
+
+			patches, err := parsePatch(patch)
+			if err != nil {
+				return "", fmt.Errorf("invalid patch: %w", err)
+			}
+
+			// 先计算所有文件的结果，全部成功后再写入
+			results := make([]patchResult, 0, len(patches))
+			for _, fp := range patches {
+				result, err := preparePatch(ctx, b, tracker, fp)
+				if err != nil {
+					return "", fmt.Errorf("%w (no files were changed)", err)
+				}
+				results = append(results, result)
+			}
+
+			if err := commitPatches(ctx, b, results); err != nil {
+				return "", err
+			}
+
+			if tracker != nil {
+				for _, r := range results {
+					if r.patch.newPath != "" {
+						tracker.Record(ctx, b, r.patch.newPath)
+					}
+				}
+			}
+			return formatPatchResult(results), nil
+		},
+	)
+}
+
+// preparePatch 读取源文件并计算应用补丁后的内容
+func preparePatch(ctx context.Context, b backend.Backend, tracker *FileTracker, fp filePatch) (patchResult, error) {
+	result := patchResult{patch: fp, file: newTextFile()}
+
+	if fp.oldPath == "" {
+		if _, err := backend.Stat(ctx, b, fp.newPath); err == nil {
+			return result, fmt.Errorf("cannot create %s: file already exists", fp.newPath)
+		}
+	} else {
+		if tracker != nil {
+			if err := tracker.CheckEdit(ctx, b, fp.oldPath); err != nil {
+				return result, err
+			}
+		}
+		file, err := readTextFile(ctx, b, fp.oldPath)
+		if err != nil {
+			return result, err
+		}
+		result.file = file
+
+		if fp.newPath != "" && fp.newPath != fp.oldPath {
+			if _, err := backend.Stat(ctx, b, fp.newPath); err == nil {
+				return result, fmt.Errorf("cannot rename %s to %s: destination already exists", fp.oldPath, fp.newPath)
+			}
+		}
+	}
+
+	if fp.newPath == "" {
+		return result, nil
+	}
+
+	newText, notes, err := applyHunks(result.file.text, fp.hunks)
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", fp.newPath, err)
+	}
+	result.newText = newText
+	result.notes = notes
+	return result, nil
+}
+
+// commitPatches 写入所有补丁结果，中途失败时尽量恢复已写入的文件
+func commitPatches(ctx context.Context, b backend.Backend, results []patchResult) error {
+	var undo []func()
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	for _, r := range results {
+		fp := r.patch
+		if fp.newPath != "" {
+			data, err := r.file.encode(r.newText)
+			if err == nil {
+				_, err = backend.WriteBytes(ctx, b, fp.newPath, data)
+			}
+			if err != nil {
+				rollback()
+				return fmt.Errorf("failed to write %s: %w", fp.newPath, err)
+			}
+			undo = append(undo, restoreFunc(ctx, b, fp.newPath, r.file.raw, fp.oldPath == fp.newPath))
+		}
+
+		if fp.oldPath != "" && fp.oldPath != fp.newPath {
+			if err := b.DeleteFile(ctx, fp.oldPath); err != nil {
+				rollback()
+				return fmt.Errorf("failed to delete %s: %w", fp.oldPath, err)
+			}
+			undo = append(undo, restoreFunc(ctx, b, fp.oldPath, r.file.raw, true))
+		}
+	}
+	return nil
+}
+
+// restoreFunc 返回恢复文件原始状态的函数（existed 为 false 时删除文件）
+func restoreFunc(ctx context.Context, b backend.Backend, path string, raw []byte, existed bool) func() {
+	return func() {
+		if existed {
+			backend.WriteBytes(ctx, b, path, raw)
+		} else {
+			b.DeleteFile(ctx, path)
+		}
+	}
+}
+
+// formatPatchResult 格式化补丁应用结果
+func formatPatchResult(results []patchResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Applied patch to %d file(s):\n", len(results))
+
+	var diffs strings.Builder
+	for _, r := range results {
+		fp := r.patch
+		switch {
+		case fp.oldPath == "":
+			fmt.Fprintf(&sb, "- created %s\n", fp.newPath)
+		case fp.newPath == "":
+			fmt.Fprintf(&sb, "- deleted %s\n", fp.oldPath)
+		case fp.oldPath != fp.newPath:
+			fmt.Fprintf(&sb, "- renamed %s -> %s\n", fp.oldPath, fp.newPath)
+		default:
+			fmt.Fprintf(&sb, "- modified %s\n", fp.newPath)
+		}
+		for _, note := range r.notes {
+			fmt.Fprintf(&sb, "  %s\n", note)
+		}
+
+		if fp.newPath != "" {
+			diffs.WriteString(unifiedDiff(fp.newPath, r.file.text, r.newText))
+		}
+	}
+
+	if diffs.Len() > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(truncateDiff(diffs.String()))
+	}
+	return sb.String()
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	newText := "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	want := "--- a/n.txt\n+++ b/n.txt\n" +
+		"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n"
+	if got := unifiedDiff("/n.txt", oldText, newText); got != want {
+		t.Errorf("Unexpected diff:\n%s", got)
+	}
+	if got := unifiedDiff("/n.txt", oldText, oldText); got != "" {
+		t.Errorf("Expected empty diff, got %q", got)
+	}
+}
+
+func TestApplyHunks_RoundTrip(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nB\nc\nd\ne\nf\ng\nh\nj\nk\n"
+
+	patches, err := parsePatch(unifiedDiff("/x.txt", oldText, newText))
+	if err != nil {
+		t.Fatalf("parsePatch failed: %v", err)
+	}
+	got, notes, err := applyHunks(oldText, patches[0].hunks)
+	if err != nil {
+		t.Fatalf("applyHunks failed: %v", err)
+	}
+	if got != newText {
+		t.Errorf("Expected %q, got %q", newText, got)
+	}
+	if len(notes) != 0 {
+		t.Errorf("Expected no notes, got %v", notes)
+	}
+}
+
+func TestApplyHunks_OffsetAndFuzz(t *testing.T) {
+	text := "header\nextra\nfunc f() {\n\treturn 1\n}\n"
+	patches, err := parsePatch(`--- a/f.go
++++ b/f.go
+@@ -1,3 +1,3 @@
+ func f() {
+-    return 1
++    return 2
+ }
+`)
+	if err != nil {
+		t.Fatalf("parsePatch failed: %v", err)
+	}
+
+	got, notes, err := applyHunks(text, patches[0].hunks)
+	if err != nil {
+		t.Fatalf("applyHunks failed: %v", err)
+	}
+	if got != "header\nextra\nfunc f() {\n    return 2\n}\n" {
+		t.Errorf("Unexpected result: %q", got)
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "ignoring indentation at line 3") {
+		t.Errorf("Unexpected notes: %v", notes)
+	}
+
+	_, _, err = applyHunks("other\n", patches[0].hunks)
+	if err == nil || !strings.Contains(err.Error(), "context not found") {
+		t.Errorf("Expected context error, got %v", err)
+	}
+}
+
+func TestParsePatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"no headers", "just some text"},
+		{"bad hunk header", "--- a/x\n+++ b/x\n@@ bad\n"},
+		{"unexpected line", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n?b\n"},
+		{"no hunks", "--- a/x\n+++ b/x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parsePatch(tt.patch); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestApplyPatchTool(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "main.go", "package main\n\nfunc main() {\n}\n")
+	b.WriteFile(ctx, "old.txt", "bye\n")
+
+	tool := NewApplyPatchTool(b)
+	result, err := tool.Execute(ctx, map[string]any{"patch": `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,2 +3,3 @@
+ func main() {
++	hello()
+ }
+--- /dev/null
++++ b/hello.go
+@@ -0,0 +1,3 @@
++package main
+
++func hello() {}
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`})
+	if err != nil {
+		t.Fatalf("apply_patch failed: %v", err)
+	}
+	for _, want := range []string{"modified main.go", "created hello.go", "deleted old.txt", "+\thello()"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected result to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	if content, _ := b.ReadFile(ctx, "main.go", 0, 0); content != "package main\n\nfunc main() {\n\thello()\n}\n" {
+		t.Errorf("Unexpected main.go: %q", content)
+	}
+	if content, _ := b.ReadFile(ctx, "hello.go", 0, 0); content != "package main\n\nfunc hello() {}\n" {
+		t.Errorf("Unexpected hello.go: %q", content)
+	}
+	if _, err := b.ReadFile(ctx, "old.txt", 0, 0); err == nil {
+		t.Error("Expected old.txt to be deleted")
+	}
+}
+
+func TestApplyPatchTool_Atomic(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "a.txt", "a\n")
+	b.WriteFile(ctx, "b.txt", "b\n")
+
+	tool := NewApplyPatchTool(b)
+	_, err := tool.Execute(ctx, map[string]any{"patch": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-a
++A
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-missing
++B
+`})
+	if err == nil || !strings.Contains(err.Error(), "no files were changed") {
+		t.Fatalf("Expected failure, got %v", err)
+	}
+	if content, _ := b.ReadFile(ctx, "a.txt", 0, 0); content != "a\n" {
+		t.Errorf("a.txt should be unchanged, got %q", content)
+	}
+}
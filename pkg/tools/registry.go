@@ -60,3 +60,15 @@ func (r *Registry) Remove(name string) {
 
 	delete(r.tools, name)
 }
+
+// Clone 复制注册表（工具实例共享），修改副本不影响原注册表
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clone := NewRegistry()
+	for name, tool := range r.tools {
+		clone.tools[name] = tool
+	}
+	return clone
+}
@@ -0,0 +1,451 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// TestOptions 测试运行参数
+type TestOptions struct {
+	Packages []string // 要测试的包（如 ./...）
+	Run      string   // 只运行匹配的测试（正则）
+	Coverage bool     // 是否统计覆盖率
+}
+
+// TestRunner 可插拔的测试运行器：负责构造测试命令并解析其输出
+type TestRunner interface {
+	// Name 运行器名称（如 go）
+	Name() string
+	// Command 构造测试命令的参数列表，args[0] 为命令名（通过后端按参数列表执行，不经过 shell）
+	Command(opts TestOptions) []string
+	// Parse 将命令输出解析为结构化报告
+	Parse(stdout, stderr string) *TestReport
+}
+
+// TestReport 结构化测试报告
+type TestReport struct {
+	Packages []TestPackage
+	Passed   int
+	Failed   int
+	Skipped  int
+	Failures []TestFailure
+	Output   string // 无法归入任何包的输出（如命令本身出错）
+}
+
+// TestPackage 单个包的测试结果
+type TestPackage struct {
+	Name     string
+	Status   string // ok、FAIL 或 no test files
+	Elapsed  float64
+	Coverage string // 如 85.0%，未统计时为空
+}
+
+// TestFailure 失败的测试（Test 为空表示包级失败，如编译错误）
+type TestFailure struct {
+	Package  string
+	Test     string
+	Location string // 文件:行号
+	Output   string // 输出片段
+}
+
+// maxFailureOutputLines 每个失败测试保留的输出行数
+const maxFailureOutputLines = 30
+
+// maxReportedFailures 报告中列出的失败测试数
+const maxReportedFailures = 20
+
+// OK 是否全部通过
+func (r *TestReport) OK() bool {
+	if len(r.Failures) > 0 {
+		return false
+	}
+	for _, p := range r.Packages {
+		if p.Status == "FAIL" {
+			return false
+		}
+	}
+	return len(r.Packages) > 0
+}
+
+// Format 格式化报告
+func (r *TestReport) Format() string {
+	var sb strings.Builder
+
+	status := "PASS"
+	if !r.OK() {
+		status = "FAIL"
+	}
+	fmt.Fprintf(&sb, "%s: %d package(s), %d passed, %d failed, %d skipped\n",
+		status, len(r.Packages), r.Passed, r.Failed, r.Skipped)
+
+	if len(r.Packages) > 0 {
+		sb.WriteString("\nPackages:\n")
+		for _, p := range r.Packages {
+			fmt.Fprintf(&sb, "%-4s %s", p.Status, p.Name)
+			if p.Elapsed > 0 {
+				fmt.Fprintf(&sb, " (%.2fs)", p.Elapsed)
+			}
+			if p.Coverage != "" {
+				fmt.Fprintf(&sb, " coverage: %s", p.Coverage)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		sb.WriteString("\nFailures:\n")
+		for i, f := range r.Failures {
+			if i == maxReportedFailures {
+				fmt.Fprintf(&sb, "... and %d more\n", len(r.Failures)-maxReportedFailures)
+				break
+			}
+			name := f.Test
+			if name == "" {
+				name = "[package]"
+			}
+			fmt.Fprintf(&sb, "--- %s %s", f.Package, name)
+			if f.Location != "" {
+				fmt.Fprintf(&sb, " at %s", f.Location)
+			}
+			sb.WriteString("\n")
+			for _, line := range strings.Split(f.Output, "\n") {
+				fmt.Fprintf(&sb, "    %s\n", line)
+			}
+		}
+	}
+
+	if r.Output != "" {
+		sb.WriteString("\nOutput:\n")
+		sb.WriteString(r.Output)
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// GoTestRunner 基于 go test -json 的测试运行器
+type GoTestRunner struct{}
+
+// NewGoTestRunner 创建 Go 测试运行器
+func NewGoTestRunner() *GoTestRunner {
+	return &GoTestRunner{}
+}
+
+// Name 返回运行器名称
+func (r *GoTestRunner) Name() string {
+	return "go"
+}
+
+// Command 构造 go test -json 命令
+func (r *GoTestRunner) Command(opts TestOptions) []string {
+	args := []string{"go", "test", "-json"}
+	if opts.Run != "" {
+		args = append(args, "-run", opts.Run)
+	}
+	if opts.Coverage {
+		args = append(args, "-cover")
+	}
+	return append(args, opts.Packages...)
+}
+
+// goTestEvent go test -json 输出的事件（参见 go doc test2json）
+type goTestEvent struct {
+	Action      string
+	Package     string
+	Test        string
+	Elapsed     float64
+	Output      string
+	ImportPath  string // build-output 事件
+	FailedBuild string // 因编译失败而失败的包
+}
+
+// goTestState 解析过程中单个测试的状态
+type goTestState struct {
+	name   string
+	status string
+	output []string
+}
+
+// goPackageState 解析过程中单个包的状态
+type goPackageState struct {
+	name        string
+	status      string
+	elapsed     float64
+	coverage    string
+	failedBuild string
+	output      []string
+	tests       []*goTestState
+	byName      map[string]*goTestState
+}
+
+var (
+	coveragePattern = regexp.MustCompile(`coverage: ([\d.]+%) of statements`)
+	// locationPattern 匹配测试输出和调用栈中的 文件:行号
+	locationPattern = regexp.MustCompile(`^\s*(\S+\.go):(\d+)`)
+)
+
+// Parse 解析 go test -json 输出
+func (r *GoTestRunner) Parse(stdout, stderr string) *TestReport {
+	var packages []*goPackageState
+	byName := make(map[string]*goPackageState)
+	buildOutput := make(map[string][]string)
+	var other []string
+
+	pkg := func(name string) *goPackageState {
+		p, ok := byName[name]
+		if !ok {
+			p = &goPackageState{name: name, byName: make(map[string]*goTestState)}
+			byName[name] = p
+			packages = append(packages, p)
+		}
+		return p
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		var ev goTestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			if strings.TrimSpace(line) != "" {
+				other = append(other, line)
+			}
+			continue
+		}
+
+		switch {
+		case ev.Action == "build-output":
+			buildOutput[ev.ImportPath] = append(buildOutput[ev.ImportPath], strings.TrimSuffix(ev.Output, "\n"))
+		case ev.Package == "":
+			continue
+		case ev.Test != "":
+			p := pkg(ev.Package)
+			t, ok := p.byName[ev.Test]
+			if !ok {
+				t = &goTestState{name: ev.Test}
+				p.byName[ev.Test] = t
+				p.tests = append(p.tests, t)
+			}
+			switch ev.Action {
+			case "output":
+				t.output = append(t.output, strings.TrimSuffix(ev.Output, "\n"))
+			case "pass", "fail", "skip":
+				t.status = ev.Action
+			}
+		default:
+			p := pkg(ev.Package)
+			switch ev.Action {
+			case "output":
+				p.output = append(p.output, strings.TrimSuffix(ev.Output, "\n"))
+				if m := coveragePattern.FindStringSubmatch(ev.Output); m != nil {
+					p.coverage = m[1]
+				}
+			case "pass", "fail", "skip":
+				p.status = ev.Action
+				p.elapsed = ev.Elapsed
+				p.failedBuild = ev.FailedBuild
+			}
+		}
+	}
+
+	// go 命令自身的错误写入 stderr（部分后端将合并输出同时放在 stdout 和 stderr 中）
+	if stderr != stdout {
+		for _, line := range strings.Split(stderr, "\n") {
+			if strings.TrimSpace(line) != "" {
+				other = append(other, line)
+			}
+		}
+	}
+
+	report := &TestReport{}
+	for _, p := range packages {
+		report.Packages = append(report.Packages, TestPackage{
+			Name:     p.name,
+			Status:   goPackageStatus(p.status),
+			Elapsed:  p.elapsed,
+			Coverage: p.coverage,
+		})
+
+		failures := 0
+		for _, t := range p.tests {
+			switch t.status {
+			case "pass":
+				report.Passed++
+			case "skip":
+				report.Skipped++
+			case "fail", "":
+				// 未结束的测试（panic 或超时）仅在包失败时计为失败
+				if t.status == "" && p.status != "fail" {
+					continue
+				}
+				// 子测试失败时父测试也会失败，只报告最内层的失败
+				if hasFailedSubtest(p, t.name) {
+					continue
+				}
+				failures++
+				report.Failures = append(report.Failures, goFailure(p.name, t.name, t.output))
+			}
+		}
+
+		if p.status == "fail" && failures == 0 {
+			output := p.output
+			if p.failedBuild != "" {
+				output = buildOutput[p.failedBuild]
+			}
+			report.Failures = append(report.Failures, goFailure(p.name, "", output))
+		}
+	}
+	report.Failed = len(report.Failures)
+
+	if len(other) > 0 {
+		report.Output = strings.Join(truncateLines(other, maxFailureOutputLines), "\n")
+	}
+	return report
+}
+
+// goPackageStatus 将包的结束动作转换为 go test 的状态文本
+func goPackageStatus(action string) string {
+	switch action {
+	case "pass":
+		return "ok"
+	case "skip":
+		return "no test files"
+	default:
+		return "FAIL"
+	}
+}
+
+// hasFailedSubtest 检查测试是否有失败的子测试
+func hasFailedSubtest(p *goPackageState, name string) bool {
+	prefix := name + "/"
+	return slices.ContainsFunc(p.tests, func(t *goTestState) bool {
+		return strings.HasPrefix(t.name, prefix) && t.status != "pass" && t.status != "skip"
+	})
+}
+
+// goFailure 从测试输出中提取失败片段和位置
+func goFailure(pkg, test string, output []string) TestFailure {
+	var lines []string
+	location := ""
+	for _, line := range output {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- FAIL") ||
+			trimmed == "FAIL" || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "# ") {
+			continue
+		}
+		if location == "" {
+			// 跳过标准库调用栈，定位到用户代码
+			if m := locationPattern.FindStringSubmatch(line); m != nil &&
+				!strings.Contains(m[1], "/src/runtime/") && !strings.Contains(m[1], "/src/testing/") {
+				location = m[1] + ":" + m[2]
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return TestFailure{
+		Package:  pkg,
+		Test:     test,
+		Location: location,
+		Output:   strings.Join(truncateLines(lines, maxFailureOutputLines), "\n"),
+	}
+}
+
+// truncateLines 保留前 max 行并注明省略的行数
+func truncateLines(lines []string, max int) []string {
+	if len(lines) <= max {
+		return lines
+	}
+	return append(lines[:max:max], fmt.Sprintf("... (%d more lines)", len(lines)-max))
+}
+
+// NewTestTool 创建测试工具，通过后端执行测试命令并返回结构化结果
+// 未指定运行器时使用 go test
+func NewTestTool(b backend.Backend, runners ...TestRunner) Tool {
+	if len(runners) == 0 {
+		runners = []TestRunner{NewGoTestRunner()}
+	}
+	names := make([]string, len(runners))
+	for i, r := range runners {
+		names[i] = r.Name()
+	}
+
+	return NewBaseTool(
+		"run_tests",
+		`运行测试并返回结构化结果（比通过 bash 运行测试更易读）。
+
+**输出**：
+- 各包的状态、耗时和覆盖率
+- 通过/失败/跳过的测试数
+- 失败测试的名称、文件:行号和输出片段
+
+**参数说明**：
+- packages: 要测试的包（默认 ./...）
+- run: 只运行名称匹配该正则的测试（可选）
+- coverage: 是否统计覆盖率（默认 false）
+- runner: 测试运行器（默认 `+names[0]+`，可选：`+strings.Join(names, ", ")+`）
+- timeout: 超时时间（秒），默认 300 秒`,
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"packages": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "要测试的包（默认 ./...）",
+				},
+				"run": map[string]any{
+					"type":        "string",
+					"description": "只运行名称匹配该正则的测试",
+				},
+				"coverage": map[string]any{
+					"type":        "boolean",
+					"description": "是否统计覆盖率",
+				},
+				"runner": map[string]any{
+					"type":        "string",
+					"description": "测试运行器",
+					"enum":        names,
+				},
+				"timeout": map[string]any{
+					"type":        "integer",
+					"description": "超时时间（秒），默认 300 秒",
+				},
+			},
+		},
+		func(ctx context.Context, args map[string]any) (string, error) {
+			runner := runners[0]
+			if name := GetStringArg(args, "runner", ""); name != "" {
+				idx := slices.Index(names, name)
+				if idx < 0 {
+					return "", fmt.Errorf("unknown test runner %q (available: %s)", name, strings.Join(names, ", "))
+				}
+				runner = runners[idx]
+			}
+
+			opts := TestOptions{
+				Run:      GetStringArg(args, "run", ""),
+				Coverage: GetBoolArg(args, "coverage", false),
+				Packages: GetStringSliceArg(args, "packages"),
+			}
+			if len(opts.Packages) == 0 {
+				opts.Packages = []string{"./..."}
+			}
+
+			// 包名不能被当作参数
+			for _, p := range opts.Packages {
+				if p == "" || strings.HasPrefix(p, "-") {
+					return "", fmt.Errorf("invalid package %q", p)
+				}
+			}
+
+			timeout := GetIntArg(args, "timeout", 300)
+			result, err := backend.ExecuteArgs(ctx, b, runner.Command(opts), timeout*1000)
+			if err != nil {
+				return "", fmt.Errorf("failed to run tests: %w", err)
+			}
+			return runner.Parse(result.Stdout, result.Stderr).Format(), nil
+		},
+	)
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// goTestOutput 截取自 go test -json -cover 的真实输出
+const goTestOutput = `{"Action":"start","Package":"example.com/gt/a"}
+{"Action":"run","Package":"example.com/gt/a","Test":"TestOK"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestOK","Output":"=== RUN   TestOK\n"}
+{"Action":"pass","Package":"example.com/gt/a","Test":"TestOK","Elapsed":0}
+{"Action":"run","Package":"example.com/gt/a","Test":"TestFail"}
+{"Action":"run","Package":"example.com/gt/a","Test":"TestFail/sub"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestFail/sub","Output":"=== RUN   TestFail/sub\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestFail/sub","Output":"    a_test.go:7: want 1, got 2\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestFail/sub","Output":"--- FAIL: TestFail/sub (0.00s)\n"}
+{"Action":"fail","Package":"example.com/gt/a","Test":"TestFail/sub","Elapsed":0}
+{"Action":"run","Package":"example.com/gt/a","Test":"TestFail/subok"}
+{"Action":"pass","Package":"example.com/gt/a","Test":"TestFail/subok","Elapsed":0}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestFail","Output":"--- FAIL: TestFail (0.00s)\n"}
+{"Action":"fail","Package":"example.com/gt/a","Test":"TestFail","Elapsed":0}
+{"Action":"run","Package":"example.com/gt/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/gt/a","Test":"TestSkip","Elapsed":0}
+{"Action":"run","Package":"example.com/gt/a","Test":"TestPanic"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestPanic","Output":"--- FAIL: TestPanic (0.00s)\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestPanic","Output":"panic: assignment to entry in nil map [recovered, repanicked]\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestPanic","Output":"\t/usr/local/go/src/testing/testing.go:2123 +0x232\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestPanic","Output":"\t/usr/local/go/src/runtime/panic.go:859 +0x125\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestPanic","Output":"example.com/gt/a.TestPanic(0x20551c574d88?)\n"}
+{"Action":"output","Package":"example.com/gt/a","Test":"TestPanic","Output":"\t/tmp/gt/a/a_test.go:11 +0x28\n"}
+{"Action":"fail","Package":"example.com/gt/a","Test":"TestPanic","Elapsed":0}
+{"Action":"output","Package":"example.com/gt/a","Output":"FAIL\texample.com/gt/a\t0.005s\n"}
+{"Action":"fail","Package":"example.com/gt/a","Elapsed":0.006}
+{"ImportPath":"example.com/gt/b","Action":"build-output","Output":"# example.com/gt/b\n"}
+{"ImportPath":"example.com/gt/b","Action":"build-output","Output":"b/b.go:3:22: cannot use \"x\" (untyped string constant) as int value in return statement\n"}
+{"ImportPath":"example.com/gt/b","Action":"build-fail"}
+{"Action":"start","Package":"example.com/gt/b"}
+{"Action":"output","Package":"example.com/gt/b","Output":"FAIL\texample.com/gt/b [build failed]\n"}
+{"Action":"fail","Package":"example.com/gt/b","Elapsed":0,"FailedBuild":"example.com/gt/b"}
+{"Action":"start","Package":"example.com/gt/c"}
+{"Action":"run","Package":"example.com/gt/c","Test":"TestG"}
+{"Action":"pass","Package":"example.com/gt/c","Test":"TestG","Elapsed":0}
+{"Action":"output","Package":"example.com/gt/c","Output":"coverage: 100.0% of statements\n"}
+{"Action":"pass","Package":"example.com/gt/c","Elapsed":0.005}
+`
+
+func TestGoTestRunner_Parse(t *testing.T) {
+	report := NewGoTestRunner().Parse(goTestOutput, "")
+
+	if len(report.Packages) != 3 {
+		t.Fatalf("expected 3 packages, got %+v", report.Packages)
+	}
+	if report.Packages[0].Status != "FAIL" || report.Packages[2].Status != "ok" || report.Packages[2].Coverage != "100.0%" {
+		t.Errorf("unexpected packages: %+v", report.Packages)
+	}
+	if report.Passed != 3 || report.Skipped != 1 || report.Failed != 3 {
+		t.Errorf("expected 3 passed, 1 skipped, 3 failed, got %d/%d/%d", report.Passed, report.Skipped, report.Failed)
+	}
+
+	want := []TestFailure{
+		{Package: "example.com/gt/a", Test: "TestFail/sub", Location: "a_test.go:7"},
+		{Package: "example.com/gt/a", Test: "TestPanic", Location: "/tmp/gt/a/a_test.go:11"},
+		{Package: "example.com/gt/b", Test: "", Location: "b/b.go:3"},
+	}
+	for i, w := range want {
+		f := report.Failures[i]
+		if f.Package != w.Package || f.Test != w.Test || f.Location != w.Location {
+			t.Errorf("failure %d: expected %+v, got %+v", i, w, f)
+		}
+	}
+	if !strings.Contains(report.Failures[0].Output, "want 1, got 2") || strings.Contains(report.Failures[0].Output, "=== RUN") {
+		t.Errorf("unexpected failure output: %q", report.Failures[0].Output)
+	}
+
+	out := report.Format()
+	for _, s := range []string{"FAIL: 3 package(s), 3 passed, 3 failed, 1 skipped", "ok   example.com/gt/c", "coverage: 100.0%", "--- example.com/gt/b [package] at b/b.go:3"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in report:\n%s", s, out)
+		}
+	}
+}
+
+func TestGoTestRunner_ParseCommandError(t *testing.T) {
+	report := NewGoTestRunner().Parse("", "go: cannot find main module\n")
+	if report.OK() || !strings.Contains(report.Format(), "go: cannot find main module") {
+		t.Errorf("expected command error in report, got:\n%s", report.Format())
+	}
+}
+
+func TestTestTool(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/demo\n\ngo 1.21\n",
+		"demo.go":      "package demo\n\nfunc Add(a, b int) int { return a + b }\n",
+		"demo_test.go": "package demo\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 1) != 3 {\n\t\tt.Errorf(\"Add(1, 1) = %d\", Add(1, 1))\n\t}\n}\n\nfunc TestOK(t *testing.T) {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := backend.NewFilesystemBackend(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewTestTool(b)
+	result, err := tool.Execute(context.Background(), map[string]any{"coverage": true})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	for _, s := range []string{"1 passed, 1 failed", "example.com/demo TestAdd at demo_test.go:7", "Add(1, 1) = 2", "coverage:"} {
+		if !strings.Contains(result, s) {
+			t.Errorf("expected %q in result:\n%s", s, result)
+		}
+	}
+
+	result, err = tool.Execute(context.Background(), map[string]any{"run": "TestOK"})
+	if err != nil || !strings.HasPrefix(result, "PASS: 1 package(s), 1 passed") {
+		t.Errorf("expected passing run, got %q (err=%v)", result, err)
+	}
+
+	// 参数按列表传递，包含空白的正则不会被拆分
+	result, err = tool.Execute(context.Background(), map[string]any{"run": "^TestOK$|^Test Missing$"})
+	if err != nil || !strings.HasPrefix(result, "PASS: 1 package(s), 1 passed") {
+		t.Errorf("expected passing run for pattern with whitespace, got %q (err=%v)", result, err)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]any{"packages": []any{"-exec=rm"}}); err == nil {
+		t.Error("expected error for flag-like package")
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"runner": "pytest"}); err == nil {
+		t.Error("expected error for unknown runner")
+	}
+}
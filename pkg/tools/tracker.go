@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// fileVersion 文件在被读取时的版本
+type fileVersion struct {
+	hash    string // 内容的 SHA-256
+	modTime int64  // 修改时间（后端不支持时为 0）
+}
+
+// FileTracker 记录一次运行中模型已读取的文件及其版本，用于防止过期写入：
+// 编辑未读取过的文件，或文件在读取后被外部修改时拒绝写入
+type FileTracker struct {
+	mu    sync.Mutex
+	files map[string]fileVersion
+	scope func(ctx context.Context) *FileTracker // 返回 ctx 所属作用域的跟踪器
+}
+
+// NewFileTracker 创建文件读取跟踪器
+func NewFileTracker() *FileTracker {
+	return &FileTracker{
+		files: make(map[string]fileVersion),
+	}
+}
+
+// NewScopedFileTracker 创建按作用域（如一次执行）分别记录的跟踪器
+// scope 返回 ctx 所属作用域的跟踪器，返回 nil 时使用自身的记录
+func NewScopedFileTracker(scope func(ctx context.Context) *FileTracker) *FileTracker {
+	t := NewFileTracker()
+	t.scope = scope
+	return t
+}
+
+// resolve 返回 ctx 所属作用域的跟踪器
+func (t *FileTracker) resolve(ctx context.Context) *FileTracker {
+	if t.scope != nil {
+		if scoped := t.scope(ctx); scoped != nil {
+			return scoped
+		}
+	}
+	return t
+}
+
+// Reset 清空所有记录（新的运行开始时调用）
+func (t *FileTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files = make(map[string]fileVersion)
+}
+
+// Record 记录文件的当前版本（读取或成功写入后调用）
+func (t *FileTracker) Record(ctx context.Context, b backend.Backend, filePath string) error {
+	t = t.resolve(ctx)
+	version, err := currentVersion(ctx, b, filePath)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[trackerKey(filePath)] = version
+	return nil
+}
+
+// CheckWrite 检查是否允许写入文件
+// 不存在的文件允许创建；已存在的文件必须在本次运行中读取过，且读取后未被修改
+func (t *FileTracker) CheckWrite(ctx context.Context, b backend.Backend, filePath string) error {
+	t = t.resolve(ctx)
+	current, err := currentVersion(ctx, b, filePath)
+	if err != nil {
+		// 文件不存在，允许创建
+		return nil
+	}
+	return t.check(filePath, current)
+}
+
+// CheckEdit 检查是否允许编辑文件：文件必须在本次运行中读取过，且读取后未被修改
+func (t *FileTracker) CheckEdit(ctx context.Context, b backend.Backend, filePath string) error {
+	t = t.resolve(ctx)
+	current, err := currentVersion(ctx, b, filePath)
+	if err != nil {
+		return err
+	}
+	return t.check(filePath, current)
+}
+
+// check 比较当前版本与读取时记录的版本
+func (t *FileTracker) check(filePath string, current fileVersion) error {
+	t.mu.Lock()
+	recorded, ok := t.files[trackerKey(filePath)]
+	t.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("file has not been read yet: %s. Use read_file to read it before modifying it", filePath)
+	}
+	if recorded.hash != current.hash {
+		detail := ""
+		if recorded.modTime != 0 && current.modTime != 0 && recorded.modTime != current.modTime {
+			detail = fmt.Sprintf(" (last read version modified at %s, current version modified at %s)",
+				time.Unix(recorded.modTime, 0).Format(time.RFC3339),
+				time.Unix(current.modTime, 0).Format(time.RFC3339))
+		}
+		return fmt.Errorf("file has been modified since it was last read: %s%s. Use read_file to read the latest content before modifying it", filePath, detail)
+	}
+	return nil
+}
+
+// currentVersion 读取文件的当前版本
+func currentVersion(ctx context.Context, b backend.Backend, filePath string) (fileVersion, error) {
+	data, err := backend.ReadBytes(ctx, b, filePath)
+	if err != nil {
+		return fileVersion{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	version := fileVersion{hash: hex.EncodeToString(sum[:])}
+	if info, err := backend.Stat(ctx, b, filePath); err == nil {
+		version.modTime = info.ModTime
+	}
+	return version, nil
+}
+
+// trackerKey 规范化路径，使 "a.go"、"/a.go"、"./a.go" 对应同一文件
+func trackerKey(filePath string) string {
+	return path.Clean("/" + strings.TrimPrefix(filePath, "/"))
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// newTrackedTools 创建共享同一 tracker 的 read/write/edit 工具
+func newTrackedTools(b backend.Backend) (read, write, edit Tool, tracker *FileTracker) {
+	tracker = NewFileTracker()
+	return NewReadFileToolWithTracker(b, tracker),
+		NewWriteFileToolWithTracker(b, tracker),
+		NewEditFileToolWithTracker(b, tracker),
+		tracker
+}
+
+func TestFileTracker_EditRequiresRead(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/main.go", "package main")
+	read, _, edit, _ := newTrackedTools(b)
+
+	editArgs := map[string]any{"path": "/main.go", "old_string": "main", "new_string": "app"}
+
+	_, err := edit.Execute(ctx, editArgs)
+	if err == nil || !strings.Contains(err.Error(), "has not been read") {
+		t.Fatalf("Expected not-read error, got %v", err)
+	}
+
+	if _, err := read.Execute(ctx, map[string]any{"path": "/main.go"}); err != nil {
+		t.Fatalf("read_file failed: %v", err)
+	}
+	if _, err := edit.Execute(ctx, editArgs); err != nil {
+		t.Fatalf("edit_file after read failed: %v", err)
+	}
+
+	// 自己的编辑会更新记录，连续编辑无需重新读取
+	if _, err := edit.Execute(ctx, map[string]any{"path": "/main.go", "old_string": "app", "new_string": "cli"}); err != nil {
+		t.Errorf("Consecutive edit failed: %v", err)
+	}
+}
+
+func TestFileTracker_DetectsExternalModification(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/config.yaml", "port: 80")
+	read, write, edit, _ := newTrackedTools(b)
+
+	read.Execute(ctx, map[string]any{"path": "/config.yaml"})
+
+	// 其他人在读取后修改了文件
+	b.WriteFile(ctx, "/config.yaml", "port: 8080")
+
+	_, err := edit.Execute(ctx, map[string]any{"path": "/config.yaml", "old_string": "port", "new_string": "listen"})
+	if err == nil || !strings.Contains(err.Error(), "modified since it was last read") {
+		t.Fatalf("Expected stale edit error, got %v", err)
+	}
+	_, err = write.Execute(ctx, map[string]any{"path": "/config.yaml", "content": "port: 1"})
+	if err == nil {
+		t.Fatal("Expected stale write error")
+	}
+
+	// 重新读取后可以继续
+	read.Execute(ctx, map[string]any{"path": "/config.yaml"})
+	if _, err := edit.Execute(ctx, map[string]any{"path": "/config.yaml", "old_string": "8080", "new_string": "9090"}); err != nil {
+		t.Errorf("Edit after re-read failed: %v", err)
+	}
+	if content, _ := b.ReadFile(ctx, "/config.yaml", 0, 0); content != "port: 9090" {
+		t.Errorf("Expected %q, got %q", "port: 9090", content)
+	}
+}
+
+func TestFileTracker_WriteNewFileAllowed(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	_, write, edit, tracker := newTrackedTools(b)
+
+	if _, err := write.Execute(ctx, map[string]any{"path": "/new.txt", "content": "hello"}); err != nil {
+		t.Fatalf("Writing a new file should be allowed: %v", err)
+	}
+	// 新建的文件可以直接编辑
+	if _, err := edit.Execute(ctx, map[string]any{"path": "/new.txt", "old_string": "hello", "new_string": "hi"}); err != nil {
+		t.Errorf("Edit after write failed: %v", err)
+	}
+
+	// Reset 后需要重新读取
+	tracker.Reset()
+	if _, err := write.Execute(ctx, map[string]any{"path": "/new.txt", "content": "again"}); err == nil {
+		t.Error("Expected error overwriting unread file after reset")
+	}
+}
+
+func TestFileTracker_MoveAndCopyCheckStaleWrites(t *testing.T) {
+	b := backend.NewStateBackend()
+	ctx := context.Background()
+	b.WriteFile(ctx, "/a.txt", "a")
+	b.WriteFile(ctx, "/b.txt", "b")
+	read, _, _, tracker := newTrackedTools(b)
+	move := NewMoveFileToolWithTracker(b, tracker)
+	copyTool := NewCopyFileToolWithTracker(b, tracker)
+
+	// 覆盖未读取的目标文件被拒绝
+	read.Execute(ctx, map[string]any{"path": "/a.txt"})
+	_, err := copyTool.Execute(ctx, map[string]any{"source": "/a.txt", "destination": "/b.txt", "overwrite": true})
+	if err == nil || !strings.Contains(err.Error(), "has not been read") {
+		t.Fatalf("Expected not-read error for destination, got %v", err)
+	}
+
+	// 目标文件读取后被外部修改，覆盖被拒绝
+	read.Execute(ctx, map[string]any{"path": "/b.txt"})
+	b.WriteFile(ctx, "/b.txt", "changed")
+	_, err = move.Execute(ctx, map[string]any{"source": "/a.txt", "destination": "/b.txt", "overwrite": true})
+	if err == nil || !strings.Contains(err.Error(), "modified since it was last read") {
+		t.Fatalf("Expected stale destination error, got %v", err)
+	}
+
+	// 移动未读取的源文件被拒绝
+	b.WriteFile(ctx, "/c.txt", "c")
+	_, err = move.Execute(ctx, map[string]any{"source": "/c.txt", "destination": "/d.txt"})
+	if err == nil || !strings.Contains(err.Error(), "has not been read") {
+		t.Fatalf("Expected not-read error for source, got %v", err)
+	}
+
+	// 新目标无需读取，移动后的文件可以直接覆盖
+	if _, err := copyTool.Execute(ctx, map[string]any{"source": "/b.txt", "destination": "/e.txt"}); err != nil {
+		t.Fatalf("copy_file to new destination failed: %v", err)
+	}
+	if _, err := move.Execute(ctx, map[string]any{"source": "/a.txt", "destination": "/e.txt", "overwrite": true}); err != nil {
+		t.Fatalf("move_file over copied file failed: %v", err)
+	}
+	if content, _ := b.ReadFile(ctx, "/e.txt", 0, 0); content != "a" {
+		t.Errorf("Expected moved content, got %q", content)
+	}
+}
+
+func TestTrackerKey(t *testing.T) {
+	for _, p := range []string{"a.go", "/a.go", "./a.go", "/dir/../a.go"} {
+		if got := trackerKey(p); got != "/a.go" {
+			t.Errorf("trackerKey(%q) = %q, want %q", p, got, "/a.go")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+)
+
+// 错误码
+const (
+	CodeCanceled         = "canceled"          // 上下文被取消
+	CodeDeadlineExceeded = "deadline_exceeded" // 上下文超时
+	CodeThreadBusy       = "thread_busy"       // 线程正在执行
+	CodeInternal         = "internal"          // 其他错误
+)
+
+// Error 错误的编码格式，解码后作为事件的 error 使用
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// coder 可以提供自定义错误码的错误
+type coder interface {
+	ErrorCode() string
+}
+
+// NewError 把 error 转换为编码格式，err 为 nil 时返回 nil
+// 错误码优先取错误链中的 *Error 或 ErrorCode() 方法，其次识别已知错误，否则为 internal
+func NewError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	e := &Error{Code: CodeInternal, Message: err.Error()}
+	var we *Error
+	var c coder
+	switch {
+	case errors.As(err, &we):
+		e.Code = we.Code
+	case errors.As(err, &c):
+		e.Code = c.ErrorCode()
+	case errors.Is(err, context.Canceled):
+		e.Code = CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code = CodeDeadlineExceeded
+	case errors.Is(err, agent.ErrThreadBusy):
+		e.Code = CodeThreadBusy
+	}
+	return e
+}
@@ -0,0 +1,172 @@
+// Package wire 定义 Agent 事件和 LLM 流式事件的版本化 JSON / NDJSON 编码
+// 用于通过网络传输事件或写入日志，以及由其他进程解码消费
+package wire
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// Version 当前编码格式版本，格式发生不兼容变化时递增
+const Version = 1
+
+// Kind 事件来源
+type Kind string
+
+const (
+	KindAgent  Kind = "agent"  // agent.AgentEvent
+	KindStream Kind = "stream" // llm.StreamEvent
+)
+
+// Event 事件的编码格式
+type Event struct {
+	Version    int                 `json:"v"`
+	Kind       Kind                `json:"kind"`
+	Type       string              `json:"type"`
+	RunID      string              `json:"run_id,omitempty"`
+	Iteration  int                 `json:"iteration,omitempty"`
+	Timestamp  time.Time           `json:"ts,omitzero"`
+	Content    string              `json:"content,omitempty"`
+	ToolCall   *llm.ToolCall       `json:"tool_call,omitempty"`
+	ToolResult *llm.ToolResult     `json:"tool_result,omitempty"`
+	Reasoning  *llm.ReasoningBlock `json:"reasoning,omitempty"`
+	StopReason string              `json:"stop_reason,omitempty"`
+	Error      *Error              `json:"error,omitempty"`
+	SubAgent   *agent.SubAgentInfo `json:"subagent,omitempty"`
+	Result     *agent.InvokeOutput `json:"result,omitempty"` // 结束事件携带的最终消息、文件和元数据
+	Metadata   map[string]any      `json:"metadata,omitempty"`
+	Done       bool                `json:"done,omitempty"`
+}
+
+// resultKeys 结束事件元数据中属于最终结果的字段
+var resultKeys = []string{"messages", "files", "metadata"}
+
+// FromAgentEvent 把 Agent 事件转换为编码格式
+// 结束事件元数据中的 messages / files / metadata 转为 Result，其余元数据原样保留
+func FromAgentEvent(event agent.AgentEvent) *Event {
+	e := &Event{
+		Version:    Version,
+		Kind:       KindAgent,
+		Type:       string(event.Type),
+		RunID:      event.RunID,
+		Iteration:  event.Iteration,
+		Timestamp:  event.Timestamp,
+		Content:    event.Content,
+		ToolCall:   event.ToolCall,
+		ToolResult: event.ToolResult,
+		Error:      NewError(event.Error),
+		SubAgent:   event.SubAgent,
+		Metadata:   maps.Clone(event.Metadata),
+		Done:       event.Done,
+	}
+
+	if event.Type == agent.AgentEventTypeEnd {
+		if messages, ok := e.Metadata["messages"].([]llm.Message); ok {
+			e.Result = &agent.InvokeOutput{Messages: messages}
+			e.Result.Files, _ = e.Metadata["files"].(map[string]string)
+			e.Result.Metadata, _ = e.Metadata["metadata"].(map[string]any)
+			for _, key := range resultKeys {
+				delete(e.Metadata, key)
+			}
+		}
+	}
+	if len(e.Metadata) == 0 {
+		e.Metadata = nil
+	}
+	return e
+}
+
+// FromStreamEvent 把 LLM 流式事件转换为编码格式
+// 流式事件本身不带执行 ID、迭代和时间，需要时由调用方在编码前填充
+func FromStreamEvent(event llm.StreamEvent) *Event {
+	return &Event{
+		Version:    Version,
+		Kind:       KindStream,
+		Type:       string(event.Type),
+		Content:    event.Content,
+		ToolCall:   event.ToolCall,
+		Reasoning:  event.Reasoning,
+		StopReason: event.StopReason,
+		Error:      NewError(event.Error),
+		Metadata:   event.Metadata,
+		Done:       event.Done,
+	}
+}
+
+// AgentEvent 还原为 Agent 事件，Result 放回结束事件的元数据
+func (e *Event) AgentEvent() (agent.AgentEvent, error) {
+	if e.Kind != KindAgent {
+		return agent.AgentEvent{}, fmt.Errorf("not an agent event: %s", e.Kind)
+	}
+
+	event := agent.AgentEvent{
+		Type:       agent.AgentEventType(e.Type),
+		Content:    e.Content,
+		ToolCall:   e.ToolCall,
+		ToolResult: e.ToolResult,
+		Iteration:  e.Iteration,
+		Metadata:   maps.Clone(e.Metadata),
+		SubAgent:   e.SubAgent,
+		Done:       e.Done,
+		RunID:      e.RunID,
+		Timestamp:  e.Timestamp,
+	}
+	if e.Error != nil {
+		event.Error = e.Error
+	}
+	if e.Result != nil {
+		if event.Metadata == nil {
+			event.Metadata = make(map[string]any, len(resultKeys))
+		}
+		event.Metadata["messages"] = e.Result.Messages
+		event.Metadata["files"] = e.Result.Files
+		event.Metadata["metadata"] = e.Result.Metadata
+	}
+	return event, nil
+}
+
+// StreamEvent 还原为 LLM 流式事件
+func (e *Event) StreamEvent() (llm.StreamEvent, error) {
+	if e.Kind != KindStream {
+		return llm.StreamEvent{}, fmt.Errorf("not a stream event: %s", e.Kind)
+	}
+
+	event := llm.StreamEvent{
+		Type:       llm.StreamEventType(e.Type),
+		Content:    e.Content,
+		ToolCall:   e.ToolCall,
+		Reasoning:  e.Reasoning,
+		StopReason: e.StopReason,
+		Metadata:   e.Metadata,
+		Done:       e.Done,
+	}
+	if e.Error != nil {
+		event.Error = e.Error
+	}
+	return event, nil
+}
+
+// Marshal 把事件编码为 JSON
+func Marshal(e *Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal 解码 JSON 事件，拒绝缺少版本或版本高于 Version 的事件
+func Unmarshal(data []byte) (*Event, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+	if e.Version < 1 || e.Version > Version {
+		return nil, fmt.Errorf("unsupported event version: %d", e.Version)
+	}
+	if e.Kind != KindAgent && e.Kind != KindStream {
+		return nil, fmt.Errorf("unknown event kind: %q", e.Kind)
+	}
+	return &e, nil
+}
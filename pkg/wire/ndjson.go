@@ -0,0 +1,55 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// maxLineSize 解码时单行事件的最大长度（结束事件携带完整消息历史）
+const maxLineSize = 64 * 1024 * 1024
+
+// Encoder 以 NDJSON 格式（每行一个事件）写出事件
+type Encoder struct {
+	enc *json.Encoder
+}
+
+// NewEncoder 创建 NDJSON 编码器
+func NewEncoder(w io.Writer) *Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Encoder{enc: enc}
+}
+
+// Encode 写出一个事件（以换行结尾）
+func (e *Encoder) Encode(event *Event) error {
+	return e.enc.Encode(event)
+}
+
+// Decoder 从 NDJSON 流中读取事件
+type Decoder struct {
+	scanner *bufio.Scanner
+}
+
+// NewDecoder 创建 NDJSON 解码器
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &Decoder{scanner: scanner}
+}
+
+// Decode 读取下一个事件，跳过空行；流结束时返回 io.EOF
+func (d *Decoder) Decode() (*Event, error) {
+	for d.scanner.Scan() {
+		line := bytes.TrimSpace(d.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return Unmarshal(line)
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,257 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+	"github.com/zhoucx/deepagents-go/pkg/tools"
+)
+
+var update = flag.Bool("update", false, "更新 golden 文件")
+
+// goldenEvents 覆盖所有事件类型的样例
+func goldenEvents() []*Event {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	call := &llm.ToolCall{ID: "call_1", Name: "ls", Input: map[string]any{"path": "/"}}
+	sub := &agent.SubAgentInfo{ID: "sub-1", Name: "general-purpose", Depth: 1, Task: "列出文件"}
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "列出文件"},
+		{Role: llm.RoleAssistant, Content: "完成"},
+	}
+
+	agentEvents := []agent.AgentEvent{
+		{Type: agent.AgentEventTypeStart},
+		{Type: agent.AgentEventTypeLLMStart, Iteration: 1},
+		{Type: agent.AgentEventTypeLLMThinking, Content: "先看看目录", Iteration: 1},
+		{Type: agent.AgentEventTypeLLMText, Content: "<查看>", Iteration: 1},
+		{Type: agent.AgentEventTypeLLMToolCall, ToolCall: call, Iteration: 1},
+		{Type: agent.AgentEventTypeLLMEnd, Iteration: 1, Metadata: map[string]any{"stop_reason": "tool_use"}},
+		{Type: agent.AgentEventTypeToolStart, ToolCall: call, Iteration: 1},
+		{Type: agent.AgentEventTypeLLMText, Content: "子任务", SubAgent: sub, RunID: "run-sub"},
+		{Type: agent.AgentEventTypeToolResult, ToolResult: &llm.ToolResult{ToolCallID: "call_1", Content: "a.txt"}, Iteration: 1},
+		{Type: agent.AgentEventTypeIterationEnd, Iteration: 1},
+		{Type: agent.AgentEventTypeHandoff, Metadata: map[string]any{"from": "supervisor", "to": "coder"}},
+		{Type: agent.AgentEventTypeHandoffEnd, Metadata: map[string]any{"from": "coder", "to": "supervisor"}},
+		{Type: agent.AgentEventTypeNodeStart, Iteration: 1, Metadata: map[string]any{"node": "plan"}},
+		{Type: agent.AgentEventTypeNodeEnd, Iteration: 1, Metadata: map[string]any{"node": "plan"}, Error: errors.New("node failed")},
+		{Type: agent.AgentEventTypeError, Error: fmt.Errorf("llm generate failed: %w", context.DeadlineExceeded), Done: true},
+		{Type: agent.AgentEventTypeError, Error: agent.ErrThreadBusy, Done: true},
+		{
+			Type: agent.AgentEventTypeEnd,
+			Metadata: map[string]any{
+				"messages": messages,
+				"files":    map[string]string{"/a.txt": "hello"},
+				"metadata": map[string]any{"session_id": "s1"},
+			},
+			Done: true,
+		},
+	}
+	streamEvents := []llm.StreamEvent{
+		{Type: llm.StreamEventTypeStart},
+		{Type: llm.StreamEventTypeThinking, Content: "先看看"},
+		{Type: llm.StreamEventTypeThinking, Reasoning: &llm.ReasoningBlock{Text: "先看看", Signature: "sig"}},
+		{Type: llm.StreamEventTypeText, Content: "完成"},
+		{Type: llm.StreamEventTypeToolUse, ToolCall: call},
+		{Type: llm.StreamEventTypePing},
+		{Type: llm.StreamEventTypeMetadata, Metadata: map[string]any{"input_tokens": float64(12)}},
+		{Type: llm.StreamEventTypeEnd, StopReason: "end_turn", Done: true},
+		{Type: llm.StreamEventTypeError, Error: context.Canceled, Done: true},
+	}
+
+	var events []*Event
+	for _, event := range agentEvents {
+		if event.RunID == "" {
+			event.RunID = "run-1"
+		}
+		event.Timestamp = ts
+		events = append(events, FromAgentEvent(event))
+	}
+	for _, event := range streamEvents {
+		e := FromStreamEvent(event)
+		e.RunID = "run-1"
+		e.Iteration = 2
+		e.Timestamp = ts
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestEncoder_Golden(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, event := range goldenEvents() {
+		if err := enc.Encode(event); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	path := filepath.Join("testdata", "events.ndjson")
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("encoding differs from %s (run with -update to regenerate):\n%s", path, buf.String())
+	}
+}
+
+func TestDecoder_Golden(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "events.ndjson"))
+	if err != nil {
+		t.Fatalf("open golden file: %v", err)
+	}
+	defer f.Close()
+
+	dec := NewDecoder(f)
+	var decoded []*Event
+	for {
+		event, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		decoded = append(decoded, event)
+	}
+
+	// 往返编码后与原始事件一致
+	want := goldenEvents()
+	if len(decoded) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(decoded))
+	}
+	for i := range want {
+		a, _ := Marshal(decoded[i])
+		b, _ := Marshal(want[i])
+		if !bytes.Equal(a, b) {
+			t.Errorf("event %d round trip mismatch:\n got %s\nwant %s", i, a, b)
+		}
+	}
+}
+
+func TestEvent_AgentEventRoundTrip(t *testing.T) {
+	events := goldenEvents()
+
+	// 结束事件还原后携带最终结果
+	end, err := events[16].AgentEvent()
+	if err != nil {
+		t.Fatalf("AgentEvent failed: %v", err)
+	}
+	messages, _ := end.Metadata["messages"].([]llm.Message)
+	files, _ := end.Metadata["files"].(map[string]string)
+	if len(messages) != 2 || files["/a.txt"] != "hello" || end.RunID != "run-1" || !end.Done {
+		t.Errorf("unexpected end event: %+v", end)
+	}
+
+	// 错误还原为带错误码的 *Error
+	failed, _ := events[14].AgentEvent()
+	var we *Error
+	if !errors.As(failed.Error, &we) || we.Code != CodeDeadlineExceeded || !strings.HasPrefix(we.Message, "llm generate failed") {
+		t.Errorf("unexpected error: %#v", failed.Error)
+	}
+	// 再次编码保留错误码
+	if NewError(failed.Error).Code != CodeDeadlineExceeded {
+		t.Error("expected error code to survive re-encoding")
+	}
+
+	// 种类不匹配时报错
+	if _, err := events[0].StreamEvent(); err == nil {
+		t.Error("expected error converting agent event to stream event")
+	}
+	stream, err := events[len(events)-1].StreamEvent()
+	if err != nil || stream.Type != llm.StreamEventTypeError || stream.Error == nil {
+		t.Errorf("unexpected stream event: %+v, %v", stream, err)
+	}
+}
+
+func TestUnmarshal_RejectsUnknownVersion(t *testing.T) {
+	tests := []string{
+		`{"kind":"agent","type":"start"}`,
+		`{"v":2,"kind":"agent","type":"start"}`,
+		`{"v":1,"kind":"other","type":"start"}`,
+		`not json`,
+	}
+	for _, data := range tests {
+		if _, err := Unmarshal([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestEncoder_RunnableStream(t *testing.T) {
+	executor := agent.NewRunnable(&agent.Config{LLMClient: &echoLLMClient{}, ToolRegistry: tools.NewRegistry()})
+	stream, err := executor.InvokeStream(context.Background(), &agent.InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "你好"}},
+	})
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for event := range stream {
+		if err := enc.Encode(FromAgentEvent(event)); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+	var last *Event
+	for {
+		event, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if event.RunID == "" || event.Timestamp.IsZero() {
+			t.Errorf("expected run id and timestamp: %+v", event)
+		}
+		last = event
+	}
+	if last == nil || last.Type != string(agent.AgentEventTypeEnd) || last.Result == nil {
+		t.Fatalf("expected end event with result, got %+v", last)
+	}
+	want := []llm.Message{{Role: llm.RoleUser, Content: "你好"}, {Role: llm.RoleAssistant, Content: "你好"}}
+	if !reflect.DeepEqual(last.Result.Messages, want) {
+		t.Errorf("unexpected result messages: %+v", last.Result.Messages)
+	}
+}
+
+// echoLLMClient 回显最后一条消息的模拟客户端
+type echoLLMClient struct{}
+
+func (c *echoLLMClient) Generate(ctx context.Context, req *llm.ModelRequest) (*llm.ModelResponse, error) {
+	return &llm.ModelResponse{Content: req.Messages[len(req.Messages)-1].Content, StopReason: "end_turn"}, nil
+}
+
+func (c *echoLLMClient) StreamGenerate(ctx context.Context, req *llm.ModelRequest) (<-chan llm.StreamEvent, error) {
+	resp, _ := c.Generate(ctx, req)
+	eventChan := make(chan llm.StreamEvent, 2)
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeText, Content: resp.Content}
+	eventChan <- llm.StreamEvent{Type: llm.StreamEventTypeEnd, StopReason: resp.StopReason, Done: true}
+	close(eventChan)
+	return eventChan, nil
+}
+
+func (c *echoLLMClient) CountTokens(messages []llm.Message) int {
+	return 0
+}
@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// AgentNodeConfig Agent 节点配置
+type AgentNodeConfig struct {
+	Agent     agent.Agent                            // 执行节点的 Agent
+	Input     func(state *agent.State) []llm.Message // 构造输入消息，默认使用状态中的全部消息
+	OutputKey string                                 // 非空时将最终回答写入元数据的该键，而不是追加到消息
+}
+
+// AgentNode 将 Agent 作为节点：以状态中的消息为输入，运行后追加新消息并合并文件和元数据
+func AgentNode(a agent.Agent) NodeFunc {
+	return AgentNodeWithConfig(AgentNodeConfig{Agent: a})
+}
+
+// AgentNodeWithConfig 按配置将 Agent 作为节点
+// Agent 在状态的副本上运行，只有它新增或修改的内容写回共享状态，并行分支互不覆盖
+func AgentNodeWithConfig(cfg AgentNodeConfig) NodeFunc {
+	return func(ctx context.Context, state *agent.State) error {
+		if cfg.Agent == nil {
+			return fmt.Errorf("agent node has no agent configured")
+		}
+		snapshot := state.Clone()
+		input := &agent.InvokeInput{
+			Messages: snapshot.Messages,
+			Files:    snapshot.Clone().Files,
+			Metadata: snapshot.Clone().Metadata,
+		}
+		if cfg.Input != nil {
+			input.Messages = cfg.Input(snapshot)
+		}
+
+		name, _ := ctx.Value(nodeNameKey{}).(string)
+		output, err := agent.InvokeNested(ctx, cfg.Agent, input, agent.SubAgentInfo{Name: name})
+		if err != nil {
+			return err
+		}
+
+		if cfg.OutputKey != "" {
+			state.SetMetadata(cfg.OutputKey, agent.LastAssistantMessage(output))
+		} else {
+			added := output.Messages
+			if len(added) >= len(input.Messages) {
+				added = added[len(input.Messages):]
+			}
+			for _, msg := range added {
+				state.AddMessage(msg)
+			}
+		}
+		for path, content := range output.Files {
+			if old, ok := snapshot.Files[path]; !ok || old != content {
+				state.SetFile(path, content)
+			}
+		}
+		for key, value := range output.Metadata {
+			if old, ok := snapshot.Metadata[key]; !ok || !reflect.DeepEqual(old, value) {
+				state.SetMetadata(key, value)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// fakeAgent 追加固定回答并写入文件和元数据的 Agent
+type fakeAgent struct {
+	answer string
+	inputs []*agent.InvokeInput
+}
+
+func (a *fakeAgent) Invoke(ctx context.Context, input *agent.InvokeInput) (*agent.InvokeOutput, error) {
+	a.inputs = append(a.inputs, input)
+	files := map[string]string{"/draft.md": a.answer}
+	for path, content := range input.Files {
+		files[path] = content
+	}
+	metadata := map[string]any{"author": "fake"}
+	for key, value := range input.Metadata {
+		metadata[key] = value
+	}
+	messages := append(append([]llm.Message{}, input.Messages...), llm.Message{Role: llm.RoleAssistant, Content: a.answer})
+	return &agent.InvokeOutput{Messages: messages, Files: files, Metadata: metadata}, nil
+}
+
+func (a *fakeAgent) InvokeStream(ctx context.Context, input *agent.InvokeInput) (<-chan agent.AgentEvent, error) {
+	output, _ := a.Invoke(ctx, input)
+	events := make(chan agent.AgentEvent, 2)
+	events <- agent.AgentEvent{Type: agent.AgentEventTypeStart}
+	events <- agent.AgentEvent{Type: agent.AgentEventTypeEnd, Metadata: map[string]any{
+		"messages": output.Messages, "files": output.Files, "metadata": output.Metadata,
+	}, Done: true}
+	close(events)
+	return events, nil
+}
+
+func TestAgentNode_MergesOutput(t *testing.T) {
+	state := agent.NewState()
+	state.AddMessage(llm.Message{Role: llm.RoleUser, Content: "write"})
+	state.SetFile("/notes.md", "notes")
+	state.SetMetadata("topic", "go")
+
+	inner := &fakeAgent{answer: "draft"}
+	if err := AgentNode(inner)(context.Background(), state); err != nil {
+		t.Fatalf("AgentNode failed: %v", err)
+	}
+
+	messages := state.GetMessages()
+	if len(messages) != 2 || messages[1].Content != "draft" {
+		t.Errorf("expected the answer to be appended, got %+v", messages)
+	}
+	if content, _ := state.GetFile("/draft.md"); content != "draft" {
+		t.Errorf("expected new file to be merged, got %q", content)
+	}
+	if author, _ := state.GetMetadata("author"); author != "fake" {
+		t.Errorf("expected new metadata to be merged, got %v", author)
+	}
+
+	// Agent 对输入的修改不影响共享状态
+	inner.inputs[0].Metadata["topic"] = "changed"
+	if topic, _ := state.GetMetadata("topic"); topic != "go" {
+		t.Errorf("agent input should be a copy, got %v", topic)
+	}
+}
+
+func TestAgentNode_OutputKey(t *testing.T) {
+	state := agent.NewState()
+	state.AddMessage(llm.Message{Role: llm.RoleUser, Content: "classify me"})
+
+	node := AgentNodeWithConfig(AgentNodeConfig{
+		Agent: &fakeAgent{answer: "bug"},
+		Input: func(state *agent.State) []llm.Message {
+			return []llm.Message{{Role: llm.RoleUser, Content: "Classify: " + state.Messages[0].Content}}
+		},
+		OutputKey: "category",
+	})
+	if err := node(context.Background(), state); err != nil {
+		t.Fatalf("AgentNode failed: %v", err)
+	}
+	if category, _ := state.GetMetadata("category"); category != "bug" {
+		t.Errorf("expected answer in metadata, got %v", category)
+	}
+	if len(state.GetMessages()) != 1 {
+		t.Errorf("messages should not change when OutputKey is set")
+	}
+}
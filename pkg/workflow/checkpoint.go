@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+)
+
+// Checkpoint 工作流运行的检查点（每一步完成后保存）
+// 状态经过 JSON 序列化，恢复后元数据中的数字为 float64、结构体为 map[string]any
+type Checkpoint struct {
+	RunID     string              `json:"run_id"`
+	Step      int                 `json:"step"`            // 已完成的步数
+	Next      []string            `json:"next"`            // 下一步要执行的节点
+	Joins     map[string][]string `json:"joins,omitempty"` // 汇合节点 -> 已完成的来源节点
+	State     *agent.State        `json:"state"`
+	Done      bool                `json:"done"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// Checkpointer 检查点存储
+type Checkpointer interface {
+	Save(ctx context.Context, cp *Checkpoint) error
+	Load(ctx context.Context, runID string) (*Checkpoint, error)
+}
+
+// encodeCheckpoint 序列化检查点
+func encodeCheckpoint(cp *Checkpoint) ([]byte, error) {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化检查点失败: %w", err)
+	}
+	return data, nil
+}
+
+// decodeCheckpoint 反序列化检查点，补全空的映射表
+func decodeCheckpoint(data []byte) (*Checkpoint, error) {
+	cp := &Checkpoint{State: agent.NewState()}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("解析检查点失败: %w", err)
+	}
+	if cp.State == nil {
+		cp.State = agent.NewState()
+	}
+	if cp.State.Files == nil {
+		cp.State.Files = make(map[string]string)
+	}
+	if cp.State.Metadata == nil {
+		cp.State.Metadata = make(map[string]any)
+	}
+	if cp.Joins == nil {
+		cp.Joins = make(map[string][]string)
+	}
+	return cp, nil
+}
+
+// MemoryCheckpointer 内存中的检查点存储（保存序列化后的副本）
+type MemoryCheckpointer struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+// NewMemoryCheckpointer 创建内存检查点存储
+func NewMemoryCheckpointer() *MemoryCheckpointer {
+	return &MemoryCheckpointer{data: make(map[string][]byte)}
+}
+
+// Save 保存检查点
+func (c *MemoryCheckpointer) Save(ctx context.Context, cp *Checkpoint) error {
+	data, err := encodeCheckpoint(cp)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[cp.RunID] = data
+	return nil
+}
+
+// Load 加载检查点
+func (c *MemoryCheckpointer) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	c.mu.Lock()
+	data, ok := c.data[runID]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("未找到检查点: %s", runID)
+	}
+	return decodeCheckpoint(data)
+}
+
+// BackendCheckpointer 通过后端存储检查点，每个运行一个 JSON 文件
+type BackendCheckpointer struct {
+	backend backend.Backend
+	dir     string
+}
+
+// NewBackendCheckpointer 创建后端检查点存储，dir 为空时使用 workflows/checkpoints
+func NewBackendCheckpointer(b backend.Backend, dir string) *BackendCheckpointer {
+	if dir == "" {
+		dir = "workflows/checkpoints"
+	}
+	return &BackendCheckpointer{backend: b, dir: dir}
+}
+
+// Save 保存检查点
+func (c *BackendCheckpointer) Save(ctx context.Context, cp *Checkpoint) error {
+	data, err := encodeCheckpoint(cp)
+	if err != nil {
+		return err
+	}
+	if _, err := c.backend.WriteFile(ctx, c.path(cp.RunID), string(data)); err != nil {
+		return fmt.Errorf("写入检查点失败: %w", err)
+	}
+	return nil
+}
+
+// Load 加载检查点
+func (c *BackendCheckpointer) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	content, err := c.backend.ReadFile(ctx, c.path(runID), 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("未找到检查点: %s: %w", runID, err)
+	}
+	return decodeCheckpoint([]byte(content))
+}
+
+// path 返回检查点文件路径
+func (c *BackendCheckpointer) path(runID string) string {
+	return path.Join(c.dir, runID+".json")
+}
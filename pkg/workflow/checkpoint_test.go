@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/backend"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+func TestBackendCheckpointer_RoundTrip(t *testing.T) {
+	checkpointer := NewBackendCheckpointer(backend.NewStateBackend(), "")
+	state := agent.NewState()
+	state.AddMessage(llm.Message{Role: llm.RoleUser, Content: "hi"})
+	state.SetFile("/a.txt", "a")
+	state.SetMetadata("count", 2)
+
+	ctx := context.Background()
+	err := checkpointer.Save(ctx, &Checkpoint{
+		RunID: "run-1",
+		Step:  3,
+		Next:  []string{"review"},
+		Joins: map[string][]string{"merge": {"a"}},
+		State: state,
+	})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	cp, err := checkpointer.Load(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cp.Step != 3 || cp.Next[0] != "review" || cp.Joins["merge"][0] != "a" {
+		t.Errorf("unexpected checkpoint: %+v", cp)
+	}
+	if msgs := cp.State.GetMessages(); len(msgs) != 1 || msgs[0].Content != "hi" {
+		t.Errorf("unexpected messages: %+v", msgs)
+	}
+	if content, _ := cp.State.GetFile("/a.txt"); content != "a" {
+		t.Errorf("unexpected file content: %q", content)
+	}
+	// 经过 JSON 序列化，数字恢复为 float64
+	if count, _ := cp.State.GetMetadata("count"); count != float64(2) {
+		t.Errorf("unexpected metadata: %v", count)
+	}
+
+	if _, err := checkpointer.Load(ctx, "missing"); err == nil {
+		t.Error("expected error for missing checkpoint")
+	}
+}
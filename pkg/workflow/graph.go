@@ -0,0 +1,196 @@
+// Package workflow 基于 agent.State 的图工作流引擎
+// 节点是操作共享状态的函数或 Agent，边可以是固定的、条件的或汇合的；
+// 同一步就绪的节点并行执行，允许有步数限制的环，支持流式事件和检查点恢复
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+)
+
+// End 表示工作流结束的虚拟节点
+const End = "__end__"
+
+// NodeFunc 节点函数，读写共享状态
+// 并行分支中的节点共享同一个状态，需通过 State 的方法（AddMessage、SetFile、SetMetadata 等）访问
+type NodeFunc func(ctx context.Context, state *agent.State) error
+
+// RouteFunc 条件边，根据状态返回下一个节点名称（返回 End 表示结束）
+type RouteFunc func(ctx context.Context, state *agent.State) (string, error)
+
+// join 汇合边：所有来源节点完成后才执行目标节点
+type join struct {
+	sources []string
+	target  string
+}
+
+// Graph 工作流图的构建器
+// 构建方法可链式调用，错误在 Compile 时统一返回
+type Graph struct {
+	nodes  map[string]NodeFunc
+	edges  map[string][]string
+	routes map[string]RouteFunc
+	joins  []join
+	entry  string
+	err    error
+}
+
+// NewGraph 创建工作流图
+func NewGraph() *Graph {
+	return &Graph{
+		nodes:  make(map[string]NodeFunc),
+		edges:  make(map[string][]string),
+		routes: make(map[string]RouteFunc),
+	}
+}
+
+// AddNode 添加节点，第一个添加的节点默认为入口
+func (g *Graph) AddNode(name string, fn NodeFunc) *Graph {
+	switch {
+	case name == "" || name == End:
+		g.fail(fmt.Errorf("invalid node name %q", name))
+	case fn == nil:
+		g.fail(fmt.Errorf("node %q has no function", name))
+	case g.nodes[name] != nil:
+		g.fail(fmt.Errorf("node %q already exists", name))
+	default:
+		g.nodes[name] = fn
+		if g.entry == "" {
+			g.entry = name
+		}
+	}
+	return g
+}
+
+// AddEdge 添加固定边；同一节点有多条出边时，目标节点在下一步并行执行
+func (g *Graph) AddEdge(from, to string) *Graph {
+	g.edges[from] = append(g.edges[from], to)
+	return g
+}
+
+// AddConditionalEdge 添加条件边，节点完成后由 route 决定下一个节点（与固定边互斥）
+func (g *Graph) AddConditionalEdge(from string, route RouteFunc) *Graph {
+	if g.routes[from] != nil {
+		g.fail(fmt.Errorf("node %q already has a conditional edge", from))
+	}
+	g.routes[from] = route
+	return g
+}
+
+// AddJoin 添加汇合边：sources 中的节点全部完成后执行 to（用于并行分支的汇合）
+func (g *Graph) AddJoin(sources []string, to string) *Graph {
+	for _, j := range g.joins {
+		if j.target == to {
+			g.fail(fmt.Errorf("node %q already has a join", to))
+		}
+	}
+	g.joins = append(g.joins, join{sources: sources, target: to})
+	return g
+}
+
+// SetEntry 设置入口节点
+func (g *Graph) SetEntry(name string) *Graph {
+	g.entry = name
+	return g
+}
+
+// fail 记录第一个构建错误
+func (g *Graph) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// Compile 校验图并创建可执行的工作流
+func (g *Graph) Compile(config *Config) (*Workflow, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	if g.nodes[g.entry] == nil {
+		return nil, fmt.Errorf("entry node %q does not exist", g.entry)
+	}
+	for from, targets := range g.edges {
+		if g.nodes[from] == nil {
+			return nil, fmt.Errorf("edge from unknown node %q", from)
+		}
+		if g.routes[from] != nil {
+			return nil, fmt.Errorf("node %q has both fixed and conditional edges", from)
+		}
+		for _, to := range targets {
+			if to != End && g.nodes[to] == nil {
+				return nil, fmt.Errorf("edge %s -> %s points to unknown node", from, to)
+			}
+		}
+	}
+	for from := range g.routes {
+		if g.nodes[from] == nil {
+			return nil, fmt.Errorf("conditional edge from unknown node %q", from)
+		}
+	}
+	for _, j := range g.joins {
+		if g.nodes[j.target] == nil {
+			return nil, fmt.Errorf("join target %q does not exist", j.target)
+		}
+		if len(j.sources) == 0 {
+			return nil, fmt.Errorf("join to %q has no sources", j.target)
+		}
+		for _, source := range j.sources {
+			if g.nodes[source] == nil {
+				return nil, fmt.Errorf("join source %q does not exist", source)
+			}
+		}
+	}
+
+	if config == nil {
+		config = &Config{}
+	}
+	maxSteps := config.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	return &Workflow{graph: g, config: config, maxSteps: maxSteps}, nil
+}
+
+// next 计算已完成节点之后要执行的节点（保持首次出现的顺序并去重）
+// pending 记录汇合边已完成的来源节点，会被更新
+func (g *Graph) next(ctx context.Context, completed []string, state *agent.State, pending map[string][]string) ([]string, error) {
+	var next []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != End && !seen[name] {
+			seen[name] = true
+			next = append(next, name)
+		}
+	}
+
+	for _, name := range completed {
+		if route := g.routes[name]; route != nil {
+			to, err := route(ctx, state)
+			if err != nil {
+				return nil, fmt.Errorf("route from %s failed: %w", name, err)
+			}
+			if to != End && g.nodes[to] == nil {
+				return nil, fmt.Errorf("route from %s returned unknown node %q", name, to)
+			}
+			add(to)
+		}
+		for _, to := range g.edges[name] {
+			add(to)
+		}
+
+		for _, j := range g.joins {
+			if !slices.Contains(j.sources, name) || slices.Contains(pending[j.target], name) {
+				continue
+			}
+			pending[j.target] = append(pending[j.target], name)
+			if len(pending[j.target]) == len(j.sources) {
+				delete(pending, j.target)
+				add(j.target)
+			}
+		}
+	}
+	return next, nil
+}
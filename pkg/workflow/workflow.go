@@ -0,0 +1,240 @@
+package workflow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+)
+
+const (
+	// defaultMaxSteps 默认的最大步数（限制环的执行次数）
+	defaultMaxSteps = 25
+	// RunIDKey 运行 ID 在状态元数据中的键；Invoke 时可通过 InvokeInput.Metadata 指定
+	RunIDKey = "run_id"
+)
+
+// Config 工作流配置
+type Config struct {
+	MaxSteps     int          // 最大步数（每一步并行执行所有就绪节点），0 表示默认值 25
+	Checkpointer Checkpointer // 非 nil 时每一步完成后保存检查点，可通过 Resume 恢复
+}
+
+// Workflow 编译后的工作流，实现 agent.Agent 接口
+type Workflow struct {
+	graph    *Graph
+	config   *Config
+	maxSteps int
+}
+
+// nodeNameKey 用于在上下文中存储当前节点名称
+type nodeNameKey struct{}
+
+// Invoke 以输入为初始状态执行工作流
+func (w *Workflow) Invoke(ctx context.Context, input *agent.InvokeInput) (*agent.InvokeOutput, error) {
+	cp := w.newRun(input)
+	// 非流式执行时不向外层事件流转发节点内部的事件
+	if err := w.run(agent.WithEventEmitter(ctx, nil), cp, nil); err != nil {
+		return nil, err
+	}
+	return output(cp), nil
+}
+
+// InvokeStream 以输入为初始状态流式执行工作流
+func (w *Workflow) InvokeStream(ctx context.Context, input *agent.InvokeInput) (<-chan agent.AgentEvent, error) {
+	return w.stream(ctx, w.newRun(input)), nil
+}
+
+// Resume 从检查点恢复运行（从最后完成的一步之后继续）；已完成的运行直接返回结果
+func (w *Workflow) Resume(ctx context.Context, runID string) (*agent.InvokeOutput, error) {
+	cp, err := w.load(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.run(agent.WithEventEmitter(ctx, nil), cp, nil); err != nil {
+		return nil, err
+	}
+	return output(cp), nil
+}
+
+// ResumeStream 从检查点流式恢复运行
+func (w *Workflow) ResumeStream(ctx context.Context, runID string) (<-chan agent.AgentEvent, error) {
+	cp, err := w.load(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return w.stream(ctx, cp), nil
+}
+
+// newRun 创建新运行的初始检查点
+func (w *Workflow) newRun(input *agent.InvokeInput) *Checkpoint {
+	state := agent.NewState()
+	state.Messages = append(state.Messages, input.Messages...)
+	for path, content := range input.Files {
+		state.Files[path] = content
+	}
+	for key, value := range input.Metadata {
+		state.Metadata[key] = value
+	}
+
+	runID, _ := state.Metadata[RunIDKey].(string)
+	if runID == "" {
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		runID = "run-" + time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+		state.Metadata[RunIDKey] = runID
+	}
+	return &Checkpoint{
+		RunID: runID,
+		Next:  []string{w.graph.entry},
+		Joins: make(map[string][]string),
+		State: state,
+	}
+}
+
+// load 加载检查点
+func (w *Workflow) load(ctx context.Context, runID string) (*Checkpoint, error) {
+	if w.config.Checkpointer == nil {
+		return nil, fmt.Errorf("workflow has no checkpointer configured")
+	}
+	cp, err := w.config.Checkpointer.Load(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint %s: %w", runID, err)
+	}
+	return cp, nil
+}
+
+// save 保存检查点
+func (w *Workflow) save(ctx context.Context, cp *Checkpoint) error {
+	if w.config.Checkpointer == nil {
+		return nil
+	}
+	cp.UpdatedAt = time.Now()
+	if err := w.config.Checkpointer.Save(ctx, cp); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// stream 在后台执行工作流并返回事件流
+func (w *Workflow) stream(ctx context.Context, cp *Checkpoint) <-chan agent.AgentEvent {
+	eventChan := make(chan agent.AgentEvent, 20)
+
+	go func() {
+		defer close(eventChan)
+
+		// 事件以工作流运行 ID 作为 RunID
+		emit := func(event agent.AgentEvent) {
+			agent.SendEvent(ctx, eventChan, agent.StampEvent(event, cp.RunID))
+		}
+		emit(agent.AgentEvent{Type: agent.AgentEventTypeStart})
+
+		if err := w.run(agent.WithEventEmitter(ctx, emit), cp, emit); err != nil {
+			emit(agent.AgentEvent{Type: agent.AgentEventTypeError, Error: err, Done: true})
+			return
+		}
+
+		out := output(cp)
+		emit(agent.AgentEvent{
+			Type: agent.AgentEventTypeEnd,
+			Metadata: map[string]any{
+				"messages": out.Messages,
+				"files":    out.Files,
+				"metadata": out.Metadata,
+			},
+			Done: true,
+		})
+	}()
+
+	return eventChan
+}
+
+// run 按步执行工作流直到没有就绪节点
+// 每一步并行执行所有就绪节点，全部成功后计算下一步的节点并保存检查点；
+// 失败时检查点停留在上一步，恢复时重新执行失败的这一步
+func (w *Workflow) run(ctx context.Context, cp *Checkpoint, emit agent.EventEmitter) error {
+	if cp.Done {
+		return nil
+	}
+	if cp.Step == 0 {
+		if err := w.save(ctx, cp); err != nil {
+			return err
+		}
+	}
+
+	for len(cp.Next) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if cp.Step >= w.maxSteps {
+			return fmt.Errorf("workflow exceeded the maximum of %d steps", w.maxSteps)
+		}
+		step := cp.Step + 1
+
+		errs := make([]error, len(cp.Next))
+		var wg sync.WaitGroup
+		for i, name := range cp.Next {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = w.runNode(ctx, name, step, cp.State, emit)
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+
+		next, err := w.graph.next(ctx, cp.Next, cp.State, cp.Joins)
+		if err != nil {
+			return err
+		}
+		cp.Step = step
+		cp.Next = next
+		if err := w.save(ctx, cp); err != nil {
+			return err
+		}
+
+		if emit != nil {
+			emit(agent.AgentEvent{Type: agent.AgentEventTypeIterationEnd, Iteration: step})
+		}
+	}
+
+	cp.Done = true
+	return w.save(ctx, cp)
+}
+
+// runNode 执行单个节点并发送 node_start / node_end 事件，panic 视为节点失败
+func (w *Workflow) runNode(ctx context.Context, name string, step int, state *agent.State, emit agent.EventEmitter) (err error) {
+	metadata := map[string]any{"node": name, "step": step}
+	if emit != nil {
+		emit(agent.AgentEvent{Type: agent.AgentEventTypeNodeStart, Iteration: step, Metadata: metadata})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			err = fmt.Errorf("node %s failed: %w", name, err)
+		}
+		if emit != nil {
+			emit(agent.AgentEvent{Type: agent.AgentEventTypeNodeEnd, Iteration: step, Metadata: metadata, Error: err})
+		}
+	}()
+
+	return w.graph.nodes[name](context.WithValue(ctx, nodeNameKey{}, name), state)
+}
+
+// output 根据检查点中的状态生成输出
+func output(cp *Checkpoint) *agent.InvokeOutput {
+	return &agent.InvokeOutput{
+		Messages: cp.State.GetMessages(),
+		Files:    cp.State.Files,
+		Metadata: cp.State.Metadata,
+	}
+}
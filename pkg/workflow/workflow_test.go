@@ -0,0 +1,269 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhoucx/deepagents-go/pkg/agent"
+	"github.com/zhoucx/deepagents-go/pkg/llm"
+)
+
+// recorder 记录节点执行顺序
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) node(name string) NodeFunc {
+	return func(ctx context.Context, state *agent.State) error {
+		r.mu.Lock()
+		r.calls = append(r.calls, name)
+		r.mu.Unlock()
+		state.AddMessage(llm.Message{Role: llm.RoleAssistant, Content: name})
+		return nil
+	}
+}
+
+func (r *recorder) count(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, call := range r.calls {
+		if call == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWorkflow_ConditionalLoop(t *testing.T) {
+	rec := &recorder{}
+	review := func(ctx context.Context, state *agent.State) error {
+		n, _ := state.GetMetadata("reviews")
+		count, _ := n.(int)
+		state.SetMetadata("reviews", count+1)
+		return rec.node("review")(ctx, state)
+	}
+
+	wf, err := NewGraph().
+		AddNode("classify", rec.node("classify")).
+		AddNode("retrieve", rec.node("retrieve")).
+		AddNode("draft", rec.node("draft")).
+		AddNode("review", review).
+		AddEdge("classify", "retrieve").
+		AddEdge("retrieve", "draft").
+		AddEdge("draft", "review").
+		AddConditionalEdge("review", func(ctx context.Context, state *agent.State) (string, error) {
+			if n, _ := state.GetMetadata("reviews"); n.(int) < 3 {
+				return "draft", nil
+			}
+			return End, nil
+		}).
+		Compile(nil)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	output, err := wf.Invoke(context.Background(), &agent.InvokeInput{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "question"}},
+		Metadata: map[string]any{RunIDKey: "run-1"},
+	})
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+
+	want := "classify retrieve draft review draft review draft review"
+	if got := strings.Join(rec.calls, " "); got != want {
+		t.Errorf("unexpected order:\n got %s\nwant %s", got, want)
+	}
+	if len(output.Messages) != 9 || output.Metadata[RunIDKey] != "run-1" {
+		t.Errorf("unexpected output: %d messages, metadata %v", len(output.Messages), output.Metadata)
+	}
+}
+
+func TestWorkflow_ParallelJoin(t *testing.T) {
+	rec := &recorder{}
+	started := make(chan struct{})
+	// a 和 b 并行执行：b 等待 a 开始后才完成
+	a := func(ctx context.Context, state *agent.State) error {
+		close(started)
+		return rec.node("a")(ctx, state)
+	}
+	b := func(ctx context.Context, state *agent.State) error {
+		<-started
+		return rec.node("b")(ctx, state)
+	}
+	merge := func(ctx context.Context, state *agent.State) error {
+		if rec.count("a2") != 1 || rec.count("b") != 1 {
+			return fmt.Errorf("merge ran before both branches finished: %v", rec.calls)
+		}
+		return rec.node("merge")(ctx, state)
+	}
+
+	wf, err := NewGraph().
+		AddNode("start", rec.node("start")).
+		AddNode("a", a).
+		AddNode("a2", rec.node("a2")).
+		AddNode("b", b).
+		AddNode("merge", merge).
+		AddEdge("start", "a").
+		AddEdge("start", "b").
+		AddEdge("a", "a2").
+		AddJoin([]string{"a2", "b"}, "merge").
+		AddEdge("merge", End).
+		Compile(nil)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	output, err := wf.Invoke(context.Background(), &agent.InvokeInput{})
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if rec.count("merge") != 1 || len(output.Messages) != 5 {
+		t.Errorf("unexpected calls %v with %d messages", rec.calls, len(output.Messages))
+	}
+}
+
+func TestWorkflow_MaxSteps(t *testing.T) {
+	rec := &recorder{}
+	wf, err := NewGraph().
+		AddNode("loop", rec.node("loop")).
+		AddEdge("loop", "loop").
+		Compile(&Config{MaxSteps: 5})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if _, err := wf.Invoke(context.Background(), &agent.InvokeInput{}); err == nil || !strings.Contains(err.Error(), "maximum of 5 steps") {
+		t.Errorf("expected step limit error, got %v", err)
+	}
+	if rec.count("loop") != 5 {
+		t.Errorf("expected 5 iterations, got %d", rec.count("loop"))
+	}
+}
+
+func TestGraph_CompileErrors(t *testing.T) {
+	noop := func(ctx context.Context, state *agent.State) error { return nil }
+	route := func(ctx context.Context, state *agent.State) (string, error) { return End, nil }
+
+	tests := map[string]*Graph{
+		"empty":              NewGraph(),
+		"duplicate node":     NewGraph().AddNode("a", noop).AddNode("a", noop),
+		"reserved name":      NewGraph().AddNode(End, noop),
+		"unknown entry":      NewGraph().AddNode("a", noop).SetEntry("b"),
+		"unknown edge":       NewGraph().AddNode("a", noop).AddEdge("a", "b"),
+		"fixed and routed":   NewGraph().AddNode("a", noop).AddNode("b", noop).AddEdge("a", "b").AddConditionalEdge("a", route),
+		"unknown route from": NewGraph().AddNode("a", noop).AddConditionalEdge("b", route),
+		"unknown join":       NewGraph().AddNode("a", noop).AddJoin([]string{"a", "x"}, "a"),
+	}
+	for name, g := range tests {
+		if _, err := g.Compile(nil); err == nil {
+			t.Errorf("%s: expected compile error", name)
+		}
+	}
+}
+
+func TestWorkflow_Stream(t *testing.T) {
+	rec := &recorder{}
+	inner := &fakeAgent{answer: "drafted"}
+	wf, err := NewGraph().
+		AddNode("plan", rec.node("plan")).
+		AddNode("draft", AgentNode(inner)).
+		AddEdge("plan", "draft").
+		Compile(nil)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	stream, err := wf.InvokeStream(context.Background(), &agent.InvokeInput{})
+	if err != nil {
+		t.Fatalf("InvokeStream failed: %v", err)
+	}
+
+	var nodes []string
+	var nested int
+	var final []llm.Message
+	for event := range stream {
+		switch {
+		case event.SubAgent != nil:
+			nested++
+			if event.SubAgent.Name != "draft" {
+				t.Errorf("expected nested events tagged with the node name, got %+v", event.SubAgent)
+			}
+		case event.Type == agent.AgentEventTypeNodeStart:
+			nodes = append(nodes, fmt.Sprintf("%s@%d", event.Metadata["node"], event.Iteration))
+		case event.Type == agent.AgentEventTypeEnd:
+			final, _ = event.Metadata["messages"].([]llm.Message)
+		case event.Type == agent.AgentEventTypeError:
+			t.Fatalf("unexpected error: %v", event.Error)
+		}
+	}
+	if strings.Join(nodes, " ") != "plan@1 draft@2" {
+		t.Errorf("unexpected node events: %v", nodes)
+	}
+	if nested == 0 {
+		t.Error("expected agent node events to be forwarded")
+	}
+	if len(final) != 2 || final[1].Content != "drafted" {
+		t.Errorf("unexpected final messages: %+v", final)
+	}
+}
+
+func TestWorkflow_Resume(t *testing.T) {
+	rec := &recorder{}
+	fail := true
+	flaky := func(ctx context.Context, state *agent.State) error {
+		if fail {
+			return errors.New("temporary outage")
+		}
+		return rec.node("flaky")(ctx, state)
+	}
+
+	checkpointer := NewMemoryCheckpointer()
+	wf, err := NewGraph().
+		AddNode("first", rec.node("first")).
+		AddNode("flaky", flaky).
+		AddNode("last", rec.node("last")).
+		AddEdge("first", "flaky").
+		AddEdge("flaky", "last").
+		Compile(&Config{Checkpointer: checkpointer})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	_, err = wf.Invoke(context.Background(), &agent.InvokeInput{Metadata: map[string]any{RunIDKey: "run-resume"}})
+	if err == nil || !strings.Contains(err.Error(), "node flaky failed: temporary outage") {
+		t.Fatalf("expected flaky node error, got %v", err)
+	}
+	cp, err := checkpointer.Load(context.Background(), "run-resume")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cp.Step != 1 || cp.Next[0] != "flaky" || cp.Done {
+		t.Errorf("unexpected checkpoint: step=%d next=%v done=%v", cp.Step, cp.Next, cp.Done)
+	}
+
+	fail = false
+	output, err := wf.Resume(context.Background(), "run-resume")
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if strings.Join(rec.calls, " ") != "first flaky last" {
+		t.Errorf("completed steps should not rerun: %v", rec.calls)
+	}
+	if len(output.Messages) != 3 {
+		t.Errorf("expected 3 messages, got %d", len(output.Messages))
+	}
+
+	// 已完成的运行直接返回结果
+	if _, err := wf.Resume(context.Background(), "run-resume"); err != nil || len(rec.calls) != 3 {
+		t.Errorf("resuming a finished run should be a no-op: %v %v", err, rec.calls)
+	}
+	if _, err := wf.Resume(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown run")
+	}
+}